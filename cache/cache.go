@@ -0,0 +1,173 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cache provides a cache abstraction with pluggable backends, caches
+// are configured by properties with the prefix spring.cache.<name>.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/clock"
+)
+
+// Cache is the interface that cache backends implement.
+type Cache interface {
+	// Get returns the value of the key, and whether the key is found.
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+	// Set sets the value of the key, the key never expires if ttl is zero.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Delete deletes the key.
+	Delete(ctx context.Context, key string) error
+	// Clear deletes all keys.
+	Clear(ctx context.Context) error
+}
+
+// Config is the configuration of a cache.
+type Config struct {
+	Type    string        `value:"${type:=local}"`     // backend type
+	TTL     time.Duration `value:"${ttl:=0s}"`         // default ttl of keys, zero means never expire
+	MaxSize int           `value:"${max-size:=10000}"` // max keys of local cache, zero means unlimited
+}
+
+// Factory creates a Cache for a backend type.
+type Factory func(name string, config Config) (Cache, error)
+
+var (
+	factoryMutex sync.RWMutex
+	factories    = map[string]Factory{
+		"local": func(name string, config Config) (Cache, error) {
+			return NewLocal(config), nil
+		},
+	}
+)
+
+// RegisterFactory registers a Factory for the backend type, so that backends
+// such as redis can be plugged in by setting spring.cache.<name>.type.
+func RegisterFactory(typ string, f Factory) {
+	factoryMutex.Lock()
+	defer factoryMutex.Unlock()
+	factories[typ] = f
+}
+
+func getFactory(typ string) (Factory, bool) {
+	factoryMutex.RLock()
+	defer factoryMutex.RUnlock()
+	f, ok := factories[typ]
+	return f, ok
+}
+
+// Manager holds the named caches.
+type Manager struct {
+	Clock  clock.Clock `autowire:"?"` // passed to caches having SetClock, real time if nil
+	once   sync.Once
+	caches map[string]Cache
+}
+
+// NewManager returns a Manager, it's registered as a bean and bound to the
+// properties with the prefix spring.cache, for example:
+//
+//	spring.cache.users.type=local
+//	spring.cache.users.ttl=10m
+//	spring.cache.users.max-size=1000
+func NewManager(configs map[string]Config) (*Manager, error) {
+	m := &Manager{caches: make(map[string]Cache)}
+	for name, config := range configs {
+		f, ok := getFactory(config.Type)
+		if !ok {
+			return nil, fmt.Errorf("cache %q: unknown type %q", name, config.Type)
+		}
+		c, err := f(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("cache %q: %w", name, err)
+		}
+		m.caches[name] = c
+	}
+	return m, nil
+}
+
+// Cache returns the cache named name, it returns an error if not found.
+func (m *Manager) Cache(name string) (Cache, error) {
+	m.once.Do(func() {
+		if m.Clock == nil {
+			return
+		}
+		for _, c := range m.caches {
+			if s, ok := c.(interface{ SetClock(clock.Clock) }); ok {
+				s.SetClock(m.Clock)
+			}
+		}
+	})
+	c, ok := m.caches[name]
+	if !ok {
+		return nil, fmt.Errorf("cache %q not found", name)
+	}
+	return c, nil
+}
+
+// Names returns the sorted names of all caches.
+func (m *Manager) Names() []string {
+	var names []string
+	for name := range m.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Of returns a constructor that gets the cache named name from the Manager,
+// it can be used to register a Cache bean, for example:
+//
+//	gs.Provide(cache.Of("users")).Name("users")
+func Of(name string) func(m *Manager) (Cache, error) {
+	return func(m *Manager) (Cache, error) {
+		return m.Cache(name)
+	}
+}
+
+// GetOrLoad returns the cached value of the key, or calls loader and caches
+// its result when the key isn't found. It's the way to cache the results of
+// methods, for example:
+//
+//	func (s *UserService) GetUser(ctx context.Context, id string) (*User, error) {
+//		v, err := cache.GetOrLoad(ctx, s.Cache, "user:"+id, 0, func(ctx context.Context) (interface{}, error) {
+//			return s.loadUser(ctx, id)
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return v.(*User), nil
+//	}
+func GetOrLoad(ctx context.Context, c Cache, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	v, ok, err := c.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return v, nil
+	}
+	if v, err = loader(ctx); err != nil {
+		return nil, err
+	}
+	if err = c.Set(ctx, key, v, ttl); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/cache"
+	"github.com/go-spring/spring-core/clock"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lru", func(t *testing.T) {
+		c := cache.NewLocal(cache.Config{MaxSize: 2})
+		assert.Nil(t, c.Set(ctx, "a", 1, 0))
+		assert.Nil(t, c.Set(ctx, "b", 2, 0))
+		_, ok, _ := c.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Nil(t, c.Set(ctx, "c", 3, 0))
+		_, ok, _ = c.Get(ctx, "b")
+		assert.False(t, ok)
+		v, ok, _ := c.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, v, 1)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c := cache.NewLocal(cache.Config{TTL: 10 * time.Millisecond})
+		assert.Nil(t, c.Set(ctx, "a", 1, 0))
+		assert.Nil(t, c.Set(ctx, "b", 2, time.Hour))
+		time.Sleep(20 * time.Millisecond)
+		_, ok, _ := c.Get(ctx, "a")
+		assert.False(t, ok)
+		_, ok, _ = c.Get(ctx, "b")
+		assert.True(t, ok)
+	})
+
+	t.Run("delete and clear", func(t *testing.T) {
+		c := cache.NewLocal(cache.Config{})
+		assert.Nil(t, c.Set(ctx, "a", 1, 0))
+		assert.Nil(t, c.Set(ctx, "b", 2, 0))
+		assert.Nil(t, c.Delete(ctx, "a"))
+		_, ok, _ := c.Get(ctx, "a")
+		assert.False(t, ok)
+		assert.Nil(t, c.Clear(ctx))
+		_, ok, _ = c.Get(ctx, "b")
+		assert.False(t, ok)
+	})
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLocal(cache.Config{})
+
+	count := 0
+	loader := func(ctx context.Context) (interface{}, error) {
+		count++
+		return "user", nil
+	}
+	for i := 0; i < 2; i++ {
+		v, err := cache.GetOrLoad(ctx, c, "user:1", 0, loader)
+		assert.Nil(t, err)
+		assert.Equal(t, v, "user")
+	}
+	assert.Equal(t, count, 1)
+
+	_, err := cache.GetOrLoad(ctx, c, "user:2", 0, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("not found")
+	})
+	assert.Error(t, err, "not found")
+	_, ok, _ := c.Get(ctx, "user:2")
+	assert.False(t, ok)
+}
+
+func TestManager(t *testing.T) {
+
+	_, err := cache.NewManager(map[string]cache.Config{
+		"users": {Type: "unknown"},
+	})
+	assert.Error(t, err, "cache \"users\": unknown type \"unknown\"")
+
+	cache.RegisterFactory("mock", func(name string, config cache.Config) (cache.Cache, error) {
+		return cache.NewLocal(config), nil
+	})
+
+	m, err := cache.NewManager(map[string]cache.Config{
+		"users":  {Type: "local"},
+		"orders": {Type: "mock"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, m.Names(), []string{"orders", "users"})
+
+	c, err := cache.Of("users")(m)
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+
+	_, err = m.Cache("unknown")
+	assert.Error(t, err, "cache \"unknown\" not found")
+
+	f := clock.NewFake(time.Now())
+	m, err = cache.NewManager(map[string]cache.Config{
+		"users": {Type: "local", TTL: time.Minute},
+	})
+	assert.Nil(t, err)
+	m.Clock = f
+	c, err = m.Cache("users")
+	assert.Nil(t, err)
+	ctx := context.Background()
+	assert.Nil(t, c.Set(ctx, "a", 1, 0))
+	f.Advance(30 * time.Second)
+	_, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+	f.Advance(31 * time.Second)
+	_, ok, _ = c.Get(ctx, "a")
+	assert.False(t, ok)
+}
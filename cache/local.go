@@ -0,0 +1,125 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/clock"
+)
+
+type localEntry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+// Local is an in-memory Cache that evicts the least recently used keys when
+// the size exceeds MaxSize.
+type Local struct {
+	mutex   sync.Mutex
+	clock   clock.Clock
+	ttl     time.Duration
+	maxSize int
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// NewLocal returns an in-memory Cache.
+func NewLocal(config Config) *Local {
+	return &Local{
+		clock:   clock.Real(),
+		ttl:     config.TTL,
+		maxSize: config.MaxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// SetClock sets the Clock used to expire keys, it's useful in tests.
+func (c *Local) SetClock(clk clock.Clock) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.clock = clock.Or(clk)
+}
+
+func (c *Local) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := e.Value.(*localEntry)
+	if !entry.expireAt.IsZero() && c.clock.Now().After(entry.expireAt) {
+		c.remove(e)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(e)
+	return entry.value, true, nil
+}
+
+// Set sets the value of the key, it uses the configured ttl if ttl is zero.
+func (c *Local) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	entry := &localEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expireAt = c.clock.Now().Add(ttl)
+	}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return nil
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+func (c *Local) Delete(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	return nil
+}
+
+func (c *Local) Clear(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	return nil
+}
+
+func (c *Local) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*localEntry).key)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package clock provides a time abstraction, so that time-dependent logic
+// such as ttl, backoff and periodic jobs can be tested with a fake clock.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the interface of the time source.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTicker returns a Ticker that ticks every d.
+	NewTicker(d time.Duration) Ticker
+	// Sleep waits d, it returns ctx.Err() when ctx is done before d elapses.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Ticker is the interface of a ticker created by Clock.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+}
+
+type realClock struct{}
+
+var system = &realClock{}
+
+// Real returns the Clock backed by the system time.
+func Real() Clock {
+	return system
+}
+
+// Or returns c if it isn't nil, otherwise the real Clock.
+func Or(c Clock) Clock {
+	if c == nil {
+		return system
+	}
+	return c
+}
+
+func (*realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (*realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{t: time.NewTicker(d)}
+}
+
+func (*realClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t *realTicker) Stop() {
+	t.t.Stop()
+}
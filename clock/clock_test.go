@@ -0,0 +1,70 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/clock"
+)
+
+func TestFake(t *testing.T) {
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := clock.NewFake(start)
+	assert.Equal(t, f.Now(), start)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Sleep(context.Background(), time.Minute)
+	}()
+	for f.Sleepers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	f.Advance(30 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("sleep returned too early")
+	default:
+	}
+
+	f.Advance(30 * time.Second)
+	assert.Nil(t, <-done)
+	assert.Equal(t, f.Now(), start.Add(time.Minute))
+
+	ticker := f.NewTicker(10 * time.Second)
+	f.Advance(25 * time.Second)
+	assert.Equal(t, <-ticker.C(), start.Add(70*time.Second))
+	ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := f.Sleep(ctx, time.Minute)
+	assert.Error(t, err, "context canceled")
+	assert.Equal(t, f.Sleepers(), 0)
+}
+
+func TestReal(t *testing.T) {
+	c := clock.Or(nil)
+	assert.Equal(t, c, clock.Real())
+	err := c.Sleep(context.Background(), time.Millisecond)
+	assert.Nil(t, err)
+}
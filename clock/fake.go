@@ -0,0 +1,150 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves forward by Advance, sleepers and
+// tickers are triggered when the time reaches their deadlines.
+type Fake struct {
+	mutex    sync.Mutex
+	now      time.Time
+	sleepers []*fakeSleeper
+	tickers  []*fakeTicker
+}
+
+type fakeSleeper struct {
+	until time.Time
+	done  chan struct{}
+}
+
+// NewFake returns a Fake clock starting at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the current fake time.
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+// Sleep waits until the fake time is advanced by d.
+func (f *Fake) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	f.mutex.Lock()
+	s := &fakeSleeper{until: f.now.Add(d), done: make(chan struct{})}
+	f.sleepers = append(f.sleepers, s)
+	f.mutex.Unlock()
+	select {
+	case <-ctx.Done():
+		f.removeSleeper(s)
+		return ctx.Err()
+	case <-s.done:
+		return nil
+	}
+}
+
+func (f *Fake) removeSleeper(s *fakeSleeper) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for i, x := range f.sleepers {
+		if x == s {
+			f.sleepers = append(f.sleepers[:i], f.sleepers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Sleepers returns the number of the goroutines blocked in Sleep, tests
+// can wait for it before calling Advance.
+func (f *Fake) Sleepers() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.sleepers)
+}
+
+// NewTicker returns a Ticker that ticks every d of the fake time.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	t := &fakeTicker{f: f, d: d, next: f.now.Add(d), c: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance moves the fake time forward by d, wakes up the sleepers and fires
+// the tickers whose deadlines are reached. Like time.Ticker, ticks are
+// dropped when the receiver is slow.
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+
+	sleepers := f.sleepers[:0]
+	for _, s := range f.sleepers {
+		if f.now.Before(s.until) {
+			sleepers = append(sleepers, s)
+			continue
+		}
+		close(s.done)
+	}
+	f.sleepers = sleepers
+
+	for _, t := range f.tickers {
+		for !f.now.Before(t.next) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+}
+
+type fakeTicker struct {
+	f    *Fake
+	d    time.Duration
+	next time.Time
+	c    chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.f.mutex.Lock()
+	defer t.f.mutex.Unlock()
+	for i, x := range t.f.tickers {
+		if x == t {
+			t.f.tickers = append(t.f.tickers[:i], t.f.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -35,11 +35,28 @@ var (
 
 // ParsedTag a value tag includes at most three parts: required key, optional
 // default value, and optional splitter, the syntax is ${key:=value}||splitter.
+// The splitter can be a chain of splitters and normalizers separated by >>,
+// such as ${key}||csv>>trim>>unique, or ${key}>>csv>>trim>>unique for short.
+// The key starting with / like ${/spring.app.name} is an absolute key, which
+// ignores the key prefix of the enclosing struct or namespace.
+// The modifier !empty right after the } like ${key}!empty makes binding fail
+// when the property is present but empty.
 type ParsedTag struct {
 	Key      string // short property key
 	Def      string // default value
 	HasDef   bool   // has default value
-	Splitter string // splitter's name
+	Splitter string // splitter's name, or a chain of splitters and normalizers
+	NotEmpty bool   // property can't be present but empty
+}
+
+// notEmptyModifier is the tag modifier that makes binding fail when the
+// property is present but empty, such as ${db.url}!empty.
+const notEmptyModifier = "!empty"
+
+// errEmptyValue returns the error of a present but empty property.
+func errEmptyValue(key string) error {
+	err := fmt.Errorf("property %q is present but empty", key)
+	return util.Wrapf(err, code.FileLine(), "resolve property %q error", key)
 }
 
 // ParseTag parses a value tag, returns its key, and default value, and splitter.
@@ -62,8 +79,15 @@ func ParseTag(tag string) (ret ParsedTag, err error) {
 		err = util.Wrapf(err, code.FileLine(), "parse tag %q error", tag)
 		return
 	}
+	tail := strings.TrimSpace(tag[j+1:])
+	if strings.HasPrefix(tail, notEmptyModifier) {
+		ret.NotEmpty = true
+		tail = strings.TrimSpace(tail[len(notEmptyModifier):])
+	}
 	if i > j {
 		ret.Splitter = strings.TrimSpace(tag[i+2:])
+	} else if strings.HasPrefix(tail, chainSep) {
+		ret.Splitter = strings.TrimSpace(tail[len(chainSep):])
 	}
 	ss := strings.SplitN(tag[k+2:j], ":=", 2)
 	ret.Key = ss[0]
@@ -92,7 +116,10 @@ func (param *BindParam) BindTag(tag string, validate string) error {
 		parsedTag.Key = "ANONYMOUS"
 	}
 	param.Tag = parsedTag
-	if param.Key == "" {
+	if strings.HasPrefix(parsedTag.Key, "/") { // absolute key
+		param.Tag.Key = parsedTag.Key[1:]
+		param.Key = param.Tag.Key
+	} else if param.Key == "" {
 		param.Key = parsedTag.Key
 	} else if parsedTag.Key != "" {
 		param.Key = param.Key + "." + parsedTag.Key
@@ -103,25 +130,41 @@ func (param *BindParam) BindTag(tag string, validate string) error {
 
 type Filter func(i interface{}, param BindParam) (bool, error)
 
+// isValueType returns whether t is a value type, the types that have
+// converters and their slices are also value types, such as []net.IP.
+func isValueType(t reflect.Type) bool {
+	if util.IsValueType(t) {
+		return true
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return converters[t] != nil
+}
+
 // BindValue binds properties to a value.
 func BindValue(p *Properties, v reflect.Value, t reflect.Type, param BindParam, filter Filter) error {
 
-	if !util.IsValueType(t) {
+	// types that have converters are bound as a whole, such as net.IP.
+	fn := converters[t]
+
+	if fn == nil && !isValueType(t) {
 		err := errors.New("target should be value type")
 		return util.Wrapf(err, code.FileLine(), "bind %s error", param.Path)
 	}
 
-	switch v.Kind() {
-	case reflect.Map:
-		return bindMap(p, v, t, param, filter)
-	case reflect.Array:
-		err := errors.New("use slice instead of array")
-		return util.Wrapf(err, code.FileLine(), "bind %s error", param.Path)
-	case reflect.Slice:
-		return bindSlice(p, v, t, param, filter)
+	if fn == nil {
+		switch v.Kind() {
+		case reflect.Map:
+			return bindMap(p, v, t, param, filter)
+		case reflect.Array:
+			err := errors.New("use slice instead of array")
+			return util.Wrapf(err, code.FileLine(), "bind %s error", param.Path)
+		case reflect.Slice:
+			return bindSlice(p, v, t, param, filter)
+		}
 	}
 
-	fn := converters[t]
 	if fn == nil && v.Kind() == reflect.Struct {
 		if err := bindStruct(p, v, t, param, filter); err != nil {
 			return util.Wrapf(err, code.FileLine(), "bind %s error", param.Path)
@@ -258,6 +301,9 @@ func getSlice(p *Properties, et reflect.Type, param BindParam) (*Properties, err
 		}
 	}
 	if strVal == "" {
+		if param.Tag.NotEmpty {
+			return nil, errEmptyValue(param.Key)
+		}
 		return nil, nil
 	}
 
@@ -268,10 +314,8 @@ func getSlice(p *Properties, et reflect.Type, param BindParam) (*Properties, err
 
 	if s := param.Tag.Splitter; s == "" {
 		arrVal = strings.Split(strVal, ",")
-	} else if fn := splitters[s]; fn != nil {
-		if arrVal, err = fn(strVal); err != nil {
-			return nil, err
-		}
+	} else if arrVal, err = splitChain(strVal, s); err != nil {
+		return nil, util.Wrapf(err, code.FileLine(), "split property %q error", param.Key)
 	}
 
 	p = New()
@@ -284,6 +328,44 @@ func getSlice(p *Properties, et reflect.Type, param BindParam) (*Properties, err
 	return p, nil
 }
 
+// chainSep separates the splitters and normalizers in a chain.
+const chainSep = ">>"
+
+// splitChain splits s by the chain of splitters and normalizers in order. A
+// splitter splits every element and flattens the results, a normalizer
+// processes the whole []string. The value is split by comma first if the
+// chain doesn't start with a splitter.
+func splitChain(s string, chain string) ([]string, error) {
+	arr := []string{s}
+	for i, name := range strings.Split(chain, chainSep) {
+		name = strings.TrimSpace(name)
+		if fn, ok := splitters[name]; ok {
+			var ret []string
+			for _, v := range arr {
+				r, err := fn(v)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, r...)
+			}
+			arr = ret
+			continue
+		}
+		fn, ok := normalizers[name]
+		if !ok {
+			return nil, fmt.Errorf("splitter or normalizer %q not found", name)
+		}
+		if i == 0 {
+			arr = strings.Split(s, ",")
+		}
+		var err error
+		if arr, err = fn(arr); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
 // bindMap binds properties to a map value.
 func bindMap(p *Properties, v reflect.Value, t reflect.Type, param BindParam, filter Filter) (err error) {
 
@@ -361,6 +443,9 @@ func bindStruct(p *Properties, v reflect.Value, t reflect.Type, param BindParam,
 			if err := BindValue(p, fv, ft.Type, subParam, filter); err != nil {
 				return util.Wrapf(err, code.FileLine(), "bind %s error", param.Path)
 			}
+			if err := ValidateField(fv, ft.Tag); err != nil {
+				return util.Wrapf(err, code.FileLine(), "bind %s error", subParam.Path)
+			}
 			continue
 		}
 
@@ -391,19 +476,75 @@ func bindStruct(p *Properties, v reflect.Value, t reflect.Type, param BindParam,
 
 // resolve returns property references processed property value.
 func resolve(p *Properties, param BindParam) (string, error) {
+	return resolveKey(p, param, nil)
+}
+
+// resolveKey returns property value of the key, refs is the chain of keys
+// that are being resolved and used to detect cyclic references.
+func resolveKey(p *Properties, param BindParam, refs []string) (string, error) {
+	for i, key := range refs {
+		if key == param.Key {
+			chain := strings.Join(append(refs[i:len(refs):len(refs)], param.Key), " -> ")
+			err := fmt.Errorf("found cyclic property reference: %s", chain)
+			return "", util.Wrapf(err, code.FileLine(), "resolve property %q error", param.Key)
+		}
+	}
+	if len(refs) >= maxResolveDepth {
+		chain := strings.Join(append(refs[:len(refs):len(refs)], param.Key), " -> ")
+		err := fmt.Errorf("property reference depth exceeds %d: %s", maxResolveDepth, chain)
+		return "", util.Wrapf(err, code.FileLine(), "resolve property %q error", param.Key)
+	}
+	p.markUsed(param.Key)
 	val := p.storage.Get(param.Key)
+	if isEncrypted(val) {
+		s, err := decrypt(val)
+		if err != nil {
+			return "", util.Wrapf(err, code.FileLine(), "decrypt property %q error", param.Key)
+		}
+		return s, nil
+	}
 	if val != "" {
-		return resolveString(p, val)
+		return resolveRefs(p, val, append(refs[:len(refs):len(refs)], param.Key))
+	}
+	if param.Tag.NotEmpty && p.storage.Has(param.Key) {
+		return "", errEmptyValue(param.Key)
 	}
 	if param.Tag.HasDef {
-		return resolveString(p, param.Tag.Def)
+		if strings.HasPrefix(param.Tag.Def, defaultFuncPrefix) {
+			return callDefaultFunc(param)
+		}
+		return resolveRefs(p, param.Tag.Def, refs)
 	}
 	err := fmt.Errorf("property %q %w", param.Key, errNotExist)
 	return "", util.Wrapf(err, code.FileLine(), "resolve property %q error", param.Key)
 }
 
+// defaultFuncPrefix is the prefix of default value that calls a DefaultFunc.
+const defaultFuncPrefix = "@fn:"
+
+// callDefaultFunc returns the default value computed by a DefaultFunc.
+func callDefaultFunc(param BindParam) (string, error) {
+	name := strings.TrimPrefix(param.Tag.Def, defaultFuncPrefix)
+	fn, ok := defaultFuncs[name]
+	if !ok {
+		err := fmt.Errorf("default func %q not found", name)
+		return "", util.Wrapf(err, code.FileLine(), "resolve property %q error", param.Key)
+	}
+	s, err := fn()
+	if err != nil {
+		return "", util.Wrapf(err, code.FileLine(), "resolve property %q error", param.Key)
+	}
+	return s, nil
+}
+
 // resolveString returns property references processed string.
 func resolveString(p *Properties, s string) (string, error) {
+	return resolveRefs(p, s, nil)
+}
+
+// resolveRefs returns property references processed string, refs is the
+// chain of keys that are being resolved.
+func resolveRefs(p *Properties, s string, refs []string) (string, error) {
 
 	var (
 		length = len(s)
@@ -446,12 +587,12 @@ func resolveString(p *Properties, s string) (string, error) {
 		return "", util.Wrapf(err, code.FileLine(), "resolve string %q error", s)
 	}
 
-	s1, err := resolve(p, param)
+	s1, err := resolveKey(p, param, refs)
 	if err != nil {
 		return "", util.Wrapf(err, code.FileLine(), "resolve string %q error", s)
 	}
 
-	s2, err := resolveString(p, s[end+1:])
+	s2, err := resolveRefs(p, s[end+1:], refs)
 	if err != nil {
 		return "", util.Wrapf(err, code.FileLine(), "resolve string %q error", s)
 	}
@@ -18,6 +18,7 @@ package conf_test
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -216,3 +217,107 @@ func TestProperties_Bind(t *testing.T) {
 		assert.Equal(t, s.M, map[string]string{})
 	})
 }
+
+func TestDefaultFunc(t *testing.T) {
+
+	conf.RegisterDefaultFunc("defaultQueueName", func() (string, error) {
+		return "queue-host", nil
+	})
+	conf.RegisterDefaultFunc("failedQueueName", func() (string, error) {
+		return "", errors.New("no hostname")
+	})
+
+	t.Run("default", func(t *testing.T) {
+		var s struct {
+			Name string `value:"${queue.name:=@fn:defaultQueueName}"`
+		}
+		err := conf.New().Bind(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Name, "queue-host")
+	})
+
+	t.Run("property", func(t *testing.T) {
+		p := conf.New()
+		err := p.Set("queue.name", "queue")
+		assert.Nil(t, err)
+		var s struct {
+			Name string `value:"${queue.name:=@fn:defaultQueueName}"`
+		}
+		err = p.Bind(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Name, "queue")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var s struct {
+			Name string `value:"${queue.name:=@fn:unknown}"`
+		}
+		err := conf.New().Bind(&s)
+		assert.Error(t, err, "default func \"unknown\" not found")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var s struct {
+			Name string `value:"${queue.name:=@fn:failedQueueName}"`
+		}
+		err := conf.New().Bind(&s)
+		assert.Error(t, err, "no hostname")
+	})
+}
+
+func TestNotEmptyModifier(t *testing.T) {
+
+	p := conf.New()
+	assert.Nil(t, p.Set("db.url", ""))
+	assert.Nil(t, p.Set("db.hosts", ""))
+
+	t.Run("empty", func(t *testing.T) {
+		var s struct {
+			URL string `value:"${db.url:=mysql://localhost}!empty"`
+		}
+		err := p.Bind(&s)
+		assert.Error(t, err, "property \"db.url\" is present but empty")
+	})
+
+	t.Run("empty slice", func(t *testing.T) {
+		var s struct {
+			Hosts []string `value:"${db.hosts}!empty>>trim"`
+		}
+		err := p.Bind(&s)
+		assert.Error(t, err, "property \"db.hosts\" is present but empty")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var s struct {
+			Name string `value:"${db.name:=test}!empty"`
+		}
+		err := p.Bind(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Name, "test")
+	})
+
+	t.Run("without modifier", func(t *testing.T) {
+		var s struct {
+			URL string `value:"${db.url:=mysql://localhost}"`
+		}
+		err := p.Bind(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.URL, "mysql://localhost")
+	})
+}
+
+func TestAbsoluteKey(t *testing.T) {
+	p := conf.New()
+	assert.Nil(t, p.Set("app.name", "test"))
+	assert.Nil(t, p.Set("db.url", "mysql://root"))
+	var s struct {
+		DB struct {
+			URL  string `value:"${url}"`
+			Name string `value:"${/app.name}"`
+		} `value:"${db}"`
+	}
+	err := p.Bind(&s)
+	assert.Nil(t, err)
+	assert.Equal(t, s.DB.URL, "mysql://root")
+	assert.Equal(t, s.DB.Name, "test")
+}
@@ -15,24 +15,30 @@
  */
 
 // Package conf reads configuration from many format file, such as Java
-// properties, yaml, toml, etc.
+// properties, yaml, toml, hcl, etc.
 package conf
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/url"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-spring/spring-base/cast"
 	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/conf/hcl"
 	"github.com/go-spring/spring-core/conf/internal"
 	"github.com/go-spring/spring-core/conf/prop"
+	"github.com/go-spring/spring-core/conf/sops"
 	"github.com/go-spring/spring-core/conf/toml"
 	"github.com/go-spring/spring-core/conf/yaml"
 )
@@ -43,17 +49,72 @@ type Splitter func(string) ([]string, error)
 // Reader parses []byte into nested map[string]interface{}.
 type Reader func(b []byte) (map[string]interface{}, error)
 
+// Normalizer processes []string split from a property value, such as trimming
+// spaces or removing duplicates.
+type Normalizer func([]string) ([]string, error)
+
+// DefaultFunc returns a default value that depends on runtime data, such as
+// hostname or pod name.
+type DefaultFunc func() (string, error)
+
 var (
-	readers    = map[string]Reader{}
-	splitters  = map[string]Splitter{}
-	converters = map[reflect.Type]util.Converter{}
+	readers      = map[string]Reader{}
+	splitters    = map[string]Splitter{}
+	normalizers  = map[string]Normalizer{}
+	converters   = map[reflect.Type]util.Converter{}
+	defaultFuncs = map[string]DefaultFunc{}
 )
 
+// maxResolveDepth is the max depth of nested property references.
+var maxResolveDepth = 32
+
 func init() {
 
 	RegisterReader(prop.Read, ".properties")
 	RegisterReader(yaml.Read, ".yaml", ".yml")
 	RegisterReader(toml.Read, ".toml", ".tml")
+	RegisterReader(hcl.Read, ".hcl")
+	RegisterReader(sops.NewReader(sops.EnvKey(sops.DataKeyEnv)), ".sops.yaml", ".sops.yml", ".sops.json")
+
+	// splits string as a csv record, fields may be quoted.
+	RegisterSplitter("csv", func(s string) ([]string, error) {
+		r := csv.NewReader(strings.NewReader(s))
+		r.TrimLeadingSpace = true
+		return r.Read()
+	})
+
+	// trims spaces of every element.
+	RegisterNormalizer("trim", func(arr []string) ([]string, error) {
+		ret := make([]string, len(arr))
+		for i, s := range arr {
+			ret[i] = strings.TrimSpace(s)
+		}
+		return ret, nil
+	})
+
+	// removes empty elements.
+	RegisterNormalizer("nonempty", func(arr []string) ([]string, error) {
+		var ret []string
+		for _, s := range arr {
+			if s != "" {
+				ret = append(ret, s)
+			}
+		}
+		return ret, nil
+	})
+
+	// removes duplicate elements and keeps the first ones.
+	RegisterNormalizer("unique", func(arr []string) ([]string, error) {
+		var ret []string
+		m := make(map[string]struct{})
+		for _, s := range arr {
+			if _, ok := m[s]; !ok {
+				m[s] = struct{}{}
+				ret = append(ret, s)
+			}
+		}
+		return ret, nil
+	})
 
 	// converts string into time.Time. The string value may have its own
 	// time format defined after >> splitter, otherwise it uses a default
@@ -73,6 +134,35 @@ func init() {
 	RegisterConverter(func(s string) (time.Duration, error) {
 		return cast.ToDurationE(s)
 	})
+
+	// converts string into *time.Location by the IANA name, such as
+	// "Asia/Shanghai", "UTC" or "Local".
+	RegisterConverter(func(s string) (*time.Location, error) {
+		return time.LoadLocation(strings.TrimSpace(s))
+	})
+
+	// converts string into *url.URL, the URL may be relative.
+	RegisterConverter(func(s string) (*url.URL, error) {
+		return url.Parse(strings.TrimSpace(s))
+	})
+
+	// converts string into net.IP, both IPv4 and IPv6 are supported.
+	RegisterConverter(func(s string) (net.IP, error) {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", s)
+		}
+		return ip, nil
+	})
+
+	// converts string into net.IPNet by CIDR notation, such as "10.0.0.0/8".
+	RegisterConverter(func(s string) (net.IPNet, error) {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(s))
+		if err != nil {
+			return net.IPNet{}, err
+		}
+		return *ipNet, nil
+	})
 }
 
 // RegisterReader registers its Reader for some kind of file extension.
@@ -87,16 +177,55 @@ func RegisterSplitter(name string, fn Splitter) {
 	splitters[name] = fn
 }
 
+// RegisterNormalizer registers a Normalizer and named it, then it can be
+// chained after splitters like `value:"${list}>>csv>>trim>>unique"`.
+func RegisterNormalizer(name string, fn Normalizer) {
+	normalizers[name] = fn
+}
+
+// RegisterDefaultFunc registers a DefaultFunc and named it, then it can be
+// used as a default value like `value:"${queue.name:=@fn:defaultQueueName}"`.
+func RegisterDefaultFunc(name string, fn DefaultFunc) {
+	defaultFuncs[name] = fn
+}
+
+// SetMaxResolveDepth sets the max depth of nested property references, such
+// as a=${b}, b=${c}, c=${d}, resolving fails when the depth exceeds it.
+func SetMaxResolveDepth(depth int) {
+	if depth <= 0 {
+		panic(errors.New("max resolve depth should be positive"))
+	}
+	maxResolveDepth = depth
+}
+
 // RegisterConverter registers its converter for non-primitive type such as
-// time.Time, time.Duration, or other user-defined value type.
+// time.Time, time.Duration, or other user-defined value type. The converter
+// should be func(string)(type,error) and the type may be a pointer such as
+// *url.URL, it's also used for the elements of slices like []time.Duration.
+// There are built-in converters for time.Time, time.Duration, *time.Location,
+// *url.URL, net.IP and net.IPNet.
 func RegisterConverter(fn util.Converter) {
 	t := reflect.TypeOf(fn)
-	if !util.IsConverter(t) {
+	if !isConverter(t) {
 		panic(errors.New("converter should be func(string)(type,error)"))
 	}
 	converters[t.Out(0)] = fn
 }
 
+// isConverter returns whether t is a converter, besides the value types the
+// converter can also return a pointer such as *url.URL.
+func isConverter(t reflect.Type) bool {
+	if util.IsConverter(t) {
+		return true
+	}
+	return t.Kind() == reflect.Func &&
+		t.NumIn() == 1 &&
+		t.In(0).Kind() == reflect.String &&
+		t.NumOut() == 2 &&
+		t.Out(0).Kind() == reflect.Ptr &&
+		util.IsErrorType(t.Out(1))
+}
+
 // Properties stores the data with map[string]string and the keys are case-sensitive,
 // you can get one of them by its key, or bind some of them to a value.
 // There are too many formats of configuration files, and too many conflicts between
@@ -111,6 +240,19 @@ func RegisterConverter(fn util.Converter) {
 // by node. So `conf` uses a tree to strictly verify and a flat map to store.
 type Properties struct {
 	storage *internal.Storage
+	tracker *keyTracker
+}
+
+// keyTracker records the keys that have been read.
+type keyTracker struct {
+	mutex sync.Mutex
+	used  map[string]struct{}
+}
+
+func (t *keyTracker) mark(key string) {
+	t.mutex.Lock()
+	t.used[key] = struct{}{}
+	t.mutex.Unlock()
 }
 
 // New creates empty *Properties.
@@ -151,7 +293,20 @@ func (p *Properties) Load(file string) error {
 	if err != nil {
 		return err
 	}
-	return p.Bytes(b, filepath.Ext(file))
+	return p.Bytes(b, FileExt(file))
+}
+
+// FileExt returns the extension of the file name that selects its Reader.
+// The longest registered extension wins, so application.sops.yaml is read
+// by the Reader of .sops.yaml rather than that of .yaml.
+func FileExt(name string) string {
+	ext := filepath.Ext(name)
+	for s := range readers {
+		if len(s) > len(ext) && strings.HasSuffix(name, s) {
+			ext = s
+		}
+	}
+	return ext
 }
 
 // Read creates *Properties from io.Reader, ext is the file name extension.
@@ -195,10 +350,62 @@ func (p *Properties) Bytes(b []byte, ext string) error {
 	return nil
 }
 
+// Marshal encodes properties into []byte in the properties format, the keys
+// are sorted so that the result is stable, and can be loaded by Unmarshal in
+// other processes, for example, replicating configuration to followers.
+func Marshal(p *Properties) ([]byte, error) {
+	m := make(map[string]string)
+	for _, k := range p.Keys() {
+		m[k] = p.storage.Get(k) // keeps encrypted values
+	}
+	return prop.Write(m)
+}
+
+// Unmarshal creates *Properties from []byte encoded by Marshal.
+func Unmarshal(b []byte) (*Properties, error) {
+	return Bytes(b, ".properties")
+}
+
+// Copy returns a copy of the properties, the copy tracks its own read keys
+// if the source properties tracks.
 func (p *Properties) Copy() *Properties {
-	return &Properties{
+	c := &Properties{
 		storage: p.storage.Copy(),
 	}
+	if p.tracker != nil {
+		c.Track()
+	}
+	return c
+}
+
+// Track starts to record keys read by Get, Has, Resolve and Bind, so that
+// UnusedKeys can report keys that have never been consumed.
+func (p *Properties) Track() {
+	p.tracker = &keyTracker{used: make(map[string]struct{})}
+}
+
+// markUsed records the key as read when tracking.
+func (p *Properties) markUsed(key string) {
+	if p.tracker != nil {
+		p.tracker.mark(key)
+	}
+}
+
+// UnusedKeys returns sorted keys that have never been read since Track was
+// called, it returns nil if the properties doesn't track.
+func (p *Properties) UnusedKeys() []string {
+	if p.tracker == nil {
+		return nil
+	}
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	var keys []string
+	for _, k := range p.storage.Keys() {
+		if _, ok := p.tracker.used[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
 }
 
 // Keys returns all sorted keys.
@@ -208,11 +415,13 @@ func (p *Properties) Keys() []string {
 
 // Has returns whether key exists.
 func (p *Properties) Has(key string) bool {
+	p.markUsed(key)
 	return p.storage.Has(key)
 }
 
 type getArg struct {
 	def string
+	raw bool
 }
 
 type GetOption func(arg *getArg)
@@ -224,16 +433,35 @@ func Def(v string) GetOption {
 	}
 }
 
-// Get returns key's value, using Def to return a default value.
-func (p *Properties) Get(key string, opts ...GetOption) string {
-	val := p.storage.Get(key)
-	if val != "" {
-		return val
+// Raw returns the value as it is stored, values like `ENC(scheme,data)` are
+// not decrypted. It's used to copy properties without exposing plaintext.
+func Raw() GetOption {
+	return func(arg *getArg) {
+		arg.raw = true
 	}
+}
+
+// Get returns key's value, using Def to return a default value. The value
+// like `ENC(scheme,data)` is decrypted by the registered Decryptor. When
+// decrypting fails, Get returns an empty string rather than the ciphertext,
+// use Resolve("${key}") or Bind to get the error.
+func (p *Properties) Get(key string, opts ...GetOption) string {
+	p.markUsed(key)
 	arg := getArg{}
 	for _, opt := range opts {
 		opt(&arg)
 	}
+	val := p.storage.Get(key)
+	if val != "" {
+		if arg.raw {
+			return val
+		}
+		s, err := decrypt(val)
+		if err != nil {
+			return ""
+		}
+		return s
+	}
 	return arg.def
 }
 
@@ -329,9 +557,10 @@ func Tag(tag string) BindOption {
 // Bind binds properties to a value, the bind value can be primitive type,
 // map, slice, struct. When binding to struct, the tag 'value' indicates
 // which properties should be bind. The 'value' tags are defined by
-// value:"${a:=b|splitter}", 'a' is the key, 'b' is the default value,
+// value:"${a:=b}||splitter", 'a' is the key, 'b' is the default value,
 // 'splitter' is the Splitter's name when you want split string value
-// into []string value.
+// into []string value, it also can be a chain of splitters and normalizers
+// like value:"${a}>>csv>>trim>>unique".
 func (p *Properties) Bind(i interface{}, opts ...BindOption) error {
 
 	var v reflect.Value
@@ -20,6 +20,9 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"net"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -27,6 +30,7 @@ import (
 	"github.com/go-spring/spring-base/assert"
 	"github.com/go-spring/spring-base/cast"
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/validate"
 )
 
 func TestProperties_Load(t *testing.T) {
@@ -238,6 +242,35 @@ func TestProperties_Ref(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, s.KeyIsEmpty, "kie")
 	})
+
+	t.Run("cyclic reference", func(t *testing.T) {
+		p := conf.New()
+		_ = p.Set("a", "${b}")
+		_ = p.Set("b", "x-${c}")
+		_ = p.Set("c", "${a}")
+		_, err := p.Resolve("${a}")
+		assert.Error(t, err, "found cyclic property reference: a -> b -> c -> a")
+		var s struct {
+			B string `value:"${b}"`
+		}
+		err = p.Bind(&s)
+		assert.Error(t, err, "found cyclic property reference: b -> c -> a -> b")
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		conf.SetMaxResolveDepth(2)
+		defer conf.SetMaxResolveDepth(32)
+		p := conf.New()
+		_ = p.Set("a", "${b}")
+		_ = p.Set("b", "${c}")
+		_ = p.Set("c", "${d}")
+		_ = p.Set("d", "ok")
+		_, err := p.Resolve("${a}")
+		assert.Error(t, err, "property reference depth exceeds 2: a -> b -> c")
+		str, err := p.Resolve("${c}")
+		assert.Nil(t, err)
+		assert.Equal(t, str, "ok")
+	})
 }
 
 func TestBindSlice(t *testing.T) {
@@ -375,6 +408,8 @@ func TestResolve(t *testing.T) {
 	assert.NotNil(t, err)
 	str, _ = p.Resolve("my name is ${name} my name is ${name}")
 	assert.Equal(t, str, "my name is Jim my name is Jim")
+	str, _ = p.Resolve("my name is ${/name}")
+	assert.Equal(t, str, "my name is Jim")
 }
 
 func TestProperties_Has(t *testing.T) {
@@ -499,3 +534,321 @@ func TestSplitter(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, points, []image.Point{{X: 1, Y: 2}, {X: 3, Y: 4}})
 }
+
+func TestSplitterChain(t *testing.T) {
+
+	t.Run("csv", func(t *testing.T) {
+		p := conf.New()
+		err := p.Set("list", `a, "b,c",a,, d `)
+		assert.Nil(t, err)
+		var s []string
+		err = p.Bind(&s, conf.Tag("${list}>>csv>>trim>>nonempty>>unique"))
+		assert.Nil(t, err)
+		assert.Equal(t, s, []string{"a", "b,c", "d"})
+	})
+
+	t.Run("normalizer first", func(t *testing.T) {
+		var s []string
+		err := conf.New().Bind(&s, conf.Tag("${:= x , y ,x}||trim>>unique"))
+		assert.Nil(t, err)
+		assert.Equal(t, s, []string{"x", "y"})
+	})
+
+	t.Run("splitter and converter", func(t *testing.T) {
+		conf.RegisterConverter(PointConverter)
+		conf.RegisterSplitter("PointSplitter", PointSplitter)
+		var points []image.Point
+		err := conf.New().Bind(&points, conf.Tag("${:=(1,2)(3,4)(1,2)}>>PointSplitter>>unique"))
+		assert.Nil(t, err)
+		assert.Equal(t, points, []image.Point{{X: 1, Y: 2}, {X: 3, Y: 4}})
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var s []string
+		err := conf.New().Bind(&s, conf.Tag("${:=a,b}>>trim>>unknown"))
+		assert.Error(t, err, "splitter or normalizer \"unknown\" not found")
+	})
+}
+
+func TestMarshal(t *testing.T) {
+
+	p := conf.New()
+	err := p.Set("a.b", []string{"x", "y"})
+	assert.Nil(t, err)
+	err = p.Set("a.c", map[string]interface{}{"d": 3, "e": ""})
+	assert.Nil(t, err)
+	err = p.Set("s", "${a.c.d}")
+	assert.Nil(t, err)
+
+	b, err := conf.Marshal(p)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "a.b[0] = x\na.b[1] = y\na.c.d = 3\na.c.e = \ns = ${a.c.d}\n")
+
+	r, err := conf.Unmarshal(b)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Keys(), p.Keys())
+	for _, k := range p.Keys() {
+		assert.Equal(t, r.Get(k), p.Get(k))
+	}
+}
+
+func TestProperties_UnusedKeys(t *testing.T) {
+
+	p := conf.New()
+	assert.Nil(t, p.Set("a.b", "1"))
+	assert.Nil(t, p.Set("a.c", "2"))
+	assert.Nil(t, p.Set("d", "3"))
+	assert.Nil(t, p.Set("e", "4"))
+	assert.Nil(t, p.UnusedKeys())
+
+	p.Track()
+	assert.Equal(t, p.UnusedKeys(), []string{"a.b", "a.c", "d", "e"})
+
+	var s struct {
+		B int `value:"${a.b}"`
+		F int `value:"${f:=5}"`
+	}
+	assert.Nil(t, p.Bind(&s))
+	assert.Equal(t, p.Get("d"), "3")
+	assert.Equal(t, p.UnusedKeys(), []string{"a.c", "e"})
+
+	c := p.Copy()
+	assert.Equal(t, c.UnusedKeys(), []string{"a.b", "a.c", "d", "e"})
+}
+
+func TestDecryptor(t *testing.T) {
+
+	key := []byte("0123456789abcdef")
+	conf.RegisterDecryptor("AES", conf.AESDecryptor(key))
+	conf.RegisterDecryptor("REV", func(data string) (string, error) {
+		b := []byte(data)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return string(b), nil
+	})
+
+	secret, err := conf.EncryptAES(key, "p@ssw0rd")
+	assert.Nil(t, err)
+
+	p := conf.New()
+	assert.Nil(t, p.Set("db.password", secret))
+	assert.Nil(t, p.Set("db.user", "ENC(REV, toor)"))
+	assert.Nil(t, p.Set("db.token", "ENC(XXX,abc)"))
+	assert.Equal(t, p.Get("db.password"), "p@ssw0rd")
+	assert.Equal(t, p.Get("db.user"), "root")
+	assert.Equal(t, p.Get("db.password", conf.Raw()), secret)
+
+	t.Run("no decryptor", func(t *testing.T) {
+		assert.Equal(t, p.Get("db.token"), "")
+		assert.Equal(t, p.Get("db.token", conf.Def("def")), "")
+		assert.Equal(t, p.Get("db.token", conf.Raw()), "ENC(XXX,abc)")
+		_, err = p.Resolve("${db.token}")
+		assert.Error(t, err, "decryptor \"XXX\" not found")
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		var s struct {
+			User     string `value:"${user}"`
+			Password string `value:"${password}"`
+		}
+		err = p.Bind(&s, conf.Key("db"))
+		assert.Nil(t, err)
+		assert.Equal(t, s.User, "root")
+		assert.Equal(t, s.Password, "p@ssw0rd")
+		var token string
+		err = p.Bind(&token, conf.Key("db.token"))
+		assert.Error(t, err, "decryptor \"XXX\" not found")
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		b, err := conf.Marshal(p)
+		assert.Nil(t, err)
+		assert.True(t, strings.Contains(string(b), secret))
+		assert.False(t, strings.Contains(string(b), "p@ssw0rd"))
+	})
+}
+
+func TestValidator(t *testing.T) {
+
+	conf.RegisterValidator("prefix", func(v reflect.Value, param string) (bool, error) {
+		return strings.HasPrefix(v.String(), param), nil
+	})
+
+	type Server struct {
+		Port  int      `value:"${port}" validate:"required,min=1,max=65535"`
+		Mode  string   `value:"${mode:=}" validate:"omitempty,oneof=dev prod"`
+		Hosts []string `value:"${hosts:=}" validate:"max=2"`
+		Name  string   `value:"${name:=srv-a}" validate:"prefix=srv-"`
+	}
+
+	newProperties := func(m map[string]interface{}) *conf.Properties {
+		p := conf.New()
+		for k, v := range m {
+			assert.Nil(t, p.Set(k, v))
+		}
+		return p
+	}
+
+	t.Run("success", func(t *testing.T) {
+		p := newProperties(map[string]interface{}{
+			"port":  8080,
+			"hosts": []string{"a", "b"},
+		})
+		var s Server
+		assert.Nil(t, p.Bind(&s))
+		assert.Equal(t, s.Port, 8080)
+		assert.Equal(t, s.Mode, "")
+	})
+
+	t.Run("required", func(t *testing.T) {
+		p := newProperties(map[string]interface{}{"port": 0})
+		var s Server
+		err := p.Bind(&s)
+		assert.Error(t, err, "validate failed on \"required\" for value 0")
+	})
+
+	t.Run("max", func(t *testing.T) {
+		p := newProperties(map[string]interface{}{"port": 70000})
+		var s Server
+		err := p.Bind(&s)
+		assert.Error(t, err, "validate failed on \"max=65535\" for value 70000")
+		p = newProperties(map[string]interface{}{"port": 80, "hosts": []string{"a", "b", "c"}})
+		err = p.Bind(&s)
+		assert.Error(t, err, "validate failed on \"max=2\"")
+	})
+
+	t.Run("oneof", func(t *testing.T) {
+		p := newProperties(map[string]interface{}{"port": 80, "mode": "test"})
+		var s Server
+		err := p.Bind(&s)
+		assert.Error(t, err, "validate failed on \"oneof=dev prod\" for value test")
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		p := newProperties(map[string]interface{}{"port": 80, "name": "app"})
+		var s Server
+		err := p.Bind(&s)
+		assert.Error(t, err, "validate failed on \"prefix=srv-\" for value app")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var s struct {
+			Port int `value:"${port:=80}" validate:"positive"`
+		}
+		err := conf.New().Bind(&s)
+		assert.Error(t, err, "validator \"positive\" not found")
+	})
+
+	t.Run("pluggable", func(t *testing.T) {
+		v := &tagValidator{}
+		defer func(old validate.Interface) { validate.Validator = old }(validate.Validator)
+		validate.Validator = v
+		p := newProperties(map[string]interface{}{"port": 70000})
+		var s Server
+		err := p.Bind(&s)
+		assert.Error(t, err, "pluggable: required,min=1,max=65535")
+		assert.Equal(t, v.tags, []string{"required,min=1,max=65535"})
+	})
+}
+
+// tagValidator claims the validate tag, so the built-in rules are skipped.
+type tagValidator struct {
+	tags []string
+}
+
+func (v *tagValidator) TagName() string {
+	return "validate"
+}
+
+func (v *tagValidator) Struct(i interface{}) error {
+	return nil
+}
+
+func (v *tagValidator) Field(i interface{}, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	v.tags = append(v.tags, tag)
+	return errors.New("pluggable: " + tag)
+}
+
+func TestValidateStruct(t *testing.T) {
+
+	type Item struct {
+		Name  string `json:"name" validate:"required"`
+		Count int    `json:"count" expr:"$>0"`
+	}
+
+	type Order struct {
+		ID    string `json:"id" validate:"required,len=4"`
+		Items []Item `json:"items" validate:"min=1"`
+		Note  string `json:"-" validate:"required"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		o := &Order{ID: "0001", Items: []Item{{Name: "a", Count: 1}}}
+		assert.Nil(t, conf.ValidateStruct(o))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		o := &Order{ID: "01", Items: []Item{{Name: "a", Count: 1}, {Count: 0}}}
+		err := conf.ValidateStruct(o)
+		var e *conf.ValidationError
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, e.Fields, []conf.FieldError{
+			{Field: "id", Rule: "len=4", Message: "validate failed on \"len=4\" for value 01"},
+			{Field: "items[1].name", Rule: "required", Message: "validate failed on \"required\" for value "},
+			{Field: "items[1].count", Rule: "$>0", Message: "validate failed on \"$>0\" for value 0"},
+		})
+		assert.Error(t, err, "validation failed: id: validate failed on \"len=4\" for value 01; items\\[1\\].name")
+	})
+}
+
+func TestBuiltinConverters(t *testing.T) {
+
+	type Config struct {
+		Timeouts []time.Duration `value:"${timeouts:=1s,500ms}"`
+		Endpoint *url.URL        `value:"${endpoint}"`
+		IP       net.IP          `value:"${ip}"`
+		IPs      []net.IP        `value:"${ips:=127.0.0.1,::1}"`
+		Subnet   net.IPNet       `value:"${subnet}"`
+		Location *time.Location  `value:"${location:=UTC}"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		p := conf.New()
+		assert.Nil(t, p.Set("endpoint", "https://example.com:8443/api?v=1"))
+		assert.Nil(t, p.Set("ip", "10.0.0.1"))
+		assert.Nil(t, p.Set("subnet", "192.168.0.0/16"))
+		var c Config
+		assert.Nil(t, p.Bind(&c))
+		assert.Equal(t, c.Timeouts, []time.Duration{time.Second, 500 * time.Millisecond})
+		assert.Equal(t, c.Endpoint.Host, "example.com:8443")
+		assert.Equal(t, c.Endpoint.Query().Get("v"), "1")
+		assert.Equal(t, c.IP.String(), "10.0.0.1")
+		assert.Equal(t, len(c.IPs), 2)
+		assert.Equal(t, c.IPs[1].String(), "::1")
+		assert.Equal(t, c.Subnet.String(), "192.168.0.0/16")
+		assert.True(t, c.Subnet.Contains(net.ParseIP("192.168.1.1")))
+		assert.Equal(t, c.Location, time.UTC)
+	})
+
+	t.Run("invalid ip", func(t *testing.T) {
+		var ip net.IP
+		err := conf.New().Bind(&ip, conf.Tag("${:=10.0.0.256}"))
+		assert.Error(t, err, "invalid IP address \"10.0.0.256\"")
+	})
+
+	t.Run("invalid cidr", func(t *testing.T) {
+		var ipNet net.IPNet
+		err := conf.New().Bind(&ipNet, conf.Tag("${:=10.0.0.0}"))
+		assert.Error(t, err, "invalid CIDR address: 10.0.0.0")
+	})
+
+	t.Run("invalid location", func(t *testing.T) {
+		var loc *time.Location
+		err := conf.New().Bind(&loc, conf.Tag("${:=Mars/Olympus}"))
+		assert.Error(t, err, "unknown time zone Mars/Olympus")
+	})
+}
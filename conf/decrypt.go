@@ -0,0 +1,111 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Decryptor decrypts the data of an encrypted property value.
+type Decryptor func(data string) (string, error)
+
+var decryptors = map[string]Decryptor{}
+
+// RegisterDecryptor registers a Decryptor for the scheme, then property values
+// like `ENC(scheme,data)` are decrypted transparently when getting or binding.
+func RegisterDecryptor(scheme string, fn Decryptor) {
+	decryptors[scheme] = fn
+}
+
+// isEncrypted returns whether the property value is like `ENC(scheme,data)`.
+func isEncrypted(val string) bool {
+	return strings.HasPrefix(val, "ENC(") && strings.HasSuffix(val, ")")
+}
+
+// decrypt returns the plaintext of `ENC(scheme,data)` value, other values are
+// returned as they are.
+func decrypt(val string) (string, error) {
+	if !isEncrypted(val) {
+		return val, nil
+	}
+	s := val[len("ENC(") : len(val)-1]
+	i := strings.Index(s, ",")
+	if i < 0 {
+		return "", errors.New("encrypted value should be ENC(scheme,data)")
+	}
+	scheme := strings.TrimSpace(s[:i])
+	fn, ok := decryptors[scheme]
+	if !ok {
+		return "", fmt.Errorf("decryptor %q not found", scheme)
+	}
+	return fn(strings.TrimSpace(s[i+1:]))
+}
+
+// AESDecryptor returns a Decryptor that decrypts base64 encoded AES-GCM data,
+// the nonce is the prefix of data. The key should be 16, 24 or 32 bytes, it's
+// usually loaded from the environment or a key management service rather than
+// configuration files.
+func AESDecryptor(key []byte) Decryptor {
+	return func(data string) (string, error) {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", err
+		}
+		n := gcm.NonceSize()
+		if len(b) < n {
+			return "", errors.New("encrypted data is too short")
+		}
+		plaintext, err := gcm.Open(nil, b[:n], b[n:], nil)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+}
+
+// EncryptAES encrypts the plaintext by AES-GCM and returns `ENC(AES,data)`
+// which can be decrypted by the AESDecryptor registered for the AES scheme.
+func EncryptAES(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	b := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return "ENC(AES," + base64.StdEncoding.EncodeToString(b) + ")", nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
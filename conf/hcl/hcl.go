@@ -0,0 +1,391 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package hcl reads configuration in the HCL (HashiCorp Configuration Language)
+// format. Only the subset used by configuration files is supported: attributes,
+// blocks with labels, lists, objects, heredocs and comments. Interpolations in
+// strings are kept as they are, other expressions aren't supported.
+package hcl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Read parses []byte in the hcl format into map. Blocks with the same name are
+// merged, labels of a block become nested keys, for example `service "web" {}`
+// is read as map{"service": map{"web": map{}}}.
+func Read(b []byte) (map[string]interface{}, error) {
+	p := &parser{s: []rune(string(b)), line: 1}
+	m := make(map[string]interface{})
+	if err := p.parseBody(m, false); err != nil {
+		return nil, fmt.Errorf("hcl: line %d: %w", p.line, err)
+	}
+	return m, nil
+}
+
+type parser struct {
+	s    []rune
+	pos  int
+	line int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) next() rune {
+	c := p.s[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skip skips spaces and comments, newlines are skipped only if newline is true.
+func (p *parser) skip(newline bool) error {
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '\n':
+			if !newline {
+				return nil
+			}
+			p.next()
+		case unicode.IsSpace(c):
+			p.next()
+		case c == '#' || c == '/' && p.pos+1 < len(p.s) && p.s[p.pos+1] == '/':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		case c == '/' && p.pos+1 < len(p.s) && p.s[p.pos+1] == '*':
+			p.pos += 2
+			for {
+				if p.eof() {
+					return errors.New("unterminated comment")
+				}
+				if p.next() == '*' && p.peek() == '/' {
+					p.next()
+					break
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isIdent(c rune) bool {
+	return c == '_' || c == '-' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func (p *parser) parseIdent() (string, error) {
+	start := p.pos
+	for !p.eof() && isIdent(p.peek()) {
+		p.next()
+	}
+	if start == p.pos {
+		if p.eof() {
+			return "", errors.New("unexpected end of input")
+		}
+		return "", fmt.Errorf("unexpected %q", p.peek())
+	}
+	return string(p.s[start:p.pos]), nil
+}
+
+// parseKey parses an identifier or a quoted string.
+func (p *parser) parseKey() (string, error) {
+	if p.peek() == '"' {
+		return p.parseString()
+	}
+	return p.parseIdent()
+}
+
+// parseBody parses attributes and blocks into m until EOF or '}'.
+func (p *parser) parseBody(m map[string]interface{}, nested bool) error {
+	for {
+		if err := p.skip(true); err != nil {
+			return err
+		}
+		if p.eof() {
+			if nested {
+				return errors.New("missing '}'")
+			}
+			return nil
+		}
+		if p.peek() == '}' {
+			if !nested {
+				return errors.New("unexpected '}'")
+			}
+			p.next()
+			return nil
+		}
+		if p.peek() == ',' { // separator in the object literal
+			p.next()
+			continue
+		}
+		if err := p.parseItem(m); err != nil {
+			return err
+		}
+	}
+}
+
+// parseItem parses `key = value`, `key: value` or `key "label"... { body }`.
+func (p *parser) parseItem(m map[string]interface{}) error {
+
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if err = p.skip(false); err != nil {
+		return err
+	}
+
+	if c := p.peek(); c == '=' || c == ':' {
+		p.next()
+		if err = p.skip(false); err != nil {
+			return err
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		return merge(m, []string{key}, v)
+	}
+
+	path := []string{key}
+	for p.peek() != '{' {
+		if p.eof() {
+			return errors.New("unexpected end of input")
+		}
+		label, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		path = append(path, label)
+		if err = p.skip(false); err != nil {
+			return err
+		}
+	}
+	p.next()
+
+	body := make(map[string]interface{})
+	if err = p.parseBody(body, true); err != nil {
+		return err
+	}
+	return merge(m, path, body)
+}
+
+// merge sets v at path of m, objects on the same path are merged.
+func merge(m map[string]interface{}, path []string, v interface{}) error {
+	for _, k := range path[:len(path)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			if _, exist := m[k]; exist {
+				return fmt.Errorf("duplicate key %q", k)
+			}
+			sub = make(map[string]interface{})
+			m[k] = sub
+		}
+		m = sub
+	}
+	k := path[len(path)-1]
+	old, exist := m[k]
+	if !exist {
+		m[k] = v
+		return nil
+	}
+	src, ok1 := v.(map[string]interface{})
+	dst, ok2 := old.(map[string]interface{})
+	if ok1 != ok2 {
+		return fmt.Errorf("duplicate key %q", k)
+	}
+	if !ok1 { // the last attribute wins
+		m[k] = v
+		return nil
+	}
+	for sk, sv := range src {
+		if err := merge(dst, []string{sk}, sv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		return p.parseString()
+	case c == '<':
+		return p.parseHeredoc()
+	case c == '[':
+		return p.parseList()
+	case c == '{':
+		p.next()
+		m := make(map[string]interface{})
+		if err := p.parseBody(m, true); err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		s, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		return s, nil
+	}
+}
+
+func (p *parser) parseString() (string, error) {
+	start := p.pos
+	p.next()
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", errors.New("unterminated string")
+		}
+		c := p.next()
+		if c == '\\' && !p.eof() {
+			p.next()
+			continue
+		}
+		if c == '"' {
+			break
+		}
+	}
+	return strconv.Unquote(string(p.s[start:p.pos]))
+}
+
+// parseHeredoc parses `<<EOF` or `<<-EOF` strings, the latter removes the
+// common leading spaces of lines.
+func (p *parser) parseHeredoc() (string, error) {
+	if p.pos+1 >= len(p.s) || p.s[p.pos+1] != '<' {
+		return "", fmt.Errorf("unexpected %q", p.peek())
+	}
+	p.pos += 2
+	indent := false
+	if p.peek() == '-' {
+		indent = true
+		p.next()
+	}
+	marker, err := p.parseIdent()
+	if err != nil {
+		return "", err
+	}
+	if err = p.skip(false); err != nil {
+		return "", err
+	}
+	if p.eof() || p.next() != '\n' {
+		return "", errors.New("heredoc marker should end with newline")
+	}
+	var lines []string
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated heredoc %s", marker)
+		}
+		start := p.pos
+		for !p.eof() && p.peek() != '\n' {
+			p.next()
+		}
+		line := string(p.s[start:p.pos])
+		if strings.TrimSpace(line) == marker {
+			break
+		}
+		lines = append(lines, line)
+		if !p.eof() {
+			p.next()
+		}
+	}
+	if indent {
+		lines = trimIndent(lines)
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func trimIndent(lines []string) []string {
+	n := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		i := len(line) - len(strings.TrimLeft(line, " \t"))
+		if n < 0 || i < n {
+			n = i
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= n && n > 0 {
+			lines[i] = line[n:]
+		}
+	}
+	return lines
+}
+
+func (p *parser) parseList() ([]interface{}, error) {
+	p.next()
+	ret := make([]interface{}, 0)
+	for {
+		if err := p.skip(true); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, errors.New("missing ']'")
+		}
+		if p.peek() == ']' {
+			p.next()
+			return ret, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+		if err = p.skip(true); err != nil {
+			return nil, err
+		}
+		if p.peek() == ',' {
+			p.next()
+		} else if p.peek() != ']' {
+			return nil, errors.New("missing ',' or ']'")
+		}
+	}
+}
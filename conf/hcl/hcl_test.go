@@ -0,0 +1,132 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hcl_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/conf/hcl"
+)
+
+func TestRead(t *testing.T) {
+
+	t.Run("basic type", func(t *testing.T) {
+		r, err := hcl.Read([]byte(`
+			# comment
+			bool = false
+			int = 3 // comment
+			float = 3.0
+			string1 = "3"
+			string2 = "hello\tworld"
+			/* multi-line
+			   comment */
+			time: "2018-02-17T15:02:31+08:00"
+			ident = prod
+		`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, r, map[string]interface{}{
+			"bool":    false,
+			"int":     int64(3),
+			"float":   3.0,
+			"string1": "3",
+			"string2": "hello\tworld",
+			"time":    "2018-02-17T15:02:31+08:00",
+			"ident":   "prod",
+		})
+	})
+
+	t.Run("list and object", func(t *testing.T) {
+		r, err := hcl.Read([]byte(`
+			ports = [80, 443,]
+			hosts = [
+				"a.example.com",
+				"b.example.com"
+			]
+			limits = { cpu = 2, memory = "4Gi" }
+		`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, r, map[string]interface{}{
+			"ports": []interface{}{int64(80), int64(443)},
+			"hosts": []interface{}{"a.example.com", "b.example.com"},
+			"limits": map[string]interface{}{
+				"cpu":    int64(2),
+				"memory": "4Gi",
+			},
+		})
+	})
+
+	t.Run("block", func(t *testing.T) {
+		r, err := hcl.Read([]byte(`
+			server {
+				port = 8080
+			}
+			server {
+				host = "localhost"
+			}
+			service "web" "v1" {
+				replicas = 2
+			}
+			service "db" {
+				replicas = 1
+			}
+		`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, r, map[string]interface{}{
+			"server": map[string]interface{}{
+				"port": int64(8080),
+				"host": "localhost",
+			},
+			"service": map[string]interface{}{
+				"web": map[string]interface{}{
+					"v1": map[string]interface{}{
+						"replicas": int64(2),
+					},
+				},
+				"db": map[string]interface{}{
+					"replicas": int64(1),
+				},
+			},
+		})
+	})
+
+	t.Run("heredoc", func(t *testing.T) {
+		r, err := hcl.Read([]byte("a = <<EOF\nhello\n  world\nEOF\nb = <<-EOT\n    hello\n      world\n    EOT\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, r, map[string]interface{}{
+			"a": "hello\n  world\n",
+			"b": "hello\n  world\n",
+		})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := hcl.Read([]byte("a {\n  b = 1\n"))
+		assert.Error(t, err, "hcl: line 3: missing '}'")
+		_, err = hcl.Read([]byte("a = [1 2]"))
+		assert.Error(t, err, "missing ',' or ']'")
+		_, err = hcl.Read([]byte("a = 1\na {\n}"))
+		assert.Error(t, err, "duplicate key \"a\"")
+	})
+}
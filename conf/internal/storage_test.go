@@ -233,3 +233,18 @@ func TestStorage(t *testing.T) {
 		assert.Equal(t, s.Keys(), []string{"a.b[0].c[0]", "a.b[0].d.e"})
 	}
 }
+
+func TestStorage_Copy(t *testing.T) {
+
+	s := internal.NewStorage().Copy()
+	assert.Nil(t, s.Data())
+	err := s.Set("a.b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, s.Get("a.b"), "c")
+
+	c := s.Copy()
+	err = c.Set("a.d", "e")
+	assert.Nil(t, err)
+	assert.Equal(t, c.Keys(), []string{"a.b", "a.d"})
+	assert.Equal(t, s.Keys(), []string{"a.b"})
+}
@@ -16,7 +16,12 @@
 
 package prop
 
-import "github.com/magiconair/properties"
+import (
+	"bytes"
+	"sort"
+
+	"github.com/magiconair/properties"
+)
 
 // Read parses []byte in the properties format into map.
 func Read(b []byte) (map[string]interface{}, error) {
@@ -35,3 +40,28 @@ func Read(b []byte) (map[string]interface{}, error) {
 	}
 	return ret, nil
 }
+
+// Write encodes map into []byte in the properties format, the keys are sorted
+// so that the same map always produces the same result.
+func Write(m map[string]string) ([]byte, error) {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := properties.NewProperties()
+	p.DisableExpansion = true
+	for _, k := range keys {
+		if _, _, err := p.Set(k, m[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := p.Write(&buf, properties.UTF8); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
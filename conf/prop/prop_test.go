@@ -118,3 +118,13 @@ func TestRead(t *testing.T) {
 		})
 	})
 }
+
+func TestWrite(t *testing.T) {
+	b, err := prop.Write(map[string]string{
+		"b":    "2",
+		"a":    "1",
+		"c[0]": "hello world",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "a = 1\nb = 2\nc[0] = hello world\n")
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONReader returns a reader that parses []byte in the protobuf JSON format
+// into map, the fields are validated against the message schema m. Both the
+// proto names and the json names of fields are accepted, but the keys of the
+// map are always the proto names.
+func JSONReader(m *Message) func(b []byte) (map[string]interface{}, error) {
+	return func(b []byte) (map[string]interface{}, error) {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		var v map[string]interface{}
+		if err := d.Decode(&v); err != nil {
+			return nil, fmt.Errorf("proto: %w", err)
+		}
+		r, err := fromJSON(m, v)
+		if err != nil {
+			return nil, fmt.Errorf("proto: %w", err)
+		}
+		return r, nil
+	}
+}
+
+func fromJSON(m *Message, v map[string]interface{}) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+	for name, val := range v {
+		f, ok := m.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q in %s", name, m.FullName)
+		}
+		if err := fieldFromJSON(r, f, val); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", m.FullName, f.Name, err)
+		}
+	}
+	return r, nil
+}
+
+func fieldFromJSON(r map[string]interface{}, f *Field, v interface{}) error {
+
+	if v == nil {
+		return nil
+	}
+
+	if f.IsMap() {
+		entries, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expect object but %T", v)
+		}
+		keyField, _ := f.Message.Field("key")
+		valueField, _ := f.Message.Field("value")
+		for k, val := range entries {
+			key, err := scalar(keyField, k)
+			if err != nil {
+				return err
+			}
+			entry := map[string]interface{}{"key": key}
+			if val, err = valueFromJSON(valueField, val); err != nil {
+				return err
+			}
+			entry["value"] = val
+			if err = setValue(r, f, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if f.Repeated {
+		values, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("expect array but %T", v)
+		}
+		r[f.Name] = []interface{}{}
+		for _, val := range values {
+			val, err := valueFromJSON(f, val)
+			if err != nil {
+				return err
+			}
+			if err = setValue(r, f, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	val, err := valueFromJSON(f, v)
+	if err != nil {
+		return err
+	}
+	return setValue(r, f, val)
+}
+
+func valueFromJSON(f *Field, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if f.Kind == MessageKind {
+			return fromJSON(f.Message, val)
+		}
+	case bool:
+		if f.Kind == BoolKind {
+			return val, nil
+		}
+	case json.Number:
+		switch f.Kind {
+		case BoolKind, StringKind, BytesKind, MessageKind:
+		default:
+			return scalar(f, val.String())
+		}
+	case string:
+		switch f.Kind {
+		case BoolKind, MessageKind:
+		case BytesKind:
+			if _, err := base64.StdEncoding.DecodeString(val); err != nil {
+				if _, err = base64.URLEncoding.DecodeString(val); err != nil {
+					return nil, err
+				}
+			}
+			return val, nil
+		default:
+			return scalar(f, val)
+		}
+	}
+	return nil, fmt.Errorf("invalid value %v for %s field", v, f.Kind)
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proto_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/conf/proto"
+)
+
+func appendVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+func varint(num int, v uint64) []byte {
+	b := appendVarint(nil, uint64(num<<3))
+	return appendVarint(b, v)
+}
+
+func message(num int, fields ...[]byte) []byte {
+	var data []byte
+	for _, f := range fields {
+		data = append(data, f...)
+	}
+	b := appendVarint(nil, uint64(num<<3|2))
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func str(num int, s string) []byte {
+	return message(num, []byte(s))
+}
+
+func field(name string, number int, label int, typ int, typeName string) []byte {
+	b := [][]byte{str(1, name), varint(3, uint64(number)), varint(4, uint64(label)), varint(5, uint64(typ))}
+	if typeName != "" {
+		b = append(b, str(6, typeName))
+	}
+	return message(2, b...)
+}
+
+// descriptor returns the FileDescriptorSet of the following proto file.
+//
+//	syntax = "proto3";
+//	package app;
+//	enum Level { DEBUG = 0; INFO = 1; }
+//	message Server { string addr = 1; int64 timeout_ms = 2; }
+//	message Config {
+//	  string name = 1;
+//	  int32 port = 2;
+//	  repeated string hosts = 3;
+//	  Level level = 4;
+//	  Server server = 5;
+//	  map<string, int64> limits = 6;
+//	  float ratio = 7;
+//	  bool debug = 8;
+//	  bytes key = 9;
+//	  repeated Server backups = 10;
+//	}
+func descriptor() []byte {
+	const optional, repeated = 1, 3
+	return message(1,
+		str(1, "app.proto"),
+		str(2, "app"),
+		message(5,
+			str(1, "Level"),
+			message(2, str(1, "DEBUG"), varint(2, 0)),
+			message(2, str(1, "INFO"), varint(2, 1)),
+		),
+		message(4,
+			str(1, "Server"),
+			field("addr", 1, optional, 9, ""),
+			field("timeout_ms", 2, optional, 3, ""),
+		),
+		message(4,
+			str(1, "Config"),
+			field("name", 1, optional, 9, ""),
+			field("port", 2, optional, 5, ""),
+			field("hosts", 3, repeated, 9, ""),
+			field("level", 4, optional, 14, ".app.Level"),
+			field("server", 5, optional, 11, ".app.Server"),
+			field("limits", 6, repeated, 11, ".app.Config.LimitsEntry"),
+			field("ratio", 7, optional, 2, ""),
+			field("debug", 8, optional, 8, ""),
+			field("key", 9, optional, 12, ""),
+			field("backups", 10, repeated, 11, ".app.Server"),
+			message(3,
+				str(1, "LimitsEntry"),
+				field("key", 1, optional, 9, ""),
+				field("value", 2, optional, 3, ""),
+				message(7, varint(7, 1)),
+			),
+		),
+	)
+}
+
+func schema(t *testing.T) *proto.Message {
+	s, err := proto.NewSchema(descriptor())
+	assert.Nil(t, err)
+	m, ok := s.Message("app.Config")
+	assert.True(t, ok)
+	return m
+}
+
+var expect = map[string]interface{}{
+	"name":  "demo",
+	"port":  int64(8080),
+	"hosts": []interface{}{"a", "b"},
+	"level": "INFO",
+	"server": map[string]interface{}{
+		"addr":       ":9090",
+		"timeout_ms": int64(3000),
+	},
+	"limits": map[string]interface{}{
+		"read":  int64(10),
+		"write": int64(5),
+	},
+	"ratio": 0.1,
+	"debug": true,
+	"key":   "aGVsbG8=",
+	"backups": []interface{}{
+		map[string]interface{}{"addr": ":9091"},
+		map[string]interface{}{"addr": ":9092"},
+	},
+}
+
+func TestNewSchema(t *testing.T) {
+	m := schema(t)
+	assert.Equal(t, len(m.Fields), 10)
+	f, ok := m.Field("limits")
+	assert.True(t, ok)
+	assert.True(t, f.IsMap())
+	f, ok = m.Field("timeoutMs")
+	assert.False(t, ok)
+	server, _ := m.Field("server")
+	f, ok = server.Message.Field("timeoutMs")
+	assert.True(t, ok)
+	assert.Equal(t, f.Kind, proto.Int64Kind)
+
+	_, err := proto.NewSchema([]byte{0x0a, 0x10})
+	assert.Error(t, err, "proto: invalid descriptor: invalid length")
+}
+
+func TestJSONReader(t *testing.T) {
+	read := proto.JSONReader(schema(t))
+
+	t.Run("success", func(t *testing.T) {
+		r, err := read([]byte(`{
+			"name": "demo",
+			"port": 8080,
+			"hosts": ["a", "b"],
+			"level": 1,
+			"server": {"addr": ":9090", "timeoutMs": "3000"},
+			"limits": {"read": 10, "write": "5"},
+			"ratio": 0.1,
+			"debug": true,
+			"key": "aGVsbG8=",
+			"backups": [{"addr": ":9091"}, {"addr": ":9092"}]
+		}`))
+		assert.Nil(t, err)
+		assert.Equal(t, r, expect)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		testcases := []struct {
+			data   string
+			expect string
+		}{
+			{`{"unknown": 1}`, `unknown field "unknown" in app.Config`},
+			{`{"port": 3000000000}`, `app.Config.port: .* value out of range`},
+			{`{"port": true}`, `app.Config.port: invalid value true for int32 field`},
+			{`{"name": 3}`, `app.Config.name: invalid value 3 for string field`},
+			{`{"level": "WARN"}`, `invalid value "WARN" for enum app.Level`},
+			{`{"hosts": "a"}`, `app.Config.hosts: expect array but string`},
+			{`{"server": {"timeout_ms": "3s"}}`, `app.Server.timeout_ms: .* invalid syntax`},
+		}
+		for _, c := range testcases {
+			_, err := read([]byte(c.data))
+			assert.Error(t, err, c.expect)
+		}
+	})
+}
+
+func TestTextReader(t *testing.T) {
+	read := proto.TextReader(schema(t))
+
+	t.Run("success", func(t *testing.T) {
+		r, err := read([]byte(`
+			# comment
+			name: "de" 'mo'
+			port: 8080
+			hosts: ["a"]
+			hosts: "b"
+			level: INFO
+			server {
+				addr: ":9090"
+				timeout_ms: 3000
+			}
+			limits { key: "read" value: 10 }
+			limits: < key: "write", value: 5 >
+			ratio: 0.1f
+			debug: true;
+			key: "hello"
+			backups: [{addr: ":9091"}, {addr: ":9092"}]
+		`))
+		assert.Nil(t, err)
+		assert.Equal(t, r, expect)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		testcases := []struct {
+			data   string
+			expect string
+		}{
+			{`unknown: 1`, `line 1: unknown field "unknown" in app.Config`},
+			{"name: \"a\"\nname: \"b\"", `line 2: app.Config.name: non-repeated field is repeated`},
+			{`port: "80"`, `app.Config.port: invalid string value for int32 field`},
+			{`name: demo`, `app.Config.name: expect string but demo`},
+			{`server { addr: ":80" `, `unexpected end of input`},
+			{`level: 3`, `invalid value "3" for enum app.Level`},
+			{`port 80`, `expect ':' after port`},
+		}
+		for _, c := range testcases {
+			_, err := read([]byte(c.data))
+			assert.Error(t, err, c.expect)
+		}
+	})
+}
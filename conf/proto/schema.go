@@ -0,0 +1,404 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package proto reads configuration files in the protobuf JSON or text format
+// and validates them against a message schema, so that organizations that
+// standardize configuration on proto schemas get typed properties. The schema
+// is loaded from a FileDescriptorSet (protoc --descriptor_set_out) or built by
+// hand, the protobuf runtime isn't required.
+package proto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of field value.
+type Kind int
+
+const (
+	BoolKind Kind = iota + 1
+	Int32Kind
+	Int64Kind
+	Uint32Kind
+	Uint64Kind
+	FloatKind
+	DoubleKind
+	StringKind
+	BytesKind
+	EnumKind
+	MessageKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case BoolKind:
+		return "bool"
+	case Int32Kind:
+		return "int32"
+	case Int64Kind:
+		return "int64"
+	case Uint32Kind:
+		return "uint32"
+	case Uint64Kind:
+		return "uint64"
+	case FloatKind:
+		return "float"
+	case DoubleKind:
+		return "double"
+	case StringKind:
+		return "string"
+	case BytesKind:
+		return "bytes"
+	case EnumKind:
+		return "enum"
+	case MessageKind:
+		return "message"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Message describes a protobuf message.
+type Message struct {
+	FullName string
+	Fields   []*Field
+	MapEntry bool // whether it's the synthetic entry message of a map field
+}
+
+// Field returns the field whose proto name or json name is name.
+func (m *Message) Field(name string) (*Field, bool) {
+	for _, f := range m.Fields {
+		if f.Name == name || f.JSONName == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Field describes a field of message.
+type Field struct {
+	Name     string
+	JSONName string
+	Kind     Kind
+	Repeated bool
+	Message  *Message // the type of MessageKind field
+	Enum     *Enum    // the type of EnumKind field
+}
+
+// IsMap returns whether the field is a map field.
+func (f *Field) IsMap() bool {
+	return f.Repeated && f.Kind == MessageKind && f.Message.MapEntry
+}
+
+// Enum describes a protobuf enum.
+type Enum struct {
+	FullName string
+	Values   map[string]int32
+}
+
+// name returns the name of enum value n.
+func (e *Enum) name(n int32) (string, bool) {
+	for k, v := range e.Values {
+		if v == n {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Schema contains messages and enums loaded from a FileDescriptorSet.
+type Schema struct {
+	messages map[string]*Message
+	enums    map[string]*Enum
+}
+
+// Message returns the message whose full name is name, e.g. "app.Config".
+func (s *Schema) Message(name string) (*Message, bool) {
+	m, ok := s.messages[strings.TrimPrefix(name, ".")]
+	return m, ok
+}
+
+// Enum returns the enum whose full name is name.
+func (s *Schema) Enum(name string) (*Enum, bool) {
+	e, ok := s.enums[strings.TrimPrefix(name, ".")]
+	return e, ok
+}
+
+// rawField is the FieldDescriptorProto to be resolved.
+type rawField struct {
+	message  *Message
+	name     string
+	jsonName string
+	label    uint64
+	typ      uint64
+	typeName string
+}
+
+// NewSchema loads messages and enums from the binary encoded
+// google.protobuf.FileDescriptorSet, which is generated by protoc with the
+// --descriptor_set_out and --include_imports options.
+func NewSchema(b []byte) (*Schema, error) {
+	s := &Schema{
+		messages: make(map[string]*Message),
+		enums:    make(map[string]*Enum),
+	}
+	var fields []*rawField
+	err := walk(b, func(num int, _ uint64, data []byte) error {
+		if num != 1 { // FileDescriptorSet.file
+			return nil
+		}
+		return s.loadFile(data, &fields)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("proto: invalid descriptor: %w", err)
+	}
+	for _, f := range fields {
+		if err = s.resolve(f); err != nil {
+			return nil, fmt.Errorf("proto: %s.%s: %w", f.message.FullName, f.name, err)
+		}
+	}
+	return s, nil
+}
+
+// loadFile loads a FileDescriptorProto.
+func (s *Schema) loadFile(b []byte, fields *[]*rawField) error {
+	var (
+		pkg      string
+		messages [][]byte
+		enums    [][]byte
+	)
+	err := walk(b, func(num int, _ uint64, data []byte) error {
+		switch num {
+		case 2: // package
+			pkg = string(data)
+		case 4: // message_type
+			messages = append(messages, data)
+		case 5: // enum_type
+			enums = append(enums, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, data := range enums {
+		if err = s.loadEnum(pkg, data); err != nil {
+			return err
+		}
+	}
+	for _, data := range messages {
+		if err = s.loadMessage(pkg, data, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// loadMessage loads a DescriptorProto and its nested types.
+func (s *Schema) loadMessage(scope string, b []byte, fields *[]*rawField) error {
+	m := &Message{}
+	var nested, enums [][]byte
+	err := walk(b, func(num int, _ uint64, data []byte) error {
+		switch num {
+		case 1: // name
+			m.FullName = joinName(scope, string(data))
+		case 2: // field
+			f := &rawField{message: m}
+			*fields = append(*fields, f)
+			return walk(data, func(num int, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					f.name = string(data)
+				case 4:
+					f.label = v
+				case 5:
+					f.typ = v
+				case 6:
+					f.typeName = string(data)
+				case 10:
+					f.jsonName = string(data)
+				}
+				return nil
+			})
+		case 3: // nested_type
+			nested = append(nested, data)
+		case 4: // enum_type
+			enums = append(enums, data)
+		case 7: // options
+			return walk(data, func(num int, v uint64, _ []byte) error {
+				if num == 7 { // map_entry
+					m.MapEntry = v != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.messages[m.FullName] = m
+	for _, data := range enums {
+		if err = s.loadEnum(m.FullName, data); err != nil {
+			return err
+		}
+	}
+	for _, data := range nested {
+		if err = s.loadMessage(m.FullName, data, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadEnum loads an EnumDescriptorProto.
+func (s *Schema) loadEnum(scope string, b []byte) error {
+	e := &Enum{Values: make(map[string]int32)}
+	err := walk(b, func(num int, _ uint64, data []byte) error {
+		switch num {
+		case 1: // name
+			e.FullName = joinName(scope, string(data))
+		case 2: // value
+			var name string
+			var number uint64
+			err := walk(data, func(num int, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					name = string(data)
+				case 2:
+					number = v
+				}
+				return nil
+			})
+			e.Values[name] = int32(number)
+			return err
+		}
+		return nil
+	})
+	s.enums[e.FullName] = e
+	return err
+}
+
+// fieldKinds maps FieldDescriptorProto.Type to Kind, 10 is TYPE_GROUP which
+// isn't supported.
+var fieldKinds = map[uint64]Kind{
+	1: DoubleKind, 2: FloatKind, 3: Int64Kind, 4: Uint64Kind, 5: Int32Kind,
+	6: Uint64Kind, 7: Uint32Kind, 8: BoolKind, 9: StringKind, 11: MessageKind,
+	12: BytesKind, 13: Uint32Kind, 14: EnumKind, 15: Int32Kind, 16: Int64Kind,
+	17: Int32Kind, 18: Int64Kind,
+}
+
+// resolve adds the field to its message after resolving its type.
+func (s *Schema) resolve(r *rawField) error {
+	kind, ok := fieldKinds[r.typ]
+	if !ok {
+		return fmt.Errorf("unsupported field type %d", r.typ)
+	}
+	f := &Field{
+		Name:     r.name,
+		JSONName: r.jsonName,
+		Kind:     kind,
+		Repeated: r.label == 3, // LABEL_REPEATED
+	}
+	if f.JSONName == "" {
+		f.JSONName = jsonName(f.Name)
+	}
+	switch kind {
+	case MessageKind:
+		if f.Message, ok = s.Message(r.typeName); !ok {
+			return fmt.Errorf("message %s not found", r.typeName)
+		}
+	case EnumKind:
+		if f.Enum, ok = s.Enum(r.typeName); !ok {
+			return fmt.Errorf("enum %s not found", r.typeName)
+		}
+	}
+	r.message.Fields = append(r.message.Fields, f)
+	return nil
+}
+
+// jsonName returns the lowerCamelCase name of field like protoc does.
+func jsonName(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// walk iterates over the fields of the binary encoded message, v is the value
+// of varint and fixed fields, data is the value of length-delimited fields.
+func walk(b []byte, fn func(num int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("invalid tag")
+		}
+		b = b[n:]
+		var (
+			v    uint64
+			data []byte
+		)
+		switch tag & 7 {
+		case 0:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errors.New("invalid varint")
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return errors.New("invalid fixed64")
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errors.New("invalid length")
+			}
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return errors.New("invalid fixed32")
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+		if err := fn(int(tag>>3), v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proto
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TextReader returns a reader that parses []byte in the protobuf text format
+// into map, the fields are validated against the message schema m. Extensions
+// and Any expansions aren't supported.
+func TextReader(m *Message) func(b []byte) (map[string]interface{}, error) {
+	return func(b []byte) (map[string]interface{}, error) {
+		p := &textParser{s: []rune(string(b)), line: 1}
+		r, err := p.parseMessage(m, 0)
+		if err != nil {
+			return nil, fmt.Errorf("proto: line %d: %w", p.line, err)
+		}
+		return r, nil
+	}
+}
+
+type textParser struct {
+	s    []rune
+	pos  int
+	line int
+}
+
+// skip skips spaces and comments.
+func (p *textParser) skip() {
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		case unicode.IsSpace(c):
+			if c == '\n' {
+				p.line++
+			}
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next non-space rune, or 0 at the end.
+func (p *textParser) peek() rune {
+	p.skip()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// consume consumes the next rune if it's c.
+func (p *textParser) consume(c rune) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// ident reads an identifier or a number literal.
+func (p *textParser) ident() (string, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.s) && isIdentRune(p.s[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos >= len(p.s) {
+			return "", errors.New("unexpected end of input")
+		}
+		return "", fmt.Errorf("unexpected %q", p.s[p.pos])
+	}
+	return string(p.s[start:p.pos]), nil
+}
+
+// str reads adjacent quoted strings and concatenates them.
+func (p *textParser) str() (string, error) {
+	var sb strings.Builder
+	for {
+		q := p.peek()
+		if q != '"' && q != '\'' {
+			return sb.String(), nil
+		}
+		start := p.pos
+		for p.pos++; ; p.pos++ {
+			if p.pos >= len(p.s) || p.s[p.pos] == '\n' {
+				return "", errors.New("unterminated string")
+			}
+			if p.s[p.pos] == '\\' {
+				p.pos++
+				continue
+			}
+			if p.s[p.pos] == q {
+				break
+			}
+		}
+		p.pos++
+		lit := string(p.s[start+1 : p.pos-1])
+		if q == '\'' { // unquotes it as a double-quoted string
+			lit = strings.Replace(lit, `\'`, `'`, -1)
+			lit = strings.Replace(lit, `"`, `\"`, -1)
+		}
+		s, err := strconv.Unquote(`"` + lit + `"`)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", string(p.s[start:p.pos]))
+		}
+		sb.WriteString(s)
+	}
+}
+
+func (p *textParser) parseMessage(m *Message, end rune) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+	for {
+		c := p.peek()
+		if c == end {
+			if end != 0 {
+				p.pos++
+			}
+			return r, nil
+		}
+		if c == 0 {
+			return nil, errors.New("unexpected end of input")
+		}
+		if c == '[' {
+			return nil, errors.New("extensions aren't supported")
+		}
+
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := m.Field(name)
+		if !ok || f.Name != name {
+			return nil, fmt.Errorf("unknown field %q in %s", name, m.FullName)
+		}
+
+		colon := p.consume(':')
+		if !colon && f.Kind != MessageKind {
+			return nil, fmt.Errorf("expect ':' after %s", name)
+		}
+
+		if p.consume('[') {
+			if !f.Repeated {
+				return nil, fmt.Errorf("%s.%s: non-repeated field has list value", m.FullName, name)
+			}
+			if _, ok = r[f.Name]; !ok && !f.IsMap() {
+				r[f.Name] = []interface{}{}
+			}
+			for !p.consume(']') {
+				if err = p.parseField(r, f); err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", m.FullName, name, err)
+				}
+				if !p.consume(',') && p.peek() != ']' {
+					return nil, errors.New("expect ',' or ']'")
+				}
+			}
+		} else if err = p.parseField(r, f); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", m.FullName, name, err)
+		}
+
+		if !p.consume(',') {
+			p.consume(';')
+		}
+	}
+}
+
+// parseField parses a value of field f and saves it into r.
+func (p *textParser) parseField(r map[string]interface{}, f *Field) error {
+	v, err := p.parseValue(f)
+	if err != nil {
+		return err
+	}
+	return setValue(r, f, v)
+}
+
+func (p *textParser) parseValue(f *Field) (interface{}, error) {
+
+	if f.Kind == MessageKind {
+		var end rune
+		switch {
+		case p.consume('{'):
+			end = '}'
+		case p.consume('<'):
+			end = '>'
+		default:
+			return nil, errors.New("expect '{' or '<'")
+		}
+		return p.parseMessage(f.Message, end)
+	}
+
+	if c := p.peek(); c == '"' || c == '\'' {
+		s, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		switch f.Kind {
+		case StringKind:
+			return s, nil
+		case BytesKind:
+			return base64.StdEncoding.EncodeToString([]byte(s)), nil
+		default:
+			return nil, fmt.Errorf("invalid string value for %s field", f.Kind)
+		}
+	}
+
+	s, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	switch f.Kind {
+	case StringKind, BytesKind:
+		return nil, fmt.Errorf("expect string but %s", s)
+	case FloatKind, DoubleKind:
+		if n := len(s); n > 1 && (s[n-1] == 'f' || s[n-1] == 'F') && !strings.HasSuffix(strings.ToLower(s), "inf") {
+			s = s[:n-1]
+		}
+	}
+	return scalar(f, s)
+}
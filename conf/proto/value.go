@@ -0,0 +1,133 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// scalar converts the literal s to the value of field f. Enum values are
+// converted to their names, bytes values are kept as base64 strings.
+func scalar(f *Field, s string) (interface{}, error) {
+	switch f.Kind {
+	case BoolKind:
+		return strconv.ParseBool(s)
+	case Int32Kind:
+		return strconv.ParseInt(s, 0, 32)
+	case Int64Kind:
+		return strconv.ParseInt(s, 0, 64)
+	case Uint32Kind:
+		return strconv.ParseUint(s, 0, 32)
+	case Uint64Kind:
+		return strconv.ParseUint(s, 0, 64)
+	case FloatKind:
+		return parseFloat(s, 32)
+	case DoubleKind:
+		return parseFloat(s, 64)
+	case StringKind, BytesKind:
+		return s, nil
+	case EnumKind:
+		if _, ok := f.Enum.Values[s]; ok {
+			return s, nil
+		}
+		if n, err := strconv.ParseInt(s, 0, 32); err == nil {
+			if name, ok := f.Enum.name(int32(n)); ok {
+				return name, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid value %q for enum %s", s, f.Enum.FullName)
+	default:
+		return nil, fmt.Errorf("unexpected scalar value for %s field", f.Kind)
+	}
+}
+
+// parseFloat parses s as float, the special values inf, -inf and nan are
+// supported in both the json and the text formats.
+func parseFloat(s string, bitSize int) (float64, error) {
+	switch strings.ToLower(s) {
+	case "inf", "+inf", "infinity", "+infinity":
+		return math.Inf(1), nil
+	case "-inf", "-infinity":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	if _, err := strconv.ParseFloat(s, bitSize); err != nil {
+		return 0, err
+	}
+	// parses again so that 0.1 isn't saved as 0.10000000149011612.
+	return strconv.ParseFloat(s, 64)
+}
+
+// setValue saves the value of field f into m, repeated values are appended,
+// map entries are saved into nested map.
+func setValue(m map[string]interface{}, f *Field, v interface{}) error {
+	switch {
+	case f.IsMap():
+		entry := v.(map[string]interface{})
+		key, ok := entry["key"]
+		if !ok {
+			return errors.New("missing map key")
+		}
+		r, _ := m[f.Name].(map[string]interface{})
+		if r == nil {
+			r = make(map[string]interface{})
+			m[f.Name] = r
+		}
+		value, ok := entry["value"]
+		if !ok {
+			value = zero(f.Message)
+		}
+		r[fmt.Sprint(key)] = value
+	case f.Repeated:
+		r, _ := m[f.Name].([]interface{})
+		m[f.Name] = append(r, v)
+	default:
+		if _, ok := m[f.Name]; ok {
+			return errors.New("non-repeated field is repeated")
+		}
+		m[f.Name] = v
+	}
+	return nil
+}
+
+// zero returns the default value of the value field of map entry m.
+func zero(m *Message) interface{} {
+	f, ok := m.Field("value")
+	if !ok {
+		return ""
+	}
+	switch f.Kind {
+	case MessageKind:
+		return map[string]interface{}{}
+	case BoolKind:
+		return false
+	case StringKind, BytesKind:
+		return ""
+	case EnumKind:
+		if name, ok := f.Enum.name(0); ok {
+			return name
+		}
+		return ""
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sops decrypts files encrypted by SOPS (https://github.com/mozilla/sops).
+// Only the AES256_GCM values are decrypted here, the data key that encrypts
+// the values is provided by a KeySource, so that age, kms, or any other key
+// management service can be plugged in without bringing their dependencies.
+// The MAC of the file is always verified, a file whose values were changed,
+// added or removed after it was encrypted is rejected.
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MetadataKey is the top-level key where SOPS stores its metadata.
+const MetadataKey = "sops"
+
+// DataKeyEnv is the environment variable that the default reader registered
+// by conf reads the base64 encoded data key from.
+const DataKeyEnv = "SPRING_SOPS_DATA_KEY"
+
+// KeySource returns the data key that encrypts the values, the metadata of
+// the file is passed in so that the encrypted data key in it can be decrypted
+// by age, kms, etc.
+type KeySource interface {
+	DataKey(metadata map[interface{}]interface{}) ([]byte, error)
+}
+
+// KeySourceFunc is the func type of KeySource.
+type KeySourceFunc func(metadata map[interface{}]interface{}) ([]byte, error)
+
+func (f KeySourceFunc) DataKey(metadata map[interface{}]interface{}) ([]byte, error) {
+	return f(metadata)
+}
+
+// EnvKey returns a KeySource that reads a base64 encoded data key from the
+// environment variable.
+func EnvKey(name string) KeySource {
+	return KeySourceFunc(func(_ map[interface{}]interface{}) ([]byte, error) {
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("env %s not found", name)
+		}
+		return base64.StdEncoding.DecodeString(s)
+	})
+}
+
+// NewReader returns a Reader for the yaml and json files encrypted by SOPS.
+// The values are decrypted in the order they appear in the file, which is
+// the order SOPS computes the MAC in, so the file is parsed here instead of
+// by a generic reader. When no SOPS metadata is found, the values are
+// returned as they are.
+func NewReader(keys KeySource) func([]byte) (map[string]interface{}, error) {
+	return func(b []byte) (map[string]interface{}, error) {
+
+		var doc yaml.MapSlice
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+
+		var (
+			meta  yaml.MapSlice
+			found bool
+		)
+		for _, item := range doc {
+			if item.Key == MetadataKey {
+				meta, found = item.Value.(yaml.MapSlice)
+				if !found {
+					return nil, errors.New("invalid sops metadata")
+				}
+			}
+		}
+		if !found {
+			return toMap(doc), nil
+		}
+
+		metadata := make(map[interface{}]interface{})
+		for _, item := range meta {
+			metadata[item.Key] = item.Value
+		}
+		key, err := keys.DataKey(metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		d := &decrypter{key: key, hash: sha512.New()}
+		ret := make(map[string]interface{})
+		for _, item := range doc {
+			if item.Key == MetadataKey {
+				continue
+			}
+			k := fmt.Sprint(item.Key)
+			if ret[k], err = d.decrypt(item.Value, k+":"); err != nil {
+				return nil, err
+			}
+		}
+		if err = d.verify(metadata); err != nil {
+			return nil, err
+		}
+		return ret, nil
+	}
+}
+
+// toMap converts the ordered document into the map returned by yaml.Read.
+func toMap(doc yaml.MapSlice) map[string]interface{} {
+	ret := make(map[string]interface{})
+	for _, item := range doc {
+		ret[fmt.Sprint(item.Key)] = toValue(item.Value)
+	}
+	return ret
+}
+
+func toValue(v interface{}) interface{} {
+	switch e := v.(type) {
+	case yaml.MapSlice:
+		ret := make(map[interface{}]interface{})
+		for _, item := range e {
+			ret[item.Key] = toValue(item.Value)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(e))
+		for i, val := range e {
+			ret[i] = toValue(val)
+		}
+		return ret
+	default:
+		return v
+	}
+}
+
+// decrypter decrypts the values and hashes their plaintexts for the MAC.
+type decrypter struct {
+	key  []byte
+	hash hash.Hash
+}
+
+// decrypt decrypts v recursively, path is the additional data used by SOPS,
+// which joins the map keys with a trailing ':'.
+func (d *decrypter) decrypt(v interface{}, path string) (interface{}, error) {
+	var err error
+	switch e := v.(type) {
+	case yaml.MapSlice:
+		ret := make(map[interface{}]interface{})
+		for _, item := range e {
+			s := fmt.Sprint(item.Key)
+			if ret[item.Key], err = d.decrypt(item.Value, path+s+":"); err != nil {
+				return nil, err
+			}
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(e))
+		for i, val := range e {
+			if ret[i], err = d.decrypt(val, path); err != nil {
+				return nil, err
+			}
+		}
+		return ret, nil
+	case string:
+		if strings.HasPrefix(e, "ENC[") && strings.HasSuffix(e, "]") {
+			if v, err = decryptValue(e, path, d.key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	d.hash.Write(toBytes(v))
+	return v, nil
+}
+
+// verify checks the MAC stored in the metadata, which is the upper case hex
+// of the SHA-512 of all plaintexts, encrypted with the last modified time as
+// the additional data.
+func (d *decrypter) verify(metadata map[interface{}]interface{}) error {
+	mac, ok := metadata["mac"].(string)
+	if !ok || mac == "" {
+		return errors.New("sops metadata has no mac")
+	}
+	var lastModified string
+	switch t := metadata["lastmodified"].(type) {
+	case time.Time:
+		lastModified = t.Format(time.RFC3339)
+	case string:
+		lastModified = t
+		if r, err := time.Parse(time.RFC3339, t); err == nil {
+			lastModified = r.Format(time.RFC3339)
+		}
+	default:
+		return errors.New("sops metadata has no lastmodified")
+	}
+	v, err := decryptValue(mac, lastModified, d.key)
+	if err != nil {
+		return fmt.Errorf("decrypt mac error: %w", err)
+	}
+	if v != fmt.Sprintf("%X", d.hash.Sum(nil)) {
+		return errors.New("sops mac mismatch, the file has been modified")
+	}
+	return nil
+}
+
+// toBytes returns the bytes of v that SOPS writes into the MAC.
+func toBytes(v interface{}) []byte {
+	switch e := v.(type) {
+	case string:
+		return []byte(e)
+	case int:
+		return []byte(strconv.Itoa(e))
+	case float64:
+		return []byte(strconv.FormatFloat(e, 'f', -1, 64))
+	case bool:
+		if e {
+			return []byte("True")
+		}
+		return []byte("False")
+	case nil:
+		return nil
+	default:
+		return []byte(fmt.Sprint(e))
+	}
+}
+
+// decryptValue decrypts the value in the format of
+// ENC[AES256_GCM,data:...,iv:...,tag:...,type:...].
+func decryptValue(s string, path string, key []byte) (interface{}, error) {
+
+	ss := strings.Split(s[len("ENC["):len(s)-1], ",")
+	if ss[0] != "AES256_GCM" {
+		return nil, fmt.Errorf("unsupported encryption %q", ss[0])
+	}
+
+	fields := make(map[string]string)
+	for _, field := range ss[1:] {
+		i := strings.Index(field, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid encrypted value %q", s)
+		}
+		fields[field[:i]] = field[i+1:]
+	}
+
+	data, err := base64.StdEncoding.DecodeString(fields["data"])
+	if err != nil {
+		return nil, err
+	}
+	iv, err := base64.StdEncoding.DecodeString(fields["iv"])
+	if err != nil {
+		return nil, err
+	}
+	tag, err := base64.StdEncoding.DecodeString(fields["tag"])
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	b, err := gcm.Open(nil, iv, append(data, tag...), []byte(path))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %q error: %w", strings.TrimSuffix(path, ":"), err)
+	}
+
+	switch str := string(b); fields["type"] {
+	case "int":
+		return strconv.Atoi(str)
+	case "float":
+		return strconv.ParseFloat(str, 64)
+	case "bool":
+		return strconv.ParseBool(str)
+	default:
+		return str, nil
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sops_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/conf/sops"
+)
+
+const lastModified = "2021-06-01T08:00:00Z"
+
+func encrypt(key []byte, value string, path string, typ string) string {
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCMWithNonceSize(block, 32)
+	iv := make([]byte, 32)
+	b := gcm.Seal(nil, iv, []byte(value), []byte(path))
+	n := len(b) - gcm.Overhead()
+	s := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", s(b[:n]), s(iv), s(b[n:]), typ)
+}
+
+// metadata returns the sops metadata whose mac covers the plaintexts.
+func metadata(key []byte, plaintexts ...string) string {
+	h := sha512.New()
+	for _, s := range plaintexts {
+		h.Write([]byte(s))
+	}
+	mac := encrypt(key, fmt.Sprintf("%X", h.Sum(nil)), lastModified, "str")
+	return "sops:\n  version: 3.7.1\n  lastmodified: \"" + lastModified + "\"\n  mac: " + mac + "\n"
+}
+
+func TestNewReader(t *testing.T) {
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	_ = os.Setenv("SOPS_DATA_KEY", base64.StdEncoding.EncodeToString(key))
+	defer func() { _ = os.Unsetenv("SOPS_DATA_KEY") }()
+
+	r := sops.NewReader(sops.EnvKey("SOPS_DATA_KEY"))
+
+	encrypted := func() string {
+		str := `
+			db:
+				password: "` + encrypt(key, "123456", "db:password:", "str") + `"
+				ports:
+					- "` + encrypt(key, "3306", "db:ports:", "int") + `"
+				ssl: "` + encrypt(key, "true", "db:ssl:", "bool") + `"
+				host: 127.0.0.1
+		`
+		str = strings.ReplaceAll(str, "\n\t\t\t", "\n")
+		return strings.TrimSpace(strings.ReplaceAll(str, "\t", "  ")) + "\n"
+	}()
+
+	t.Run("plain", func(t *testing.T) {
+		m, err := r([]byte("a: b"))
+		assert.Nil(t, err)
+		assert.Equal(t, m, map[string]interface{}{"a": "b"})
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		str := encrypted + metadata(key, "123456", "3306", "True", "127.0.0.1")
+		m, err := r([]byte(str))
+		assert.Nil(t, err)
+		assert.Equal(t, m, map[string]interface{}{
+			"db": map[interface{}]interface{}{
+				"password": "123456",
+				"ports":    []interface{}{3306},
+				"ssl":      true,
+				"host":     "127.0.0.1",
+			},
+		})
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		str := encrypted + metadata(key, "123456", "3306", "True", "127.0.0.1")
+		str = strings.Replace(str, "127.0.0.1", "10.0.0.1", 1)
+		_, err := r([]byte(str))
+		assert.Error(t, err, "sops mac mismatch, the file has been modified")
+	})
+
+	t.Run("no mac", func(t *testing.T) {
+		str := encrypted + "sops:\n  version: 3.7.1\n"
+		_, err := r([]byte(str))
+		assert.Error(t, err, "sops metadata has no mac")
+	})
+
+	t.Run("wrong path", func(t *testing.T) {
+		str := `password: "` + encrypt(key, "123456", "db:password:", "str") + `"`
+		str += "\n" + metadata(key, "123456")
+		_, err := r([]byte(str))
+		assert.Error(t, err, "decrypt \"password\" error: cipher: message authentication failed")
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		_ = os.Setenv(sops.DataKeyEnv, base64.StdEncoding.EncodeToString(key))
+		defer func() { _ = os.Unsetenv(sops.DataKeyEnv) }()
+		assert.Equal(t, conf.FileExt("config/application.sops.yaml"), ".sops.yaml")
+		str := encrypted + metadata(key, "123456", "3306", "True", "127.0.0.1")
+		p, err := conf.Bytes([]byte(str), ".sops.yaml")
+		assert.Nil(t, err)
+		assert.Equal(t, p.Get("db.password"), "123456")
+		assert.Equal(t, p.Get("db.ports[0]"), "3306")
+	})
+}
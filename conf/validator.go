@@ -0,0 +1,277 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/validate"
+)
+
+// ValidateTag is the struct tag that holds validation rules of a field, such
+// as `validate:"required,min=1,max=65535"`. The rules are checked after the
+// field is bound, from left to right, and the first failure stops binding.
+const ValidateTag = "validate"
+
+// Validator checks a bound value with the param of the rule, for example the
+// param of `min=1` is "1". It returns false if the value is invalid, and an
+// error if the param is invalid.
+type Validator func(v reflect.Value, param string) (bool, error)
+
+var validators = map[string]Validator{}
+
+func init() {
+
+	// the value should not be zero value.
+	RegisterValidator("required", func(v reflect.Value, param string) (bool, error) {
+		return !v.IsZero(), nil
+	})
+
+	// the number should not be less than param, or the length of string,
+	// slice or map should not be less than param.
+	RegisterValidator("min", func(v reflect.Value, param string) (bool, error) {
+		r, err := compareTo(v, param)
+		return r >= 0, err
+	})
+
+	// the number should not be greater than param, or the length of string,
+	// slice or map should not be greater than param.
+	RegisterValidator("max", func(v reflect.Value, param string) (bool, error) {
+		r, err := compareTo(v, param)
+		return r <= 0, err
+	})
+
+	// the number should be equal to param, or the length of string, slice or
+	// map should be equal to param.
+	RegisterValidator("len", func(v reflect.Value, param string) (bool, error) {
+		r, err := compareTo(v, param)
+		return r == 0, err
+	})
+
+	// the value should be one of the space separated param.
+	RegisterValidator("oneof", func(v reflect.Value, param string) (bool, error) {
+		s := fmt.Sprint(v.Interface())
+		for _, e := range strings.Fields(param) {
+			if e == s {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// RegisterValidator registers a Validator and named it, then it can be used in
+// validate tags like `validate:"required,name=param"`. The rule `omitempty`
+// is reserved, it skips the following rules when the value is zero value.
+func RegisterValidator(name string, fn Validator) {
+	validators[name] = fn
+}
+
+// ValidateField checks the bound field with the rules of its validate tag.
+// When the pluggable validate.Validator claims the same tag name, the rules
+// are left to it, so that a tag is never checked by two validators.
+func ValidateField(v reflect.Value, tag reflect.StructTag) error {
+	rules, ok := lookupRules(tag)
+	if !ok {
+		return nil
+	}
+	_, err := checkRules(v, rules)
+	return err
+}
+
+// lookupRules returns the rules of validate tag unless the tag name is taken
+// by the pluggable validate.Validator.
+func lookupRules(tag reflect.StructTag) (string, bool) {
+	if validate.TagName() == ValidateTag {
+		return "", false
+	}
+	return tag.Lookup(ValidateTag)
+}
+
+// checkRules checks the value with the rules of validate tag, it returns the
+// first failed rule and the reason.
+func checkRules(v reflect.Value, tag string) (string, error) {
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if rule == "omitempty" {
+			if v.IsZero() {
+				return "", nil
+			}
+			continue
+		}
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i > 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		fn, ok := validators[name]
+		if !ok {
+			return rule, fmt.Errorf("validator %q not found", name)
+		}
+		ok, err := fn(v, param)
+		if err != nil {
+			return rule, fmt.Errorf("validate %q error: %w", rule, err)
+		}
+		if !ok {
+			return rule, fmt.Errorf("validate failed on %q for value %v", rule, v.Interface())
+		}
+	}
+	return "", nil
+}
+
+// FieldError is a field that failed validation, Field is the path of the
+// field which prefers the name in json tag.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by ValidateStruct, it holds all the fields that
+// failed validation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var ss []string
+	for _, f := range e.Fields {
+		ss = append(ss, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(ss, "; ")
+}
+
+// ValidateStruct checks all fields of the struct, including the nested ones,
+// with the rules of validate tag and the expression of expr tag, just like
+// binding properties. It's useful for the structs that aren't bound from
+// properties, such as web requests. It returns a *ValidationError if any
+// field failed validation.
+func ValidateStruct(i interface{}) error {
+	var e ValidationError
+	validateStruct(reflect.ValueOf(i), "", &e)
+	if len(e.Fields) > 0 {
+		return &e
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, path string, e *ValidationError) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateStruct(v.Index(i), fmt.Sprintf("%s[%d]", path, i), e)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		fv := v.Field(i)
+		if !fv.CanInterface() {
+			continue
+		}
+		name := ft.Name
+		if s, ok := ft.Tag.Lookup("json"); ok {
+			if s = strings.Split(s, ",")[0]; s == "-" {
+				continue
+			} else if s != "" {
+				name = s
+			}
+		}
+		if ft.Anonymous {
+			name = ""
+		}
+		fieldPath := name
+		if path != "" && name != "" {
+			fieldPath = path + "." + name
+		} else if path != "" {
+			fieldPath = path
+		}
+		if rules, ok := lookupRules(ft.Tag); ok {
+			if rule, err := checkRules(fv, rules); err != nil {
+				e.Fields = append(e.Fields, FieldError{Field: fieldPath, Rule: rule, Message: err.Error()})
+				continue
+			}
+		}
+		if tag, ok := ft.Tag.Lookup(validate.TagName()); ok {
+			if err := validate.Field(exprValue(fv), tag); err != nil {
+				e.Fields = append(e.Fields, FieldError{Field: fieldPath, Rule: tag, Message: err.Error()})
+				continue
+			}
+		}
+		validateStruct(fv, fieldPath, e)
+	}
+}
+
+// exprValue returns the value for expr tag, numbers are converted to int64,
+// uint64 or float64 as same as binding properties.
+func exprValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		return v.Interface()
+	}
+}
+
+// compareTo compares the number or the length of value with param, it returns
+// -1, 0 or +1 like strings.Compare.
+func compareTo(v reflect.Value, param string) (int, error) {
+	var f float64
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		f = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = v.Float()
+	default:
+		return 0, fmt.Errorf("unsupported type %s", v.Type())
+	}
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case f < n:
+		return -1, nil
+	case f > n:
+		return +1, nil
+	default:
+		return 0, nil
+	}
+}
@@ -17,15 +17,50 @@
 package yaml
 
 import (
+	"bytes"
+	"io"
+
 	"gopkg.in/yaml.v2"
 )
 
-// Read parses []byte in the yaml format into map.
+// Read parses []byte in the yaml format into map. Anchors, aliases and merge
+// keys are resolved, and multiple documents separated by `---` are merged in
+// order, the later documents override the earlier ones, maps are merged
+// recursively and other values are replaced.
 func Read(b []byte) (map[string]interface{}, error) {
 	m := make(map[string]interface{})
-	err := yaml.Unmarshal(b, &m)
-	if err != nil {
-		return nil, err
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var doc map[string]interface{}
+		if err := d.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		for k, v := range doc {
+			m[k] = merge(m[k], v)
+		}
 	}
 	return m, nil
 }
+
+// merge merges src into dst when both of them are maps, otherwise returns src.
+func merge(dst, src interface{}) interface{} {
+	d, ok := dst.(map[interface{}]interface{})
+	if !ok {
+		return src
+	}
+	s, ok := src.(map[interface{}]interface{})
+	if !ok {
+		return src
+	}
+	r := make(map[interface{}]interface{}, len(d)+len(s))
+	for k, v := range d {
+		r[k] = v
+	}
+	for k, v := range s {
+		r[k] = merge(r[k], v)
+	}
+	return r
+}
@@ -164,4 +164,61 @@ func TestRead(t *testing.T) {
 			"map":   map[interface{}]interface{}{},
 		})
 	})
+
+	t.Run("anchors & aliases", func(t *testing.T) {
+		str := `
+			base: &base
+				timeout: 3s
+				retry: 2
+			hosts: &hosts
+				- a
+				- b
+			dev:
+				<<: *base
+				retry: 5
+				hosts: *hosts
+		`
+		str = strings.ReplaceAll(str, "\t", "  ")
+		r, err := yaml.Read([]byte(str))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, r["dev"], map[interface{}]interface{}{
+			"timeout": "3s",
+			"retry":   5,
+			"hosts":   []interface{}{"a", "b"},
+		})
+	})
+
+	t.Run("multi documents", func(t *testing.T) {
+		str := strings.Join([]string{`
+			app:
+				name: demo
+				port: 8080
+				hosts: [a, b]
+		`, ``, `
+			app:
+				port: 9090
+				hosts: [c]
+			log: debug
+		`}, "\n---\n")
+		str = strings.ReplaceAll(str, "\t", "  ")
+		r, err := yaml.Read([]byte(str))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, r, map[string]interface{}{
+			"app": map[interface{}]interface{}{
+				"name":  "demo",
+				"port":  9090,
+				"hosts": []interface{}{"c"},
+			},
+			"log": "debug",
+		})
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		_, err := yaml.Read([]byte("a: 1\n---\n- b\n"))
+		assert.Error(t, err, "cannot unmarshal")
+	})
 }
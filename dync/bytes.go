@@ -0,0 +1,115 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-base/atomic"
+	"github.com/go-spring/spring-core/conf"
+)
+
+// 字节数的单位，同时支持 KB 和 KiB 两种写法，都表示 1024 的倍数。
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseBytes 解析 512 、64KB 、10MiB 等格式的字节数，单位不区分大小写。
+func ParseBytes(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+type BytesValidateFunc func(v int64) error
+
+// Bytes 可动态刷新的字节数，属性值的格式参考 ParseBytes 。
+type Bytes struct {
+	v atomic.Int64
+	f BytesValidateFunc
+	c func(old, new int64)
+	b atomic.Bool // 是否已经完成首次赋值
+}
+
+func (x *Bytes) Value() int64 {
+	return x.v.Load()
+}
+
+func (x *Bytes) OnValidate(f BytesValidateFunc) {
+	x.f = f
+}
+
+// OnChange 设置属性值发生变化时的回调函数，绑定时的首次赋值不会触发回调。
+func (x *Bytes) OnChange(f func(old, new int64)) {
+	x.c = f
+}
+
+func (x *Bytes) getBytes(prop *conf.Properties, param conf.BindParam) (int64, error) {
+	s, err := GetProperty(prop, param)
+	if err != nil {
+		return 0, err
+	}
+	return ParseBytes(s)
+}
+
+func (x *Bytes) Refresh(prop *conf.Properties, param conf.BindParam) error {
+	v, err := x.getBytes(prop, param)
+	if err != nil {
+		return err
+	}
+	old := x.v.Load()
+	x.v.Store(v)
+	if bound := x.b.Swap(true); x.c != nil && bound && old != v {
+		x.c(old, v)
+	}
+	return nil
+}
+
+func (x *Bytes) Validate(prop *conf.Properties, param conf.BindParam) error {
+	v, err := x.getBytes(prop, param)
+	if err != nil {
+		return err
+	}
+	if x.f != nil {
+		return x.f(v)
+	}
+	return nil
+}
+
+func (x *Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.Value())
+}
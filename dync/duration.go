@@ -30,6 +30,8 @@ type DurationValidateFunc func(v time.Duration) error
 type Duration struct {
 	v atomic.Duration
 	f DurationValidateFunc
+	c func(old, new time.Duration)
+	b atomic.Bool // 是否已经完成首次赋值
 }
 
 func (x *Duration) Value() time.Duration {
@@ -40,6 +42,11 @@ func (x *Duration) OnValidate(f DurationValidateFunc) {
 	x.f = f
 }
 
+// OnChange 设置属性值发生变化时的回调函数，绑定时的首次赋值不会触发回调。
+func (x *Duration) OnChange(f func(old, new time.Duration)) {
+	x.c = f
+}
+
 func (x *Duration) getDuration(prop *conf.Properties, param conf.BindParam) (time.Duration, error) {
 	s, err := GetProperty(prop, param)
 	if err != nil {
@@ -57,7 +64,11 @@ func (x *Duration) Refresh(prop *conf.Properties, param conf.BindParam) error {
 	if err != nil {
 		return err
 	}
+	old := x.v.Load()
 	x.v.Store(v)
+	if bound := x.b.Swap(true); x.c != nil && bound && old != v {
+		x.c(old, v)
+	}
 	return nil
 }
 
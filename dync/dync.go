@@ -21,6 +21,8 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-spring/spring-base/atomic"
 	"github.com/go-spring/spring-core/conf"
@@ -38,10 +40,35 @@ type Field struct {
 	param conf.BindParam
 }
 
-// Properties 动态属性
+// RefreshEvent 一次属性刷新的统计信息。
+type RefreshEvent struct {
+	Keys     int           // 发生变化的属性数量
+	Fields   int           // 需要更新的绑定对象数量
+	Dropped  int64         // Listener 丢弃的通知数量
+	Duration time.Duration // 刷新耗时
+	Err      error         // 刷新失败的原因
+	Frozen   []string      // 属性发生变化但是已经冻结的绑定路径
+}
+
+// RefreshObserver 属性刷新的观察者，可以用来对接 Prometheus 等监控系统。
+type RefreshObserver interface {
+	OnRefresh(e RefreshEvent)
+}
+
+// RefreshObserverFunc func 形式的 RefreshObserver 。
+type RefreshObserverFunc func(e RefreshEvent)
+
+func (f RefreshObserverFunc) OnRefresh(e RefreshEvent) {
+	f(e)
+}
+
+// Properties 动态属性，绑定和刷新可以在不同的 goroutine 中同时进行。
 type Properties struct {
-	value  atomic.Value
-	fields []*Field
+	value     atomic.Value
+	mutex     sync.RWMutex // 保护 fields、frozen 以及 observers
+	fields    []*Field
+	frozen    []conf.BindParam
+	observers []RefreshObserver
 }
 
 func New() *Properties {
@@ -50,6 +77,13 @@ func New() *Properties {
 	return p
 }
 
+// Observe 添加属性刷新的观察者。
+func (p *Properties) Observe(o RefreshObserver) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.observers = append(p.observers, o)
+}
+
 func (p *Properties) load() *conf.Properties {
 	return p.value.Load().(*conf.Properties)
 }
@@ -74,6 +108,16 @@ func (p *Properties) Bind(i interface{}, opts ...conf.BindOption) error {
 	return p.load().Bind(i, opts...)
 }
 
+// UnusedKeys 返回当前属性中从未被读取的 key ，参考 conf.Properties.UnusedKeys 的解释。
+func (p *Properties) UnusedKeys() []string {
+	return p.load().UnusedKeys()
+}
+
+// Snapshot 返回当前属性的副本，修改副本不会影响当前的属性。
+func (p *Properties) Snapshot() *conf.Properties {
+	return p.load().Copy()
+}
+
 func (p *Properties) Update(m map[string]interface{}) error {
 
 	flat := make(map[string]string)
@@ -90,10 +134,12 @@ func (p *Properties) Update(m map[string]interface{}) error {
 	}
 	sort.Strings(keys)
 
+	p.mutex.Lock()
 	prop := p.load().Copy()
 	for _, k := range keys {
 		err := prop.Set(k, flat[k])
 		if err != nil {
+			p.mutex.Unlock()
 			return err
 		}
 	}
@@ -102,6 +148,7 @@ func (p *Properties) Update(m map[string]interface{}) error {
 
 func (p *Properties) Refresh(prop *conf.Properties) (err error) {
 
+	p.mutex.Lock()
 	old := p.load()
 	oldKeys := old.Keys()
 	newKeys := prop.Keys()
@@ -128,16 +175,15 @@ func (p *Properties) Refresh(prop *conf.Properties) (err error) {
 	return p.refreshKeys(prop, keys)
 }
 
+// refreshKeys 使用 prop 刷新绑定了 keys 的对象，调用之前需要持有写锁，返回之前
+// 释放写锁，然后通知观察者，因此观察者可以调用 Properties 的方法。
 func (p *Properties) refreshKeys(prop *conf.Properties, keys []string) (err error) {
 
+	start := time.Now()
 	updateIndexes := make(map[int]*Field)
 	for _, key := range keys {
 		for index, field := range p.fields {
-			s := strings.TrimPrefix(key, field.param.Key)
-			if len(s) == len(key) {
-				continue
-			}
-			if len(s) == 0 || s[0] == '.' || s[0] == '[' {
+			if matchKey(key, field.param.Key) {
 				if _, ok := updateIndexes[index]; !ok {
 					updateIndexes[index] = field
 				}
@@ -157,7 +203,89 @@ func (p *Properties) refreshKeys(prop *conf.Properties, keys []string) (err erro
 		}
 	}
 
-	return p.refreshFields(prop, updateFields)
+	observers := p.observers
+	if len(observers) == 0 {
+		defer p.mutex.Unlock()
+		return p.refreshFields(prop, updateFields)
+	}
+
+	dropped := countDropped(updateFields)
+	err = p.refreshFields(prop, updateFields)
+	e := RefreshEvent{
+		Keys:     len(keys),
+		Fields:   len(updateFields),
+		Dropped:  countDropped(updateFields) - dropped,
+		Duration: time.Since(start),
+		Err:      err,
+		Frozen:   p.frozenPaths(keys),
+	}
+	p.mutex.Unlock()
+
+	for _, o := range observers {
+		o.OnRefresh(e)
+	}
+	return err
+}
+
+// matchKey 返回 key 是否为 prefix 或者 prefix 的子属性。
+func matchKey(key string, prefix string) bool {
+	s := strings.TrimPrefix(key, prefix)
+	if len(s) == len(key) {
+		return false
+	}
+	return len(s) == 0 || s[0] == '.' || s[0] == '['
+}
+
+// frozenPaths 返回属性发生变化的冻结绑定的路径。
+func (p *Properties) frozenPaths(keys []string) []string {
+	var ret []string
+	for _, param := range p.frozen {
+		for _, key := range keys {
+			if matchKey(key, param.Key) {
+				ret = append(ret, param.Path)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// BoundKeys 返回绑定了动态属性的 key 的数量，同一个 key 绑定多次时只计算一次。
+func (p *Properties) BoundKeys() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	keys := make(map[string]struct{})
+	for _, f := range p.fields {
+		keys[f.param.Key] = struct{}{}
+	}
+	return len(keys)
+}
+
+// Subscriptions 返回所有绑定的 Listener 中还没有关闭的订阅者。
+func (p *Properties) Subscriptions() []Subscription {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	var ret []Subscription
+	for _, f := range p.fields {
+		if l, ok := f.value.(*Listener); ok {
+			for _, s := range l.Subscriptions() {
+				s.Key = f.param.Key
+				ret = append(ret, s)
+			}
+		}
+	}
+	return ret
+}
+
+// countDropped 返回 Listener 类型的绑定对象丢弃的通知总数。
+func countDropped(fields []*Field) int64 {
+	var n int64
+	for _, f := range fields {
+		if l, ok := f.value.(*Listener); ok {
+			n += l.Dropped()
+		}
+	}
+	return n
 }
 
 func (p *Properties) refreshFields(prop *conf.Properties, fields []*Field) (err error) {
@@ -216,6 +344,39 @@ func (p *Properties) BindValue(v reflect.Value, param conf.BindParam) error {
 }
 
 func (p *Properties) bindValue(i interface{}, param conf.BindParam) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	ok, err := p.bindOnce(i, param)
+	if err != nil || !ok {
+		return false, err
+	}
+	p.fields = append(p.fields, &Field{
+		value: i.(Value),
+		param: param,
+	})
+	return true, nil
+}
+
+// BindFrozen 绑定属性但是不参与之后的属性刷新，Value 类型的对象也只会绑定一次，
+// 属性发生变化时通过 RefreshEvent.Frozen 报告。
+func (p *Properties) BindFrozen(v reflect.Value, param conf.BindParam) error {
+	p.mutex.Lock()
+	p.frozen = append(p.frozen, param)
+	p.mutex.Unlock()
+	if v.Kind() == reflect.Ptr {
+		ok, err := p.bindOnce(v.Interface(), param)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return conf.BindValue(p.load(), v.Elem(), v.Elem().Type(), param, p.bindOnce)
+}
+
+// bindOnce 使用当前的属性刷新 Value 类型的对象，不记录绑定关系。
+func (p *Properties) bindOnce(i interface{}, param conf.BindParam) (bool, error) {
 
 	v, ok := i.(Value)
 	if !ok {
@@ -231,11 +392,6 @@ func (p *Properties) bindValue(i interface{}, param conf.BindParam) (bool, error
 	if err != nil {
 		return false, err
 	}
-
-	p.fields = append(p.fields, &Field{
-		value: v,
-		param: param,
-	})
 	return true, nil
 }
 
@@ -21,7 +21,10 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-spring/spring-base/assert"
 	"github.com/go-spring/spring-base/cast"
@@ -213,3 +216,250 @@ func TestDynamic(t *testing.T) {
 		assert.Equal(t, string(b), `{"Integer":4,"Int":4,"Float":2.3,"Map":{"a":"1","b":"2"},"Slice":["3","4"],"Event":{}}`)
 	})
 }
+
+func TestListener(t *testing.T) {
+
+	type ListenerConfig struct {
+		Listener dync.Listener `value:"${listener}"`
+	}
+
+	mgr := dync.New()
+	cfg := new(ListenerConfig)
+	err := mgr.BindValue(reflect.ValueOf(cfg), conf.BindParam{})
+	assert.Nil(t, err)
+
+	coalesce := cfg.Listener.Listen(1, dync.Coalesce)
+	dropNewest := cfg.Listener.Listen(1, dync.DropNewest)
+
+	for i := 1; i <= 3; i++ {
+		p := conf.New()
+		_ = p.Set("listener", i)
+		err = mgr.Refresh(p)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, (<-coalesce).Get("listener"), "3")
+	assert.Equal(t, (<-dropNewest).Get("listener"), "1")
+	assert.Equal(t, cfg.Listener.Dropped(), int64(4))
+
+	subs := mgr.Subscriptions()
+	assert.Equal(t, len(subs), 2)
+	assert.Equal(t, subs[0].Key, "listener")
+	assert.Equal(t, subs[0].Stack, "")
+
+	dync.TraceStacks(true)
+	defer dync.TraceStacks(false)
+	traced := cfg.Listener.Listen(1, dync.Block)
+
+	cfg.Listener.Close(coalesce)
+	cfg.Listener.Close(dropNewest)
+	_, ok := <-coalesce
+	assert.False(t, ok)
+
+	subs = mgr.Subscriptions()
+	assert.Equal(t, len(subs), 1)
+	assert.True(t, strings.Contains(subs[0].Stack, "TestListener"))
+
+	cfg.Listener.Close(traced)
+	assert.Equal(t, len(mgr.Subscriptions()), 0)
+}
+
+func TestListener_BlockClose(t *testing.T) {
+
+	type ListenerConfig struct {
+		Listener dync.Listener `value:"${listener}"`
+	}
+
+	mgr := dync.New()
+	cfg := new(ListenerConfig)
+	err := mgr.BindValue(reflect.ValueOf(cfg), conf.BindParam{})
+	assert.Nil(t, err)
+
+	blocked := cfg.Listener.Listen(1, dync.Block)
+	refreshed := make(chan error)
+	go func() {
+		for i := 1; i <= 2; i++ {
+			p := conf.New()
+			_ = p.Set("listener", i)
+			if err := mgr.Refresh(p); err != nil {
+				refreshed <- err
+				return
+			}
+		}
+		refreshed <- nil
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		other := cfg.Listener.Listen(1, dync.Coalesce)
+		cfg.Listener.Close(other)
+		cfg.Listener.Close(blocked)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked by a pending refresh")
+	}
+	assert.Nil(t, <-refreshed)
+	assert.Equal(t, (<-blocked).Get("listener"), "1")
+	_, ok := <-blocked
+	assert.False(t, ok)
+}
+
+func TestProperties_Observe(t *testing.T) {
+
+	mgr, _, err := newTest()
+	assert.Nil(t, err)
+
+	var events []dync.RefreshEvent
+	mgr.Observe(dync.RefreshObserverFunc(func(e dync.RefreshEvent) {
+		events = append(events, e)
+	}))
+
+	err = mgr.Update(map[string]interface{}{"int": 4, "float": 2.3})
+	assert.Nil(t, err)
+	err = mgr.Update(map[string]interface{}{"int": 9})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[0].Keys, 2)
+	assert.Equal(t, events[0].Fields, 3)
+	assert.Nil(t, events[0].Err)
+	assert.NotNil(t, events[1].Err)
+}
+
+func TestWatch(t *testing.T) {
+
+	mgr := dync.New()
+
+	timeout, err := mgr.WatchDuration("timeout", time.Second, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, timeout.Value(), time.Second)
+
+	size, err := mgr.WatchBytes("size", 1024, 1<<20)
+	assert.Nil(t, err)
+	assert.Equal(t, size.Value(), int64(1024))
+
+	var changes []string
+	timeout.OnChange(func(old, new time.Duration) {
+		changes = append(changes, fmt.Sprintf("%v->%v", old, new))
+	})
+
+	p := conf.New()
+	_ = p.Set("timeout", "5s")
+	_ = p.Set("size", "64KB")
+	err = mgr.Refresh(p)
+	assert.Nil(t, err)
+	assert.Equal(t, timeout.Value(), 5*time.Second)
+	assert.Equal(t, size.Value(), int64(64<<10))
+	assert.Equal(t, changes, []string{"1s->5s"})
+
+	p = conf.New()
+	_ = p.Set("timeout", "2m")
+	_ = p.Set("size", "64KB")
+	err = mgr.Refresh(p)
+	assert.Error(t, err, "property \"timeout\" should be less than or equal to 1m0s, but 2m0s")
+	assert.Equal(t, timeout.Value(), 5*time.Second)
+
+	p = conf.New()
+	_ = p.Set("timeout", "5s")
+	_ = p.Set("size", "0")
+	err = mgr.Refresh(p)
+	assert.Error(t, err, "property \"size\" should be greater than 0, but 0")
+	assert.Equal(t, size.Value(), int64(64<<10))
+
+	_, err = dync.ParseBytes("10XB")
+	assert.Error(t, err, "invalid size \"10XB\"")
+}
+
+func TestProperties_Concurrent(t *testing.T) {
+
+	mgr := dync.New()
+	mgr.Observe(dync.RefreshObserverFunc(func(e dync.RefreshEvent) {
+		mgr.BoundKeys() // 观察者可以调用 Properties 的方法
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			_, err := mgr.WatchDuration(fmt.Sprintf("timeout-%d", i), time.Second, 0)
+			assert.Nil(t, err)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			err := mgr.Update(map[string]interface{}{"timeout-0": fmt.Sprintf("%ds", i+1)})
+			assert.Nil(t, err)
+		}(i)
+		go func() {
+			defer wg.Done()
+			mgr.BoundKeys()
+			mgr.Subscriptions()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, mgr.BoundKeys(), 4)
+}
+
+func TestOnChange(t *testing.T) {
+
+	var cfg struct {
+		Timeout dync.Duration `value:"${timeout:=1s}"`
+		Size    dync.Bytes    `value:"${size:=1KB}"`
+	}
+
+	var changes []string
+	cfg.Timeout.OnChange(func(old, new time.Duration) {
+		changes = append(changes, fmt.Sprintf("timeout:%v->%v", old, new))
+	})
+	cfg.Size.OnChange(func(old, new int64) {
+		changes = append(changes, fmt.Sprintf("size:%d->%d", old, new))
+	})
+
+	mgr := dync.New()
+	err := mgr.BindValue(reflect.ValueOf(&cfg), conf.BindParam{})
+	assert.Nil(t, err)
+	assert.Equal(t, cfg.Timeout.Value(), time.Second)
+	assert.Equal(t, cfg.Size.Value(), int64(1024))
+	assert.Nil(t, changes) // 绑定时的首次赋值不会触发回调
+
+	p := conf.New()
+	_ = p.Set("timeout", "5s")
+	_ = p.Set("size", "1KB")
+	err = mgr.Refresh(p)
+	assert.Nil(t, err)
+	assert.Equal(t, changes, []string{"timeout:1s->5s"})
+}
+
+func TestWatch_Concurrent(t *testing.T) {
+
+	mgr := dync.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			_, err := mgr.WatchDuration(fmt.Sprintf("timeout-%d", i), time.Second, 0)
+			assert.Nil(t, err)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := mgr.WatchBytes(fmt.Sprintf("size-%d", i), 1024, 0)
+			assert.Nil(t, err)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			p := conf.New()
+			_ = p.Set("timeout-0", fmt.Sprintf("%ds", i+1))
+			_ = p.Set("size-0", fmt.Sprintf("%dKB", i+1))
+			assert.Nil(t, mgr.Refresh(p))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, mgr.BoundKeys(), 8)
+}
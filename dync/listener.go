@@ -0,0 +1,187 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dync
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// ListenPolicy 订阅者的通知队列已满时的处理策略。
+type ListenPolicy int
+
+const (
+	Coalesce   = ListenPolicy(iota) // 丢弃最早的通知，保证订阅者总能收到最新的属性
+	DropNewest                      // 丢弃新产生的通知
+	Block                           // 阻塞刷新过程直到订阅者腾出队列空间，不会丢弃通知
+)
+
+var traceStacks int32
+
+// TraceStacks 设置是否记录订阅者的创建堆栈，用于排查没有关闭的订阅者，默认不记录。
+func TraceStacks(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&traceStacks, v)
+}
+
+// Subscription 一个还没有关闭的订阅者。
+type Subscription struct {
+	Key     string    // Listener 绑定的属性
+	Created time.Time // 创建时间
+	Stack   string    // 创建堆栈，开启 TraceStacks 时才会记录
+}
+
+type subscriber struct {
+	ch      chan *conf.Properties
+	done    chan struct{} // 关闭订阅者时关闭，用于唤醒阻塞的发送
+	mutex   sync.RWMutex  // 发送时持有读锁，关闭 ch 时持有写锁
+	closed  bool
+	policy  ListenPolicy
+	created time.Time
+	stack   string
+}
+
+// Listener 以 channel 的形式通知属性刷新，每次刷新时向每个订阅者发送刷新后的属性，
+// 同一个订阅者收到通知的顺序和属性刷新的顺序一致。绑定时的首次刷新不会发送通知。
+type Listener struct {
+	dropped int64      // 被丢弃的通知数量
+	notify  sync.Mutex // 保证通知的顺序和属性刷新的顺序一致
+	mutex   sync.Mutex // 保护 subs ，发送通知时不持有
+	subs    []*subscriber
+}
+
+// Listen 创建一个订阅者，size 为通知队列的长度，policy 为队列已满时的处理策略。
+func (l *Listener) Listen(size int, policy ListenPolicy) <-chan *conf.Properties {
+	if size <= 0 {
+		size = 1
+	}
+	sub := &subscriber{
+		ch:      make(chan *conf.Properties, size),
+		done:    make(chan struct{}),
+		policy:  policy,
+		created: time.Now(),
+	}
+	if atomic.LoadInt32(&traceStacks) == 1 {
+		sub.stack = string(debug.Stack())
+	}
+	l.mutex.Lock()
+	l.subs = append(l.subs, sub)
+	l.mutex.Unlock()
+	return sub.ch
+}
+
+// Close 关闭 Listen 返回的订阅者，关闭之后不再发送通知并且关闭 ch ，阻塞在该订阅
+// 者上的通知会被放弃。
+func (l *Listener) Close(ch <-chan *conf.Properties) {
+	var sub *subscriber
+	l.mutex.Lock()
+	for i, s := range l.subs {
+		if s.ch == ch {
+			l.subs = append(l.subs[:i], l.subs[i+1:]...)
+			sub = s
+			break
+		}
+	}
+	l.mutex.Unlock()
+	if sub == nil {
+		return
+	}
+	close(sub.done)
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	sub.closed = true
+	close(sub.ch)
+}
+
+// Subscriptions 返回还没有关闭的订阅者。
+func (l *Listener) Subscriptions() []Subscription {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var ret []Subscription
+	for _, sub := range l.subs {
+		ret = append(ret, Subscription{Created: sub.created, Stack: sub.stack})
+	}
+	return ret
+}
+
+// Dropped 返回因为队列已满而被丢弃的通知数量。
+func (l *Listener) Dropped() int64 {
+	return atomic.LoadInt64(&l.dropped)
+}
+
+func (l *Listener) Refresh(prop *conf.Properties, param conf.BindParam) error {
+	l.notify.Lock()
+	defer l.notify.Unlock()
+	l.mutex.Lock()
+	subs := append([]*subscriber(nil), l.subs...)
+	l.mutex.Unlock()
+	for _, sub := range subs {
+		l.send(sub, prop)
+	}
+	return nil
+}
+
+// send 向订阅者发送通知，订阅者已经关闭时直接返回。
+func (l *Listener) send(sub *subscriber, prop *conf.Properties) {
+
+	sub.mutex.RLock()
+	defer sub.mutex.RUnlock()
+	if sub.closed {
+		return
+	}
+
+	if sub.policy == Block {
+		select {
+		case sub.ch <- prop:
+		case <-sub.done:
+		}
+		return
+	}
+
+	for {
+		select {
+		case sub.ch <- prop:
+			return
+		default:
+		}
+		if sub.policy == DropNewest {
+			atomic.AddInt64(&l.dropped, 1)
+			return
+		}
+		select {
+		case <-sub.ch:
+			atomic.AddInt64(&l.dropped, 1)
+		default:
+		}
+	}
+}
+
+func (l *Listener) Validate(prop *conf.Properties, param conf.BindParam) error {
+	return nil
+}
+
+func (l *Listener) MarshalJSON() ([]byte, error) {
+	return json.Marshal(make(map[string]string))
+}
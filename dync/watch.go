@@ -0,0 +1,76 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dync
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// WatchDuration 绑定 key 对应的时长属性并返回实时的值，属性不存在时使用 def 。
+// 属性值必须大于 0 ，max 大于 0 时属性值不能超过 max ，刷新时校验失败的属性值
+// 不会生效。可以通过返回值的 OnChange 方法监听属性值的变化。
+func (p *Properties) WatchDuration(key string, def, max time.Duration) (*Duration, error) {
+	x := new(Duration)
+	x.OnValidate(func(v time.Duration) error {
+		if v <= 0 {
+			return fmt.Errorf("property %q should be greater than 0, but %v", key, v)
+		}
+		if max > 0 && v > max {
+			return fmt.Errorf("property %q should be less than or equal to %v, but %v", key, max, v)
+		}
+		return nil
+	})
+	if err := p.watch(x, key, def.String()); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// WatchBytes 绑定 key 对应的字节数属性并返回实时的值，属性不存在时使用 def ，
+// 属性值的格式参考 ParseBytes 。属性值必须大于 0 ，max 大于 0 时属性值不能超过
+// max ，刷新时校验失败的属性值不会生效。
+func (p *Properties) WatchBytes(key string, def, max int64) (*Bytes, error) {
+	x := new(Bytes)
+	x.OnValidate(func(v int64) error {
+		if v <= 0 {
+			return fmt.Errorf("property %q should be greater than 0, but %d", key, v)
+		}
+		if max > 0 && v > max {
+			return fmt.Errorf("property %q should be less than or equal to %d, but %d", key, max, v)
+		}
+		return nil
+	})
+	if err := p.watch(x, key, strconv.FormatInt(def, 10)); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// watch 使用 key 和默认值 def 绑定 v ，之后属性刷新时 v 会同步刷新。
+func (p *Properties) watch(v Value, key string, def string) error {
+	param := conf.BindParam{
+		Key:  key,
+		Path: key,
+		Tag:  conf.ParsedTag{Key: key, Def: def, HasDef: true},
+	}
+	_, err := p.bindValue(v, param)
+	return err
+}
@@ -24,7 +24,13 @@ import (
 
 // Eval returns the value for the expression expr.
 func Eval(input string, val interface{}) (bool, error) {
-	r, err := expr.Eval(input, map[string]interface{}{"$": val})
+	return EvalEnv(input, map[string]interface{}{"$": val})
+}
+
+// EvalEnv returns the value for the expression expr, the expression can use
+// the variables and functions in env.
+func EvalEnv(input string, env map[string]interface{}) (bool, error) {
+	r, err := expr.Eval(input, env)
 	if err != nil {
 		return false, util.Wrapf(err, code.FileLine(), "eval %q returns error", input)
 	}
@@ -16,9 +16,14 @@
 
 package grpc
 
+import (
+	"context"
+)
+
 // ServerConfig is the configuration of the grpc server.
 type ServerConfig struct {
-	Port int `value:"${port:=9090}"`
+	Port int    `value:"${port:=9090}"`
+	Addr string `value:"${addr:=}"` // overrides Port when set, such as 127.0.0.1:9090
 }
 
 // EndpointConfig is the configuration of the grpc client.
@@ -31,3 +36,20 @@ type Server struct {
 	Register interface{} // register function
 	Service  interface{} // service provider
 }
+
+// Container is a grpc server, usually implemented by a starter that wraps the
+// *grpc.Server of google.golang.org/grpc, so that this module doesn't depend on
+// any grpc implementation.
+type Container interface {
+
+	// Register registers a service provider, serviceName is the ServiceName of
+	// the grpc.ServiceDesc generated by protoc.
+	Register(serviceName string, server *Server) error
+
+	// Start listens on addr and serves until Stop is called.
+	Start(addr string) error
+
+	// Stop stops the server gracefully, the pending RPCs are canceled when ctx
+	// is done.
+	Stop(ctx context.Context) error
+}
@@ -18,23 +18,28 @@ package gs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/go-spring/spring-base/log"
 	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/cache"
+	"github.com/go-spring/spring-core/clock"
 	"github.com/go-spring/spring-core/conf"
 	"github.com/go-spring/spring-core/grpc"
 	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/gs/cond"
 	"github.com/go-spring/spring-core/mq"
+	"github.com/go-spring/spring-core/resilience"
 	"github.com/go-spring/spring-core/web"
 )
 
@@ -57,21 +62,51 @@ type tempApp struct {
 	consumers   *Consumers
 	grpcServers *GrpcServers
 	banner      string
+	resolvers   []ProfileResolver
+	labels      map[string]string
+	modules     []Module
 }
 
 // App 应用
 type App struct {
 	*tempApp
 
-	logger *log.Logger
+	logger      *log.Logger
+	loggerMutex sync.Mutex
 
 	c *container
 	b *bootstrap
 
-	exitChan chan struct{}
-
-	Events  []AppEvent  `autowire:"${application-event.collection:=*?}"`
-	Runners []AppRunner `autowire:"${command-line-runner.collection:=*?}"`
+	exitChan  chan struct{}
+	exitOnce  sync.Once
+	exitErr   error
+	readyChan chan struct{}
+	doneChan  chan struct{}
+	runErr    error
+	noSignals bool
+	lock      *runLock
+	loggers   loggerLevels
+	isolation *isolation
+
+	profiles       []string
+	codeProperties *conf.Properties
+	refreshMutex   sync.Mutex
+	lastRefresh    *RefreshStatus
+	refreshQueue   sync.Mutex
+	refreshNext    *refreshCall
+	refreshID      uint64
+	listenerMutex  sync.Mutex
+	listeners      []changeListener
+	rollout        rolloutState
+	readiness      []namedChecker
+	sources        []*configSource
+	liveness       []namedChecker
+	labels         map[string]string
+
+	Events   []AppEvent      `autowire:"${application-event.collection:=*?}"`
+	Runners  []AppRunner     `autowire:"${command-line-runner.collection:=*?}"`
+	Checkers []HealthChecker `autowire:"${health-checker.collection:=*?}"`
+	Servers  []web.Server    `autowire:"${web-server.collection:=*?}"`
 }
 
 type Consumers struct {
@@ -113,7 +148,9 @@ func NewApp() *App {
 				servers: map[string]*grpc.Server{},
 			},
 		},
-		exitChan: make(chan struct{}),
+		exitChan:  make(chan struct{}),
+		readyChan: make(chan struct{}),
+		doneChan:  make(chan struct{}),
 	}
 }
 
@@ -122,38 +159,43 @@ func (app *App) Banner(banner string) {
 	app.banner = banner
 }
 
-func (app *App) Run() error {
-
-	config := `
-		<?xml version="1.0" encoding="UTF-8"?>
-		<Configuration>
-			<Appenders>
-				<Console name="Console"/>
-			</Appenders>
-			<Loggers>
-				<Root level="info">
-					<AppenderRef ref="Console"/>
-				</Root>
-			</Loggers>
-		</Configuration>
-	`
-	if err := log.RefreshBuffer(config, ".xml"); err != nil {
-		return err
+func (app *App) Run() (err error) {
+
+	if app.c.state != Unrefreshed {
+		return &IllegalStateError{Op: "Run", State: app.c.state, Expect: refreshOnce}
+	}
+
+	defer func() {
+		app.runErr = err
+		close(app.doneChan)
+	}()
+	defer app.releaseRunLock()
+
+	if app.isolation == nil {
+		if err := app.loggers.reset(); err != nil {
+			return err
+		}
 	}
 
 	app.Object(app)
 	app.Object(app.consumers)
 	app.Object(app.grpcServers)
 	app.Object(app.router).Export((*web.Router)(nil))
+	app.registerDefaultBeans()
+
+	app.loggerMutex.Lock()
 	app.logger = log.GetLogger(util.TypeName(app))
+	app.loggerMutex.Unlock()
 
 	// 响应控制台的 Ctrl+C 及 kill 命令。
-	go func() {
-		ch := make(chan os.Signal, 1)
-		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-		sig := <-ch
-		app.ShutDown(fmt.Sprintf("signal %v", sig))
-	}()
+	if !app.noSignals {
+		go func() {
+			ch := make(chan os.Signal, 1)
+			signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+			sig := <-ch
+			app.ShutDown(fmt.Sprintf("signal %v", sig))
+		}()
+	}
 
 	if err := app.start(); err != nil {
 		return err
@@ -162,12 +204,28 @@ func (app *App) Run() error {
 	<-app.exitChan
 
 	if app.b != nil {
-		app.b.c.Close()
+		if err := app.b.c.Close(); err != nil {
+			app.logger.Error(err)
+		}
 	}
 
-	app.c.Close()
+	err = app.c.Close()
 	app.logger.Info("application exited")
-	return nil
+	if app.exitErr != nil {
+		return app.exitErr
+	}
+	return err
+}
+
+// registerDefaultBeans 注册重试策略、缓存以及时钟等默认的 bean ，用户注册了
+// 相同类型的 bean 时默认的 bean 不生效。
+func (app *App) registerDefaultBeans() {
+	c := cond.OnMissingBean((*resilience.Registry)(nil))
+	app.Provide(resilience.NewRegistry, "${spring.resilience.retry:=}").On(c)
+	c = cond.OnMissingBean((*cache.Manager)(nil))
+	app.Provide(cache.NewManager, "${spring.cache:=}").On(c)
+	c = cond.OnMissingBean((*Clock)(nil))
+	app.Object(clock.Real()).Export((*Clock)(nil)).On(c)
 }
 
 func (app *App) clear() {
@@ -180,13 +238,16 @@ func (app *App) clear() {
 
 func (app *App) start() error {
 
-	e := &configuration{
-		p:               conf.New(),
-		resourceLocator: new(defaultResourceLocator),
-	}
+	e := app.newConfiguration()
 
 	if err := e.prepare(); err != nil {
-		return err
+		return &ConfigError{Err: err}
+	}
+
+	if check, _ := strconv.ParseBool(e.p.Get(SpringVersionCheckEnabled, conf.Def("true"))); check {
+		if err := CheckModuleVersions(); err != nil {
+			return err
+		}
 	}
 
 	showBanner, _ := strconv.ParseBool(e.p.Get(SpringBannerVisible))
@@ -196,23 +257,62 @@ func (app *App) start() error {
 
 	if app.b != nil {
 		if err := app.b.start(e); err != nil {
-			return err
+			return &ConfigError{Err: err}
 		}
+		app.registerBootstrapSources()
+	}
+
+	if err := app.resolveProfiles(e); err != nil {
+		return &ConfigError{Err: err}
 	}
 
+	// 保存代码设置的属性，刷新属性时以此为基础重新加载配置
+	app.profiles = e.ActiveProfiles
+	app.codeProperties = app.c.initProperties.Copy()
+
 	if err := app.loadProperties(e); err != nil {
-		return err
+		return &ConfigError{Err: err}
+	}
+
+	if err := app.resolveLabels(e, app.c.initProperties); err != nil {
+		return &ConfigError{Err: err}
+	}
+
+	if err := app.loadConfigSources(app.c.initProperties); err != nil {
+		return &ConfigError{Err: err}
 	}
 
 	// 保存从环境变量和命令行解析的属性
 	for _, k := range e.p.Keys() {
-		app.c.initProperties.Set(k, e.p.Get(k))
+		app.c.initProperties.Set(k, e.p.Get(k, conf.Raw()))
 	}
 
-	if err := app.c.refresh(false); err != nil {
+	if err := app.configureModules(); err != nil {
+		return &ConfigError{Err: err}
+	}
+
+	disabled, err := app.loadLocalOverrides(e, app.c.initProperties)
+	if err != nil {
+		return &ConfigError{Err: err}
+	}
+	app.c.disableBeans(disabled)
+
+	if err := app.tuneRuntime(app.c.initProperties); err != nil {
+		return &ConfigError{Err: err}
+	}
+
+	if err := app.refreshLogRoutes(app.c.initProperties); err != nil {
+		return &ConfigError{Err: err}
+	}
+
+	if err := app.acquireRunLock(app.c.initProperties); err != nil {
 		return err
 	}
 
+	if err := app.c.refresh(false); err != nil {
+		return &WiringError{Err: err}
+	}
+
 	// 执行命令行启动器
 	for _, r := range app.Runners {
 		r.Run(app.c)
@@ -224,6 +324,7 @@ func (app *App) start() error {
 	}
 
 	app.clear()
+	app.watchConfigSources()
 
 	// 通知应用停止事件
 	app.c.Go(func(ctx context.Context) {
@@ -234,6 +335,7 @@ func (app *App) start() error {
 	})
 
 	app.logger.Info("application started successfully")
+	close(app.readyChan)
 	return nil
 }
 
@@ -293,49 +395,122 @@ func (app *App) printBanner(banner string) {
 	fmt.Println(string(padding) + Version + "\n")
 }
 
+// AddProfileResolver 添加确定激活的 profile 列表的 ProfileResolver ，在加载
+// application 配置文件之前按照添加的顺序执行，bootstrap 中注册的在最后执行。
+func (app *App) AddProfileResolver(r ProfileResolver) {
+	app.resolvers = append(app.resolvers, r)
+}
+
+func (app *App) resolveProfiles(e *configuration) error {
+	resolvers := app.resolvers
+	if app.b != nil {
+		resolvers = append(resolvers, app.b.profileResolvers...)
+	}
+	if err := e.resolveProfiles(resolvers); err != nil {
+		return err
+	}
+	app.logger.Infof("active profiles: %v", e.ActiveProfiles)
+	return nil
+}
+
 func (app *App) loadProperties(e *configuration) error {
-	var resources []Resource
 
-	for _, ext := range e.ConfigExtensions {
-		sources, err := app.loadResource(e, "application"+ext)
-		if err != nil {
-			return err
+	files, err := app.loadConfigFiles(e, readProperties)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range files {
+		for _, key := range p.Keys() {
+			app.c.initProperties.Set(key, p.Get(key, conf.Raw()))
 		}
-		resources = append(resources, sources...)
 	}
 
-	for _, profile := range e.ActiveProfiles {
+	return nil
+}
+
+// loadConfigFiles 按照优先级从低到高的顺序返回 application 以及激活的 profile 对应
+// 的配置文件。配置文件可以通过 spring.profiles.include 属性引入其他 profile 的配置
+// 文件，被引入的配置文件在引入它的配置文件之前加载，即优先级更低，引入关系可以传递，
+// 每个 profile 只加载一次。
+func (app *App) loadConfigFiles(e *configuration, read func(Resource) (*conf.Properties, error)) ([]*conf.Properties, error) {
+
+	var files []*conf.Properties
+	loaded := make(map[string]bool)
+
+	var load func(profile string) error
+	load = func(profile string) error {
+
+		if loaded[profile] {
+			return nil
+		}
+		loaded[profile] = true
+
+		filename := "application"
+		if profile != "" {
+			filename += "-" + profile
+		}
+
+		var arr []*conf.Properties
 		for _, ext := range e.ConfigExtensions {
-			sources, err := app.loadResource(e, "application-"+profile+ext)
+			resources, err := app.loadResource(e, filename+ext)
 			if err != nil {
 				return err
 			}
-			resources = append(resources, sources...)
+			for _, resource := range resources {
+				p, err := read(resource)
+				if err != nil {
+					return err
+				}
+				arr = append(arr, p)
+			}
 		}
-	}
 
-	for _, resource := range resources {
-		b, err := ioutil.ReadAll(resource)
-		if err != nil {
-			return err
-		}
-		p, err := conf.Bytes(b, filepath.Ext(resource.Name()))
-		if err != nil {
-			return err
+		for _, p := range arr {
+			var includes []string
+			tag := conf.Tag("${" + SpringProfilesInclude + ":=}")
+			if err := p.Bind(&includes, tag); err != nil {
+				return err
+			}
+			for _, s := range includes {
+				if s = strings.TrimSpace(s); s == "" {
+					continue
+				}
+				if err := load(s); err != nil {
+					return err
+				}
+			}
 		}
-		for _, key := range p.Keys() {
-			app.c.initProperties.Set(key, p.Get(key))
+
+		files = append(files, arr...)
+		return nil
+	}
+
+	if err := load(""); err != nil {
+		return nil, err
+	}
+	for _, profile := range e.ActiveProfiles {
+		if err := load(profile); err != nil {
+			return nil, err
 		}
 	}
+	return files, nil
+}
 
-	return nil
+// readProperties 读取并解析配置文件。
+func readProperties(resource Resource) (*conf.Properties, error) {
+	b, err := ioutil.ReadAll(resource)
+	if err != nil {
+		return nil, err
+	}
+	return conf.Bytes(b, conf.FileExt(resource.Name()))
 }
 
 func (app *App) loadResource(e *configuration, filename string) ([]Resource, error) {
 
 	var locators []ResourceLocator
 	locators = append(locators, e.resourceLocator)
-	if app.b != nil {
+	if app.b != nil && app.b.tempBootstrap != nil {
 		locators = append(locators, app.b.resourceLocators...)
 	}
 
@@ -352,12 +527,70 @@ func (app *App) loadResource(e *configuration, filename string) ([]Resource, err
 
 // ShutDown 关闭执行器
 func (app *App) ShutDown(msg ...string) {
-	app.logger.Infof("program will exit %s", strings.Join(msg, " "))
-	select {
-	case <-app.exitChan:
-		// chan 已关闭，无需再次关闭。
-	default:
+	app.exit(nil, strings.Join(msg, " "))
+}
+
+// ShutDownWithError 因为 err 关闭执行器，Run 在关闭所有的容器后返回 err ，
+// 一般使用 ServerError 或者 JobError 包装 err 以便区分错误的类别。
+func (app *App) ShutDownWithError(err error) {
+	app.exit(err, err.Error())
+}
+
+// exit 通知程序退出，只有第一次调用时的 err 会被 Run 返回。
+func (app *App) exit(err error, msg string) {
+	app.loggerMutex.Lock()
+	logger := app.logger
+	app.loggerMutex.Unlock()
+	if logger != nil {
+		logger.Infof("program will exit %s", msg)
+	}
+	app.exitOnce.Do(func() {
+		app.exitErr = err
 		close(app.exitChan)
+	})
+}
+
+// DisableSignalHandler 不再响应 Ctrl+C 及 kill 命令，适用于将 gs 嵌入到其他
+// 运行时中的场景，由宿主程序通过 Signal 方法控制程序的生命周期，需要在 Run 之前调用。
+func (app *App) DisableSignalHandler() {
+	app.noSignals = true
+}
+
+// Signal 以编程的方式通知程序退出，宿主程序可以不依赖操作系统信号或者全局的
+// ShutDown 函数控制程序的生命周期，reason 会被记录到日志中。
+func (app *App) Signal(reason string) {
+	app.ShutDown(reason)
+}
+
+// WaitForStartup 等待程序启动完成，即容器刷新完毕并且执行完所有的 AppRunner 和
+// AppEvent 。Run 在启动完成之前返回时返回 Run 的错误，ctx 结束时返回 ctx.Err() 。
+func (app *App) WaitForStartup(ctx context.Context) error {
+	select {
+	case <-app.readyChan:
+		return nil
+	case <-app.doneChan:
+		select {
+		case <-app.readyChan:
+			return nil
+		default:
+		}
+		if app.runErr != nil {
+			return app.runErr
+		}
+		return errors.New("application exited before started")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitForShutdown 等待 Run 返回，即程序退出并且所有的容器都已关闭，ctx 结束时
+// 返回 ctx.Err() 。
+func (app *App) WaitForShutdown(ctx context.Context) error {
+	select {
+	case <-app.doneChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -379,11 +612,21 @@ func (app *App) Property(key string, value interface{}) {
 	app.c.Property(key, value)
 }
 
+// Variant 参考 Container.Variant 的解释。
+func (app *App) Variant(name string, selectors map[string]util.BeanSelector) {
+	app.c.Variant(name, selectors)
+}
+
 // Accept 参考 Container.Accept 的解释。
 func (app *App) Accept(b *BeanDefinition) *BeanDefinition {
 	return app.c.Accept(b)
 }
 
+// TestingUnlock 参考 Container.TestingUnlock 的解释。
+func (app *App) TestingUnlock() {
+	app.c.TestingUnlock()
+}
+
 // Object 参考 Container.Object 的解释。
 func (app *App) Object(i interface{}) *BeanDefinition {
 	return app.c.Accept(NewBean(reflect.ValueOf(i)))
@@ -394,6 +637,64 @@ func (app *App) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return app.c.Accept(NewBean(ctor, args...))
 }
 
+// PropertyNamespace 在属性命名空间中注册 bean 。
+type PropertyNamespace struct {
+	c    *container
+	name string
+}
+
+// Namespace 返回模块的属性命名空间，通过它注册的 bean 的属性绑定都相对于该命名
+// 空间进行，可以避免大型项目中不同模块的属性 key 发生冲突，例如:
+//
+//	ns := app.Namespace("billing")
+//	ns.Object(new(BillingService)) // ${db.url} 绑定 billing.db.url 属性
+//
+// 更多的规则参考 BeanDefinition.Namespace 的解释。
+func (app *App) Namespace(name string) *PropertyNamespace {
+	return &PropertyNamespace{c: app.c, name: name}
+}
+
+// Object 参考 Container.Object 的解释。
+func (ns *PropertyNamespace) Object(i interface{}) *BeanDefinition {
+	return ns.c.Accept(NewBean(reflect.ValueOf(i))).Namespace(ns.name)
+}
+
+// Provide 参考 Container.Provide 的解释。
+func (ns *PropertyNamespace) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
+	return ns.c.Accept(NewBean(ctor, args...)).Namespace(ns.name)
+}
+
+// OnClosed 参考 Container.OnClosed 的解释。
+func (app *App) OnClosed(fn func()) {
+	app.c.OnClosed(fn)
+}
+
+// OnStop 参考 Container.OnStop 的解释。
+func (app *App) OnStop(fn func(ctx context.Context) error, opts ...StopOption) {
+	app.c.OnStop(fn, opts...)
+}
+
+// RegisterProvider 参考 Container.RegisterProvider 的解释。
+func (app *App) RegisterProvider(p BeanProvider) {
+	app.c.RegisterProvider(p)
+}
+
+// UnusedProperties 返回从未被读取的属性，需要开启 spring.app.track-unused-properties
+// 属性，否则返回 nil 。属性刷新之后重新开始统计。
+func (app *App) UnusedProperties() []string {
+	return app.c.p.UnusedKeys()
+}
+
+// GroupOnCondition 参考 Container.GroupOnCondition 的解释。
+func (app *App) GroupOnCondition(condition cond.Condition, fn func() []*BeanDefinition) {
+	app.c.GroupOnCondition(condition, fn)
+}
+
+// RegisterRuntimeBean 参考 Container.RegisterRuntimeBean 的解释。
+func (app *App) RegisterRuntimeBean(b *BeanDefinition) error {
+	return app.c.RegisterRuntimeBean(b)
+}
+
 // HttpGet 注册 GET 方法处理函数。
 func (app *App) HttpGet(path string, h http.HandlerFunc) *web.Mapper {
 	return app.router.HttpGet(path, h)
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/gs/cond"
+)
+
+// MaskedValue 导出状态时敏感属性的值会被替换为该值。
+const MaskedValue = "******"
+
+// SensitiveKeywords 属性名包含这些关键字 (不区分大小写) 时被视为敏感属性。
+var SensitiveKeywords = []string{"password", "secret", "token", "credential", "private-key", "access-key"}
+
+// StateArchive 应用运行状态的存档，包括生效的属性 (敏感属性已脱敏)、bean 列表、
+// 条件的判断结果以及版本信息，用于在本地复现生产环境的注入问题。
+type StateArchive struct {
+	Version    string            `json:"version"`
+	GoVersion  string            `json:"go_version"`
+	Time       time.Time         `json:"time"`
+	Profiles   []string          `json:"profiles,omitempty"`
+	Properties map[string]string `json:"properties"`
+	Beans      []ArchivedBean    `json:"beans"`
+}
+
+// ArchivedBean 存档中的 bean ，Condition 为条件的描述，Status 为刷新后的状态，
+// 条件不满足的 bean 的状态为 Deleted 。
+type ArchivedBean struct {
+	BeanMetadata
+	Status    string `json:"status"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// archiveBeans 记录所有 bean 的元数据以及条件的判断结果，容器刷新之后 bean 的索引
+// 会被清除，因此需要在刷新时记录。
+func (c *container) archiveBeans() {
+	archived := make([]ArchivedBean, 0, len(c.beans))
+	for _, b := range c.beans {
+		a := ArchivedBean{
+			BeanMetadata: getBeanMetadata(b),
+			Status:       getStatusString(b.status),
+		}
+		if b.cond != nil {
+			a.Condition = cond.Describe(b.cond)
+		}
+		archived = append(archived, a)
+	}
+	c.beansMutex.Lock()
+	c.archived = archived
+	c.beansMutex.Unlock()
+}
+
+// archivedBeans 返回刷新时记录的 bean ，可以在其他 goroutine 中调用。
+func (c *container) archivedBeans() []ArchivedBean {
+	c.beansMutex.RLock()
+	defer c.beansMutex.RUnlock()
+	return c.archived
+}
+
+// ExportState 将应用的运行状态以 JSON 格式写入 path 指定的文件，需要在 Run 之后
+// 调用，可以通过 LoadState 加载。
+func (app *App) ExportState(path string) error {
+
+	if err := app.c.checkAfterRefresh("ExportState"); err != nil {
+		return err
+	}
+
+	a := &StateArchive{
+		Version:    Version,
+		GoVersion:  runtime.Version(),
+		Time:       time.Now(),
+		Profiles:   app.profiles,
+		Properties: make(map[string]string),
+		Beans:      app.c.archivedBeans(),
+	}
+	for _, k := range app.c.p.Keys() {
+		v := app.c.p.Get(k, conf.Raw())
+		if isSensitiveKey(k) {
+			v = MaskedValue
+		}
+		a.Properties[k] = v
+	}
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// isSensitiveKey 返回属性名是否包含 SensitiveKeywords 中的关键字。
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range SensitiveKeywords {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadState 加载 ExportState 导出的运行状态。
+func LoadState(path string) (*StateArchive, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := new(StateArchive)
+	if err = json.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewContainer 使用存档中的属性和 profile 创建容器，在本地注册相同的 bean 之后
+// 刷新容器即可复现生产环境的条件判断和注入结果，注意脱敏的属性值为 MaskedValue ，
+// 可以通过 Property 方法覆盖。
+func (a *StateArchive) NewContainer() Container {
+	c := New()
+	for k, v := range a.Properties {
+		c.Property(k, v)
+	}
+	if len(a.Profiles) > 0 {
+		c.Property(SpringProfilesActive, strings.Join(a.Profiles, ","))
+	}
+	return c
+}
+
+// Bean 返回存档中 ID 为 id 的 bean ，ID 的格式参考 BeanDefinition.ID 。
+func (a *StateArchive) Bean(id string) (ArchivedBean, bool) {
+	for _, b := range a.Beans {
+		if b.Type+":"+b.Name == id {
+			return b, true
+		}
+	}
+	return ArchivedBean{}, false
+}
@@ -24,7 +24,9 @@ import (
 )
 
 type tempBootstrap struct {
-	resourceLocators []ResourceLocator `autowire:""`
+	resourceLocators []ResourceLocator       `autowire:"*?"`
+	profileResolvers []ProfileResolver       `autowire:"*?"`
+	configSources    map[string]ConfigSource `autowire:"*?"`
 }
 
 type bootstrap struct {
@@ -62,6 +64,11 @@ func (b *bootstrap) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return b.c.Accept(NewBean(ctor, args...))
 }
 
+// ProfileResolver 注册 ProfileResolver 类型的 bean 。
+func (b *bootstrap) ProfileResolver(i interface{}) *BeanDefinition {
+	return b.c.Accept(NewBean(reflect.ValueOf(i))).Export((*ProfileResolver)(nil))
+}
+
 // ResourceLocator 参考 Container.Object 的解释。
 func (b *bootstrap) ResourceLocator(i interface{}) *BeanDefinition {
 	return b.c.Accept(NewBean(reflect.ValueOf(i))).Export((*ResourceLocator)(nil))
@@ -69,7 +76,9 @@ func (b *bootstrap) ResourceLocator(i interface{}) *BeanDefinition {
 
 func (b *bootstrap) start(e *configuration) error {
 
-	b.c.Object(b)
+	// 收集 bootstrap 中注册的 ResourceLocator 等 bean ，应用启动后释放。
+	b.tempBootstrap = new(tempBootstrap)
+	b.c.Object(b.tempBootstrap)
 
 	if err := b.loadBootstrap(e); err != nil {
 		return err
@@ -77,7 +86,7 @@ func (b *bootstrap) start(e *configuration) error {
 
 	// 保存从环境变量和命令行解析的属性
 	for _, k := range e.p.Keys() {
-		b.c.initProperties.Set(k, e.p.Get(k))
+		b.c.initProperties.Set(k, e.p.Get(k, conf.Raw()))
 	}
 
 	return b.c.Refresh()
@@ -108,7 +117,7 @@ func (b *bootstrap) loadConfigFile(e *configuration, filename string) error {
 			}
 		}
 		for _, key := range p.Keys() {
-			b.c.initProperties.Set(key, p.Get(key))
+			b.c.initProperties.Set(key, p.Get(key, conf.Raw()))
 		}
 	}
 	return nil
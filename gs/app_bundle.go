@@ -0,0 +1,76 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path"
+)
+
+// SpringResourcesDir 从磁盘读取资源时的根目录，一般设置在 dev 环境的配置文件中，
+// 这样修改磁盘上的资源文件之后不需要重新编译就能立即生效。
+const SpringResourcesDir = "spring.resources.dir"
+
+// ResourceBundle 一组只读的资源文件，比如模板、静态页面等。默认从内嵌的文件系统
+// (比如 http.FS(embed.FS)) 中读取，设置 spring.resources.dir 属性之后从磁盘读取。
+// ResourceBundle 实现了 http.FileSystem 接口，可以直接传给 StaticFS 使用。
+type ResourceBundle struct {
+	dir  string          // 资源所在的目录
+	fs   http.FileSystem // 内嵌的文件系统
+	disk string          `value:"${spring.resources.dir:=}"` // 磁盘上的根目录
+}
+
+// NewResourceBundle 创建 dir 目录下的资源文件集合。
+func NewResourceBundle(dir string, fs http.FileSystem) *ResourceBundle {
+	return &ResourceBundle{dir: dir, fs: fs}
+}
+
+// Dir 返回资源所在的目录。
+func (r *ResourceBundle) Dir() string {
+	return r.dir
+}
+
+// FromDisk 返回是否从磁盘读取资源。
+func (r *ResourceBundle) FromDisk() bool {
+	return r.disk != ""
+}
+
+// Open 打开 name 对应的资源文件，name 是相对于 dir 目录的路径。从磁盘读取时每次
+// 都打开最新的文件，所以修改的内容不需要重启就能生效。
+func (r *ResourceBundle) Open(name string) (http.File, error) {
+	name = path.Join("/", r.dir, name)
+	if r.disk != "" {
+		return http.Dir(r.disk).Open(name)
+	}
+	return r.fs.Open(name)
+}
+
+// ReadFile 读取 name 对应的资源文件的全部内容。
+func (r *ResourceBundle) ReadFile(name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// Resources 注册 dir 目录下的资源文件集合，bean 的名称为 dir 。
+func (app *App) Resources(dir string, fs http.FileSystem) *BeanDefinition {
+	return app.c.Object(NewResourceBundle(dir, fs)).Name(dir)
+}
@@ -17,6 +17,7 @@
 package gs
 
 import (
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -33,17 +34,63 @@ const IncludeEnvPatterns = "INCLUDE_ENV_PATTERNS"
 // ExcludeEnvPatterns 排除符合条件的环境变量。
 const ExcludeEnvPatterns = "EXCLUDE_ENV_PATTERNS"
 
+// SpringProfilesActive 激活的 profile 列表。
+const SpringProfilesActive = "spring.profiles.active"
+
+// SpringProfilesInclude 配置文件中引入的其他 profile 列表。
+const SpringProfilesInclude = "spring.profiles.include"
+
+// K8sNamespaceFile k8s 挂载到容器中的命名空间文件。
+const K8sNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// ProfileResolver 在加载 application 配置文件之前确定激活的 profile 列表，比如
+// 根据主机名、云厂商的元数据或者 k8s 的命名空间推导 profile ，active 为属性或者
+// 环境变量指定的(或者前一个 ProfileResolver 返回的) profile 列表。
+type ProfileResolver interface {
+	ResolveProfiles(active []string) ([]string, error)
+}
+
+// ProfileResolverFunc func 形式的 ProfileResolver 。
+type ProfileResolverFunc func(active []string) ([]string, error)
+
+func (f ProfileResolverFunc) ResolveProfiles(active []string) ([]string, error) {
+	return f(active)
+}
+
+// K8sNamespaceProfileResolver 在没有显式指定 profile 时使用 k8s 的命名空间作为
+// profile ，不在 k8s 中运行时不做任何修改。
+func K8sNamespaceProfileResolver() ProfileResolver {
+	return ProfileResolverFunc(func(active []string) ([]string, error) {
+		if len(active) > 0 {
+			return active, nil
+		}
+		b, err := ioutil.ReadFile(K8sNamespaceFile)
+		if os.IsNotExist(err) {
+			return active, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ns := strings.TrimSpace(string(b)); ns != "" {
+			return []string{ns}, nil
+		}
+		return active, nil
+	})
+}
+
 type configuration struct {
 	p *conf.Properties
 
+	source *isolation // 环境变量和命令行参数，使用指针避免被当作属性绑定
+
 	resourceLocator  ResourceLocator
 	ActiveProfiles   []string `value:"${spring.profiles.active:=}"`
-	ConfigExtensions []string `value:"${spring.config.extensions:=.properties,.yaml,.yml,.toml,.tml}"`
+	ConfigExtensions []string `value:"${spring.config.extensions:=.properties,.yaml,.yml,.toml,.tml,.hcl,.sops.yaml,.sops.yml,.sops.json}"`
 }
 
 // loadSystemEnv 添加符合 includes 条件的环境变量，排除符合 excludes 条件的
 // 环境变量。如果发现存在允许通过环境变量覆盖的属性名，那么保存时转换成真正的属性名。
-func loadSystemEnv(p *conf.Properties) error {
+func loadSystemEnv(p *conf.Properties, environ []string) error {
 
 	toRex := func(patterns []string) ([]*regexp.Regexp, error) {
 		var rex []*regexp.Regexp
@@ -57,8 +104,17 @@ func loadSystemEnv(p *conf.Properties) error {
 		return rex, nil
 	}
 
+	lookupEnv := func(key string) (string, bool) {
+		for _, env := range environ {
+			if strings.HasPrefix(env, key+"=") {
+				return env[len(key)+1:], true
+			}
+		}
+		return "", false
+	}
+
 	includes := []string{".*"}
-	if s, ok := os.LookupEnv(IncludeEnvPatterns); ok {
+	if s, ok := lookupEnv(IncludeEnvPatterns); ok {
 		includes = strings.Split(s, ",")
 	}
 	includeRex, err := toRex(includes)
@@ -67,7 +123,7 @@ func loadSystemEnv(p *conf.Properties) error {
 	}
 
 	var excludes []string
-	if s, ok := os.LookupEnv(ExcludeEnvPatterns); ok {
+	if s, ok := lookupEnv(ExcludeEnvPatterns); ok {
 		excludes = strings.Split(s, ",")
 	}
 	excludeRex, err := toRex(excludes)
@@ -84,7 +140,7 @@ func loadSystemEnv(p *conf.Properties) error {
 		return false
 	}
 
-	for _, env := range os.Environ() {
+	for _, env := range environ {
 		ss := strings.SplitN(env, "=", 2)
 		k, v := ss[0], ""
 		if len(ss) > 1 {
@@ -105,10 +161,10 @@ func loadSystemEnv(p *conf.Properties) error {
 }
 
 func (e *configuration) prepare() error {
-	if err := loadSystemEnv(e.p); err != nil {
+	if err := loadSystemEnv(e.p, e.source.environ); err != nil {
 		return err
 	}
-	if err := LoadCmdArgs(os.Args, e.p); err != nil {
+	if err := LoadCmdArgs(e.source.args, e.p); err != nil {
 		return err
 	}
 	if err := e.p.Bind(e); err != nil {
@@ -119,3 +175,20 @@ func (e *configuration) prepare() error {
 	}
 	return nil
 }
+
+// resolveProfiles 依次使用 resolvers 确定激活的 profile 列表，并且同步修改
+// spring.profiles.active 属性的值。
+func (e *configuration) resolveProfiles(resolvers []ProfileResolver) error {
+	if len(resolvers) == 0 {
+		return nil
+	}
+	profiles := e.ActiveProfiles
+	for _, r := range resolvers {
+		var err error
+		if profiles, err = r.ResolveProfiles(profiles); err != nil {
+			return err
+		}
+	}
+	e.ActiveProfiles = profiles
+	return e.p.Set(SpringProfilesActive, strings.Join(profiles, ","))
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-spring/spring-core/web"
+)
+
+var (
+	webContextType = reflect.TypeOf((*web.Context)(nil)).Elem()
+	webMethods     = map[string]uint32{
+		"GET":     web.MethodGet,
+		"HEAD":    web.MethodHead,
+		"POST":    web.MethodPost,
+		"PUT":     web.MethodPut,
+		"PATCH":   web.MethodPatch,
+		"DELETE":  web.MethodDelete,
+		"CONNECT": web.MethodConnect,
+		"OPTIONS": web.MethodOptions,
+		"TRACE":   web.MethodTrace,
+		"ANY":     web.MethodAny,
+	}
+)
+
+// Controller 注册控制器 bean ，并将控制器的导出方法挂载为路由。路由定义在控制器
+// 的 _ 字段的标签上，其中 path 为路由前缀，其他的键为方法名，值的格式为
+// "METHOD /path"，多个 HTTP 方法使用 | 分隔，例如：
+//
+//	type BookController struct {
+//		_       struct{}     `path:"/books" ListBooks:"GET /" GetBook:"GET /{id}"`
+//		Service *BookService `autowire:""`
+//	}
+//
+// 方法的签名必须是 func(web.Context) 或者 func(context.Context, *struct)anything ，
+// 后者会自动绑定请求参数并将返回值序列化为 JSON 。路由定义有误或者引用了控制器不存在
+// 的导出方法时返回 error ，这时控制器不会被注册。
+func (app *App) Controller(c interface{}) (*BeanDefinition, error) {
+	mappers, err := controllerMappers(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mappers {
+		app.router.AddMapper(m)
+	}
+	return app.Object(c), nil
+}
+
+// controllerMappers 解析控制器的路由定义。
+func controllerMappers(c interface{}) ([]*web.Mapper, error) {
+
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("controller should be pointer to struct, but %s", t)
+	}
+
+	var routes []reflect.StructTag
+	for i := 0; i < t.Elem().NumField(); i++ {
+		f := t.Elem().Field(i)
+		if f.Name != "_" {
+			continue
+		}
+		keys, err := tagKeys(f.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t, err)
+		}
+		for _, k := range keys {
+			if _, ok := t.MethodByName(k); !ok && k != "path" {
+				return nil, fmt.Errorf("%s: route refers to unknown method %s", t, k)
+			}
+		}
+		routes = append(routes, f.Tag)
+	}
+
+	var mappers []*web.Mapper
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		for _, tag := range routes {
+			route, ok := tag.Lookup(m.Name)
+			if !ok {
+				continue
+			}
+			method, path, err := parseRoute(route)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t, m.Name, err)
+			}
+			h, err := controllerHandler(v.Method(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t, m.Name, err)
+			}
+			path = strings.TrimSuffix(tag.Get("path")+path, "/")
+			if path == "" {
+				path = "/"
+			}
+			mappers = append(mappers, web.NewMapper(method, path, h))
+		}
+	}
+	return mappers, nil
+}
+
+// tagKeys 按照 reflect.StructTag 的格式解析标签中所有的键。
+func tagKeys(tag reflect.StructTag) ([]string, error) {
+	var keys []string
+	s := string(tag)
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return keys, nil
+		}
+		i := strings.Index(s, ":\"")
+		if i <= 0 || strings.ContainsAny(s[:i], " \"") {
+			return nil, fmt.Errorf("invalid tag %q", string(tag))
+		}
+		key := s[:i]
+		s = s[i+2:]
+		j := 0
+		for j < len(s) && s[j] != '"' {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(s) {
+			return nil, fmt.Errorf("invalid tag %q", string(tag))
+		}
+		s = s[j+1:]
+		keys = append(keys, key)
+	}
+}
+
+// parseRoute 解析 "METHOD /path" 格式的路由定义。
+func parseRoute(route string) (uint32, string, error) {
+	ss := strings.Fields(route)
+	if len(ss) != 2 {
+		return 0, "", fmt.Errorf("invalid route %q", route)
+	}
+	var method uint32
+	for _, s := range strings.Split(ss[0], "|") {
+		m, ok := webMethods[strings.ToUpper(s)]
+		if !ok {
+			return 0, "", fmt.Errorf("invalid http method %q", s)
+		}
+		method |= m
+	}
+	return method, ss[1], nil
+}
+
+func controllerHandler(fn reflect.Value) (h web.Handler, err error) {
+	t := fn.Type()
+	if t.NumIn() == 1 && t.NumOut() == 0 && t.In(0) == webContextType {
+		f := fn.Interface().(func(web.Context))
+		return web.FUNC(f), nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return web.BIND(fn.Interface()), nil
+}
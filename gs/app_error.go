@@ -0,0 +1,111 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConfigError 加载环境变量、命令行参数以及配置文件时发生的错误，一般需要修改
+// 配置后才能恢复，不适合立即重试。
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string { return "config error: " + e.Err.Error() }
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// WiringError 刷新容器时发生的错误，包括属性绑定、依赖注入以及 bean 的构造和
+// 初始化，依赖的外部资源不可用时可能会在重试后恢复。
+type WiringError struct {
+	Err error
+}
+
+func (e *WiringError) Error() string { return "wiring error: " + e.Err.Error() }
+func (e *WiringError) Unwrap() error { return e.Err }
+
+// ServerError 服务器启动或者运行时发生的错误，例如端口被占用。
+type ServerError struct {
+	Err error
+}
+
+func (e *ServerError) Error() string { return "server error: " + e.Err.Error() }
+func (e *ServerError) Unwrap() error { return e.Err }
+
+// JobError 后台任务发生的错误，后台任务可以通过 ShutDownWithError 返回该错误。
+type JobError struct {
+	Err error
+}
+
+func (e *JobError) Error() string { return "job error: " + e.Err.Error() }
+func (e *JobError) Unwrap() error { return e.Err }
+
+// VersionError 模块之间的版本不兼容时产生的错误，Problems 为所有不满足的版本约束。
+type VersionError struct {
+	Problems []string
+}
+
+func (e *VersionError) Error() string {
+	return "version error:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// MustError Must 系列函数 panic 时抛出的错误，包含调用的函数和调用位置，启动
+// 失败时 Err 中还包含 bean 的注入路径。
+type MustError struct {
+	Op  string
+	At  string
+	Err error
+}
+
+func (e *MustError) Error() string {
+	msg := strings.Replace(e.Err.Error(), "\n", "\n\t", -1)
+	return fmt.Sprintf("gs.%s failed at %s:\n\t%s", e.Op, e.At, msg)
+}
+
+func (e *MustError) Unwrap() error { return e.Err }
+
+// IsConfigError 返回 err 是否为 ConfigError 。
+func IsConfigError(err error) bool {
+	var e *ConfigError
+	return errors.As(err, &e)
+}
+
+// IsWiringError 返回 err 是否为 WiringError 。
+func IsWiringError(err error) bool {
+	var e *WiringError
+	return errors.As(err, &e)
+}
+
+// IsServerError 返回 err 是否为 ServerError 。
+func IsServerError(err error) bool {
+	var e *ServerError
+	return errors.As(err, &e)
+}
+
+// IsJobError 返回 err 是否为 JobError 。
+func IsJobError(err error) bool {
+	var e *JobError
+	return errors.As(err, &e)
+}
+
+// IsVersionError 返回 err 是否为 VersionError 。
+func IsVersionError(err error) bool {
+	var e *VersionError
+	return errors.As(err, &e)
+}
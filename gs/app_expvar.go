@@ -0,0 +1,113 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"expvar"
+	"path"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/web"
+)
+
+// DebugVarsEndpoint expvar 的标准端点。
+const DebugVarsEndpoint = "/debug/vars"
+
+// ExpvarName App 发布到 expvar 的变量名。
+const ExpvarName = "gs"
+
+// SpringExpvarProperties 通过 expvar 发布的属性白名单，列表项可以是属性名或者
+// path.Match 形式的模式，比如 spring.application.name,redis.* ，默认不发布任何
+// 属性，敏感属性的值会被脱敏。
+const SpringExpvarProperties = "spring.app.expvar.properties"
+
+var (
+	expvarOnce sync.Once
+	expvarApp  atomic.Value // *App
+)
+
+// DebugVars 通过 expvar 发布的 App 的状态。
+type DebugVars struct {
+	State      string            `json:"state"`             // 容器的状态
+	Beans      int               `json:"beans"`             // 生效的 bean 数量
+	Startup    float64           `json:"startup"`           // 启动耗时，秒
+	Profiles   []string          `json:"profiles"`          // 激活的 profile 列表
+	Refresh    *RefreshStatus    `json:"refresh,omitempty"` // 最近一次刷新属性的结果
+	Properties map[string]string `json:"properties"`        // 白名单中的属性
+}
+
+// EnableDebugVars 将 App 的状态以 gs 为名发布到 expvar ，并且注册 GET /debug/vars
+// 端点，这样基于 expvar 的监控面板无需额外的代理就可以采集 App 的状态。单独运行的
+// net/http/pprof 服务器也会输出该变量。一个进程中只有最后调用该方法的 App 的状态
+// 会被发布。
+func (app *App) EnableDebugVars() *web.Mapper {
+	expvarApp.Store(app)
+	expvarOnce.Do(func() {
+		expvar.Publish(ExpvarName, expvar.Func(func() interface{} {
+			return expvarApp.Load().(*App).DebugVars()
+		}))
+	})
+	return app.router.HttpGet(DebugVarsEndpoint, expvar.Handler().ServeHTTP)
+}
+
+// DebugVars 返回 App 当前的状态。
+func (app *App) DebugVars() *DebugVars {
+
+	v := &DebugVars{
+		State:      app.c.currentState().String(),
+		Profiles:   app.profiles,
+		Refresh:    app.LastRefresh(),
+		Properties: make(map[string]string),
+	}
+
+	if r := app.c.report; r != nil {
+		v.Startup = r.Total.Seconds()
+	}
+	for _, b := range app.c.archivedBeans() {
+		if b.Status != getStatusString(Deleted) {
+			v.Beans++
+		}
+	}
+
+	var patterns []string
+	if err := app.c.p.Bind(&patterns, conf.Tag("${"+SpringExpvarProperties+":=}")); err != nil {
+		return v
+	}
+	for _, k := range app.c.p.Keys() {
+		if !matchAny(patterns, k) {
+			continue
+		}
+		val := app.c.p.Get(k, conf.Raw())
+		if isSensitiveKey(k) {
+			val = MaskedValue
+		}
+		v.Properties[k] = val
+	}
+	return v
+}
+
+// matchAny 返回 key 是否匹配任意一个 path.Match 形式的模式。
+func matchAny(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"os"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+)
+
+func TestEnableDebugVars(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_APP_EXPVAR_PROPERTIES", "spring.application.name,redis.*")
+	gs.Setenv("GS_REDIS_HOST", "127.0.0.1")
+	gs.Setenv("GS_REDIS_PASSWORD", "123456")
+	gs.Setenv("GS_MYSQL_HOST", "127.0.0.1")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.EnableDebugVars()
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	var v gs.DebugVars
+	err := json.Unmarshal([]byte(expvar.Get(gs.ExpvarName).String()), &v)
+	assert.Nil(t, err)
+	assert.Equal(t, v.State, "Refreshed")
+	assert.True(t, v.Beans > 0)
+	assert.Equal(t, v.Properties, map[string]string{
+		"spring.application.name": "test",
+		"redis.host":              "127.0.0.1",
+		"redis.password":          gs.MaskedValue,
+	})
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/web"
+)
+
+// HealthEndpoint 健康检查端点。
+const HealthEndpoint = "/health"
+
+// LivenessEndpoint 存活检查端点。
+const LivenessEndpoint = "/health/liveness"
+
+// ReadinessEndpoint 就绪检查端点。
+const ReadinessEndpoint = "/health/readiness"
+
+// SpringHealthTimeout 每个 HealthChecker 的超时时间，默认 3s 。
+const SpringHealthTimeout = "spring.health.timeout"
+
+// HealthcheckFlag 以健康检查模式运行程序的命令行参数。
+const HealthcheckFlag = "--healthcheck"
+
+// SpringHealthcheckURL 健康检查模式请求的地址，可以通过 -D 参数或者
+// GS_SPRING_APP_HEALTHCHECK_URL 环境变量设置。
+const SpringHealthcheckURL = "spring.app.healthcheck.url"
+
+// SpringHealthcheckTimeout 健康检查模式请求的超时时间。
+const SpringHealthcheckTimeout = "spring.app.healthcheck.timeout"
+
+// HealthChecker 健康检查，返回 error 表示不健康。实现了该接口并且导出该接口
+// 的 bean 会自动参与就绪检查。
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// HealthCheckerFunc func 形式的 HealthChecker 。
+type HealthCheckerFunc func(ctx context.Context) error
+
+func (f HealthCheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type namedChecker struct {
+	name    string
+	checker HealthChecker
+}
+
+// HealthStatus 健康检查的结果。
+type HealthStatus struct {
+	Status string                  `json:"status"`           // UP 或者 DOWN
+	Error  string                  `json:"error,omitempty"`  // 不健康的原因
+	Checks map[string]HealthStatus `json:"checks,omitempty"` // 每个 HealthChecker 的结果
+}
+
+// ReadinessCheck 注册名为 name 的就绪检查，就绪检查失败时实例不再接收流量。
+func (app *App) ReadinessCheck(name string, checker HealthChecker) {
+	app.readiness = append(app.readiness, namedChecker{name, checker})
+}
+
+// LivenessCheck 注册名为 name 的存活检查，存活检查失败时实例会被重启，所以只应
+// 该检查死锁等无法自行恢复的故障。存活检查同时也是就绪检查。
+func (app *App) LivenessCheck(name string, checker HealthChecker) {
+	app.liveness = append(app.liveness, namedChecker{name, checker})
+}
+
+// EnableHealthEndpoint 注册 GET /health 、/health/liveness 以及 /health/readiness
+// 端点，/health 与 /health/readiness 相同。所有的检查都健康时返回 200 ，否则返回
+// 503 ，响应体形如 {"status":"UP","checks":{"db":{"status":"UP"}}} 。
+func (app *App) EnableHealthEndpoint() *web.Mapper {
+	app.router.GetMapping(LivenessEndpoint, func(ctx web.Context) {
+		app.writeHealth(ctx, app.liveness)
+	})
+	app.router.GetMapping(ReadinessEndpoint, func(ctx web.Context) {
+		app.writeHealth(ctx, app.readinessCheckers())
+	})
+	return app.router.GetMapping(HealthEndpoint, func(ctx web.Context) {
+		app.writeHealth(ctx, app.readinessCheckers())
+	})
+}
+
+// readinessCheckers 返回所有的就绪检查，应用退出时增加一个总是失败的检查，以便
+// 在关闭服务器之前摘除流量。
+func (app *App) readinessCheckers() []namedChecker {
+	var checkers []namedChecker
+	checkers = append(checkers, app.liveness...)
+	checkers = append(checkers, app.readiness...)
+	for _, c := range app.Checkers {
+		checkers = append(checkers, namedChecker{reflect.TypeOf(c).String(), c})
+	}
+	select {
+	case <-app.exitChan:
+		checkers = append(checkers, namedChecker{"app", HealthCheckerFunc(func(ctx context.Context) error {
+			return errors.New("app is shutting down")
+		})})
+	default:
+	}
+	return checkers
+}
+
+func (app *App) writeHealth(ctx web.Context, checkers []namedChecker) {
+	timeout, err := time.ParseDuration(app.c.p.Get(SpringHealthTimeout, conf.Def("3s")))
+	if err != nil {
+		ctx.SetStatus(http.StatusInternalServerError)
+		ctx.String("%s", err.Error())
+		return
+	}
+	s := runHealthCheckers(ctx.Context(), checkers, timeout)
+	if s.Status != "UP" {
+		ctx.SetStatus(http.StatusServiceUnavailable)
+	}
+	ctx.JSON(s)
+}
+
+// runHealthCheckers 并发地执行所有的检查，每个检查的超时时间为 timeout 。
+func runHealthCheckers(ctx context.Context, checkers []namedChecker, timeout time.Duration) HealthStatus {
+
+	if len(checkers) == 0 {
+		return HealthStatus{Status: "UP"}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(checkers))
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c HealthChecker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- c.Check(ctx) }()
+			select {
+			case errs[i] = <-done:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+			}
+		}(i, c.checker)
+	}
+	wg.Wait()
+
+	ret := HealthStatus{Status: "UP", Checks: make(map[string]HealthStatus)}
+	for i, c := range checkers {
+		if errs[i] != nil {
+			ret.Status = "DOWN"
+			ret.Checks[c.name] = HealthStatus{Status: "DOWN", Error: errs[i].Error()}
+		} else {
+			ret.Checks[c.name] = HealthStatus{Status: "UP"}
+		}
+	}
+	return ret
+}
+
+// Healthcheck 如果命令行参数中包含 --healthcheck ，那么请求运行中实例的健康检查
+// 端点，然后以 0 (健康) 或者 1 (不健康) 退出进程，否则直接返回。这样 Dockerfile
+// 的 HEALTHCHECK 指令可以直接使用应用程序自身，而不需要在镜像中安装 curl 。
+//
+//	func main() {
+//		gs.Healthcheck()
+//		gs.Run()
+//	}
+func Healthcheck() {
+	for _, arg := range os.Args[1:] {
+		if arg == HealthcheckFlag {
+			if err := checkHealth(os.Args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+}
+
+// checkHealth 请求健康检查端点，返回非 2xx 状态码时返回 error 。
+func checkHealth(args []string) error {
+
+	p := conf.New()
+	if err := loadSystemEnv(p, os.Environ()); err != nil {
+		return err
+	}
+	if err := LoadCmdArgs(args, p); err != nil {
+		return err
+	}
+
+	url := p.Get(SpringHealthcheckURL, conf.Def("http://127.0.0.1:8080"+HealthEndpoint))
+	timeout, err := time.ParseDuration(p.Get(SpringHealthcheckTimeout, conf.Def("3s")))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("healthcheck %s return %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/web"
+)
+
+func TestHealthcheck(t *testing.T) {
+
+	if os.Getenv("GS_TEST_HEALTHCHECK") == "1" {
+		os.Args = append(os.Args, gs.HealthcheckFlag)
+		gs.Healthcheck()
+		return
+	}
+
+	status := int32(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, gs.HealthEndpoint)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	run := func() error {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHealthcheck$")
+		cmd.Env = []string{
+			"GS_TEST_HEALTHCHECK=1",
+			"GS_SPRING_APP_HEALTHCHECK_URL=" + server.URL + gs.HealthEndpoint,
+		}
+		return cmd.Run()
+	}
+
+	assert.Nil(t, run())
+
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	err := run()
+	assert.Error(t, err, "exit status 1")
+}
+
+type dbChecker struct {
+	err error
+}
+
+func (c *dbChecker) Check(ctx context.Context) error {
+	return c.err
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_HEALTH_TIMEOUT", "50ms")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.EnableHealthEndpoint()
+
+	db := &dbChecker{}
+	app.Object(db).Export((*gs.HealthChecker)(nil))
+	app.LivenessCheck("deadlock", gs.HealthCheckerFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	app.ReadinessCheck("slow", gs.HealthCheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}))
+
+	handlers := make(map[string]web.Handler)
+	type PandoraAware struct{}
+	app.Provide(func(r web.Router) PandoraAware {
+		for _, m := range r.Mappers() {
+			handlers[m.Path()] = m.Handler()
+		}
+		return PandoraAware{}
+	})
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	get := func(path string) (int, gs.HealthStatus) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		h := handlers[path]
+		h.Invoke(web.NewBaseContext(path, h, r, &web.SimpleResponse{ResponseWriter: w}))
+		var s gs.HealthStatus
+		err := json.Unmarshal(w.Body.Bytes(), &s)
+		assert.Nil(t, err)
+		return w.Code, s
+	}
+
+	code, s := get(gs.LivenessEndpoint)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, s, gs.HealthStatus{
+		Status: "UP",
+		Checks: map[string]gs.HealthStatus{"deadlock": {Status: "UP"}},
+	})
+
+	db.err = errors.New("connection refused")
+	code, s = get(gs.ReadinessEndpoint)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, s, gs.HealthStatus{
+		Status: "DOWN",
+		Checks: map[string]gs.HealthStatus{
+			"deadlock":           {Status: "UP"},
+			"slow":               {Status: "DOWN", Error: "context deadline exceeded"},
+			"*gs_test.dbChecker": {Status: "DOWN", Error: "connection refused"},
+		},
+	})
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"os"
+	"sort"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// isolation 保存隔离运行的 App 使用的环境变量和命令行参数。
+type isolation struct {
+	environ []string
+	args    []string
+}
+
+// Isolate 隔离 App 与进程的全局状态，App 不再读取进程的环境变量和命令行参数，
+// 而是使用 env 和 args 代替，args 不包含程序名。同时 App 不再响应进程信号，
+// 也不再重置全局的日志配置，这样同一个进程中可以同时运行多个互不影响的 App ，
+// 每个 App 拥有独立的属性、bean 以及服务器，内置的 starter 启动服务器失败时只
+// 关闭所在的 App 。进程级的设置在隔离运行时被忽略，包括 spring.runtime.* 的运行
+// 时参数以及 spring.app.single-instance 运行锁，但是 pprof 服务器的采样设置等
+// 第三方代码直接修改的全局状态仍然对整个进程生效。需要在 Run 之前调用。
+func (app *App) Isolate(env map[string]string, args ...string) {
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	i := &isolation{args: append([]string{os.Args[0]}, args...)}
+	for _, k := range keys {
+		i.environ = append(i.environ, k+"="+env[k])
+	}
+	app.isolation = i
+	app.noSignals = true
+}
+
+// Isolated 返回 App 是否隔离运行。
+func (app *App) Isolated() bool {
+	return app.isolation != nil
+}
+
+// newConfiguration 创建加载属性使用的 configuration ，隔离运行时使用 Isolate
+// 设置的环境变量和命令行参数。
+func (app *App) newConfiguration() *configuration {
+	e := &configuration{
+		p:               conf.New(),
+		resourceLocator: new(defaultResourceLocator),
+		source:          app.isolation,
+	}
+	if e.source == nil {
+		e.source = &isolation{environ: os.Environ(), args: os.Args}
+	}
+	return e
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+const (
+	// SpringAppLabels 应用标签的属性前缀，比如 spring.app.labels.idc=bj 。
+	SpringAppLabels = "spring.app.labels"
+
+	// SpringAppLabelsFile k8s Downward API 挂载的 Pod 标签文件。
+	SpringAppLabelsFile = "spring.app.labels-file"
+)
+
+// K8sPodLabelsFile Downward API 挂载 Pod 标签文件的默认位置。
+const K8sPodLabelsFile = "/etc/podinfo/labels"
+
+// LabeledConfigSource 支持按照标签灰度发布配置的外部配置来源，比如 Apollo 的灰度
+// 规则。加载属性之前会调用 SetLabels 方法传入应用的标签。
+type LabeledConfigSource interface {
+	ConfigSource
+	SetLabels(labels map[string]string)
+}
+
+// Label 设置应用的标签，比如 cluster 、idc 等，需要在 Run 之前调用。代码设置的标签
+// 优先级高于 Pod 标签，低于配置文件、环境变量和命令行参数设置的标签。
+func (app *App) Label(key, value string) {
+	if app.tempApp.labels == nil {
+		app.tempApp.labels = make(map[string]string)
+	}
+	app.tempApp.labels[key] = value
+}
+
+// Labels 返回应用最终生效的标签，标签在应用启动时确定，刷新属性时不会改变。
+func (app *App) Labels() map[string]string {
+	labels := make(map[string]string, len(app.labels))
+	for k, v := range app.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// resolveLabels 依次合并 Pod 标签、代码设置的标签、配置文件中的标签以及环境变量
+// 和命令行参数中的标签，并将最终生效的标签以 spring.app.labels.* 的形式保存到 p 中。
+func (app *App) resolveLabels(e *configuration, p *conf.Properties) error {
+
+	file := K8sPodLabelsFile
+	for _, r := range []*conf.Properties{e.p, p} {
+		if r.Has(SpringAppLabelsFile) {
+			file = r.Get(SpringAppLabelsFile)
+			break
+		}
+	}
+
+	labels, err := readPodLabels(file)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range app.tempApp.labels {
+		labels[k] = v
+	}
+
+	const prefix = SpringAppLabels + "."
+	for _, r := range []*conf.Properties{p, e.p} {
+		for _, k := range r.Keys() {
+			if strings.HasPrefix(k, prefix) {
+				labels[strings.TrimPrefix(k, prefix)] = r.Get(k)
+			}
+		}
+	}
+
+	app.labels = labels
+	app.applyLabels(p)
+
+	for _, s := range app.sources {
+		if l, ok := s.source.(LabeledConfigSource); ok {
+			l.SetLabels(app.Labels())
+		}
+	}
+	return nil
+}
+
+// applyLabels 将应用的标签保存到 p 中。
+func (app *App) applyLabels(p *conf.Properties) {
+	keys := make([]string, 0, len(app.labels))
+	for k := range app.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p.Set(SpringAppLabels+"."+k, app.labels[k])
+	}
+}
+
+// readPodLabels 读取 Downward API 格式的标签文件，每行一个 key="value" 形式的
+// 标签，文件不存在时返回空的标签。
+func readPodLabels(file string) (map[string]string, error) {
+
+	labels := make(map[string]string)
+	if file == "" {
+		return labels, nil
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid label %q in %s", line, file)
+		}
+		v := line[i+1:]
+		if strings.HasPrefix(v, `"`) {
+			if v, err = strconv.Unquote(v); err != nil {
+				return nil, fmt.Errorf("invalid label %q in %s", line, file)
+			}
+		}
+		labels[line[:i]] = v
+	}
+	return labels, scanner.Err()
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"strings"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// LocalOverridesFile 本地开发使用的覆盖文件，只在 dev 环境下加载，格式如下:
+//
+//	beans:
+//	  disabled:
+//	    - redisClient          # bean 名称
+//	    - github.com/x/y/z.Foo # bean 类型
+//	properties:
+//	  mock.enabled: true
+const LocalOverridesFile = "gs.local.yaml"
+
+// DevProfile 开发环境的 profile 名称。
+const DevProfile = "dev"
+
+// localOverrides 本地开发覆盖文件的内容。
+type localOverrides struct {
+	DisabledBeans []string `value:"${beans.disabled:=}"`
+}
+
+// loadLocalOverrides 在 dev 环境下加载本地覆盖文件，将覆盖的属性保存到 p 中，
+// 返回需要禁用的 bean 。
+func (app *App) loadLocalOverrides(e *configuration, p *conf.Properties) ([]string, error) {
+
+	isDev := false
+	for _, profile := range e.ActiveProfiles {
+		if profile == DevProfile {
+			isDev = true
+			break
+		}
+	}
+	if !isDev {
+		return nil, nil
+	}
+
+	resources, err := app.loadResource(e, LocalOverridesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var disabled []string
+	for _, resource := range resources {
+		r, err := readProperties(resource)
+		if err != nil {
+			return nil, err
+		}
+		var o localOverrides
+		if err = r.Bind(&o); err != nil {
+			return nil, err
+		}
+		const prefix = "properties."
+		for _, k := range r.Keys() {
+			if strings.HasPrefix(k, prefix) {
+				if err = p.Set(strings.TrimPrefix(k, prefix), r.Get(k, conf.Raw())); err != nil {
+					return nil, err
+				}
+			}
+		}
+		disabled = append(disabled, o.DisabledBeans...)
+	}
+	return disabled, nil
+}
+
+// disableBeans 把名称、类型或者 ID 与 selectors 匹配的 bean 标记为已删除。
+func (c *container) disableBeans(selectors []string) {
+	for _, s := range selectors {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		for _, b := range c.beans {
+			if b.name == s || b.typeName == s || b.ID() == s {
+				b.status = Deleted
+			}
+		}
+	}
+}
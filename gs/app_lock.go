@@ -0,0 +1,128 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// SpringSingleInstance 是否只允许同一台机器上运行一个相同应用和配置的实例，开启
+// 后第二个实例启动时会返回包含运行中实例 PID 的错误。
+const SpringSingleInstance = "spring.app.single-instance"
+
+// SpringSingleInstanceLockFile 单实例锁文件的路径，默认根据应用名称、可执行文件
+// 以及配置文件的路径在临时目录中生成。
+const SpringSingleInstanceLockFile = "spring.app.single-instance-lock-file"
+
+// runLock 基于文件锁的进程级运行锁，进程异常退出时操作系统会自动释放文件锁。
+type runLock struct {
+	file *os.File
+	path string
+}
+
+// acquireRunLock 如果开启了 spring.app.single-instance 属性则获取运行锁。
+func (app *App) acquireRunLock(p *conf.Properties) error {
+
+	if ok, _ := strconv.ParseBool(p.Get(SpringSingleInstance)); !ok {
+		return nil
+	}
+
+	// 运行锁是进程级的，同一个进程中隔离运行的 App 不能互斥。
+	if app.Isolated() {
+		app.logger.Warnf("%s is ignored by isolated app", SpringSingleInstance)
+		return nil
+	}
+
+	path := p.Get(SpringSingleInstanceLockFile)
+	if path == "" {
+		path = defaultLockFile(p)
+	}
+
+	l, err := newRunLock(path)
+	if err != nil {
+		return err
+	}
+	app.lock = l
+	app.logger.Infof("acquired run lock %s", path)
+	return nil
+}
+
+// releaseRunLock 释放运行锁。
+func (app *App) releaseRunLock() {
+	if app.lock != nil {
+		app.lock.release()
+		app.lock = nil
+	}
+}
+
+// defaultLockFile 返回默认的锁文件路径，相同应用和配置的实例使用相同的锁文件。
+func defaultLockFile(p *conf.Properties) string {
+	exe, _ := os.Executable()
+	name := p.Get("spring.application.name")
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(exe), filepath.Ext(exe))
+	}
+	h := fnv.New32a()
+	h.Write([]byte(exe))
+	h.Write([]byte(p.Get("spring.config.locations")))
+	file := fmt.Sprintf("go-spring-%s-%x.lock", name, h.Sum32())
+	return filepath.Join(os.TempDir(), file)
+}
+
+func newRunLock(path string) (*runLock, error) {
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = lockFile(f); err != nil {
+		b, _ := ioutil.ReadAll(f)
+		f.Close()
+		pid := strings.TrimSpace(string(b))
+		if pid == "" {
+			pid = "unknown"
+		}
+		return nil, fmt.Errorf("another instance (pid %s) is running, lock file %s: %w", pid, path, err)
+	}
+
+	if err = f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, err
+	}
+	return &runLock{file: f, path: path}, nil
+}
+
+// release 释放运行锁。锁文件不会被删除，否则另一个进程可能在删除之前打开并锁住了
+// 这个文件，而之后的进程又创建了新的锁文件，导致两个实例同时运行。
+func (l *runLock) release() {
+	_ = l.file.Truncate(0)
+	unlockFile(l.file)
+	l.file.Close()
+}
@@ -0,0 +1,381 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/web"
+)
+
+// LoggersEndpoint 动态修改日志级别的管理端点。
+const LoggersEndpoint = "/loggers/{name}"
+
+// SpringLogRoutes 日志标签的路由表，spring.log.routes.<logger>.tags 指定路由到
+// logger 的标签，支持 * 通配符，spring.log.routes.<logger>.level 指定 logger
+// 的日志级别，刷新属性时路由表同时生效。
+const SpringLogRoutes = "spring.log.routes"
+
+var validLoggerLevels = map[string]bool{
+	"trace": true,
+	"debug": true,
+	"info":  true,
+	"warn":  true,
+	"error": true,
+	"panic": true,
+	"fatal": true,
+}
+
+// LogRoute 日志标签到 logger 的路由。
+type LogRoute struct {
+	Tags  []string `json:"tags"`            // 路由到 logger 的标签
+	Level string   `json:"level,omitempty"` // logger 的日志级别，默认 info
+}
+
+// loggerLevels 保存运行时修改的日志级别和日志标签的路由，这些修改在下次刷新日志
+// 配置之前一直有效。
+type loggerLevels struct {
+	mutex      sync.Mutex
+	levels     map[string]string
+	routes     map[string]LogRoute // 通过 API 设置的路由
+	propRoutes map[string]LogRoute // 通过属性设置的路由
+}
+
+// reset 清空所有运行时修改的日志级别和路由，然后使用默认配置刷新日志组件。
+func (l *loggerLevels) reset() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.levels = nil
+	l.routes = nil
+	l.propRoutes = nil
+	return log.RefreshBuffer(l.config(), ".xml")
+}
+
+// setRoute 修改 name 对应 logger 的路由，route 为 nil 时删除路由，然后刷新日志组件。
+func (l *loggerLevels) setRoute(name string, route *LogRoute) error {
+
+	if name == "" {
+		return fmt.Errorf("logger name can't be empty")
+	}
+
+	var r LogRoute
+	if route != nil {
+		var err error
+		if r, err = checkLogRoute(*route); err != nil {
+			return err
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	old, ok := l.routes[name]
+	if l.routes == nil {
+		l.routes = make(map[string]LogRoute)
+	}
+	if route == nil {
+		delete(l.routes, name)
+	} else {
+		l.routes[name] = r
+	}
+
+	if err := log.RefreshBuffer(l.config(), ".xml"); err != nil {
+		if ok {
+			l.routes[name] = old
+		} else {
+			delete(l.routes, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// loadRoutes 使用属性中的路由表替换之前通过属性设置的路由，然后刷新日志组件。
+func (l *loggerLevels) loadRoutes(routes map[string]LogRoute) error {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	old := l.propRoutes
+	l.propRoutes = routes
+
+	if err := log.RefreshBuffer(l.config(), ".xml"); err != nil {
+		l.propRoutes = old
+		return err
+	}
+	return nil
+}
+
+// getRoutes 返回当前生效的路由表，通过 API 设置的路由优先于通过属性设置的路由。
+func (l *loggerLevels) getRoutes() map[string]LogRoute {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.mergeRoutes()
+}
+
+func (l *loggerLevels) mergeRoutes() map[string]LogRoute {
+	ret := make(map[string]LogRoute)
+	for k, v := range l.propRoutes {
+		ret[k] = v
+	}
+	for k, v := range l.routes {
+		ret[k] = v
+	}
+	return ret
+}
+
+// checkLogRoute 检查路由的标签和日志级别，返回规范化之后的路由。
+func checkLogRoute(r LogRoute) (LogRoute, error) {
+	var tags []string
+	for _, tag := range r.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return LogRoute{}, fmt.Errorf("log route should have tags")
+	}
+	level := strings.ToLower(r.Level)
+	if level != "" && !validLoggerLevels[level] {
+		return LogRoute{}, fmt.Errorf("invalid logger level %q", r.Level)
+	}
+	return LogRoute{Tags: tags, Level: level}, nil
+}
+
+// parseLogRoutes 从 spring.log.routes 前缀的属性中解析日志标签的路由表，
+// logger 的名称可以包含 . 字符。
+func parseLogRoutes(p *conf.Properties) (map[string]LogRoute, error) {
+	prefix := SpringLogRoutes + "."
+	routes := make(map[string]LogRoute)
+	for _, key := range p.Keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		s := strings.TrimPrefix(key, prefix)
+		if i := strings.LastIndex(s, "["); i > 0 && strings.HasSuffix(s, "]") {
+			s = s[:i] // tags 使用数组的形式
+		}
+		i := strings.LastIndex(s, ".")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid log route property %q", key)
+		}
+		name, field := s[:i], s[i+1:]
+		r := routes[name]
+		switch field {
+		case "tags":
+			if r.Tags != nil {
+				continue
+			}
+			if err := p.Bind(&r.Tags, conf.Key(prefix+name+".tags")); err != nil {
+				return nil, err
+			}
+		case "level":
+			r.Level = p.Get(key)
+		default:
+			return nil, fmt.Errorf("invalid log route property %q", key)
+		}
+		routes[name] = r
+	}
+	for name, r := range routes {
+		v, err := checkLogRoute(r)
+		if err != nil {
+			return nil, fmt.Errorf("log route %q error: %w", name, err)
+		}
+		routes[name] = v
+	}
+	return routes, nil
+}
+
+// set 修改 name 对应的日志级别，然后刷新日志组件。
+func (l *loggerLevels) set(name string, level string) error {
+
+	if name == "" {
+		return fmt.Errorf("logger name can't be empty")
+	}
+
+	level = strings.ToLower(level)
+	if !validLoggerLevels[level] {
+		return fmt.Errorf("invalid logger level %q", level)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	old := l.levels[name]
+	if l.levels == nil {
+		l.levels = make(map[string]string)
+	}
+	l.levels[name] = level
+
+	if err := log.RefreshBuffer(l.config(), ".xml"); err != nil {
+		if old == "" {
+			delete(l.levels, name)
+		} else {
+			l.levels[name] = old
+		}
+		return err
+	}
+	return nil
+}
+
+// get 返回所有运行时修改的日志级别。
+func (l *loggerLevels) get() map[string]string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	ret := make(map[string]string)
+	for k, v := range l.levels {
+		ret[k] = v
+	}
+	return ret
+}
+
+// config 生成包含运行时日志级别的日志配置。
+func (l *loggerLevels) config() string {
+
+	routes := l.mergeRoutes()
+
+	var names []string
+	for name := range l.levels {
+		names = append(names, name)
+	}
+	for name := range routes {
+		if _, ok := l.levels[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<Console name="Console"/>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="Console"/>
+				</Root>`)
+	for _, name := range names {
+		r := routes[name]
+		level := l.levels[name]
+		if level == "" {
+			level = r.Level
+		}
+		if level == "" {
+			level = "info"
+		}
+		var tags string
+		if len(r.Tags) > 0 {
+			tags = fmt.Sprintf(` tags="%s"`, html.EscapeString(strings.Join(r.Tags, ",")))
+		}
+		buf.WriteString(fmt.Sprintf(`
+				<Logger name="%s" level="%s"%s>
+					<AppenderRef ref="Console"/>
+				</Logger>`, html.EscapeString(name), level, tags))
+	}
+	buf.WriteString(`
+			</Loggers>
+		</Configuration>
+	`)
+	return buf.String()
+}
+
+// SetLoggerLevel 运行时修改 name 对应的日志级别，修改在下次刷新日志配置之前一直有效。
+func (app *App) SetLoggerLevel(name string, level string) error {
+	if err := app.loggers.set(name, level); err != nil {
+		return err
+	}
+	if app.logger != nil {
+		app.logger.Infof("logger %q level changed to %q", name, level)
+	}
+	return nil
+}
+
+// LoggerLevels 返回所有运行时修改的日志级别。
+func (app *App) LoggerLevels() map[string]string {
+	return app.loggers.get()
+}
+
+// SetLogRoute 运行时修改日志标签到 name 对应 logger 的路由，优先于属性设置的
+// 路由，修改在下次刷新日志配置之前一直有效。
+func (app *App) SetLogRoute(name string, route LogRoute) error {
+	if err := app.loggers.setRoute(name, &route); err != nil {
+		return err
+	}
+	if app.logger != nil {
+		app.logger.Infof("logger %q route changed to %v", name, route.Tags)
+	}
+	return nil
+}
+
+// RemoveLogRoute 删除运行时设置的 name 对应 logger 的路由。
+func (app *App) RemoveLogRoute(name string) error {
+	return app.loggers.setRoute(name, nil)
+}
+
+// LogRoutes 返回当前生效的日志标签路由表。
+func (app *App) LogRoutes() map[string]LogRoute {
+	return app.loggers.getRoutes()
+}
+
+// refreshLogRoutes 使用属性中的路由表刷新日志组件。
+func (app *App) refreshLogRoutes(p *conf.Properties) error {
+	routes, err := parseLogRoutes(p)
+	if err != nil {
+		return err
+	}
+	return app.applyLogRoutes(routes)
+}
+
+// applyLogRoutes 使用路由表刷新日志组件，隔离运行的 App 不修改全局的日志配置。
+func (app *App) applyLogRoutes(routes map[string]LogRoute) error {
+	if app.isolation != nil {
+		return nil
+	}
+	return app.loggers.loadRoutes(routes)
+}
+
+// EnableLoggersEndpoint 注册 POST /loggers/{name} 管理端点，请求体的格式
+// 为 {"level":"debug"}，用于运行时修改日志级别。
+func (app *App) EnableLoggersEndpoint() *web.Mapper {
+	return app.router.PostMapping(LoggersEndpoint, func(ctx web.Context) {
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := ctx.Bind(&req); err != nil {
+			ctx.SetStatus(http.StatusBadRequest)
+			ctx.String("%s", err.Error())
+			return
+		}
+		name := ctx.PathParam("name")
+		if err := app.SetLoggerLevel(name, req.Level); err != nil {
+			ctx.SetStatus(http.StatusBadRequest)
+			ctx.String("%s", err.Error())
+			return
+		}
+		ctx.JSON(app.LoggerLevels())
+	})
+}
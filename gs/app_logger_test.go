@@ -0,0 +1,53 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+)
+
+func TestApp_SetLoggerLevel(t *testing.T) {
+	app := gs.NewApp()
+	err := app.SetLoggerLevel("", "debug")
+	assert.Error(t, err, "logger name can't be empty")
+	err = app.SetLoggerLevel("gs", "verbose")
+	assert.Error(t, err, "invalid logger level \"verbose\"")
+	err = app.SetLoggerLevel("gs", "DEBUG")
+	assert.Nil(t, err)
+	assert.Equal(t, app.LoggerLevels(), map[string]string{"gs": "debug"})
+}
+
+func TestApp_SetLogRoute(t *testing.T) {
+	app := gs.NewApp()
+	err := app.SetLogRoute("", gs.LogRoute{Tags: []string{"_dal_*"}})
+	assert.Error(t, err, "logger name can't be empty")
+	err = app.SetLogRoute("dal", gs.LogRoute{})
+	assert.Error(t, err, "log route should have tags")
+	err = app.SetLogRoute("dal", gs.LogRoute{Tags: []string{"_dal_*"}, Level: "verbose"})
+	assert.Error(t, err, "invalid logger level \"verbose\"")
+	err = app.SetLogRoute("dal", gs.LogRoute{Tags: []string{" _dal_*", ""}, Level: "DEBUG"})
+	assert.Nil(t, err)
+	assert.Equal(t, app.LogRoutes(), map[string]gs.LogRoute{
+		"dal": {Tags: []string{"_dal_*"}, Level: "debug"},
+	})
+	err = app.RemoveLogRoute("dal")
+	assert.Nil(t, err)
+	assert.Equal(t, app.LogRoutes(), map[string]gs.LogRoute{})
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/spring-core/dync"
+)
+
+// Metrics 容器的运行指标，可以通过 gs/metrics 模块以 Prometheus 文本格式输出。
+type Metrics struct {
+	Beans             map[string]int // 按照状态统计的 bean 数量
+	Startup           time.Duration  // 容器刷新的耗时
+	Refreshes         int64          // 启动之后动态属性的刷新次数
+	RefreshErrors     int64          // 动态属性刷新失败的次数
+	RefreshDuration   time.Duration  // 动态属性刷新的总耗时
+	RefreshKeys       int64          // 动态属性刷新时发生变化的属性总数
+	RefreshFields     int64          // 动态属性刷新时更新的绑定对象总数
+	RefreshDropped    int64          // 动态属性刷新时 Listener 丢弃的通知总数
+	RefreshHistogram  Histogram      // 动态属性刷新耗时的分布
+	GoroutinesStarted int64          // 通过 Go 方法创建的 goroutine 总数
+	GoroutinesRunning int            // 通过 Go 方法创建并且仍在运行的 goroutine 数量
+}
+
+// Histogram 耗时的直方图，Counts[i] 为耗时不超过 Buckets[i] 的次数 (累计值)。
+type Histogram struct {
+	Buckets []time.Duration // 桶的上限，从小到大排列
+	Counts  []int64         // 耗时不超过对应上限的次数
+	Count   int64           // 总次数
+	Sum     time.Duration   // 总耗时
+}
+
+// refreshBuckets 动态属性刷新耗时直方图的桶上限。
+var refreshBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// containerStats 容器运行过程中累计的计数，只能通过 atomic 操作访问。
+type containerStats struct {
+	refreshes      int64
+	refreshErrors  int64
+	refreshNanos   int64
+	refreshKeys    int64
+	refreshFields  int64
+	refreshDropped int64
+	refreshBuckets [len(refreshBuckets)]int64 // 落在每个桶内的次数 (非累计值)
+	goroutines     int64
+}
+
+// observeRefresh 统计容器启动之后动态属性的刷新。
+func (c *container) observeRefresh(e dync.RefreshEvent) {
+	atomic.AddInt64(&c.stats.refreshes, 1)
+	atomic.AddInt64(&c.stats.refreshNanos, int64(e.Duration))
+	atomic.AddInt64(&c.stats.refreshKeys, int64(e.Keys))
+	atomic.AddInt64(&c.stats.refreshFields, int64(e.Fields))
+	atomic.AddInt64(&c.stats.refreshDropped, e.Dropped)
+	for i, b := range refreshBuckets {
+		if e.Duration <= b {
+			atomic.AddInt64(&c.stats.refreshBuckets[i], 1)
+			break
+		}
+	}
+	if e.Err != nil {
+		atomic.AddInt64(&c.stats.refreshErrors, 1)
+	}
+}
+
+// refreshHistogram 返回动态属性刷新耗时的直方图。
+func (c *container) refreshHistogram() Histogram {
+	h := Histogram{
+		Buckets: make([]time.Duration, len(refreshBuckets)),
+		Counts:  make([]int64, len(refreshBuckets)),
+		Count:   atomic.LoadInt64(&c.stats.refreshes),
+		Sum:     time.Duration(atomic.LoadInt64(&c.stats.refreshNanos)),
+	}
+	var n int64
+	for i, b := range refreshBuckets {
+		n += atomic.LoadInt64(&c.stats.refreshBuckets[i])
+		h.Buckets[i] = b
+		h.Counts[i] = n
+	}
+	return h
+}
+
+// Metrics 返回容器当前的运行指标，bean 以及 goroutine 的数量取自 Stats 。
+func (app *App) Metrics() *Metrics {
+
+	c := app.c
+	s := app.Stats()
+	m := &Metrics{
+		Beans:             s.Beans,
+		Refreshes:         atomic.LoadInt64(&c.stats.refreshes),
+		RefreshErrors:     atomic.LoadInt64(&c.stats.refreshErrors),
+		RefreshDuration:   time.Duration(atomic.LoadInt64(&c.stats.refreshNanos)),
+		RefreshKeys:       atomic.LoadInt64(&c.stats.refreshKeys),
+		RefreshFields:     atomic.LoadInt64(&c.stats.refreshFields),
+		RefreshDropped:    atomic.LoadInt64(&c.stats.refreshDropped),
+		RefreshHistogram:  c.refreshHistogram(),
+		GoroutinesStarted: atomic.LoadInt64(&c.stats.goroutines),
+		GoroutinesRunning: s.Goroutines,
+	}
+
+	if r := c.report; r != nil {
+		m.Startup = r.Total
+	}
+	return m
+}
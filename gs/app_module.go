@@ -0,0 +1,131 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-spring/spring-core/gs/arg"
+)
+
+// Module 可复用的自动配置模块，用来代替类库在 init 函数中直接注册 bean 的副作
+// 用。模块在容器刷新之前配置，模块中注册的 bean 使用模块名作为属性命名空间，比如
+// redis 模块中的 ${addr} 绑定 redis.addr 属性，模块名也是模块默认拥有的属性前缀。
+// 注意和记录版本信息的 ModuleVersion 区分。
+type Module interface {
+	Name() string
+	Configure(r ModuleRegistrar)
+}
+
+// ModuleOrder 模块可以实现该接口指定配置的顺序，Order 小的模块先配置，没有实现
+// 该接口的模块的 Order 为 0 ，Order 相同时按照注册的顺序配置。
+type ModuleOrder interface {
+	Order() int
+}
+
+// ModuleRegistrar 模块注册 bean 以及声明属性前缀的接口。
+type ModuleRegistrar interface {
+
+	// Object 在模块的属性命名空间中注册对象形式的 bean 。
+	Object(i interface{}) *BeanDefinition
+
+	// Provide 在模块的属性命名空间中注册构造函数形式的 bean 。
+	Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition
+
+	// OwnProperties 声明模块拥有的属性前缀，不同的模块拥有相同或者嵌套的属性前缀
+	// 时应用启动失败。
+	OwnProperties(prefix ...string)
+}
+
+// moduleRegistrar 在模块的属性命名空间中注册 bean 并记录声明的属性前缀。
+type moduleRegistrar struct {
+	c          *container
+	name       string
+	properties []string
+}
+
+func (r *moduleRegistrar) Object(i interface{}) *BeanDefinition {
+	return r.c.Accept(NewBean(reflect.ValueOf(i))).Namespace(r.name)
+}
+
+func (r *moduleRegistrar) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
+	return r.c.Accept(NewBean(ctor, args...)).Namespace(r.name)
+}
+
+func (r *moduleRegistrar) OwnProperties(prefix ...string) {
+	r.properties = append(r.properties, prefix...)
+}
+
+// RegisterModule 添加自动配置模块，需要在 Run 之前调用，模块的名称不能重复。
+func (app *App) RegisterModule(m Module) {
+	app.c.checkBeforeRefresh("RegisterModule")
+	app.modules = append(app.modules, m)
+}
+
+// configureModules 按照顺序配置所有的模块，并检查模块名称和属性前缀是否冲突。
+func (app *App) configureModules() error {
+
+	modules := append([]Module(nil), app.modules...)
+	sort.SliceStable(modules, func(i, j int) bool {
+		return moduleOrder(modules[i]) < moduleOrder(modules[j])
+	})
+
+	names := make(map[string]struct{})
+	owners := make(map[string]string)
+	for _, m := range modules {
+
+		name := m.Name()
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("duplicate module %q", name)
+		}
+		names[name] = struct{}{}
+
+		r := &moduleRegistrar{c: app.c, name: name, properties: []string{name}}
+		m.Configure(r)
+
+		for _, prefix := range r.properties {
+			for owned, owner := range owners {
+				if owner != name && overlapPrefix(prefix, owned) {
+					return fmt.Errorf("property prefix %q of module %q conflicts with %q of module %q", prefix, name, owned, owner)
+				}
+			}
+			owners[prefix] = name
+		}
+	}
+	return nil
+}
+
+func moduleOrder(m Module) int {
+	if o, ok := m.(ModuleOrder); ok {
+		return o.Order()
+	}
+	return 0
+}
+
+// overlapPrefix 返回两个属性前缀是否相同或者嵌套，比如 redis 和 redis.pool 。
+func overlapPrefix(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if !strings.HasPrefix(b, a) {
+		return false
+	}
+	return len(a) == len(b) || b[len(a)] == '.' || b[len(a)] == '['
+}
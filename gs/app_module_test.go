@@ -0,0 +1,106 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+)
+
+type moduleClient struct {
+	Addr string `value:"${addr:=localhost}"`
+	Name string `value:"${/spring.application.name:=}"`
+}
+
+type testModule struct {
+	name     string
+	order    int
+	prefixes []string
+	client   *moduleClient
+	trace    *[]string
+}
+
+func (m *testModule) Name() string { return m.name }
+
+func (m *testModule) Order() int { return m.order }
+
+func (m *testModule) Configure(r gs.ModuleRegistrar) {
+	*m.trace = append(*m.trace, m.name)
+	r.Object(m.client).Name(m.name + "Client")
+	r.OwnProperties(m.prefixes...)
+}
+
+func runModules(modules ...gs.Module) error {
+	os.Clearenv()
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.Property("spring.application.name", "test")
+	app.Property("redis.addr", "127.0.0.1:6379")
+	for _, m := range modules {
+		app.RegisterModule(m)
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- app.Run() }()
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(100 * time.Millisecond):
+	}
+	app.Signal("test done")
+	return app.WaitForShutdown(context.Background())
+}
+
+func TestModule(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		var trace []string
+		redis := &testModule{name: "redis", order: 2, client: new(moduleClient), trace: &trace}
+		mq := &testModule{name: "mq", order: 1, prefixes: []string{"spring.mq"}, client: new(moduleClient), trace: &trace}
+		err := runModules(redis, mq)
+		assert.Nil(t, err)
+		assert.Equal(t, trace, []string{"mq", "redis"})
+		assert.Equal(t, redis.client.Addr, "127.0.0.1:6379")
+		assert.Equal(t, redis.client.Name, "test")
+		assert.Equal(t, mq.client.Addr, "localhost")
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		var trace []string
+		err := runModules(
+			&testModule{name: "redis", client: new(moduleClient), trace: &trace},
+			&testModule{name: "redis", client: new(moduleClient), trace: &trace},
+		)
+		var e *gs.ConfigError
+		assert.True(t, errors.As(err, &e))
+		assert.Error(t, err, "duplicate module \"redis\"")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		var trace []string
+		err := runModules(
+			&testModule{name: "redis", client: new(moduleClient), trace: &trace},
+			&testModule{name: "cache", prefixes: []string{"redis.cache"}, client: new(moduleClient), trace: &trace},
+		)
+		assert.Error(t, err, "property prefix \"redis.cache\" of module \"cache\" conflicts with \"redis\" of module \"redis\"")
+	})
+}
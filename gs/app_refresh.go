@@ -0,0 +1,331 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/web"
+)
+
+// RefreshEndpoint 刷新属性的管理端点，POST 触发刷新，GET 查看最近一次刷新的结果。
+const RefreshEndpoint = "/actuator/refresh"
+
+// SpringRefreshToken 访问刷新端点需要的令牌，通过 Authorization: Bearer <token>
+// 请求头传递，未设置时刷新端点拒绝所有请求。
+const SpringRefreshToken = "spring.app.refresh.token"
+
+// RefreshSource 一个配置来源的刷新结果。
+type RefreshSource struct {
+	Name  string `json:"name"`            // 配置来源的名称
+	Keys  int    `json:"keys"`            // 加载到的属性数量
+	Error string `json:"error,omitempty"` // 加载失败的原因
+}
+
+// RefreshStatus 一次属性刷新的结果。
+type RefreshStatus struct {
+	ID       uint64           `json:"id"`                 // 刷新的序号，从 1 开始递增
+	Time     time.Time        `json:"time"`               // 刷新的时间
+	Sources  []RefreshSource  `json:"sources"`            // 每个配置来源的结果
+	Changed  int              `json:"changed"`            // 发生变化的属性数量
+	Changes  *PropertyChanges `json:"changes,omitempty"`  // 发生变化的属性
+	Version  string           `json:"version"`            // 配置版本
+	Deferred bool             `json:"deferred,omitempty"` // 是否等待分批生效
+	Error    string           `json:"error,omitempty"`    // 刷新失败的原因
+}
+
+// refreshCall 一次排队等待执行的属性刷新，排队期间到达的调用共享它的结果。
+type refreshCall struct {
+	done   chan struct{}
+	status *RefreshStatus
+	err    error
+}
+
+// RefreshProperties 重新加载环境变量、命令行参数、配置文件以及外部配置来源最近一次
+// 发送的属性，然后刷新动态属性，任何一个配置来源加载失败时都不会修改当前的属性。
+// 激活的 profile 保持启动时的结果。并发的调用依次执行，正在刷新时到达的调用合并成
+// 下一次刷新并共享同一个结果，调用者可以通过 RefreshStatus.ID 区分是哪一次刷新。
+func (app *App) RefreshProperties() (*RefreshStatus, error) {
+
+	app.refreshQueue.Lock()
+	call, owner := app.refreshNext, false
+	if call == nil {
+		call, owner = &refreshCall{done: make(chan struct{})}, true
+		app.refreshNext = call
+	}
+	app.refreshQueue.Unlock()
+
+	if owner {
+		app.refreshMutex.Lock()
+		app.refreshQueue.Lock()
+		app.refreshNext = nil
+		app.refreshID++
+		id := app.refreshID
+		app.refreshQueue.Unlock()
+		call.status, call.err = app.doRefresh(id)
+		app.refreshMutex.Unlock()
+		close(call.done)
+	}
+
+	<-call.done
+	return call.status, call.err
+}
+
+// doRefresh 执行一次属性刷新，调用者需要持有 refreshMutex 。
+func (app *App) doRefresh(id uint64) (*RefreshStatus, error) {
+
+	status := &RefreshStatus{ID: id, Time: time.Now()}
+	err := app.refreshProperties(status)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	app.lastRefresh = status
+
+	if app.logger != nil {
+		if err != nil {
+			app.logger.Errorf("refresh #%d properties error: %v", id, err)
+		} else {
+			app.logger.Infof("properties refreshed #%d, %d keys changed", id, status.Changed)
+		}
+	}
+	return status, err
+}
+
+func (app *App) refreshProperties(status *RefreshStatus) error {
+
+	if app.codeProperties == nil {
+		return errors.New("should call after Run")
+	}
+
+	report := func(name string, p *conf.Properties, err error) error {
+		s := RefreshSource{Name: name}
+		if err != nil {
+			s.Error = err.Error()
+		} else {
+			s.Keys = len(p.Keys())
+		}
+		status.Sources = append(status.Sources, s)
+		if err != nil {
+			return fmt.Errorf("load %s error: %w", name, err)
+		}
+		return nil
+	}
+
+	e := app.newConfiguration()
+	if err := report("environment", e.p, e.prepare()); err != nil {
+		return err
+	}
+	e.ActiveProfiles = app.profiles
+
+	p := app.codeProperties.Copy()
+
+	files, err := app.loadConfigFiles(e, func(resource Resource) (*conf.Properties, error) {
+		r, err := readProperties(resource)
+		return r, report(resource.Name(), r, err)
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range files {
+		for _, key := range r.Keys() {
+			p.Set(key, r.Get(key, conf.Raw()))
+		}
+	}
+
+	for _, s := range app.sources {
+		r := s.get()
+		if err = report(s.name, r, nil); err != nil {
+			return err
+		}
+		for _, key := range r.Keys() {
+			p.Set(key, r.Get(key, conf.Raw()))
+		}
+	}
+
+	for _, k := range e.p.Keys() {
+		p.Set(k, e.p.Get(k, conf.Raw()))
+	}
+	app.applyLabels(p)
+
+	if _, err = app.loadLocalOverrides(e, p); err != nil {
+		return report(LocalOverridesFile, nil, err)
+	}
+
+	status.Changes = diffProperties(app.c.p.Snapshot(), p)
+	status.Changed = status.Changes.Len()
+	status.Version = configVersion(p)
+	if status.Changed == 0 {
+		app.rollout.clearPending()
+		return nil
+	}
+
+	routes, err := parseLogRoutes(p)
+	if err != nil {
+		return err
+	}
+
+	ok, err := app.admitRollout(status.Version)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		status.Deferred = true
+		return nil
+	}
+
+	if err = app.c.p.Refresh(p); err != nil {
+		return err
+	}
+	app.applyRollout(status.Version)
+	app.notifyPropertyChange(status.Changes)
+	return app.applyLogRoutes(routes)
+}
+
+// PropertyChanges 一次属性刷新中新增、删除以及值发生变化的属性名，均按字典序排列。
+type PropertyChanges struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// Len 返回发生变化的属性数量。
+func (c *PropertyChanges) Len() int {
+	return len(c.Added) + len(c.Removed) + len(c.Modified)
+}
+
+// Filter 返回 prefix 及其子属性的变化，prefix 可以写成 redis 或者 redis.* 的
+// 形式，为空时返回所有的变化。
+func (c *PropertyChanges) Filter(prefix string) *PropertyChanges {
+	prefix = strings.TrimSuffix(prefix, ".*")
+	filter := func(keys []string) []string {
+		var ret []string
+		for _, k := range keys {
+			if hasKeyPrefix(k, prefix) {
+				ret = append(ret, k)
+			}
+		}
+		return ret
+	}
+	return &PropertyChanges{
+		Added:    filter(c.Added),
+		Removed:  filter(c.Removed),
+		Modified: filter(c.Modified),
+	}
+}
+
+// hasKeyPrefix 返回属性名 key 是否为 prefix 本身或者它的子属性。
+func hasKeyPrefix(key, prefix string) bool {
+	if prefix == "" || key == prefix {
+		return true
+	}
+	if !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	c := key[len(prefix)]
+	return c == '.' || c == '['
+}
+
+// diffProperties 返回新增、删除以及值发生变化的属性。
+func diffProperties(old, p *conf.Properties) *PropertyChanges {
+	c := new(PropertyChanges)
+	for _, k := range p.Keys() {
+		if !old.Has(k) {
+			c.Added = append(c.Added, k)
+		} else if old.Get(k, conf.Raw()) != p.Get(k, conf.Raw()) {
+			c.Modified = append(c.Modified, k)
+		}
+	}
+	for _, k := range old.Keys() {
+		if !p.Has(k) {
+			c.Removed = append(c.Removed, k)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+	sort.Strings(c.Modified)
+	return c
+}
+
+type changeListener struct {
+	prefix string
+	fn     func(changes *PropertyChanges)
+}
+
+// OnPropertyChange 订阅 prefix 及其子属性的变化，RefreshProperties 使新的属性
+// 生效之后，如果其中有属性发生了变化，那么使用这些变化调用 fn 。
+func (app *App) OnPropertyChange(prefix string, fn func(changes *PropertyChanges)) {
+	app.listenerMutex.Lock()
+	defer app.listenerMutex.Unlock()
+	app.listeners = append(app.listeners, changeListener{prefix, fn})
+}
+
+// notifyPropertyChange 通知订阅了属性变化的函数。
+func (app *App) notifyPropertyChange(changes *PropertyChanges) {
+	app.listenerMutex.Lock()
+	listeners := app.listeners
+	app.listenerMutex.Unlock()
+	for _, l := range listeners {
+		if c := changes.Filter(l.prefix); c.Len() > 0 {
+			l.fn(c)
+		}
+	}
+}
+
+// LastRefresh 返回最近一次刷新属性的结果，还没有刷新过时返回 nil 。
+func (app *App) LastRefresh() *RefreshStatus {
+	app.refreshMutex.Lock()
+	defer app.refreshMutex.Unlock()
+	return app.lastRefresh
+}
+
+// EnableRefreshEndpoint 注册 /actuator/refresh 管理端点，POST 请求重新加载所有
+// 的配置来源并返回刷新结果，GET 请求返回最近一次的刷新结果。请求需要携带与
+// spring.app.refresh.token 属性相同的令牌。
+func (app *App) EnableRefreshEndpoint() *web.Mapper {
+	return app.router.RequestMapping(web.MethodGet|web.MethodPost, RefreshEndpoint, func(ctx web.Context) {
+
+		token := app.c.p.Get(SpringRefreshToken)
+		auth := ctx.Header(web.HeaderAuthorization)
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+			ctx.SetStatus(http.StatusUnauthorized)
+			ctx.String("invalid refresh token")
+			return
+		}
+
+		if ctx.Request().Method == http.MethodGet {
+			status := app.LastRefresh()
+			if status == nil {
+				status = &RefreshStatus{}
+			}
+			ctx.JSON(status)
+			return
+		}
+
+		status, err := app.RefreshProperties()
+		if err != nil {
+			ctx.SetStatus(http.StatusInternalServerError)
+		}
+		ctx.JSON(status)
+	})
+}
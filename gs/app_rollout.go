@@ -0,0 +1,156 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// SpringConfigRollout 分批生效配置的属性前缀，支持的属性有 percent 、bake-period
+// 以及 instance 。刷新属性发现新的配置版本时，只有实例标识的哈希值落在 percent
+// 范围内的实例立即生效，其他实例等待 bake-period 之后再生效。instance 默认为主机名。
+const SpringConfigRollout = "spring.config.rollout"
+
+// RolloutStatus 当前实例的配置版本生效情况。
+type RolloutStatus struct {
+	Instance  string    `json:"instance"`          // 实例标识
+	Canary    bool      `json:"canary"`            // 是否为先行生效的实例
+	Applied   string    `json:"applied,omitempty"` // 已经生效的配置版本
+	AppliedAt time.Time `json:"appliedAt"`         // 配置版本生效的时间
+	Pending   string    `json:"pending,omitempty"` // 等待生效的配置版本
+	Seen      time.Time `json:"seen"`              // 首次发现等待生效的配置版本的时间
+}
+
+// rolloutState 配置版本的生效状态，由 refreshMutex 保护。
+type rolloutState struct {
+	RolloutStatus
+	cancel chan struct{} // 关闭时取消观察期结束后的重新刷新
+}
+
+// clearPending 清除等待生效的配置版本。
+func (r *rolloutState) clearPending() {
+	if r.cancel != nil {
+		close(r.cancel)
+		r.cancel = nil
+	}
+	r.Pending = ""
+	r.Seen = time.Time{}
+}
+
+type rolloutConfig struct {
+	Percent    int           `value:"${percent:=100}"`
+	BakePeriod time.Duration `value:"${bake-period:=0s}"`
+	Instance   string        `value:"${instance:=}"`
+}
+
+// configVersion 返回属性内容的摘要作为配置版本。
+func configVersion(p *conf.Properties) string {
+	keys := p.Keys()
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(p.Get(k)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// isCanary 根据实例标识的哈希值判断实例是否属于先行生效的 percent% 。
+func isCanary(instance string, percent int) bool {
+	h := fnv.New32a()
+	h.Write([]byte(instance))
+	return int(h.Sum32()%100) < percent
+}
+
+// admitRollout 返回配置版本 version 是否可以在当前实例生效，不能生效时在观察期
+// 结束后自动重新刷新属性，程序退出时放弃等待。
+func (app *App) admitRollout(version string) (bool, error) {
+
+	var c rolloutConfig
+	if err := app.c.p.Bind(&c, conf.Key(SpringConfigRollout)); err != nil {
+		return false, err
+	}
+	if c.Instance == "" {
+		c.Instance, _ = os.Hostname()
+	}
+
+	r := &app.rollout
+	r.Instance = c.Instance
+	r.Canary = isCanary(c.Instance, c.Percent)
+
+	if r.Pending != version {
+		r.clearPending()
+		r.Pending = version
+		r.Seen = time.Now()
+	}
+
+	if c.Percent >= 100 || r.Canary {
+		return true, nil
+	}
+
+	wait := c.BakePeriod - time.Since(r.Seen)
+	if wait <= 0 {
+		return true, nil
+	}
+
+	if r.cancel == nil {
+		cancel := make(chan struct{})
+		r.cancel = cancel
+		app.c.Go(func(ctx context.Context) {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			select {
+			case <-t.C:
+				_, _ = app.RefreshProperties()
+			case <-cancel:
+			case <-ctx.Done():
+			}
+		})
+	}
+	if app.logger != nil {
+		app.logger.Infof("config version %s is deferred for %v on instance %s", version, wait, c.Instance)
+	}
+	return false, nil
+}
+
+// applyRollout 记录配置版本 version 已经在当前实例生效。
+func (app *App) applyRollout(version string) {
+	r := &app.rollout
+	r.clearPending()
+	r.Applied = version
+	r.AppliedAt = time.Now()
+	if app.logger != nil {
+		app.logger.Infof("config version %s is applied on instance %s", version, r.Instance)
+	}
+}
+
+// Rollout 返回当前实例的配置版本生效情况。
+func (app *App) Rollout() RolloutStatus {
+	app.refreshMutex.Lock()
+	defer app.refreshMutex.Unlock()
+	return app.rollout.RolloutStatus
+}
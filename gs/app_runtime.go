@@ -0,0 +1,154 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// SpringRuntimeMaxProcs 设置 GOMAXPROCS ，值为 auto 时根据 cgroup 的 CPU 配额计
+// 算，值为数字时直接使用该值，未设置时不做修改。
+const SpringRuntimeMaxProcs = "spring.runtime.gomaxprocs"
+
+// SpringRuntimeGCPercent 设置 GC 的触发比例，参考 debug.SetGCPercent 的解释。
+const SpringRuntimeGCPercent = "spring.runtime.gc-percent"
+
+// SpringRuntimeHttpTransport 调整 http.DefaultTransport 的属性前缀，支持的属性有
+// max-idle-conns 、max-idle-conns-per-host 、idle-conn-timeout 以及
+// tls-handshake-timeout ，未设置的属性保持不变。
+const SpringRuntimeHttpTransport = "spring.runtime.http-transport"
+
+// tuneRuntime 根据 spring.runtime.* 属性调整运行时以及标准库的参数。这些参数是
+// 进程级的，隔离运行的 App 不做调整，避免影响同一个进程中的其他 App 。
+func (app *App) tuneRuntime(p *conf.Properties) error {
+
+	if app.Isolated() {
+		for _, key := range []string{SpringRuntimeMaxProcs, SpringRuntimeGCPercent, SpringRuntimeHttpTransport} {
+			if p.Has(key) {
+				app.logger.Warnf("%s is ignored by isolated app", key)
+			}
+		}
+		return nil
+	}
+
+	if s := p.Get(SpringRuntimeMaxProcs); s != "" {
+		n, err := maxProcs(s)
+		if err != nil {
+			return err
+		}
+		prev := runtime.GOMAXPROCS(n)
+		app.logger.Infof("GOMAXPROCS changed from %d to %d", prev, n)
+	}
+
+	if s := p.Get(SpringRuntimeGCPercent); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		prev := debug.SetGCPercent(n)
+		app.logger.Infof("GC percent changed from %d to %d", prev, n)
+	}
+
+	if p.Has(SpringRuntimeHttpTransport) {
+		t, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			app.logger.Warnf("http.DefaultTransport isn't *http.Transport, ignore %s", SpringRuntimeHttpTransport)
+			return nil
+		}
+		var c struct {
+			MaxIdleConns        int           `value:"${max-idle-conns:=-1}"`
+			MaxIdleConnsPerHost int           `value:"${max-idle-conns-per-host:=-1}"`
+			IdleConnTimeout     time.Duration `value:"${idle-conn-timeout:=-1ns}"`
+			TLSHandshakeTimeout time.Duration `value:"${tls-handshake-timeout:=-1ns}"`
+		}
+		if err := p.Bind(&c, conf.Key(SpringRuntimeHttpTransport)); err != nil {
+			return err
+		}
+		if c.MaxIdleConns >= 0 {
+			t.MaxIdleConns = c.MaxIdleConns
+		}
+		if c.MaxIdleConnsPerHost >= 0 {
+			t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+		}
+		if c.IdleConnTimeout >= 0 {
+			t.IdleConnTimeout = c.IdleConnTimeout
+		}
+		if c.TLSHandshakeTimeout >= 0 {
+			t.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+		}
+		app.logger.Infof("http.DefaultTransport tuned by %s", SpringRuntimeHttpTransport)
+	}
+	return nil
+}
+
+// maxProcs 返回 spring.runtime.gomaxprocs 属性对应的 GOMAXPROCS 。
+func maxProcs(s string) (int, error) {
+	if s != "auto" {
+		return strconv.Atoi(s)
+	}
+	n := runtime.NumCPU()
+	if quota, ok := cgroupCPUQuota(); ok && quota < float64(n) {
+		n = int(math.Ceil(quota))
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
+
+// cgroupCPUQuota 读取 cgroup v2 或者 v1 的 CPU 配额，没有限制时返回 false 。
+func cgroupCPUQuota() (float64, bool) {
+
+	if b, err := ioutil.ReadFile("/sys/fs/cgroup/cpu.max"); err == nil {
+		ss := strings.Fields(string(b))
+		if len(ss) != 2 || ss[0] == "max" {
+			return 0, false
+		}
+		return cpuQuota(ss[0], ss[1])
+	}
+
+	quota, err := ioutil.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	if err != nil {
+		return 0, false
+	}
+	period, err := ioutil.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if err != nil {
+		return 0, false
+	}
+	return cpuQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func cpuQuota(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// ConfigSource 外部配置来源，比如 Nacos 、Apollo 、Consul 等配置中心。配置来源的
+// 属性优先级高于配置文件，低于环境变量和命令行参数。
+type ConfigSource interface {
+
+	// Load 加载全部的属性，应用启动时调用。
+	Load() (*conf.Properties, error)
+
+	// Watch 开始监听配置的变化，需要立即返回，配置发生变化时向 ch 发送变化之后的
+	// 全部属性。如果配置来源同时实现了 io.Closer 接口，应用退出时调用其 Close 方法。
+	Watch(ch chan<- *conf.Properties)
+}
+
+// configSource 注册的配置来源以及最近一次加载到的属性。
+type configSource struct {
+	name   string
+	source ConfigSource
+	mutex  sync.Mutex
+	p      *conf.Properties
+}
+
+func (s *configSource) get() *conf.Properties {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.p
+}
+
+func (s *configSource) set(p *conf.Properties) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.p = p
+}
+
+// RegisterConfigSource 注册名为 name 的外部配置来源，需要在 Run 之前调用。配置来源
+// 发送变化之后的属性时自动调用 RefreshProperties 刷新动态属性。
+func (app *App) RegisterConfigSource(name string, source ConfigSource) {
+	app.sources = append(app.sources, &configSource{name: name, source: source})
+}
+
+// registerBootstrapSources 注册 bootstrap 中导出为 ConfigSource 的 bean ，配置
+// 来源的名称就是 bean 的名称，按照名称的顺序排在代码注册的配置来源之后。
+func (app *App) registerBootstrapSources() {
+	names := make([]string, 0, len(app.b.configSources))
+	for name := range app.b.configSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		app.RegisterConfigSource(name, app.b.configSources[name])
+	}
+}
+
+// loadConfigSources 加载所有的外部配置来源并且将属性保存到 p 中。
+func (app *App) loadConfigSources(p *conf.Properties) error {
+	for _, s := range app.sources {
+		r, err := s.source.Load()
+		if err != nil {
+			return fmt.Errorf("load config source %s error: %w", s.name, err)
+		}
+		s.set(r)
+		for _, key := range r.Keys() {
+			p.Set(key, r.Get(key, conf.Raw()))
+		}
+	}
+	return nil
+}
+
+// watchConfigSources 监听所有外部配置来源的变化，应用退出时停止监听。
+func (app *App) watchConfigSources() {
+	for _, s := range app.sources {
+		s := s
+		ch := make(chan *conf.Properties, 1)
+		s.source.Watch(ch)
+		app.c.Go(func(ctx context.Context) {
+			defer func() {
+				if c, ok := s.source.(interface{ Close() error }); ok {
+					_ = c.Close()
+				}
+			}()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case p := <-ch:
+					app.logger.Infof("config source %s changed", s.name)
+					s.set(p)
+					_, _ = app.RefreshProperties()
+				}
+			}
+		})
+	}
+}
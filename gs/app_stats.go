@@ -0,0 +1,64 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"net"
+	"strconv"
+)
+
+// Stats 容器当前状态的快照，获取的代价很小，适合自定义的扩缩容或者看门狗程序定期
+// 采集。
+type Stats struct {
+	Beans      map[string]int // 按照状态统计的 bean 数量
+	BoundKeys  int            // 绑定了动态属性的 key 的数量
+	Goroutines int            // 通过 Go 方法创建并且仍在运行的 goroutine 数量
+	Servers    []ServerStats  // web 服务器以及当前的连接数
+}
+
+// ServerStats web 服务器的状态。
+type ServerStats struct {
+	Address string // 监听地址
+	Conns   int    // 当前的连接数
+}
+
+// Stats 返回容器当前状态的快照，可以在刷新属性或者注册 bean 的同时调用。
+func (app *App) Stats() *Stats {
+
+	c := app.c
+	s := &Stats{
+		Beans:     make(map[string]int),
+		BoundKeys: c.p.BoundKeys(),
+	}
+
+	for _, b := range c.archivedBeans() {
+		s.Beans[b.Status]++
+	}
+
+	c.jobsMutex.Lock()
+	s.Goroutines = len(c.jobs)
+	c.jobsMutex.Unlock()
+
+	for _, server := range app.Servers {
+		config := server.Config()
+		s.Servers = append(s.Servers, ServerStats{
+			Address: net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+			Conns:   server.Conns(),
+		})
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/dync"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/web"
+)
+
+type statsWorker struct {
+	Size  dync.Int64 `value:"${worker.size:=4}"`
+	Limit dync.Int64 `value:"${worker.size:=4}"`
+	stop  chan struct{}
+}
+
+func (w *statsWorker) OnInit(ctx gs.Context) error {
+	ctx.Go(func(context.Context) { <-w.stop })
+	return nil
+}
+
+func TestApp_Stats(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.Object(web.NewHttpServer(web.ServerConfig{Host: "127.0.0.1", Port: 18084}, nil)).Export((*web.Server)(nil))
+
+	worker := &statsWorker{stop: make(chan struct{})}
+	app.Object(worker)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	s := app.Stats()
+	assert.True(t, s.Beans["Wired"] > 0)
+	assert.Equal(t, s.BoundKeys, 1)
+	assert.True(t, s.Goroutines > 0)
+	assert.Equal(t, s.Servers, []gs.ServerStats{{Address: "127.0.0.1:18084"}})
+
+	m := app.Metrics()
+	assert.Equal(t, m.Beans, s.Beans)
+	assert.Equal(t, m.GoroutinesRunning, s.Goroutines)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			app.Stats()
+			app.Metrics()
+		}
+	}()
+	gs.Setenv("GS_WORKER_SIZE", "8")
+	_, err := app.RefreshProperties()
+	assert.Nil(t, err)
+	<-done
+	assert.Equal(t, worker.Size.Value(), int64(8))
+
+	m = app.Metrics()
+	assert.Equal(t, m.Refreshes, int64(1))
+	assert.True(t, m.RefreshKeys >= 1)
+	assert.Equal(t, m.RefreshFields, int64(2))
+	assert.Equal(t, m.RefreshDropped, int64(0))
+	h := m.RefreshHistogram
+	assert.Equal(t, h.Count, int64(1))
+	assert.Equal(t, h.Sum, m.RefreshDuration)
+	assert.Equal(t, len(h.Counts), len(h.Buckets))
+	for i, b := range h.Buckets {
+		if m.RefreshDuration <= b {
+			assert.Equal(t, h.Counts[i], int64(1))
+		} else {
+			assert.Equal(t, h.Counts[i], int64(0))
+		}
+	}
+
+	close(worker.stop)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, app.Stats().Goroutines, s.Goroutines-1)
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
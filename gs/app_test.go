@@ -17,15 +17,37 @@
 package gs_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/cache"
+	"github.com/go-spring/spring-core/clock"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/dync"
+	"github.com/go-spring/spring-core/grpc"
 	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/resilience"
+	"github.com/go-spring/spring-core/web"
 )
 
-func startApplication(cfgLocation string, fn func(gs.Context)) *gs.App {
+func startApplication(t *testing.T, cfgLocation string, fn func(gs.Context)) *gs.App {
 
 	app := gs.NewApp()
 	gs.Setenv("GS_SPRING_BANNER_VISIBLE", "true")
@@ -42,8 +64,7 @@ func startApplication(cfgLocation string, fn func(gs.Context)) *gs.App {
 			panic(err)
 		}
 	}()
-
-	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, app.WaitForStartup(context.Background()))
 	return app
 }
 
@@ -52,7 +73,7 @@ func TestConfig(t *testing.T) {
 	t.Run("config via env", func(t *testing.T) {
 		os.Clearenv()
 		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
-		app := startApplication("testdata/config/", func(ctx gs.Context) {
+		app := startApplication(t, "testdata/config/", func(ctx gs.Context) {
 			assert.Equal(t, ctx.Prop("spring.profiles.active"), "dev")
 		})
 		defer app.ShutDown("run test end")
@@ -61,7 +82,7 @@ func TestConfig(t *testing.T) {
 	t.Run("config via env 2", func(t *testing.T) {
 		os.Clearenv()
 		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
-		app := startApplication("testdata/config/", func(ctx gs.Context) {
+		app := startApplication(t, "testdata/config/", func(ctx gs.Context) {
 			assert.Equal(t, ctx.Prop("spring.profiles.active"), "dev")
 		})
 		defer app.ShutDown("run test end")
@@ -70,7 +91,7 @@ func TestConfig(t *testing.T) {
 	t.Run("profile via env&config 2", func(t *testing.T) {
 		os.Clearenv()
 		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
-		app := startApplication("testdata/config/", func(ctx gs.Context) {
+		app := startApplication(t, "testdata/config/", func(ctx gs.Context) {
 			assert.Equal(t, ctx.Prop("spring.profiles.active"), "dev")
 			//keys := ctx.Properties().Keys()
 			//sort.Strings(keys)
@@ -81,3 +102,1128 @@ func TestConfig(t *testing.T) {
 		defer app.ShutDown("run test end")
 	})
 }
+
+type localBean struct{}
+
+func TestLocalOverrides(t *testing.T) {
+
+	t.Run("dev", func(t *testing.T) {
+		os.Clearenv()
+		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+		var h struct {
+			Mock     bool       `value:"${local.mock}"`
+			Disabled *localBean `autowire:"disabledBean?"`
+			Enabled  *localBean `autowire:"enabledBean"`
+		}
+
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		app.Object(new(localBean)).Name("disabledBean")
+		app.Object(new(localBean)).Name("enabledBean")
+		app.Object(&h)
+		go func() { _ = app.Run() }()
+		assert.Nil(t, app.WaitForStartup(context.Background()))
+		assert.True(t, h.Mock)
+		assert.Nil(t, h.Disabled)
+		assert.NotNil(t, h.Enabled)
+		app.Signal("test done")
+		assert.Nil(t, app.WaitForShutdown(context.Background()))
+	})
+
+	t.Run("not dev", func(t *testing.T) {
+		os.Clearenv()
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+		var h struct {
+			Disabled *localBean `autowire:"disabledBean?"`
+		}
+
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		app.Object(new(localBean)).Name("disabledBean")
+		app.Object(&h)
+		go func() { _ = app.Run() }()
+		assert.Nil(t, app.WaitForStartup(context.Background()))
+		assert.NotNil(t, h.Disabled)
+		app.Signal("test done")
+		assert.Nil(t, app.WaitForShutdown(context.Background()))
+	})
+
+	t.Run("set error", func(t *testing.T) {
+		os.Clearenv()
+		gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "dev")
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+		gs.Setenv("GS_LOCAL_MOCK_ENABLED", "true")
+
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		err := app.Run()
+		assert.Error(t, err, "property 'local.mock' is a map")
+	})
+}
+
+func TestProfileResolver(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.AddProfileResolver(gs.ProfileResolverFunc(func(active []string) ([]string, error) {
+		assert.Equal(t, len(active), 0)
+		return []string{"test"}, nil
+	}))
+
+	type PandoraAware struct{}
+	app.Provide(func(ctx gs.Context) PandoraAware {
+		assert.Equal(t, ctx.Prop("spring.profiles.active"), "test")
+		assert.Equal(t, ctx.Prop("spring.application.name"), "test.yaml")
+		return PandoraAware{}
+	})
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	app.ShutDown("run test end")
+}
+
+type BookService struct {
+	Name string `value:"${book.name:=go-spring}"`
+}
+
+type GetBookRequest struct {
+	ID string `uri:"id"`
+}
+
+type BookController struct {
+	_       struct{}     `path:"/books" ListBooks:"GET /" GetBook:"GET|HEAD /{id}"`
+	Service *BookService `autowire:""`
+}
+
+func (c *BookController) ListBooks(ctx web.Context) {
+	ctx.JSON([]string{c.Service.Name})
+}
+
+func (c *BookController) GetBook(ctx context.Context, req *GetBookRequest) interface{} {
+	return c.Service.Name
+}
+
+func (c *BookController) Unmapped(ctx web.Context) {}
+
+type BadRouteController struct {
+	_ struct{} `path:"/books" Unmapped:"FETCH /"`
+}
+
+func (c *BadRouteController) Unmapped(ctx web.Context) {}
+
+type UnknownRouteController struct {
+	_ struct{} `path:"/books" ListBook:"GET /"`
+}
+
+func (c *UnknownRouteController) ListBooks(ctx web.Context) {}
+
+func TestController(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.Object(new(BookService))
+	_, err := app.Controller(new(BookController))
+	assert.Nil(t, err)
+
+	_, err = app.Controller(BookController{})
+	assert.Error(t, err, "controller should be pointer to struct")
+
+	_, err = app.Controller(new(BadRouteController))
+	assert.Error(t, err, "BadRouteController.Unmapped: invalid http method \"FETCH\"")
+
+	_, err = app.Controller(new(UnknownRouteController))
+	assert.Error(t, err, "UnknownRouteController: route refers to unknown method ListBook")
+
+	type PandoraAware struct{}
+	app.Provide(func(r web.Router, c *BookController) PandoraAware {
+		routes := make(map[string]uint32)
+		for _, m := range r.Mappers() {
+			routes[m.Path()] = m.Method()
+		}
+		assert.Equal(t, routes, map[string]uint32{
+			"/books":      web.MethodGet,
+			"/books/{id}": web.MethodGet | web.MethodHead,
+		})
+		assert.Equal(t, c.Service.Name, "go-spring")
+		return PandoraAware{}
+	})
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	app.ShutDown("run test end")
+}
+
+type fixedClock struct {
+	clock.Clock
+}
+
+func TestDefaultBeans(t *testing.T) {
+
+	run := func(t *testing.T, fn func(app *gs.App)) {
+		os.Clearenv()
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		fn(app)
+		go func() {
+			if err := app.Run(); err != nil {
+				panic(err)
+			}
+		}()
+		assert.Nil(t, app.WaitForStartup(context.Background()))
+		app.Signal("test done")
+		assert.Nil(t, app.WaitForShutdown(context.Background()))
+	}
+
+	t.Run("default", func(t *testing.T) {
+		var bean struct {
+			Clock    gs.Clock             `autowire:""`
+			Registry *resilience.Registry `autowire:""`
+			Manager  *cache.Manager       `autowire:""`
+		}
+		run(t, func(app *gs.App) { app.Object(&bean) })
+		assert.Equal(t, bean.Clock, clock.Real())
+		assert.NotNil(t, bean.Registry)
+		assert.NotNil(t, bean.Manager)
+	})
+
+	t.Run("user defined", func(t *testing.T) {
+		r, err := resilience.NewRegistry(nil)
+		assert.Nil(t, err)
+		m, err := cache.NewManager(nil)
+		assert.Nil(t, err)
+		c := &fixedClock{clock.Real()}
+		var bean struct {
+			Clock    gs.Clock             `autowire:""`
+			Registry *resilience.Registry `autowire:""`
+			Manager  *cache.Manager       `autowire:""`
+		}
+		run(t, func(app *gs.App) {
+			app.Object(c).Export((*gs.Clock)(nil))
+			app.Object(r)
+			app.Object(m)
+			app.Object(&bean)
+		})
+		assert.Same(t, bean.Clock, c)
+		assert.Same(t, bean.Registry, r)
+		assert.Same(t, bean.Manager, m)
+	})
+}
+
+func TestWaitForShutdown(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	closed := false
+	app.Object(&callDestroy{}).Destroy(func(d *callDestroy) { closed = true })
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := app.WaitForShutdown(ctx)
+	assert.Error(t, err, "context deadline exceeded")
+
+	app.Signal("host exit")
+	app.Signal("host exit again")
+
+	err = app.WaitForShutdown(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, closed)
+}
+
+func TestWaitForStartup(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	t.Run("started", func(t *testing.T) {
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		started := false
+		app.Object(new(callDestroy)).Init(func(*callDestroy) { started = true })
+		go func() {
+			if err := app.Run(); err != nil {
+				panic(err)
+			}
+		}()
+		assert.Nil(t, app.WaitForStartup(context.Background()))
+		assert.True(t, started)
+		app.Signal("test done")
+		assert.Nil(t, app.WaitForShutdown(context.Background()))
+		assert.Nil(t, app.WaitForStartup(context.Background()))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		app.Object(&struct {
+			Value int `value:"${not.exist}"`
+		}{})
+		go func() { _ = app.Run() }()
+		err := app.WaitForStartup(context.Background())
+		assert.True(t, gs.IsWiringError(err))
+	})
+}
+
+func TestSingleInstance(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_APP_SINGLE-INSTANCE", "true")
+	gs.Setenv("GS_SPRING_APP_SINGLE-INSTANCE-LOCK-FILE", filepath.Join(os.TempDir(), "gs-single-instance.lock"))
+
+	first := gs.NewApp()
+	first.DisableSignalHandler()
+	go func() {
+		if err := first.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, first.WaitForStartup(context.Background()))
+
+	second := gs.NewApp()
+	second.DisableSignalHandler()
+	err := second.Run()
+	assert.Error(t, err, "another instance \\(pid [0-9]+\\) is running")
+
+	first.Signal("test done")
+	assert.Nil(t, first.WaitForShutdown(context.Background()))
+
+	// 释放运行锁时不删除锁文件，之后的实例锁住同一个文件。
+	lockFile := filepath.Join(os.TempDir(), "gs-single-instance.lock")
+	b, err := ioutil.ReadFile(lockFile)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "")
+
+	third := gs.NewApp()
+	third.DisableSignalHandler()
+	go func() {
+		if err := third.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, third.WaitForStartup(context.Background()))
+	b, err = ioutil.ReadFile(lockFile)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), strconv.Itoa(os.Getpid()))
+	third.Signal("test done")
+	assert.Nil(t, third.WaitForShutdown(context.Background()))
+}
+
+func TestRefreshProperties(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_REFRESH_VALUE", "3")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	_, err := app.RefreshProperties()
+	assert.Error(t, err, "should call after Run")
+
+	var bean struct {
+		Value dync.Int64 `value:"${refresh.value}"`
+	}
+	app.Object(&bean)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	assert.Equal(t, bean.Value.Value(), int64(3))
+
+	var refreshChanges, redisChanges []*gs.PropertyChanges
+	app.OnPropertyChange("refresh", func(changes *gs.PropertyChanges) {
+		refreshChanges = append(refreshChanges, changes)
+	})
+	app.OnPropertyChange("redis.*", func(changes *gs.PropertyChanges) {
+		redisChanges = append(redisChanges, changes)
+	})
+
+	gs.Setenv("GS_REFRESH_VALUE", "5")
+	status, err := app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.Equal(t, status.Changed, 1)
+	assert.Equal(t, status.Changes, &gs.PropertyChanges{Modified: []string{"refresh.value"}})
+	assert.Equal(t, status.Sources[0].Name, "environment")
+	assert.Equal(t, bean.Value.Value(), int64(5))
+	assert.Equal(t, app.LastRefresh(), status)
+	assert.Equal(t, refreshChanges, []*gs.PropertyChanges{{Modified: []string{"refresh.value"}}})
+	assert.Equal(t, len(redisChanges), 0)
+
+	gs.Setenv("GS_REDIS_HOST", "127.0.0.1")
+	gs.Setenv("GS_REDISX", "true")
+	status, err = app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.Equal(t, status.Changes, &gs.PropertyChanges{Added: []string{"redis.host", "redisx"}})
+	assert.Equal(t, len(refreshChanges), 1)
+	assert.Equal(t, redisChanges, []*gs.PropertyChanges{{Added: []string{"redis.host"}}})
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestRefreshProperties_Coalesce(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_REFRESH_VALUE", "3")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	app.OnPropertyChange("refresh", func(changes *gs.PropertyChanges) {
+		close(entered)
+		<-release
+	})
+
+	gs.Setenv("GS_REFRESH_VALUE", "5")
+	first := make(chan *gs.RefreshStatus, 1)
+	go func() {
+		status, _ := app.RefreshProperties()
+		first <- status
+	}()
+	<-entered
+
+	var wg sync.WaitGroup
+	queued := make([]*gs.RefreshStatus, 3)
+	for i := range queued {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			queued[i], _ = app.RefreshProperties()
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	s := <-first
+	assert.Equal(t, s.ID, uint64(1))
+	assert.Equal(t, s.Changed, 1)
+	assert.Equal(t, queued[0].ID, uint64(2))
+	assert.Equal(t, queued[0].Changed, 0)
+	assert.True(t, queued[0] == queued[1] && queued[1] == queued[2])
+	assert.Equal(t, app.LastRefresh(), queued[0])
+
+	s, err := app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.Equal(t, s.ID, uint64(3))
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestRunError(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	t.Run("wiring", func(t *testing.T) {
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		app.Provide(func() (*callDestroy, error) { return nil, errors.New("boom") })
+		err := app.Run()
+		assert.Error(t, err, "wiring error: .*boom")
+		assert.True(t, gs.IsWiringError(err))
+		assert.False(t, gs.IsConfigError(err))
+	})
+
+	t.Run("server", func(t *testing.T) {
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			app.ShutDownWithError(&gs.ServerError{Err: errors.New("address already in use")})
+		}()
+		err := app.Run()
+		assert.Error(t, err, "server error: address already in use")
+		assert.True(t, gs.IsServerError(err))
+		assert.False(t, gs.IsJobError(err))
+	})
+}
+
+func TestTuneRuntime(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_RUNTIME_GOMAXPROCS", "1")
+	gs.Setenv("GS_SPRING_RUNTIME_HTTP-TRANSPORT_MAX-IDLE-CONNS-PER-HOST", "32")
+
+	procs := runtime.GOMAXPROCS(0)
+	transport := http.DefaultTransport.(*http.Transport)
+	idle := transport.MaxIdleConnsPerHost
+	defer func() {
+		runtime.GOMAXPROCS(procs)
+		transport.MaxIdleConnsPerHost = idle
+	}()
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	assert.Equal(t, runtime.GOMAXPROCS(0), 1)
+	assert.Equal(t, transport.MaxIdleConnsPerHost, 32)
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestExportState(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_MYSQL_PASSWORD", "123456")
+	gs.Setenv("GS_MYSQL_URL", "mysql://localhost")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.Object(&callDestroy{}).On(cond.OnProperty("mysql.url"))
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	file := filepath.Join(os.TempDir(), "gs-state.json")
+	defer os.Remove(file)
+	err := app.ExportState(file)
+	assert.Nil(t, err)
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+
+	a, err := gs.LoadState(file)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Version, gs.Version)
+	assert.Equal(t, a.Properties["mysql.password"], gs.MaskedValue)
+	assert.Equal(t, a.Properties["mysql.url"], "mysql://localhost")
+
+	b, ok := a.Bean("github.com/go-spring/spring-core/gs/gs_test.callDestroy:callDestroy")
+	assert.True(t, ok)
+	assert.Equal(t, b.Status, "Wired")
+	assert.Equal(t, b.Condition, cond.Describe(cond.OnProperty("mysql.url")))
+
+	c := a.NewContainer()
+	c.Object(&callDestroy{}).On(cond.OnProperty("mysql.url"))
+	assert.Nil(t, c.Refresh())
+}
+
+func TestMust(t *testing.T) {
+	assert.Panic(t, func() {
+		gs.MustObject(3)
+	}, `gs.Object failed at .*/gs/app_test.go:\d+:\n\tbean must be ref type`)
+	assert.Panic(t, func() {
+		gs.MustProvide(func() {})
+	}, `gs.Provide failed at .*/gs/app_test.go:\d+:\n\tconstructor should be`)
+
+	err := &gs.MustError{Op: "Run", At: "main.go:10", Err: errors.New("a\nb")}
+	assert.Equal(t, err.Error(), "gs.Run failed at main.go:10:\n\ta\n\tb")
+}
+
+func TestResourceBundle(t *testing.T) {
+
+	t.Run("embedded", func(t *testing.T) {
+		c := gs.New()
+		c.Object(gs.NewResourceBundle("config", http.Dir("testdata"))).Name("config")
+		var r *gs.ResourceBundle
+		err := runTest(c, func(p gs.Context) {
+			err := p.Get(&r, "config")
+			assert.Nil(t, err)
+		})
+		assert.Nil(t, err)
+		assert.False(t, r.FromDisk())
+		b, err := r.ReadFile("application.properties")
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(b), "spring.application.name=test"))
+		_, err = r.ReadFile("../../app_test.go")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("disk", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringResourcesDir, "testdata")
+		c.Object(gs.NewResourceBundle("config", http.Dir("testdata/pkg"))).Name("config")
+		var r *gs.ResourceBundle
+		err := runTest(c, func(p gs.Context) {
+			err := p.Get(&r, "config")
+			assert.Nil(t, err)
+		})
+		assert.Nil(t, err)
+		assert.True(t, r.FromDisk())
+		b, err := r.ReadFile("application.properties")
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(b), "spring.application.name=test"))
+	})
+}
+
+func TestProfilesInclude(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/include/")
+	gs.Setenv("GS_SPRING_PROFILES_ACTIVE", "prod")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	var bean struct {
+		A string `value:"${include.a}"`
+		B string `value:"${include.b}"`
+		C string `value:"${include.c}"`
+		D string `value:"${include.d}"`
+	}
+	app.Object(&bean)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	assert.Equal(t, bean.A, "app")
+	assert.Equal(t, bean.B, "common")
+	assert.Equal(t, bean.C, "prod")
+	assert.Equal(t, bean.D, "db")
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestHclConfig(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/hcl/")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	var bean struct {
+		Name string `value:"${server.name}"`
+		Port int    `value:"${server.port}"`
+	}
+	app.Object(&bean)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	assert.Equal(t, bean.Name, "hcl")
+	assert.Equal(t, bean.Port, 8080)
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestRefreshRollout(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_CONFIG_ROLLOUT_PERCENT", "0")
+	gs.Setenv("GS_SPRING_CONFIG_ROLLOUT_BAKE-PERIOD", "200ms")
+	gs.Setenv("GS_SPRING_CONFIG_ROLLOUT_INSTANCE", "instance-1")
+	gs.Setenv("GS_REFRESH_VALUE", "3")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	var bean struct {
+		Value dync.Int64 `value:"${refresh.value}"`
+	}
+	app.Object(&bean)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	gs.Setenv("GS_REFRESH_VALUE", "5")
+	status, err := app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.True(t, status.Deferred)
+	assert.Equal(t, bean.Value.Value(), int64(3))
+
+	r := app.Rollout()
+	assert.Equal(t, r.Instance, "instance-1")
+	assert.False(t, r.Canary)
+	assert.Equal(t, r.Pending, status.Version)
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, bean.Value.Value(), int64(5))
+
+	r = app.Rollout()
+	assert.Equal(t, r.Applied, status.Version)
+	assert.Equal(t, r.Pending, "")
+
+	// 程序退出时放弃等待中的重新刷新。
+	gs.Setenv("GS_REFRESH_VALUE", "7")
+	status, err = app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.True(t, status.Deferred)
+
+	app.Signal("test done")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, app.WaitForShutdown(ctx))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, bean.Value.Value(), int64(5))
+	assert.Equal(t, app.LastRefresh(), status)
+}
+
+type fakeGrpcContainer struct {
+	mutex    sync.Mutex
+	services []string
+	addr     string
+	started  chan struct{}
+	stopped  chan struct{}
+}
+
+func (c *fakeGrpcContainer) Register(serviceName string, server *grpc.Server) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.services = append(c.services, serviceName)
+	return nil
+}
+
+func (c *fakeGrpcContainer) Start(addr string) error {
+	c.mutex.Lock()
+	c.addr = addr
+	c.mutex.Unlock()
+	close(c.started)
+	<-c.stopped
+	return nil
+}
+
+func (c *fakeGrpcContainer) Stop(ctx context.Context) error {
+	close(c.stopped)
+	return nil
+}
+
+type greeterRegistrar struct{}
+
+func (r *greeterRegistrar) GrpcServices() map[string]*grpc.Server {
+	return map[string]*grpc.Server{
+		"helloworld.Greeter": {Register: func() {}, Service: r},
+	}
+}
+
+func TestGrpcStarter(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_GRPC_SERVER_ADDR", "127.0.0.1:9091")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	c := &fakeGrpcContainer{started: make(chan struct{}), stopped: make(chan struct{})}
+	app.Object(c).Export((*grpc.Container)(nil))
+	app.Object(new(greeterRegistrar)).Export((*gs.GrpcServiceRegistrar)(nil))
+	app.Object(new(gs.GrpcStarter)).Export((*gs.AppEvent)(nil))
+	app.GrpcServer("helloworld.Echo", &grpc.Server{Register: func() {}, Service: c})
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	<-c.started
+
+	c.mutex.Lock()
+	sort.Strings(c.services)
+	assert.Equal(t, c.services, []string{"helloworld.Echo", "helloworld.Greeter"})
+	assert.Equal(t, c.addr, "127.0.0.1:9091")
+	c.mutex.Unlock()
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+
+	select {
+	case <-c.stopped:
+	default:
+		t.Fatal("grpc server should be stopped")
+	}
+}
+
+type memoryConfigSource struct {
+	p      *conf.Properties
+	ch     chan<- *conf.Properties
+	closed bool
+}
+
+func (s *memoryConfigSource) Load() (*conf.Properties, error) {
+	return s.p, nil
+}
+
+func (s *memoryConfigSource) Watch(ch chan<- *conf.Properties) {
+	s.ch = ch
+}
+
+func (s *memoryConfigSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestConfigSource(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	p := conf.New()
+	_ = p.Set("remote.value", 3)
+	source := &memoryConfigSource{p: p}
+	app.RegisterConfigSource("memory", source)
+
+	var bean struct {
+		Value dync.Int64 `value:"${remote.value}"`
+	}
+	app.Object(&bean)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	assert.Equal(t, bean.Value.Value(), int64(3))
+
+	p = conf.New()
+	_ = p.Set("remote.value", 5)
+	source.ch <- p
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, bean.Value.Value(), int64(5))
+
+	status := app.LastRefresh()
+	assert.Equal(t, status.Changed, 1)
+	assert.Equal(t, status.Sources[len(status.Sources)-1], gs.RefreshSource{Name: "memory", Keys: 1})
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+	assert.True(t, source.closed)
+}
+
+func TestConfigSource_Bootstrap(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+
+	p := conf.New()
+	_ = p.Set("remote.value", 3)
+	source := &memoryConfigSource{p: p}
+	app.Bootstrap().Object(source).Name("memory").Export((*gs.ConfigSource)(nil))
+
+	var bean struct {
+		Value dync.Int64 `value:"${remote.value}"`
+	}
+	app.Object(&bean)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	assert.Equal(t, bean.Value.Value(), int64(3))
+
+	p = conf.New()
+	_ = p.Set("remote.value", 5)
+	source.ch <- p
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, bean.Value.Value(), int64(5))
+	assert.Equal(t, app.LastRefresh().Sources[len(app.LastRefresh().Sources)-1].Name, "memory")
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+	assert.True(t, source.closed)
+}
+
+type labeledConfigSource struct {
+	memoryConfigSource
+	labels map[string]string
+}
+
+func (s *labeledConfigSource) SetLabels(labels map[string]string) {
+	s.labels = labels
+}
+
+func TestAppLabels(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_APP_LABELS-FILE", "testdata/labels/podinfo")
+	gs.Setenv("GS_SPRING_APP_LABELS_IDC", "bj")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.Label("cluster", "c2")
+	app.Label("zone", "z1")
+
+	source := &labeledConfigSource{memoryConfigSource: memoryConfigSource{p: conf.New()}}
+	app.RegisterConfigSource("labeled", source)
+
+	var bean struct {
+		Cluster string       `value:"${spring.app.labels.cluster}"`
+		Destroy *callDestroy `autowire:"?"`
+	}
+	app.Object(&bean)
+	app.Object(&callDestroy{}).On(cond.OnProperty("spring.app.labels.idc", cond.HavingValue("bj")))
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	expect := map[string]string{"app": "demo", "cluster": "c2", "idc": "bj", "zone": "z1"}
+	assert.Equal(t, app.Labels(), expect)
+	assert.Equal(t, source.labels, expect)
+	assert.Equal(t, bean.Cluster, "c2")
+	assert.NotNil(t, bean.Destroy)
+
+	status, err := app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.Equal(t, status.Changed, 0)
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestAppLogRoutes(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_LOG_ROUTES_DAL_TAGS", "_dal_*")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.Property("spring.log.routes.web.server.level", "warn")
+	app.Property("spring.log.routes.web.server.tags", []string{"_gs_*", "_web_*"})
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	assert.Equal(t, app.LogRoutes(), map[string]gs.LogRoute{
+		"dal":        {Tags: []string{"_dal_*"}},
+		"web.server": {Tags: []string{"_gs_*", "_web_*"}, Level: "warn"},
+	})
+
+	gs.Setenv("GS_SPRING_LOG_ROUTES_DAL_LEVEL", "debug")
+	_, err := app.RefreshProperties()
+	assert.Nil(t, err)
+	assert.Equal(t, app.LogRoutes()["dal"], gs.LogRoute{Tags: []string{"_dal_*"}, Level: "debug"})
+
+	err = app.SetLogRoute("dal", gs.LogRoute{Tags: []string{"_dal_*"}, Level: "trace"})
+	assert.Nil(t, err)
+	assert.Equal(t, app.LogRoutes()["dal"], gs.LogRoute{Tags: []string{"_dal_*"}, Level: "trace"})
+
+	gs.Setenv("GS_SPRING_LOG_ROUTES_DAL_LEVEL", "verbose")
+	_, err = app.RefreshProperties()
+	assert.Error(t, err, "log route \"dal\" error: invalid logger level \"verbose\"")
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+func TestAppIsolate(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SERVICE_NAME", "process")
+
+	type service struct {
+		Name string `value:"${service.name}"`
+		Port int    `value:"${service.port:=0}"`
+	}
+
+	gcPercent := debug.SetGCPercent(100)
+	debug.SetGCPercent(gcPercent)
+
+	// 隔离运行的 App 忽略进程级的运行时参数以及运行锁。
+	run := func(name string, args ...string) (*gs.App, *service) {
+		app := gs.NewApp()
+		app.Isolate(map[string]string{
+			"GS_SPRING_CONFIG_LOCATIONS":              "testdata/config/",
+			"GS_SERVICE_NAME":                         name,
+			"GS_SPRING_RUNTIME_GC-PERCENT":            "50",
+			"GS_SPRING_APP_SINGLE-INSTANCE":           "true",
+			"GS_SPRING_APP_SINGLE-INSTANCE-LOCK-FILE": filepath.Join(os.TempDir(), "gs-isolate.lock"),
+		}, args...)
+		s := new(service)
+		app.Object(s)
+		go func() {
+			if err := app.Run(); err != nil {
+				panic(err)
+			}
+		}()
+		return app, s
+	}
+
+	app1, s1 := run("order", "-D", "service.port=8081")
+	app2, s2 := run("user")
+	assert.Nil(t, app1.WaitForStartup(context.Background()))
+	assert.Nil(t, app2.WaitForStartup(context.Background()))
+
+	assert.True(t, app1.Isolated())
+	assert.Equal(t, *s1, service{Name: "order", Port: 8081})
+	assert.Equal(t, *s2, service{Name: "user", Port: 0})
+	assert.Equal(t, debug.SetGCPercent(gcPercent), gcPercent)
+
+	// 内置的 starter 启动服务器失败时只关闭所在的 App 。
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	app3 := gs.NewApp()
+	app3.Isolate(map[string]string{"GS_SPRING_CONFIG_LOCATIONS": "testdata/config/"})
+	app3.Object(new(gs.WebStarter)).Export((*gs.AppEvent)(nil))
+	app3.Object(web.NewHttpServer(web.ServerConfig{Host: "127.0.0.1", Port: port}, http.NotFoundHandler())).Export((*web.Server)(nil))
+	err = app3.Run()
+	assert.True(t, gs.IsServerError(err))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, app1.WaitForShutdown(ctx), context.DeadlineExceeded)
+
+	gs.Setenv("GS_SERVICE_PORT", "9090")
+	status, err := app2.RefreshProperties()
+	assert.Nil(t, err)
+	assert.Equal(t, status.Changed, 0)
+
+	app1.Signal("test done")
+	app2.Signal("test done")
+	assert.Nil(t, app1.WaitForShutdown(context.Background()))
+	assert.Nil(t, app2.WaitForShutdown(context.Background()))
+}
+
+func TestCheckModuleVersions(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	gs.RegisterModuleVersion(gs.ModuleVersion{
+		Name:     "gs-mock",
+		Version:  "v1.0.0",
+		Requires: map[string]string{"spring-core": ">=v1.1.0 <v1.2.0", "starter-redis": ">=v1.0.0"},
+	})
+	assert.Nil(t, gs.CheckModuleVersions())
+
+	gs.RegisterModuleVersion(gs.ModuleVersion{
+		Name:     "log",
+		Version:  "v1.0.2-beta",
+		Requires: map[string]string{"spring-core": ">=v1.2"},
+	})
+	defer gs.RegisterModuleVersion(gs.ModuleVersion{Name: "log", Version: "v1.0.2"})
+
+	err := gs.CheckModuleVersions()
+	assert.Error(t, err, "module log@v1.0.2-beta requires spring-core \">=v1.2\" but found v1.1.3")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	err = app.Run()
+	assert.True(t, gs.IsVersionError(err))
+
+	gs.Setenv("GS_SPRING_APP_VERSION-CHECK_ENABLED", "false")
+	app = gs.NewApp()
+	app.DisableSignalHandler()
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+type runtimeWorker struct {
+	Size      dync.Int64 `value:"${worker.size:=4}"`
+	destroyed int32
+}
+
+func TestRegisterRuntimeBean_Concurrent(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+	gs.Setenv("GS_SPRING_APP_RUNTIME-BEANS_ENABLED", "true")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+
+	var (
+		wg         sync.WaitGroup
+		mutex      sync.Mutex
+		registered []*runtimeWorker
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			w := new(runtimeWorker)
+			b := gs.NewBean(w).Name(fmt.Sprintf("worker-%d", i)).Destroy(func(w *runtimeWorker) {
+				atomic.AddInt32(&w.destroyed, 1)
+			})
+			if err := app.RegisterRuntimeBean(b); err != nil {
+				assert.Error(t, err, "RegisterRuntimeBean should call after Refresh, but container is Clos")
+				return
+			}
+			mutex.Lock()
+			registered = append(registered, w)
+			mutex.Unlock()
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			gs.Setenv("GS_WORKER_SIZE", strconv.Itoa(i))
+			_, _ = app.RefreshProperties()
+		}(i)
+		if i == 4 {
+			go app.Signal("test done")
+		}
+	}
+	wg.Wait()
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+
+	for _, w := range registered {
+		assert.Equal(t, atomic.LoadInt32(&w.destroyed), int32(1))
+	}
+}
@@ -95,6 +95,52 @@ func Value(v interface{}) ValueArg {
 	return ValueArg{v: v}
 }
 
+// Provenance describes where the value of an argument comes from.
+type Provenance struct {
+	Index int          // index of the argument
+	Type  reflect.Type // type of the argument
+	Tag   string       // tag used to bind or wire the argument
+	Kind  string       // source kind: value, option, callable, property or bean
+}
+
+func (p Provenance) String() string {
+	return fmt.Sprintf("arg[%d] type:%s kind:%s tag:%q", p.Index, p.Type, p.Kind, p.Tag)
+}
+
+// provenance returns the Provenance of the argument.
+func provenance(idx int, arg Arg, t reflect.Type) Provenance {
+	p := Provenance{Index: idx, Type: t}
+	switch g := arg.(type) {
+	case *Callable:
+		p.Kind, p.Tag = "callable", g.fileLine
+		return p
+	case ValueArg:
+		p.Kind = "value"
+		return p
+	case *optionArg:
+		p.Kind, p.Tag = "option", g.r.fileLine
+		return p
+	case util.BeanDefinition:
+		p.Tag = g.ID()
+	case string:
+		p.Tag = g
+	default:
+		p.Tag = util.TypeName(g) + ":"
+	}
+	switch {
+	case util.IsValueType(t):
+		p.Kind = "property"
+		if p.Tag == "" {
+			p.Tag = "${}"
+		}
+	case util.IsBeanReceiver(t):
+		p.Kind = "bean"
+	default:
+		p.Kind = "invalid"
+	}
+	return p
+}
+
 // argList stores the arguments of a function.
 type argList struct {
 	logger *log.Logger
@@ -202,10 +248,13 @@ func (r *argList) get(ctx Context, fileLine string) ([]reflect.Value, error) {
 		}
 
 		// option arg may not return a value when the condition is not met.
+		p := provenance(idx, arg, t)
 		v, err := r.getArg(ctx, arg, t, fileLine)
 		if err != nil {
-			return nil, util.Wrapf(err, code.FileLine(), "returns error when getting %d arg", idx)
+			r.logger.Debugf("get %s error %s %s", p, err.Error(), fileLine)
+			return nil, util.Wrapf(err, code.FileLine(), "returns error when getting %s", p)
 		}
+		r.logger.Debugf("get %s success %s", p, fileLine)
 		if v.IsValid() {
 			result = append(result, v)
 		}
@@ -284,6 +333,7 @@ type optionArg struct {
 	logger *log.Logger
 	r      *Callable
 	c      cond.Condition
+	next   *optionArg // 条件不成立时使用的下一个 Option 函数
 }
 
 // Provide 为 Option 方法绑定运行时参数。
@@ -312,6 +362,22 @@ func (arg *optionArg) On(c cond.Condition) *optionArg {
 	return arg
 }
 
+// Or 添加一个备选的 Option 函数，组成一条按顺序判断条件的备选链，使用第一个条件
+// 成立的 Option 函数，所有条件都不成立时不提供参数。没有设置条件的 Option 函数
+// 总是成立，因此可以放在最后作为默认值，例如：
+//
+//	arg.Option(withRedisCache).On(cond.OnProperty("cache.redis")).
+//		Or(arg.Option(withLocalCache).On(cond.OnProperty("cache.local"))).
+//		Or(arg.Option(withNoCache))
+func (arg *optionArg) Or(next *optionArg) *optionArg {
+	last := arg
+	for last.next != nil {
+		last = last.next
+	}
+	last.next = next
+	return arg
+}
+
 func (arg *optionArg) call(ctx Context) (reflect.Value, error) {
 
 	// TODO 也许可以通过参数传递 *log.Logger 对象
@@ -338,6 +404,9 @@ func (arg *optionArg) call(ctx Context) (reflect.Value, error) {
 		if err != nil {
 			return reflect.Value{}, err
 		} else if !ok {
+			if arg.next != nil {
+				return arg.next.call(ctx)
+			}
 			return reflect.Value{}, nil
 		}
 	}
@@ -386,6 +455,24 @@ func (r *Callable) Arg(i int) (Arg, bool) {
 	return r.argList.args[i], true
 }
 
+// Provenance returns the Provenance of the ith binding argument.
+func (r *Callable) Provenance(i int) (Provenance, bool) {
+	arg, ok := r.Arg(i)
+	if !ok {
+		return Provenance{}, false
+	}
+	t, ok := r.In(i)
+	if !ok {
+		if !r.fnType.IsVariadic() {
+			return Provenance{}, false
+		}
+		t = r.fnType.In(r.fnType.NumIn() - 1).Elem()
+	} else if r.fnType.IsVariadic() && i == r.fnType.NumIn()-1 {
+		t = t.Elem()
+	}
+	return provenance(i, arg, t), true
+}
+
 func (r *Callable) In(i int) (reflect.Type, bool) {
 	if i >= r.fnType.NumIn() {
 		return nil, false
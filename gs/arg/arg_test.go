@@ -17,6 +17,7 @@
 package arg_test
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -168,3 +169,33 @@ func TestBind(t *testing.T) {
 	})
 
 }
+
+func TestProvenance(t *testing.T) {
+
+	type st struct{}
+	fn := func(i int, s *st, v string) {}
+	c, err := arg.Bind(fn, []arg.Arg{"${a.b.c}", "s", arg.Value("v")}, 1)
+	assert.Nil(t, err)
+
+	p, ok := c.Provenance(0)
+	assert.True(t, ok)
+	assert.Equal(t, p.String(), `arg[0] type:int kind:property tag:"${a.b.c}"`)
+
+	p, _ = c.Provenance(1)
+	assert.Equal(t, p.Kind, "bean")
+	assert.Equal(t, p.Tag, "s")
+
+	p, _ = c.Provenance(2)
+	assert.Equal(t, p.Kind, "value")
+
+	_, ok = c.Provenance(3)
+	assert.False(t, ok)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := arg.NewMockContext(ctrl)
+	ctx.EXPECT().Bind(gomock.Any(), "${a.b.c}").Return(nil)
+	ctx.EXPECT().Wire(gomock.Any(), "s").Return(errors.New("can't find bean"))
+	_, err = c.Call(ctx)
+	assert.Error(t, err, `returns error when getting arg\[1\] type:\*arg_test.st kind:bean tag:"s"`)
+}
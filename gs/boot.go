@@ -17,42 +17,139 @@
 package gs
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"reflect"
 
 	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/clock"
 	"github.com/go-spring/spring-core/grpc"
 	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/gs/cond"
 	"github.com/go-spring/spring-core/web"
 )
 
 var app = NewApp()
 
+// Clock 时钟的抽象，参考 clock.Clock 的解释。App.Run 在没有 Clock bean 时默认注册
+// 基于系统时间的 Clock ，内置的缓存、重试等模块会自动注入，测试时可以通过
+// gstest.FakeClock 替换。
+type Clock = clock.Clock
+
 // Setenv 封装 os.Setenv 函数，如果发生 error 会 panic 。
 func Setenv(key string, value string) {
 	err := os.Setenv(key, value)
 	util.Panic(err).When(err != nil)
 }
 
+// SpringHttpServerEnabled 是否启用 web 服务器，默认启用。代码中通过 Web 函数
+// 显式设置时以代码设置为准，否则以该属性的值为准。
+const SpringHttpServerEnabled = "spring.http.server.enabled"
+
+// SpringHttpAuthEnabled 是否启用 JWT 认证过滤器，认证参数通过 spring.http.auth
+// 前缀的属性进行配置，默认不启用。
+const SpringHttpAuthEnabled = "spring.http.auth.enabled"
+
+// SpringHttpCodecEnabled 是否使用 web.CodecInvoker 输出 BIND 形式处理函数的
+// 返回值，编码方式通过 spring.http.codec 前缀的属性进行配置，默认不启用。
+const SpringHttpCodecEnabled = "spring.http.codec.enabled"
+
+// SpringHttpTracingEnabled 是否启用链路追踪过滤器，追踪参数通过 spring.http.tracing
+// 前缀的属性进行配置，默认不启用。
+const SpringHttpTracingEnabled = "spring.http.tracing.enabled"
+
+// SpringHttpValidationEnabled 是否启用请求参数校验过滤器，不需要校验的路径通过
+// spring.http.validation.exclude 属性进行配置，默认不启用。
+const SpringHttpValidationEnabled = "spring.http.validation.enabled"
+
 type startup struct {
-	web bool
+	web   *bool
+	grpc  *bool
+	quic  *bool
+	pprof *bool
 }
 
+// Web 显式设置是否启用 web 服务器，优先级高于 spring.http.server.enabled 属性。
 func Web(enable bool) *startup {
-	return &startup{web: enable}
+	return &startup{web: &enable}
+}
+
+// EnableSimpleGrpcServer 显式设置是否启用 gRPC 服务器，优先级高于
+// spring.grpc.server.enabled 属性。
+func EnableSimpleGrpcServer(enable bool) *startup {
+	return new(startup).EnableSimpleGrpcServer(enable)
+}
+
+// EnableSimpleGrpcServer 显式设置是否启用 gRPC 服务器，参考同名函数的解释。
+func (s *startup) EnableSimpleGrpcServer(enable bool) *startup {
+	s.grpc = &enable
+	return s
+}
+
+// EnableSimpleQuicServer 显式设置是否启用 UDP/QUIC 服务器，优先级高于
+// spring.quic.server.enabled 属性。
+func EnableSimpleQuicServer(enable bool) *startup {
+	return new(startup).EnableSimpleQuicServer(enable)
+}
+
+// EnableSimpleQuicServer 显式设置是否启用 UDP/QUIC 服务器，参考同名函数的解释。
+func (s *startup) EnableSimpleQuicServer(enable bool) *startup {
+	s.quic = &enable
+	return s
+}
+
+// EnableSimplePProfServer 显式设置是否启用 pprof 服务器，优先级高于
+// spring.pprof.server.enabled 属性，服务器通过 pprof.server 前缀的属性进行配置。
+func EnableSimplePProfServer(enable bool) *startup {
+	return new(startup).EnableSimplePProfServer(enable)
+}
+
+// EnableSimplePProfServer 显式设置是否启用 pprof 服务器，参考同名函数的解释。
+func (s *startup) EnableSimplePProfServer(enable bool) *startup {
+	s.pprof = &enable
+	return s
 }
 
 func (s *startup) Run() error {
-	if s.web {
+	if s.web == nil {
+		c := cond.OnProperty(SpringHttpServerEnabled, cond.HavingValue("true"), cond.MatchIfMissing())
+		Object(new(WebStarter)).Export((*AppEvent)(nil)).On(c)
+	} else if *s.web {
 		Object(new(WebStarter)).Export((*AppEvent)(nil))
 	}
+	if s.grpc == nil {
+		c := cond.OnProperty(SpringGrpcServerEnabled, cond.HavingValue("true"))
+		Object(new(GrpcStarter)).Export((*AppEvent)(nil)).On(c)
+	} else if *s.grpc {
+		Object(new(GrpcStarter)).Export((*AppEvent)(nil))
+	}
+	if s.quic == nil {
+		c := cond.OnProperty(SpringQuicServerEnabled, cond.HavingValue("true"))
+		Object(new(QuicStarter)).Export((*AppEvent)(nil)).On(c)
+	} else if *s.quic {
+		Object(new(QuicStarter)).Export((*AppEvent)(nil))
+	}
+	if s.pprof == nil {
+		c := cond.OnProperty(SpringPProfServerEnabled, cond.HavingValue("true"))
+		Object(new(PProfStarter)).Export((*AppEvent)(nil)).On(c)
+	} else if *s.pprof {
+		Object(new(PProfStarter)).Export((*AppEvent)(nil))
+	}
+	c := cond.OnProperty(SpringHttpAuthEnabled, cond.HavingValue("true"))
+	Provide(web.NewJWTAuthFilter, "${spring.http.auth}").On(c)
+	c = cond.OnProperty(SpringHttpCodecEnabled, cond.HavingValue("true"))
+	Provide(web.NewCodecInvoker, "${spring.http.codec}").On(c)
+	c = cond.OnProperty(SpringHttpValidationEnabled, cond.HavingValue("true"))
+	Provide(web.NewValidationFilter, "${spring.http.validation}").On(c)
+	c = cond.OnProperty(SpringHttpTracingEnabled, cond.HavingValue("true"))
+	Object(new(TracingFilter)).Export((*web.Filter)(nil)).On(c)
 	return app.Run()
 }
 
-// Run 启动程序。
+// Run 启动程序，是否启用 web 服务器由 spring.http.server.enabled 属性决定。
 func Run() error {
-	return Web(true).Run()
+	return new(startup).Run()
 }
 
 // ShutDown 停止程序。
@@ -60,6 +157,30 @@ func ShutDown(msg ...string) {
 	app.ShutDown(msg...)
 }
 
+// ShutDownWithError 参考 App.ShutDownWithError 的解释。
+func ShutDownWithError(err error) {
+	app.ShutDownWithError(err)
+}
+
+// shutDownWithError 通知 a 因为 err 退出，a 为 nil 时 (比如 starter 注册在单独
+// 使用的容器中) 通知全局的 App 退出。
+func shutDownWithError(a *App, err error) {
+	if a == nil {
+		a = app
+	}
+	a.ShutDownWithError(err)
+}
+
+// Signal 参考 App.Signal 的解释。
+func Signal(reason string) {
+	app.Signal(reason)
+}
+
+// WaitForShutdown 参考 App.WaitForShutdown 的解释。
+func WaitForShutdown(ctx context.Context) error {
+	return app.WaitForShutdown(ctx)
+}
+
 // Banner 参考 App.Banner 的解释。
 func Banner(banner string) {
 	app.Banner(banner)
@@ -70,6 +191,36 @@ func Bootstrap() *bootstrap {
 	return app.Bootstrap()
 }
 
+// SetLoggerLevel 参考 App.SetLoggerLevel 的解释。
+func SetLoggerLevel(name string, level string) error {
+	return app.SetLoggerLevel(name, level)
+}
+
+// LoggerLevels 参考 App.LoggerLevels 的解释。
+func LoggerLevels() map[string]string {
+	return app.LoggerLevels()
+}
+
+// SetLogRoute 参考 App.SetLogRoute 的解释。
+func SetLogRoute(name string, route LogRoute) error {
+	return app.SetLogRoute(name, route)
+}
+
+// RemoveLogRoute 参考 App.RemoveLogRoute 的解释。
+func RemoveLogRoute(name string) error {
+	return app.RemoveLogRoute(name)
+}
+
+// LogRoutes 参考 App.LogRoutes 的解释。
+func LogRoutes() map[string]LogRoute {
+	return app.LogRoutes()
+}
+
+// EnableLoggersEndpoint 参考 App.EnableLoggersEndpoint 的解释。
+func EnableLoggersEndpoint() *web.Mapper {
+	return app.EnableLoggersEndpoint()
+}
+
 // OnProperty 参考 App.OnProperty 的解释。
 func OnProperty(key string, fn interface{}) {
 	app.OnProperty(key, fn)
@@ -80,6 +231,11 @@ func Property(key string, value interface{}) {
 	app.Property(key, value)
 }
 
+// Variant 参考 Container.Variant 的解释。
+func Variant(name string, selectors map[string]util.BeanSelector) {
+	app.Variant(name, selectors)
+}
+
 // Accept 参考 Container.Accept 的解释。
 func Accept(b *BeanDefinition) *BeanDefinition {
 	return app.c.Accept(b)
@@ -95,6 +251,126 @@ func Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return app.c.Accept(NewBean(ctor, args...))
 }
 
+// Namespace 参考 App.Namespace 的解释。
+func Namespace(name string) *PropertyNamespace {
+	return app.Namespace(name)
+}
+
+// OnClosed 参考 Container.OnClosed 的解释。
+func OnClosed(fn func()) {
+	app.OnClosed(fn)
+}
+
+// OnStop 参考 Container.OnStop 的解释。
+func OnStop(fn func(ctx context.Context) error, opts ...StopOption) {
+	app.OnStop(fn, opts...)
+}
+
+// AddProfileResolver 参考 App.AddProfileResolver 的解释。
+func AddProfileResolver(r ProfileResolver) {
+	app.AddProfileResolver(r)
+}
+
+// RegisterProvider 参考 Container.RegisterProvider 的解释。
+func RegisterProvider(p BeanProvider) {
+	app.RegisterProvider(p)
+}
+
+// RegisterModule 参考 App.RegisterModule 的解释。
+func RegisterModule(m Module) {
+	app.RegisterModule(m)
+}
+
+// UnusedProperties 参考 App.UnusedProperties 的解释。
+func UnusedProperties() []string {
+	return app.UnusedProperties()
+}
+
+// GroupOnCondition 参考 App.GroupOnCondition 的解释。
+func GroupOnCondition(condition cond.Condition, fn func() []*BeanDefinition) {
+	app.GroupOnCondition(condition, fn)
+}
+
+// RegisterRuntimeBean 参考 App.RegisterRuntimeBean 的解释。
+func RegisterRuntimeBean(b *BeanDefinition) error {
+	return app.RegisterRuntimeBean(b)
+}
+
+// ReadinessCheck 参考 App.ReadinessCheck 的解释。
+func ReadinessCheck(name string, checker HealthChecker) {
+	app.ReadinessCheck(name, checker)
+}
+
+// LivenessCheck 参考 App.LivenessCheck 的解释。
+func LivenessCheck(name string, checker HealthChecker) {
+	app.LivenessCheck(name, checker)
+}
+
+// EnableHealthEndpoint 参考 App.EnableHealthEndpoint 的解释。
+func EnableHealthEndpoint() *web.Mapper {
+	return app.EnableHealthEndpoint()
+}
+
+// RegisterConfigSource 参考 App.RegisterConfigSource 的解释。
+func RegisterConfigSource(name string, source ConfigSource) {
+	app.RegisterConfigSource(name, source)
+}
+
+// Label 参考 App.Label 的解释。
+func Label(key, value string) {
+	app.Label(key, value)
+}
+
+// Labels 参考 App.Labels 的解释。
+func Labels() map[string]string {
+	return app.Labels()
+}
+
+// RefreshProperties 参考 App.RefreshProperties 的解释。
+func RefreshProperties() (*RefreshStatus, error) {
+	return app.RefreshProperties()
+}
+
+// DependencyGraph 参考 App.DependencyGraph 的解释。
+func DependencyGraph() (*Graph, error) {
+	return app.DependencyGraph()
+}
+
+// StartupReport 参考 App.StartupReport 的解释。
+func StartupReport() *StartupTimings {
+	return app.StartupReport()
+}
+
+// Rollout 参考 App.Rollout 的解释。
+func Rollout() RolloutStatus {
+	return app.Rollout()
+}
+
+// LastRefresh 参考 App.LastRefresh 的解释。
+func LastRefresh() *RefreshStatus {
+	return app.LastRefresh()
+}
+
+// OnPropertyChange 参考 App.OnPropertyChange 的解释。
+func OnPropertyChange(prefix string, fn func(changes *PropertyChanges)) {
+	app.OnPropertyChange(prefix, fn)
+}
+
+// EnableRefreshEndpoint 参考 App.EnableRefreshEndpoint 的解释。
+func EnableRefreshEndpoint() *web.Mapper {
+	return app.EnableRefreshEndpoint()
+}
+
+// EnableDebugVars 参考 App.EnableDebugVars 的解释。
+func EnableDebugVars() *web.Mapper {
+	return app.EnableDebugVars()
+}
+
+// Controller 参考 App.Controller 的解释。
+func Controller(c interface{}) (*BeanDefinition, error) {
+	return app.Controller(c)
+}
+
 // HttpGet 参考 App.HttpGet 的解释。
 func HttpGet(path string, h http.HandlerFunc) *web.Mapper {
 	return app.HttpGet(path, h)
@@ -205,6 +481,11 @@ func StaticFS(prefix string, fs http.FileSystem) *web.Mapper {
 	return app.StaticFS(prefix, fs)
 }
 
+// Resources 参考 App.Resources 的解释。
+func Resources(dir string, fs http.FileSystem) *BeanDefinition {
+	return app.Resources(dir, fs)
+}
+
 // Consume 参考 App.Consume 的解释。
 func Consume(fn interface{}, topics ...string) {
 	app.Consume(fn, topics...)
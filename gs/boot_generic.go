@@ -0,0 +1,68 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"reflect"
+
+	"github.com/go-spring/spring-core/gs/arg"
+)
+
+// ObjectOf 参考 Object 的解释，obj 的类型由类型参数在编译期确定，传入非指针
+// 或者其他类型的值时无法通过编译。
+func ObjectOf[T any](obj *T) *BeanDefinition {
+	return app.c.Accept(NewBean(reflect.ValueOf(obj)))
+}
+
+// ProvideT 参考 Provide 的解释，构造函数的类型在编译期检查，bean 的类型 T 由
+// 构造函数的返回值推导，例如:
+//
+//	gs.ProvideT(NewServer)
+func ProvideT[T any](ctor func() T) *BeanDefinition {
+	return app.c.Accept(NewBean(ctor))
+}
+
+// ProvideTE 参考 ProvideT 的解释，构造函数返回 bean 和 error 。
+func ProvideTE[T any](ctor func() (T, error)) *BeanDefinition {
+	return app.c.Accept(NewBean(ctor))
+}
+
+// ProvideT1 参考 ProvideT 的解释，构造函数有一个参数，args 的用法和 Provide 相同，
+// 例如:
+//
+//	gs.ProvideT1(NewServer, "${server}")
+func ProvideT1[T, A1 any](ctor func(A1) T, args ...arg.Arg) *BeanDefinition {
+	return app.c.Accept(NewBean(ctor, args...))
+}
+
+// ProvideT1E 参考 ProvideT1 的解释，构造函数返回 bean 和 error 。
+func ProvideT1E[T, A1 any](ctor func(A1) (T, error), args ...arg.Arg) *BeanDefinition {
+	return app.c.Accept(NewBean(ctor, args...))
+}
+
+// ProvideT2 参考 ProvideT1 的解释，构造函数有两个参数。
+func ProvideT2[T, A1, A2 any](ctor func(A1, A2) T, args ...arg.Arg) *BeanDefinition {
+	return app.c.Accept(NewBean(ctor, args...))
+}
+
+// ProvideT2E 参考 ProvideT2 的解释，构造函数返回 bean 和 error 。
+func ProvideT2E[T, A1, A2 any](ctor func(A1, A2) (T, error), args ...arg.Arg) *BeanDefinition {
+	return app.c.Accept(NewBean(ctor, args...))
+}
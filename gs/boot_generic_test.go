@@ -0,0 +1,64 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+)
+
+type genericBean struct {
+	Name string
+}
+
+func newGenericBean() (*genericBean, error) {
+	return &genericBean{Name: "generic"}, nil
+}
+
+func newGenericBeanWithName(name string) *genericBean {
+	return &genericBean{Name: name}
+}
+
+func newGenericBeanWithNames(name string, suffix string) (*genericBean, error) {
+	return &genericBean{Name: name + suffix}, nil
+}
+
+func TestGenericRegistration(t *testing.T) {
+
+	b := gs.ObjectOf(&genericBean{})
+	assert.Equal(t, b.Type(), reflect.TypeOf((*genericBean)(nil)))
+
+	b = gs.ProvideTE(newGenericBean).Name("provided")
+	assert.Equal(t, b.Type(), reflect.TypeOf((*genericBean)(nil)))
+	assert.Equal(t, b.BeanName(), "provided")
+
+	b = gs.ProvideT(func() fmt.Stringer { return nil })
+	assert.Equal(t, b.Type(), reflect.TypeOf((*fmt.Stringer)(nil)).Elem())
+
+	b = gs.ProvideT1(newGenericBeanWithName, "${name:=a}")
+	assert.Equal(t, b.Type(), reflect.TypeOf((*genericBean)(nil)))
+
+	b = gs.ProvideT2E(newGenericBeanWithNames, "${name:=a}", "${suffix:=b}")
+	assert.Equal(t, b.Type(), reflect.TypeOf((*genericBean)(nil)))
+}
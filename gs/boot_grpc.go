@@ -0,0 +1,82 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-spring/spring-core/grpc"
+)
+
+// SpringGrpcServerEnabled 是否启用 gRPC 服务器，默认不启用。代码中通过
+// EnableSimpleGrpcServer 函数显式设置时以代码设置为准，否则以该属性的值为准。
+const SpringGrpcServerEnabled = "spring.grpc.server.enabled"
+
+// GrpcServiceRegistrar 批量提供 gRPC 服务的 bean ，返回服务名称到服务提供者的
+// 映射，服务名称必须对应 grpc.ServiceDesc 的 ServiceName 字段。
+type GrpcServiceRegistrar interface {
+	GrpcServices() map[string]*grpc.Server
+}
+
+// GrpcStarter gRPC 服务器启动器，收集通过 GrpcServer 函数注册的服务以及所有
+// GrpcServiceRegistrar 提供的服务，然后在 grpc.server.addr (未设置时为
+// grpc.server.port) 上启动 gRPC 服务器，应用停止时优雅地关闭服务器。
+type GrpcStarter struct {
+	Container  grpc.Container         `autowire:""`
+	Servers    *GrpcServers           `autowire:""`
+	Registrars []GrpcServiceRegistrar `autowire:"*?"`
+	Config     grpc.ServerConfig      `value:"${grpc.server}"`
+	App        *App                   `autowire:"?"`
+}
+
+// OnAppStart 应用程序启动事件。
+func (starter *GrpcStarter) OnAppStart(ctx Context) {
+
+	services := make(map[string]*grpc.Server)
+	starter.Servers.ForEach(func(serviceName string, server *grpc.Server) {
+		services[serviceName] = server
+	})
+	for _, r := range starter.Registrars {
+		for serviceName, server := range r.GrpcServices() {
+			services[serviceName] = server
+		}
+	}
+
+	for serviceName, server := range services {
+		if err := starter.Container.Register(serviceName, server); err != nil {
+			shutDownWithError(starter.App, &ServerError{Err: err})
+			return
+		}
+	}
+
+	addr := starter.Config.Addr
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", starter.Config.Port)
+	}
+
+	ctx.Go(func(_ context.Context) {
+		if err := starter.Container.Start(addr); err != nil {
+			shutDownWithError(starter.App, &ServerError{Err: err})
+		}
+	})
+}
+
+// OnAppStop 应用程序结束事件。
+func (starter *GrpcStarter) OnAppStop(ctx context.Context) {
+	_ = starter.Container.Stop(ctx)
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"github.com/go-spring/spring-core/gs/arg"
+)
+
+// 小工具或者脚本的 main 函数里逐层返回 error 没有太大意义，可以使用 Must 系列
+// 函数，出错时直接 panic 并给出调用位置。服务程序仍然推荐使用返回 error 的函数。
+
+// MustRun 参考 Run 的解释，启动失败时 panic 。
+func MustRun() {
+	new(startup).mustRun(2)
+}
+
+// MustRun 参考 startup.Run 的解释，启动失败时 panic 。
+func (s *startup) MustRun() {
+	s.mustRun(2)
+}
+
+func (s *startup) mustRun(skip int) {
+	at := callerAt(skip + 1)
+	if err := s.Run(); err != nil {
+		panic(&MustError{Op: "Run", At: at, Err: err})
+	}
+}
+
+// MustObject 参考 Object 的解释，注册失败时 panic ，bean 的位置记为调用
+// MustObject 的位置。
+func MustObject(i interface{}) *BeanDefinition {
+	return mustAccept("Object", func() *BeanDefinition {
+		return NewBean(reflect.ValueOf(i))
+	})
+}
+
+// MustProvide 参考 Provide 的解释，注册失败时 panic ，bean 的位置记为调用
+// MustProvide 的位置。
+func MustProvide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
+	return mustAccept("Provide", func() *BeanDefinition {
+		return NewBean(ctor, args...)
+	})
+}
+
+func mustAccept(op string, fn func() *BeanDefinition) *BeanDefinition {
+	file, line := caller(3)
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			panic(&MustError{Op: op, At: fmt.Sprintf("%s:%d", file, line), Err: err})
+		}
+	}()
+	b := fn()
+	b.file, b.line = file, line
+	return app.c.Accept(b)
+}
+
+func caller(skip int) (string, int) {
+	_, file, line, _ := runtime.Caller(skip)
+	return file, line
+}
+
+func callerAt(skip int) string {
+	file, line := caller(skip + 1)
+	return fmt.Sprintf("%s:%d", file, line)
+}
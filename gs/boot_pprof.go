@@ -0,0 +1,123 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+)
+
+// SpringPProfServerEnabled 是否启用 pprof 服务器，默认不启用。代码中通过
+// EnableSimplePProfServer 函数显式设置时以代码设置为准，否则以该属性的值为准。
+const SpringPProfServerEnabled = "spring.pprof.server.enabled"
+
+// PProfConfig pprof 服务器的配置，一般绑定到 pprof.server 前缀的属性。
+type PProfConfig struct {
+	Addr                 string `value:"${addr:=:6060}"`               // 监听地址
+	Prefix               string `value:"${prefix:=/debug/pprof}"`      // 端点的前缀
+	MutexProfileFraction int    `value:"${mutex-profile-fraction:=0}"` // 大于 0 时开启 mutex 采样
+	BlockProfileRate     int    `value:"${block-profile-rate:=0}"`     // 大于 0 时开启 block 采样
+	Username             string `value:"${username:=}"`                // 为空时不校验基础认证
+	Password             string `value:"${password:=}"`
+}
+
+// PProfStarter pprof 服务器启动器，在 pprof.server.addr 上单独启动一个 HTTP
+// 服务器，避免将性能分析的端点暴露在业务端口上。
+type PProfStarter struct {
+	Config PProfConfig `value:"${pprof.server}"`
+	App    *App        `autowire:"?"`
+	server *http.Server
+}
+
+// OnAppStart 应用程序启动事件。
+func (starter *PProfStarter) OnAppStart(ctx Context) {
+
+	if n := starter.Config.MutexProfileFraction; n > 0 {
+		runtime.SetMutexProfileFraction(n)
+	}
+	if n := starter.Config.BlockProfileRate; n > 0 {
+		runtime.SetBlockProfileRate(n)
+	}
+
+	l, err := net.Listen("tcp", starter.Config.Addr)
+	if err != nil {
+		shutDownWithError(starter.App, &ServerError{Err: err})
+		return
+	}
+
+	starter.server = &http.Server{Handler: starter.Handler()}
+	ctx.Go(func(_ context.Context) {
+		if err := starter.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			shutDownWithError(starter.App, &ServerError{Err: err})
+		}
+	})
+}
+
+// OnAppStop 应用程序结束事件。
+func (starter *PProfStarter) OnAppStop(ctx context.Context) {
+	if starter.server != nil {
+		_ = starter.server.Shutdown(ctx)
+	}
+}
+
+// Handler 返回挂载在 Prefix 下的 pprof 端点，配置了用户名时需要通过基础认证。
+func (starter *PProfStarter) Handler() http.Handler {
+	prefix := strings.TrimSuffix(starter.Config.Prefix, "/")
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		if !starter.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="pprof"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		switch name {
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			// pprof.Index 只能识别 /debug/pprof/ 前缀的路径
+			r.URL.Path = fmt.Sprintf("/debug/pprof/%s", name)
+			pprof.Index(w, r)
+		}
+	})
+	return mux
+}
+
+func (starter *PProfStarter) authorized(r *http.Request) bool {
+	if starter.Config.Username == "" {
+		return true
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	u := subtle.ConstantTimeCompare([]byte(user), []byte(starter.Config.Username))
+	p := subtle.ConstantTimeCompare([]byte(password), []byte(starter.Config.Password))
+	return u&p == 1
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+)
+
+func TestPProfStarter_Handler(t *testing.T) {
+
+	starter := &gs.PProfStarter{Config: gs.PProfConfig{
+		Prefix:   "/admin/pprof/",
+		Username: "admin",
+		Password: "123456",
+	}}
+	h := starter.Handler()
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/pprof/goroutine?debug=1", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, w.Header().Get("WWW-Authenticate"), `Basic realm="pprof"`)
+
+	r = httptest.NewRequest(http.MethodGet, "/admin/pprof/goroutine?debug=1", nil)
+	r.SetBasicAuth("admin", "654321")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	r = httptest.NewRequest(http.MethodGet, "/admin/pprof/goroutine?debug=1", nil)
+	r.SetBasicAuth("admin", "123456")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "goroutine profile:"))
+
+	r = httptest.NewRequest(http.MethodGet, "/admin/pprof/cmdline", nil)
+	r.SetBasicAuth("admin", "123456")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	r = httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+	r.SetBasicAuth("admin", "123456")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-spring/spring-core/quic"
+)
+
+// SpringQuicServerEnabled 是否启用 UDP/QUIC 服务器，默认不启用。代码中通过
+// EnableSimpleQuicServer 函数显式设置时以代码设置为准，否则以该属性的值为准。
+const SpringQuicServerEnabled = "spring.quic.server.enabled"
+
+// QuicStarter UDP/QUIC 服务器启动器，存在 quic.Container 类型的 bean (一般是
+// quic-go 的适配器) 时启动该服务器，否则使用 quic.PacketHandler 类型的 bean 启动
+// 内置的 UDP 服务器。服务器在 spring.quic.server.addr (未设置时为
+// spring.quic.server.port) 上监听，应用停止时最多等待 drain-timeout 让正在处理
+// 的连接结束。
+type QuicStarter struct {
+	Container quic.Container     `autowire:"?"`
+	Handler   quic.PacketHandler `autowire:"?"`
+	Config    quic.ServerConfig  `value:"${spring.quic.server}"`
+	App       *App               `autowire:"?"`
+}
+
+// OnAppStart 应用程序启动事件。
+func (starter *QuicStarter) OnAppStart(ctx Context) {
+
+	if starter.Container == nil {
+		if starter.Handler == nil {
+			err := errors.New("quic.Container or quic.PacketHandler not found")
+			shutDownWithError(starter.App, &ServerError{Err: err})
+			return
+		}
+		starter.Container = quic.NewUDPServer(starter.Config, starter.Handler)
+	}
+
+	addr := starter.Config.Addr
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", starter.Config.Port)
+	}
+
+	ctx.Go(func(_ context.Context) {
+		if err := starter.Container.Start(addr); err != nil && err != quic.ErrServerClosed {
+			shutDownWithError(starter.App, &ServerError{Err: err})
+		}
+	})
+}
+
+// OnAppStop 应用程序结束事件。
+func (starter *QuicStarter) OnAppStop(ctx context.Context) {
+	if starter.Container == nil {
+		return
+	}
+	if d := starter.Config.DrainTimeout; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	_ = starter.Container.Stop(ctx)
+}
@@ -24,17 +24,33 @@ import (
 	"github.com/go-spring/spring-core/web"
 )
 
+// HttpMiddleware 包装 web 服务器处理链的中间件，比如日志、异常恢复、跨域以及请求
+// ID 等。WebStarter 收集所有导出该接口的 bean 并按照 bean 的 Order 排序，Order
+// 越小的中间件位于越外层。
+type HttpMiddleware interface {
+	Wrap(next http.Handler) http.Handler
+}
+
 // WebStarter Web 服务器启动器
 type WebStarter struct {
-	Containers []web.Server `autowire:""`
-	Filters    []web.Filter `autowire:"${web.server.filters:=*?}"`
-	Router     web.Router   `autowire:""`
+	Containers  []web.Server      `autowire:""`
+	Filters     []web.Filter      `autowire:"${web.server.filters:=*?}"`
+	Middlewares []HttpMiddleware  `autowire:"${web.server.middlewares:=*?}"`
+	Router      web.Router        `autowire:""`
+	Invoker     *web.CodecInvoker `autowire:"?"`
+	App         *App              `autowire:"?"`
 }
 
 // OnAppStart 应用程序启动事件。
 func (starter *WebStarter) OnAppStart(ctx Context) {
 	for _, c := range starter.Containers {
+		if starter.Invoker != nil {
+			c.AddFilter(web.InvokerFilter(starter.Invoker.Invoke))
+		}
 		c.AddFilter(starter.Filters...)
+		for _, m := range starter.Middlewares {
+			c.AddMiddleware(m.Wrap)
+		}
 	}
 	for _, m := range starter.Router.Mappers() {
 		for _, c := range starter.getContainers(m) {
@@ -59,7 +75,7 @@ func (starter *WebStarter) startContainers(ctx Context) {
 		c := starter.Containers[i]
 		ctx.Go(func(_ context.Context) {
 			if err := c.Start(); err != nil && err != http.ErrServerClosed {
-				ShutDown(err.Error())
+				shutDownWithError(starter.App, &ServerError{Err: err})
 			}
 		})
 	}
@@ -71,3 +87,21 @@ func (starter *WebStarter) OnAppStop(ctx context.Context) {
 		_ = c.Stop(ctx)
 	}
 }
+
+// TracingFilter 链路追踪过滤器，开启 spring.http.tracing.export 属性时被采样的请求
+// 会发送给所有导出 web.SpanExporter 接口的 bean ，比如 OpenTelemetry 的适配器。
+type TracingFilter struct {
+	web.Filter
+	Config    web.TracingConfig  `value:"${spring.http.tracing}"`
+	Exporters []web.SpanExporter `autowire:"${span-exporter.collection:=*?}"`
+}
+
+func (f *TracingFilter) OnInit(ctx Context) error {
+	f.Filter = web.NewTracingFilter(f.Config, f.Exporters...)
+	return nil
+}
+
+// URLPatterns 返回需要追踪的路径。
+func (f *TracingFilter) URLPatterns() []string {
+	return f.Filter.(interface{ URLPatterns() []string }).URLPatterns()
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/web"
+)
+
+type traceMiddleware struct {
+	name string
+}
+
+func (m *traceMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Add("X-Trace", m.name)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestWebStarter_Middlewares(t *testing.T) {
+	os.Clearenv()
+	gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+
+	app := gs.NewApp()
+	app.DisableSignalHandler()
+	app.Object(new(gs.WebStarter)).Export((*gs.AppEvent)(nil))
+	app.Object(web.NewHttpServer(web.ServerConfig{Host: "127.0.0.1", Port: 18086}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(r.Header["X-Trace"], ",")))
+	}))).Export((*web.Server)(nil))
+	app.Object(&traceMiddleware{name: "recovery"}).Name("recovery").Export((*gs.HttpMiddleware)(nil)).Order(2)
+	app.Object(&traceMiddleware{name: "logging"}).Name("logging").Export((*gs.HttpMiddleware)(nil)).Order(1)
+
+	go func() {
+		if err := app.Run(); err != nil {
+			panic(err)
+		}
+	}()
+	assert.Nil(t, app.WaitForStartup(context.Background()))
+	waitForListen(t, "127.0.0.1:18086")
+
+	resp, err := http.Get("http://127.0.0.1:18086/")
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, string(b), "logging,recovery")
+
+	app.Signal("test done")
+	assert.Nil(t, app.WaitForShutdown(context.Background()))
+}
+
+// waitForListen 等待 web 服务器开始监听 addr ，服务器在应用启动之后异步启动。
+func waitForListen(t *testing.T, addr string) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s isn't listening", addr)
+}
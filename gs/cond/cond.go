@@ -22,8 +22,15 @@ package cond
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-spring/spring-base/util"
 	"github.com/go-spring/spring-core/conf"
@@ -52,6 +59,10 @@ func (c FuncCond) Matches(ctx Context) (bool, error) {
 	return c(ctx)
 }
 
+func (c FuncCond) String() string {
+	return "OnMatches(func)"
+}
+
 // OK returns a Condition that always returns true.
 func OK() Condition {
 	return FuncCond(func(ctx Context) (bool, error) {
@@ -74,11 +85,16 @@ func (c *not) Matches(ctx Context) (bool, error) {
 	return !ok, err
 }
 
+func (c *not) String() string {
+	return fmt.Sprintf("Not(%s)", Describe(c.c))
+}
+
 // onProperty is a Condition that checks a property and its value.
 type onProperty struct {
 	name           string
 	havingValue    string
 	matchIfMissing bool
+	notEmpty       bool
 }
 
 func (c *onProperty) Matches(ctx Context) (bool, error) {
@@ -87,6 +103,10 @@ func (c *onProperty) Matches(ctx Context) (bool, error) {
 		return c.matchIfMissing, nil
 	}
 
+	if c.notEmpty && ctx.Prop(c.name) == "" {
+		return false, nil
+	}
+
 	if c.havingValue == "" {
 		return true, nil
 	}
@@ -114,6 +134,22 @@ func (c *onProperty) Matches(ctx Context) (bool, error) {
 	return expr.Eval(c.havingValue[3:], getValue(val))
 }
 
+func (c *onProperty) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("OnProperty(%q", c.name))
+	if c.havingValue != "" {
+		sb.WriteString(fmt.Sprintf(", HavingValue(%q)", c.havingValue))
+	}
+	if c.matchIfMissing {
+		sb.WriteString(", MatchIfMissing()")
+	}
+	if c.notEmpty {
+		sb.WriteString(", NotEmpty()")
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
 // onMissingProperty is a Condition that returns true when a property doesn't exist.
 type onMissingProperty struct {
 	name string
@@ -123,6 +159,78 @@ func (c *onMissingProperty) Matches(ctx Context) (bool, error) {
 	return !ctx.Has(c.name), nil
 }
 
+func (c *onMissingProperty) String() string {
+	return fmt.Sprintf("OnMissingProperty(%q)", c.name)
+}
+
+// onEmptyProperty is a Condition that returns true when a property is present
+// but its value is empty.
+type onEmptyProperty struct {
+	name string
+}
+
+func (c *onEmptyProperty) Matches(ctx Context) (bool, error) {
+	return ctx.Has(c.name) && ctx.Prop(c.name) == "", nil
+}
+
+func (c *onEmptyProperty) String() string {
+	return fmt.Sprintf("OnEmptyProperty(%q)", c.name)
+}
+
+// onAllProperties is a Condition that returns true when all properties exist
+// and have the expected values, an empty value means any value.
+type onAllProperties struct {
+	names  []string
+	values map[string]string
+}
+
+func (c *onAllProperties) Matches(ctx Context) (bool, error) {
+	for _, name := range c.names {
+		if !ctx.Has(name) {
+			return false, nil
+		}
+		if v := c.values[name]; v != "" && ctx.Prop(name) != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (c *onAllProperties) String() string {
+	var sb strings.Builder
+	sb.WriteString("OnAllProperties(")
+	for i, name := range c.names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if v := c.values[name]; v != "" {
+			sb.WriteString(fmt.Sprintf("%s=%q", name, v))
+		} else {
+			sb.WriteString(name)
+		}
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// onAnyProperty is a Condition that returns true when any property exists.
+type onAnyProperty struct {
+	names []string
+}
+
+func (c *onAnyProperty) Matches(ctx Context) (bool, error) {
+	for _, name := range c.names {
+		if ctx.Has(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c *onAnyProperty) String() string {
+	return fmt.Sprintf("OnAnyProperty(%s)", strings.Join(c.names, ", "))
+}
+
 // onBean is a Condition that returns true when finding more than one beans.
 type onBean struct {
 	selector util.BeanSelector
@@ -133,6 +241,10 @@ func (c *onBean) Matches(ctx Context) (bool, error) {
 	return len(beans) > 0, err
 }
 
+func (c *onBean) String() string {
+	return fmt.Sprintf("OnBean(%s)", describeSelector(c.selector))
+}
+
 // onMissingBean is a Condition that returns true when finding no beans.
 type onMissingBean struct {
 	selector util.BeanSelector
@@ -143,6 +255,10 @@ func (c *onMissingBean) Matches(ctx Context) (bool, error) {
 	return len(beans) == 0, err
 }
 
+func (c *onMissingBean) String() string {
+	return fmt.Sprintf("OnMissingBean(%s)", describeSelector(c.selector))
+}
+
 // onSingleBean is a Condition that returns true when finding only one bean.
 type onSingleBean struct {
 	selector util.BeanSelector
@@ -153,13 +269,146 @@ func (c *onSingleBean) Matches(ctx Context) (bool, error) {
 	return len(beans) == 1, err
 }
 
+func (c *onSingleBean) String() string {
+	return fmt.Sprintf("OnSingleBean(%s)", describeSelector(c.selector))
+}
+
+// onType is a Condition that checks whether any bean's type is or implements
+// the given type, no matter which interfaces the bean exports.
+type onType struct {
+	t       reflect.Type
+	missing bool
+}
+
+// typeOf returns the type represented by i, which may be a reflect.Type, an
+// interface pointer like (*error)(nil) or a value of the type.
+func typeOf(i interface{}) reflect.Type {
+	t, ok := i.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(i)
+	}
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		t = t.Elem()
+	}
+	return t
+}
+
+func (c *onType) Matches(ctx Context) (bool, error) {
+	beans, err := ctx.Find("")
+	if err != nil {
+		return false, err
+	}
+	for _, b := range beans {
+		if bt := b.Type(); bt == c.t || bt.AssignableTo(c.t) {
+			return !c.missing, nil
+		}
+	}
+	return c.missing, nil
+}
+
+func (c *onType) String() string {
+	if c.missing {
+		return fmt.Sprintf("OnMissingType(%s)", util.TypeName(c.t))
+	}
+	return fmt.Sprintf("OnType(%s)", util.TypeName(c.t))
+}
+
 // onExpression is a Condition that returns true when an expression returns true.
 type onExpression struct {
 	expression string
 }
 
+// Matches evaluates the expression with helper functions, prop(key, def...)
+// returns the property's value or the optional default, hasProp(key) returns
+// whether the property exists, hasBean(selector) returns whether any bean
+// matches the selector, and profileActive(profile) returns whether the
+// profile is active.
 func (c *onExpression) Matches(ctx Context) (bool, error) {
-	return false, util.UnimplementedMethod
+	var findErr error
+	env := map[string]interface{}{
+		"prop": func(key string, def ...string) string {
+			if len(def) > 0 {
+				return ctx.Prop(key, conf.Def(def[0]))
+			}
+			return ctx.Prop(key)
+		},
+		"hasProp": ctx.Has,
+		"hasBean": func(selector string) bool {
+			beans, err := ctx.Find(selector)
+			if err != nil && findErr == nil {
+				findErr = err
+			}
+			return len(beans) > 0
+		},
+		"profileActive": func(profile string) bool {
+			for _, s := range strings.Split(ctx.Prop("spring.profiles.active"), ",") {
+				if strings.TrimSpace(s) == profile {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	ok, err := expr.EvalEnv(c.expression, env)
+	if err != nil {
+		return false, err
+	}
+	if findErr != nil {
+		return false, findErr
+	}
+	return ok, nil
+}
+
+func (c *onExpression) String() string {
+	return fmt.Sprintf("OnExpression(%q)", c.expression)
+}
+
+// onReachable is a Condition that returns true when a TCP or HTTP probe to the
+// address succeeds, the probe runs only once and its result is cached.
+type onReachable struct {
+	address string
+	timeout time.Duration
+	once    sync.Once
+	ok      bool
+	err     error
+}
+
+func (c *onReachable) Matches(ctx Context) (bool, error) {
+	c.once.Do(func() { c.ok, c.err = probe(c.address, c.timeout) })
+	return c.ok, c.err
+}
+
+func (c *onReachable) String() string {
+	return fmt.Sprintf("OnReachable(%q, %s)", c.address, c.timeout)
+}
+
+// probe returns whether the address is reachable, the address should be like
+// tcp://host:port, http://host:port/path or https://host:port/path. A HTTP
+// probe succeeds when the response status is less than 500.
+func probe(address string, timeout time.Duration) (bool, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return false, err
+	}
+	switch u.Scheme {
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", u.Host, timeout)
+		if err != nil {
+			return false, nil
+		}
+		_ = conn.Close()
+		return true, nil
+	case "http", "https":
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(address)
+		if err != nil {
+			return false, nil
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode < http.StatusInternalServerError, nil
+	default:
+		return false, fmt.Errorf("unsupported probe address %q", address)
+	}
 }
 
 // Operator defines operation between conditions, including Or、And、None.
@@ -171,6 +420,40 @@ const (
 	None = Operator(3) // all conditions must be not met.
 )
 
+func (op Operator) String() string {
+	switch op {
+	case Or:
+		return "Or"
+	case And:
+		return "And"
+	case None:
+		return "None"
+	default:
+		return strconv.Itoa(int(op))
+	}
+}
+
+// Describe returns a readable description of the Condition, it's useful for
+// logging and exporting the bean graph.
+func Describe(c Condition) string {
+	if s, ok := c.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", c)
+}
+
+// describeSelector returns a readable description of the bean selector.
+func describeSelector(selector util.BeanSelector) string {
+	switch s := selector.(type) {
+	case string:
+		return strconv.Quote(s)
+	case util.BeanDefinition:
+		return strconv.Quote(s.ID())
+	default:
+		return util.TypeName(s)
+	}
+}
+
 // group is a Condition implemented by operation of Condition(s).
 type group struct {
 	op   Operator
@@ -221,6 +504,14 @@ func (g *group) Matches(ctx Context) (bool, error) {
 	return false, fmt.Errorf("error condition operator %d", g.op)
 }
 
+func (g *group) String() string {
+	var ss []string
+	for _, c := range g.cond {
+		ss = append(ss, Describe(c))
+	}
+	return fmt.Sprintf("%s(%s)", g.op, strings.Join(ss, ", "))
+}
+
 // node is a Condition implemented by link of Condition(s).
 type node struct {
 	cond Condition
@@ -263,6 +554,17 @@ func (n *node) Matches(ctx Context) (bool, error) {
 	return false, fmt.Errorf("error condition operator %d", n.op)
 }
 
+func (n *node) String() string {
+	if n.cond == nil {
+		return ""
+	}
+	s := Describe(n.cond)
+	if n.next == nil || n.next.cond == nil {
+		return s
+	}
+	return s + " " + n.op.String() + " " + n.next.String()
+}
+
 // conditional is a Condition implemented by link of Condition(s).
 type conditional struct {
 	head *node
@@ -279,6 +581,10 @@ func (c *conditional) Matches(ctx Context) (bool, error) {
 	return c.head.Matches(ctx)
 }
 
+func (c *conditional) String() string {
+	return c.head.String()
+}
+
 // Or sets a Or operator.
 func (c *conditional) Or() *conditional {
 	n := &node{}
@@ -327,6 +633,14 @@ func HavingValue(havingValue string) PropertyOption {
 	}
 }
 
+// NotEmpty sets a Condition to return false when property is present but its
+// value is empty, it only works for leaf properties.
+func NotEmpty() PropertyOption {
+	return func(c *onProperty) {
+		c.notEmpty = true
+	}
+}
+
 // OnProperty returns a conditional that starts with a Condition that checks a property
 // and its value.
 func OnProperty(name string, options ...PropertyOption) *conditional {
@@ -353,6 +667,49 @@ func (c *conditional) OnMissingProperty(name string) *conditional {
 	return c.On(&onMissingProperty{name: name})
 }
 
+// OnEmptyProperty returns a conditional that starts with a Condition that returns
+// true when property is present but its value is empty.
+func OnEmptyProperty(name string) *conditional {
+	return New().OnEmptyProperty(name)
+}
+
+// OnEmptyProperty adds a Condition that returns true when property is present but
+// its value is empty.
+func (c *conditional) OnEmptyProperty(name string) *conditional {
+	return c.On(&onEmptyProperty{name: name})
+}
+
+// OnAllProperties returns a conditional that starts with a Condition that returns
+// true when all properties exist and have the expected values, an empty value
+// means any value. It is equal to but cheaper than combining many OnProperty
+// conditions with And.
+func OnAllProperties(properties map[string]string) *conditional {
+	return New().OnAllProperties(properties)
+}
+
+// OnAllProperties adds a Condition that returns true when all properties exist
+// and have the expected values, an empty value means any value.
+func (c *conditional) OnAllProperties(properties map[string]string) *conditional {
+	cond := &onAllProperties{values: make(map[string]string)}
+	for name, value := range properties {
+		cond.names = append(cond.names, name)
+		cond.values[name] = value
+	}
+	sort.Strings(cond.names)
+	return c.On(cond)
+}
+
+// OnAnyProperty returns a conditional that starts with a Condition that returns
+// true when any property exists.
+func OnAnyProperty(names ...string) *conditional {
+	return New().OnAnyProperty(names...)
+}
+
+// OnAnyProperty adds a Condition that returns true when any property exists.
+func (c *conditional) OnAnyProperty(names ...string) *conditional {
+	return c.On(&onAnyProperty{names: names})
+}
+
 // OnBean returns a conditional that starts with a Condition that returns true when
 // finding more than one beans.
 func OnBean(selector util.BeanSelector) *conditional {
@@ -386,8 +743,37 @@ func (c *conditional) OnSingleBean(selector util.BeanSelector) *conditional {
 	return c.On(&onSingleBean{selector: selector})
 }
 
+// OnType returns a conditional that starts with a Condition that returns true
+// when any bean's type is or implements the type of i. i may be a reflect.Type,
+// an interface pointer like (*error)(nil) or a value of the type.
+func OnType(i interface{}) *conditional {
+	return New().OnType(i)
+}
+
+// OnType adds a Condition that returns true when any bean's type is or
+// implements the type of i.
+func (c *conditional) OnType(i interface{}) *conditional {
+	return c.On(&onType{t: typeOf(i)})
+}
+
+// OnMissingType returns a conditional that starts with a Condition that returns
+// true when no bean's type is or implements the type of i, it's useful for the
+// auto-configured beans that back off when the application registers its own.
+func OnMissingType(i interface{}) *conditional {
+	return New().OnMissingType(i)
+}
+
+// OnMissingType adds a Condition that returns true when no bean's type is or
+// implements the type of i.
+func (c *conditional) OnMissingType(i interface{}) *conditional {
+	return c.On(&onType{t: typeOf(i), missing: true})
+}
+
 // OnExpression returns a conditional that starts with a Condition that returns
-// true when an expression returns true.
+// true when an expression returns true. The expression can access properties
+// and beans by helper functions, such as
+// `prop("cache.type", "local") == "redis" && !hasBean("redisClient")` or
+// `profileActive("dev") || hasProp("debug")`.
 func OnExpression(expression string) *conditional {
 	return New().OnExpression(expression)
 }
@@ -397,6 +783,20 @@ func (c *conditional) OnExpression(expression string) *conditional {
 	return c.On(&onExpression{expression: expression})
 }
 
+// OnReachable returns a conditional that starts with a Condition that returns
+// true when the address, like tcp://db:3306 or http://es:9200/_cluster/health,
+// is reachable in timeout. The probe runs only once, so that the optional
+// integrations can back off when their backing services aren't available.
+func OnReachable(address string, timeout time.Duration) *conditional {
+	return New().OnReachable(address, timeout)
+}
+
+// OnReachable adds a Condition that returns true when the address is reachable
+// in timeout.
+func (c *conditional) OnReachable(address string, timeout time.Duration) *conditional {
+	return c.On(&onReachable{address: address, timeout: timeout})
+}
+
 // OnMatches returns a conditional that starts with a Condition that returns true
 // when function returns true.
 func OnMatches(fn func(ctx Context) (bool, error)) *conditional {
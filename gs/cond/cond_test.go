@@ -17,8 +17,16 @@
 package cond_test
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-spring/spring-base/assert"
 	"github.com/go-spring/spring-base/util"
@@ -215,6 +223,109 @@ func TestOnMissingProperty(t *testing.T) {
 	})
 }
 
+func TestOnEmptyProperty(t *testing.T) {
+	t.Run("no property", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(false)
+		ok, err := cond.OnEmptyProperty("a").Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("empty property", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(true)
+		ctx.EXPECT().Prop("a").Return("")
+		ok, err := cond.OnEmptyProperty("a").Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+	t.Run("has property & NotEmpty", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(true)
+		ctx.EXPECT().Prop("a").Return("b")
+		ok, err := cond.OnProperty("a", cond.NotEmpty()).Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+	t.Run("empty property & NotEmpty", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(true)
+		ctx.EXPECT().Prop("a").Return("")
+		ok, err := cond.OnProperty("a", cond.NotEmpty(), cond.MatchIfMissing()).Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestOnAllProperties(t *testing.T) {
+	c := cond.OnAllProperties(map[string]string{"b": "1", "a": ""})
+	assert.Equal(t, cond.Describe(c), `OnAllProperties(a, b="1")`)
+	t.Run("all match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(true)
+		ctx.EXPECT().Has("b").Return(true)
+		ctx.EXPECT().Prop("b").Return("1")
+		ok, err := c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+	t.Run("missing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(false)
+		ok, err := c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("different value", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(true)
+		ctx.EXPECT().Has("b").Return(true)
+		ctx.EXPECT().Prop("b").Return("2")
+		ok, err := c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestOnAnyProperty(t *testing.T) {
+	c := cond.OnAnyProperty("a", "b")
+	assert.Equal(t, cond.Describe(c), `OnAnyProperty(a, b)`)
+	t.Run("any match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(false)
+		ctx.EXPECT().Has("b").Return(true)
+		ok, err := c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+	t.Run("none match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Has("a").Return(false)
+		ctx.EXPECT().Has("b").Return(false)
+		ok, err := c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+}
+
 func TestOnBean(t *testing.T) {
 	t.Run("return error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -303,6 +414,49 @@ func TestOnMissingBean(t *testing.T) {
 	})
 }
 
+func TestOnType(t *testing.T) {
+	t.Run("return error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Find("").Return(nil, errors.New("error"))
+		ok, err := cond.OnType((*fmt.Stringer)(nil)).Matches(ctx)
+		assert.Error(t, err, "error")
+		assert.False(t, ok)
+	})
+	t.Run("no bean", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		ctx.EXPECT().Find("").Return(nil, nil).Times(2)
+		ok, err := cond.OnType((*fmt.Stringer)(nil)).Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		ok, err = cond.OnMissingType((*fmt.Stringer)(nil)).Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+	t.Run("implements", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx := cond.NewMockContext(ctrl)
+		b1 := util.NewMockBeanDefinition(ctrl)
+		b1.EXPECT().Type().Return(reflect.TypeOf(&strings.Builder{})).AnyTimes()
+		b2 := util.NewMockBeanDefinition(ctrl)
+		b2.EXPECT().Type().Return(reflect.TypeOf(&bytes.Buffer{})).AnyTimes()
+		ctx.EXPECT().Find("").Return([]util.BeanDefinition{b1, b2}, nil).Times(3)
+		ok, err := cond.OnType((*fmt.Stringer)(nil)).Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		ok, err = cond.OnMissingType((*fmt.Stringer)(nil)).Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		ok, err = cond.OnMissingType(reflect.TypeOf((*bytes.Reader)(nil))).Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+}
+
 func TestOnSingleBean(t *testing.T) {
 	t.Run("return error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -351,8 +505,30 @@ func TestOnExpression(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	ctx := cond.NewMockContext(ctrl)
-	ok, err := cond.OnExpression("").Matches(ctx)
-	assert.Error(t, err, "unimplemented method")
+	ctx.EXPECT().Prop("cache.type", gomock.Any()).Return("redis").AnyTimes()
+	ctx.EXPECT().Prop("spring.profiles.active").Return("test, dev").AnyTimes()
+	ctx.EXPECT().Has("debug").Return(false).AnyTimes()
+	ctx.EXPECT().Find("redisClient").Return(nil, nil).AnyTimes()
+	ctx.EXPECT().Find("broken").Return(nil, errors.New("find error")).AnyTimes()
+
+	ok, err := cond.OnExpression(`prop("cache.type", "local") == "redis" && !hasBean("redisClient")`).Matches(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = cond.OnExpression(`profileActive("dev") && !hasProp("debug")`).Matches(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = cond.OnExpression(`profileActive("prod")`).Matches(ctx)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = cond.OnExpression(`hasBean("broken")`).Matches(ctx)
+	assert.Error(t, err, "find error")
+	assert.False(t, ok)
+
+	ok, err = cond.OnExpression(`prop("cache.type")`).Matches(ctx)
+	assert.Error(t, err, "doesn't return bool")
 	assert.False(t, ok)
 }
 
@@ -367,6 +543,50 @@ func TestOnMatches(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestOnReachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := cond.NewMockContext(ctrl)
+
+	t.Run("tcp", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		c := cond.OnReachable("tcp://"+l.Addr().String(), time.Second)
+		ok, err := c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_ = l.Close()
+		// the result is cached
+		ok, err = c.Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		ok, err = cond.OnReachable("tcp://"+l.Addr().String(), time.Second).Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/health" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer svr.Close()
+		ok, err := cond.OnReachable(svr.URL+"/health", time.Second).Matches(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		ok, err = cond.OnReachable(svr.URL+"/down", time.Second).Matches(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		ok, err := cond.OnReachable("udp://127.0.0.1:53", time.Second).Matches(ctx)
+		assert.Error(t, err, "unsupported probe address \"udp://127.0.0.1:53\"")
+		assert.False(t, ok)
+	})
+}
+
 func TestOnProfile(t *testing.T) {
 	t.Run("no property", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -468,3 +688,10 @@ func TestGroup(t *testing.T) {
 		assert.True(t, ok)
 	})
 }
+
+func TestDescribe(t *testing.T) {
+	c := cond.OnProperty("a", cond.HavingValue("b")).Or().OnMissingBean("x").And().OnExpression("true")
+	assert.Equal(t, cond.Describe(c), `OnProperty("a", HavingValue("b")) Or OnMissingBean("x") And OnExpression("true")`)
+	c = cond.On(cond.Not(cond.Group(cond.And, cond.OnEmptyProperty("a"), cond.OK())))
+	assert.Equal(t, cond.Describe(c), `Not(And(OnEmptyProperty("a"), OnMatches(func)))`)
+}
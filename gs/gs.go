@@ -24,10 +24,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-spring/spring-base/log"
@@ -47,21 +51,67 @@ const (
 	RefreshInit                      // 准备刷新
 	Refreshing                       // 正在刷新
 	Refreshed                        // 已刷新
+	Closing                          // 正在关闭
+	Closed                           // 已关闭
 )
 
+// SpringStartupTimeout 容器启动的超时时间，设置后构造函数的 context.Context
+// 类型的参数会在超时或者刷新结束后被取消，否则绑定为容器的 ctx 对象。
+const SpringStartupTimeout = "spring.app.startup-timeout"
+
+// SpringInitWarnThreshold bean 初始化耗时的告警阈值，超过阈值的 bean 会打印告警
+// 日志，用于发现在启动阶段意外进行网络 IO 等耗时操作的 bean 。
+const SpringInitWarnThreshold = "spring.app.init-warn-threshold"
+
+// SpringCollectAllErrors 刷新容器时是否收集所有的错误后再返回，默认遇到第一个错误就返回。
+const SpringCollectAllErrors = "spring.app.collect-all-errors"
+
+// SpringCloseChildrenFirst 关闭容器时是否先关闭子容器，默认为 true 。
+const SpringCloseChildrenFirst = "spring.app.children.close-first"
+
+// SpringBeanOverrideAllowed 允许被覆盖的 bean 列表，列表项可以是 bean 的名称、
+// 类型的全限定名或者 ID ，列表中的 bean 重复注册时后注册的覆盖先注册的。
+const SpringBeanOverrideAllowed = "spring.beans.override-allowed"
+
+// SpringTrackUnusedProperties 是否跟踪属性的使用情况，开启后容器刷新完成时打印
+// 从未被读取的属性，也可以通过 App.UnusedProperties 获取这些属性。
+const SpringTrackUnusedProperties = "spring.app.track-unused-properties"
+
+// SpringRuntimeBeansEnabled 是否允许在容器刷新之后通过 RegisterRuntimeBean 注册
+// bean ，开启后容器会一直保留 bean 的索引。
+const SpringRuntimeBeansEnabled = "spring.app.runtime-beans.enabled"
+
 var (
 	loggerType  = reflect.TypeOf((*log.Logger)(nil))
 	contextType = reflect.TypeOf((*Context)(nil)).Elem()
+	lazyType    = reflect.TypeOf(Lazy{})
+	stdCtxType  = reflect.TypeOf((*context.Context)(nil)).Elem()
 )
 
 type Container interface {
 	Context() context.Context
 	Properties() *dync.Properties
 	Property(key string, value interface{})
+	OnProperty(key string, fn interface{})
 	Object(i interface{}) *BeanDefinition
 	Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition
+	RegisterProvider(p BeanProvider)
+	Variant(name string, selectors map[string]util.BeanSelector)
+	GroupOnCondition(condition cond.Condition, fn func() []*BeanDefinition)
+	RegisterRuntimeBean(b *BeanDefinition) error
+	OnClosed(fn func())
+	OnStop(fn func(ctx context.Context) error, opts ...StopOption)
+	AddChild(child Container)
+	Children() []Container
+	DumpGraph(w io.Writer) error
+	DumpGraphAround(w io.Writer, selector util.BeanSelector, depth int) error
+	Leaks() []Leak
+	Graph() (*Graph, error)
+	StartupReport() *StartupTimings
+	Mocks() []MockSubstitution
+	TestingUnlock()
 	Refresh() error
-	Close()
+	Close() error
 }
 
 // Context 提供了一些在 IoC 容器启动后基于反射获取和使用 property 与 bean 的接
@@ -82,7 +132,116 @@ type Context interface {
 	Get(i interface{}, selectors ...util.BeanSelector) error
 	Wire(objOrCtor interface{}, ctorArgs ...arg.Arg) (interface{}, error)
 	Invoke(fn interface{}, args ...arg.Arg) ([]interface{}, error)
-	Go(fn func(ctx context.Context))
+	Go(fn func(ctx context.Context), opts ...GoOption)
+	Graph() (*Graph, error)
+}
+
+// PropertiesView 当前生效的属性的只读视图，可以注入到需要遍历属性的 bean 中，
+// 比如导出器、调试工具等。属性刷新时视图的内容被原子地替换为新的属性。
+type PropertiesView interface {
+	Keys() []string
+	Has(key string) bool
+	Get(key string, opts ...conf.GetOption) string
+	Resolve(s string) (string, error)
+	Bind(i interface{}, opts ...conf.BindOption) error
+	Snapshot() *conf.Properties
+}
+
+// propertiesView 隐藏了 *dync.Properties 的修改方法。
+type propertiesView struct {
+	p *dync.Properties
+}
+
+var propertiesViewType = reflect.TypeOf((*propertiesView)(nil))
+
+func (v *propertiesView) Keys() []string {
+	return v.p.Keys()
+}
+
+func (v *propertiesView) Has(key string) bool {
+	return v.p.Has(key)
+}
+
+func (v *propertiesView) Get(key string, opts ...conf.GetOption) string {
+	return v.p.Get(key, opts...)
+}
+
+func (v *propertiesView) Resolve(s string) (string, error) {
+	return v.p.Resolve(s)
+}
+
+func (v *propertiesView) Bind(i interface{}, opts ...conf.BindOption) error {
+	return v.p.Bind(i, opts...)
+}
+
+func (v *propertiesView) Snapshot() *conf.Properties {
+	return v.p.Snapshot()
+}
+
+// Lazy 延迟获取 bean 的句柄，注入时只记录 bean 选择器，直到第一次调用 Get 时才
+// 真正查找和注入 bean ，可以用来打破 bean 之间的循环依赖或者推迟昂贵 bean 的创建。
+// Lazy 既可以作为结构体字段也可以作为构造函数的参数，例如:
+//
+//	type Service struct {
+//		Repo gs.Lazy `autowire:"repo"`
+//	}
+//
+//	var repo *Repo
+//	err := s.Repo.Get(&repo)
+//
+//	func NewService(repo gs.Lazy) *Service
+//	gs.Provide(NewService, "repo")
+type Lazy struct {
+	h *lazyHandle
+}
+
+// lazyHandle Lazy 的状态，Lazy 被复制之后仍然共享同一个查找结果。
+type lazyHandle struct {
+	ctx  Context
+	tag  string
+	once sync.Once
+	v    reflect.Value
+	err  error
+}
+
+func newLazy(ctx Context, tag string) Lazy {
+	return Lazy{h: &lazyHandle{ctx: ctx, tag: tag}}
+}
+
+// Get 获取 bean 并赋值给 i ，i 的用法和 Context.Get 相同。只有第一次调用时查找
+// bean ，之后的调用返回缓存的结果 (包括错误)，这时 i 需要能够接收第一次获取的 bean 。
+func (l Lazy) Get(i interface{}) error {
+	h := l.h
+	if h == nil {
+		return errors.New("lazy handle isn't injected")
+	}
+	if i == nil {
+		return errors.New("i can't be nil")
+	}
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("i must be pointer")
+	}
+	v = v.Elem()
+	h.once.Do(func() {
+		if h.tag == "" {
+			h.err = h.ctx.Get(i)
+		} else {
+			h.err = h.ctx.Get(i, h.tag)
+		}
+		if h.err == nil {
+			h.v = reflect.New(v.Type()).Elem()
+			h.v.Set(v)
+		}
+	})
+	if h.err != nil {
+		return h.err
+	}
+	if !h.v.Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("lazy bean %s can't be assigned to %s", h.v.Type(), v.Type())
+	}
+	v.Set(h.v)
+	return nil
 }
 
 // ContextAware injects the Context into a struct as the field GSContext.
@@ -90,12 +249,31 @@ type ContextAware struct {
 	GSContext Context `autowire:""`
 }
 
+// MultiError 保存开启 spring.app.collect-all-errors 时刷新过程中的所有错误。
+type MultiError []error
+
+func (e MultiError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("found %d errors:", len(e)))
+	for i, err := range e {
+		buf.WriteString(fmt.Sprintf("\n[%d] %s", i+1, err.Error()))
+	}
+	return buf.String()
+}
+
 type tempContainer struct {
 	initProperties  *conf.Properties
 	beans           []*BeanDefinition
 	beansByName     map[string][]*BeanDefinition
 	beansByType     map[reflect.Type][]*BeanDefinition
 	mapOfOnProperty map[string]interface{}
+	groups          []beanGroup
+}
+
+// beanGroup 设置了条件的一组 bean 。
+type beanGroup struct {
+	cond cond.Condition
+	fn   func() []*BeanDefinition
 }
 
 // container 是 go-spring 框架的基石，实现了 Martin Fowler 在 << Inversion
@@ -110,12 +288,32 @@ type container struct {
 	logger                  *log.Logger
 	ctx                     context.Context
 	cancel                  context.CancelFunc
-	destroyers              []func()
+	startupCtx              context.Context
+	initWarnThreshold       time.Duration
+	runtimeBeans            bool
+	unlocked                bool
+	runtimeMutex            sync.Mutex
+	beansMutex              sync.RWMutex
+	mocksMutex              sync.Mutex
+	mocks                   []MockSubstitution
+	archived                []ArchivedBean
+	graph                   *Graph
+	report                  *StartupTimings
+	variants                map[string]map[string]util.BeanSelector
+	destroyers              []beanDestroyer
+	stopHooks               []*stopHook
+	closedHooks             []func()
 	state                   refreshState
-	wg                      sync.WaitGroup
+	jobsMutex               sync.Mutex
+	jobs                    map[*job]struct{}
+	stats                   *containerStats
+	parent                  *container
+	childrenMutex           sync.Mutex
+	children                []*container
 	p                       *dync.Properties
 	ContextAware            bool
-	AllowCircularReferences bool `value:"${spring.main.allow-circular-references:=false}"`
+	AllowCircularReferences bool          `value:"${spring.main.allow-circular-references:=false}"`
+	ShutdownGrace           time.Duration `value:"${spring.app.jobs.shutdown-grace:=0s}"`
 }
 
 // New 创建 IoC 容器。
@@ -125,6 +323,8 @@ func New() Container {
 		ctx:    ctx,
 		cancel: cancel,
 		p:      dync.New(),
+		jobs:   make(map[*job]struct{}),
+		stats:  new(containerStats),
 		tempContainer: &tempContainer{
 			initProperties:  conf.New(),
 			beansByName:     make(map[string][]*BeanDefinition),
@@ -156,6 +356,7 @@ func validOnProperty(fn interface{}) error {
 
 // OnProperty 当 key 对应的属性值准备好后发送一个通知。
 func (c *container) OnProperty(key string, fn interface{}) {
+	c.checkBeforeRefresh("OnProperty")
 	err := validOnProperty(fn)
 	util.Panic(err).When(err != nil)
 	c.mapOfOnProperty[key] = fn
@@ -167,17 +368,94 @@ func (c *container) OnProperty(key string, fn interface{}) {
 // 类型组合构成的属性值，其处理方式是将组合结构层层展开，可以将组合结构看成一棵树，
 // 那么叶子结点的路径就是属性的 key，叶子结点的值就是属性的值。
 func (c *container) Property(key string, value interface{}) {
+	if c.isUnlocked() {
+		p := c.p.Snapshot()
+		err := p.Set(key, value)
+		if err == nil {
+			err = c.p.Refresh(p)
+		}
+		util.Panic(err).When(err != nil)
+		return
+	}
+	c.checkBeforeRefresh("Property")
 	c.initProperties.Set(key, value)
 }
 
 func (c *container) Accept(b *BeanDefinition) *BeanDefinition {
-	if c.state >= Refreshing {
-		panic(errors.New("should call before Refresh"))
+	if c.isUnlocked() {
+		err := c.registerRuntimeBean(b, false)
+		util.Panic(err).When(err != nil)
+		return b
 	}
+	c.checkBeforeRefresh("Accept")
 	c.beans = append(c.beans, b)
 	return b
 }
 
+// RegisterRuntimeBean 在容器刷新之后注册 bean ，需要开启 spring.app.runtime-beans.enabled
+// 属性。运行时注册的 bean 可以注入已经存在的 bean ，但是不会被注入到已经存在的 bean
+// 中，只能通过 Get 或者收集 bean 的方式获取，并且在其他 bean 之前销毁。该方法可以
+// 和属性刷新以及 Close 同时调用，Close 开始之后注册的 bean 会被拒绝。
+func (c *container) RegisterRuntimeBean(b *BeanDefinition) error {
+	return c.registerRuntimeBean(b, true)
+}
+
+// registerRuntimeBean 注册运行时 bean ，整个过程持有 runtimeMutex 锁，因此 Close
+// 要么等待注册完成，要么使注册失败，销毁函数不会遗漏。
+func (c *container) registerRuntimeBean(b *BeanDefinition, checkEnabled bool) error {
+
+	c.runtimeMutex.Lock()
+	defer c.runtimeMutex.Unlock()
+
+	if err := c.checkAfterRefresh("RegisterRuntimeBean"); err != nil {
+		return err
+	}
+
+	if checkEnabled && !c.runtimeBeans {
+		return fmt.Errorf("runtime beans are disabled, set %s=true to enable", SpringRuntimeBeansEnabled)
+	}
+
+	if c.tempContainer == nil {
+		return errors.New("bean indexes have been cleared")
+	}
+
+	if b.status != Default {
+		return fmt.Errorf("%s has been registered", b)
+	}
+
+	c.beansMutex.RLock()
+	for _, x := range c.beansByName[b.name] {
+		if x.status != Deleted && x.ID() == b.ID() {
+			c.beansMutex.RUnlock()
+			return fmt.Errorf("found duplicate bean %s and %s", b, x)
+		}
+	}
+	c.beansMutex.RUnlock()
+
+	if err := c.resolveBean(b); err != nil {
+		b.status = Deleted
+		return err
+	}
+
+	if b.status == Deleted {
+		c.logger.Infof("runtime %s is skipped because of condition", b)
+		return nil
+	}
+
+	stack := newWiringStack(c.logger)
+	if err := c.wireBean(b, stack); err != nil {
+		b.status = Deleted
+		return err
+	}
+
+	c.beansMutex.Lock()
+	c.beans = append(c.beans, b)
+	c.registerBean(b)
+	c.destroyers = append(stack.sortDestroyers(), c.destroyers...)
+	c.beansMutex.Unlock()
+	return nil
+}
+
 // Object 注册对象形式的 bean ，需要注意的是该方法在注入开始后就不能再调用了。
 func (c *container) Object(i interface{}) *BeanDefinition {
 	return c.Accept(NewBean(reflect.ValueOf(i)))
@@ -188,6 +466,40 @@ func (c *container) Provide(ctor interface{}, args ...arg.Arg) *BeanDefinition {
 	return c.Accept(NewBean(ctor, args...))
 }
 
+// RegisterProvider 注册 BeanProvider 提供的所有 bean ，需要注意的是该方法在注
+// 入开始后就不能再调用了。
+func (c *container) RegisterProvider(p BeanProvider) {
+	for _, b := range p.Beans() {
+		c.Accept(b)
+	}
+}
+
+// GroupOnCondition 注册一组 bean ，容器刷新时首先判断条件，条件成立时才执行 fn
+// 获取这组 bean ，条件不成立时 fn 不会被执行，从而避免昂贵的准备工作。因为条件判
+// 断发生在 bean 决议之前，所以建议只使用和属性相关的条件。
+func (c *container) GroupOnCondition(condition cond.Condition, fn func() []*BeanDefinition) {
+	c.checkBeforeRefresh("GroupOnCondition")
+	c.groups = append(c.groups, beanGroup{cond: condition, fn: fn})
+}
+
+// registerGroups 注册条件成立的 bean 组。
+func (c *container) registerGroups() error {
+	for _, g := range c.groups {
+		ok, err := g.cond.Matches(c)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			c.logger.Debugf("skip bean group on %s", cond.Describe(g.cond))
+			continue
+		}
+		for _, b := range g.fn() {
+			c.Accept(b)
+		}
+	}
+	return nil
+}
+
 // destroyer 保存具有销毁函数的 bean 以及销毁函数的调用顺序。
 type destroyer struct {
 	current *BeanDefinition
@@ -237,6 +549,7 @@ type wiringStack struct {
 	destroyerMap map[string]*destroyer
 	beans        []*BeanDefinition
 	lazyFields   []lazyField
+	stage        int
 }
 
 func newWiringStack(logger *log.Logger) *wiringStack {
@@ -280,7 +593,7 @@ func (s *wiringStack) saveDestroyer(b *BeanDefinition) *destroyer {
 }
 
 // sortDestroyers 对具有销毁函数的 bean 按照销毁函数的依赖顺序进行排序。
-func (s *wiringStack) sortDestroyers() []func() {
+func (s *wiringStack) sortDestroyers() []beanDestroyer {
 
 	destroy := func(v reflect.Value, fn interface{}) func() {
 		return func() {
@@ -298,19 +611,34 @@ func (s *wiringStack) sortDestroyers() []func() {
 
 	destroyers := list.New()
 	for _, d := range s.destroyerMap {
+		if d.current.status != Wired {
+			continue
+		}
 		destroyers.PushBack(d)
 	}
 	destroyers = internal.TripleSort(destroyers, getBeforeDestroyers)
 
-	var ret []func()
+	var ret []beanDestroyer
 	for e := destroyers.Front(); e != nil; e = e.Next() {
 		d := e.Value.(*destroyer).current
-		ret = append(ret, destroy(d.Value(), d.destroy))
+		ret = append(ret, beanDestroyer{bean: d, fn: destroy(d.Value(), d.destroy)})
 	}
 	return ret
 }
 
+// rollback 按照被依赖先销毁的原则执行已经完成初始化的 bean 的销毁函数，并且取消
+// 容器的 ctx 对象，单个销毁函数的 panic 不会影响其他销毁函数的执行。
+func (c *container) rollback(stack *wiringStack) {
+	c.cancel()
+	for _, d := range stack.sortDestroyers() {
+		c.runClosedHook(d.fn)
+	}
+}
+
 func (c *container) clear() {
+	if c.runtimeBeans || c.unlocked {
+		return // 运行时注册 bean 需要使用这些索引
+	}
 	c.tempContainer = nil
 }
 
@@ -322,14 +650,50 @@ func (c *container) Refresh() error {
 func (c *container) refresh(autoClear bool) (err error) {
 
 	if c.state != Unrefreshed {
-		return errors.New("container already refreshed")
+		return &IllegalStateError{Op: "Refresh", State: c.state, Expect: refreshOnce}
 	}
 	c.state = RefreshInit
 
+	if ok, _ := strconv.ParseBool(c.initProperties.Get(SpringTrackUnusedProperties)); ok {
+		c.initProperties.Track()
+		c.initProperties.Has(SpringTrackUnusedProperties) // 开关自身不算作未使用
+	}
+
 	c.p.Refresh(c.initProperties)
+	c.p.Observe(dync.RefreshObserverFunc(c.observeRefresh))
+	c.p.Observe(dync.RefreshObserverFunc(c.warnFrozen))
+
+	if c.traceStacks() {
+		dync.TraceStacks(true)
+	}
+
+	if s := c.p.Get(SpringStartupTimeout); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, d)
+		defer cancel()
+		c.startupCtx = ctx
+	}
+
+	if s := c.p.Get(SpringInitWarnThreshold); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		c.initWarnThreshold = d
+	}
+
+	if s := c.p.Get(SpringRuntimeBeansEnabled); s != "" {
+		if c.runtimeBeans, err = strconv.ParseBool(s); err != nil {
+			return err
+		}
+	}
 
 	start := time.Now()
 	c.Object(c).Export((*Context)(nil))
+	c.Object(&propertiesView{p: c.p}).Export((*PropertiesView)(nil))
 	c.logger = log.GetLogger(util.TypeName(c))
 
 	for key, f := range c.mapOfOnProperty {
@@ -341,16 +705,45 @@ func (c *container) refresh(autoClear bool) (err error) {
 		reflect.ValueOf(f).Call([]reflect.Value{in})
 	}
 
+	if err = c.registerGroups(); err != nil {
+		return err
+	}
+
 	c.state = Refreshing
 
 	for _, b := range c.beans {
 		c.registerBean(b)
 	}
 
+	if err = c.checkDuplicateBeans(); err != nil {
+		return err
+	}
+
+	// 收集模式下出错的 bean 被标记为已删除，然后继续处理其他的 bean 。
+	collectAll, _ := strconv.ParseBool(c.p.Get(SpringCollectAllErrors))
+	var errs MultiError
+
 	for _, b := range c.beans {
 		if err = c.resolveBean(b); err != nil {
-			return err
+			if !collectAll {
+				return err
+			}
+			b.status = Deleted
+			errs = append(errs, fmt.Errorf("%s ↩\n=> %s", err, b))
+		}
+	}
+
+	if exportErrs := c.checkExports(); len(exportErrs) > 0 {
+		if !collectAll {
+			if len(exportErrs) == 1 {
+				return exportErrs[0]
+			}
+			return exportErrs
+		}
+		for _, e := range exportErrs {
+			e.(*ExportError).Bean.status = Deleted
 		}
+		errs = append(errs, exportErrs...)
 	}
 
 	beansById := make(map[string]*BeanDefinition)
@@ -364,36 +757,78 @@ func (c *container) refresh(autoClear bool) (err error) {
 			}
 			beanID := b.ID()
 			if d, ok := beansById[beanID]; ok {
-				return fmt.Errorf("found duplicate beans [%s] [%s]", b, d)
+				if !d.replace && !b.replace {
+					return fmt.Errorf("found duplicate beans [%s] [%s]", b, d)
+				}
+				if d.replace && !b.replace {
+					b, d = d, b
+				}
+				c.replaceBean(d, b)
 			}
 			beansById[beanID] = b
 		}
 	}
 
+	if err = c.checkCycles(c.beans); err != nil {
+		return err
+	}
+
 	stack := newWiringStack(c.logger)
 
 	defer func() {
 		if err != nil || len(stack.beans) > 0 {
-			err = fmt.Errorf("%s ↩\n%s", err, stack.path())
+			if len(stack.beans) > 0 {
+				err = fmt.Errorf("%s ↩\n%s", err, stack.path())
+			}
 			c.logger.Error(err)
 		}
 	}()
 
-	// 按照 bean id 升序注入，保证注入过程始终一致。
+	// 刷新失败时执行已经完成初始化的 bean 的销毁函数，避免泄露外部资源。
+	defer func() {
+		if err != nil {
+			c.rollback(stack)
+		}
+	}()
+
+	// 按照 bean 的注入阶段和 id 升序注入，保证注入过程始终一致。
 	{
 		var keys []string
 		for s := range beansById {
 			keys = append(keys, s)
 		}
-		sort.Strings(keys)
+		sort.Slice(keys, func(i, j int) bool {
+			bi, bj := beansById[keys[i]], beansById[keys[j]]
+			if bi.stage != bj.stage {
+				return bi.stage < bj.stage
+			}
+			return keys[i] < keys[j]
+		})
 		for _, s := range keys {
 			b := beansById[s]
+			stack.stage = b.stage
+			if collectAll && b.status == Deleted {
+				continue
+			}
 			if err = c.wireBean(b, stack); err != nil {
-				return err
+				if !collectAll {
+					return err
+				}
+				errs = append(errs, fmt.Errorf("%s ↩\n%s", err, stack.path()))
+				for _, x := range stack.beans {
+					if x.status != Wired {
+						x.status = Deleted
+					}
+				}
+				stack.beans = nil
 			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
+
 	if c.AllowCircularReferences {
 		// 处理被标记为延迟注入的那些 bean 字段
 		for _, f := range stack.lazyFields {
@@ -409,9 +844,27 @@ func (c *container) refresh(autoClear bool) (err error) {
 	c.destroyers = stack.sortDestroyers()
 	c.state = Refreshed
 
+	c.archiveBeans()
+
+	if err := c.saveGraph(); err != nil {
+		c.logger.Warnf("save graph error: %v", err)
+	}
+
+	if err := c.exportMetadata(); err != nil {
+		c.logger.Warnf("export metadata error: %v", err)
+	}
+
+	if keys := c.p.UnusedKeys(); len(keys) > 0 {
+		c.logger.Infof("unused properties: %s", strings.Join(keys, ", "))
+	}
+
 	cost := time.Now().Sub(start)
 	c.logger.Infof("refresh %d beans cost %v", len(beansById), cost)
 
+	if err := c.saveStartupReport(cost); err != nil {
+		c.logger.Warnf("save startup report error: %v", err)
+	}
+
 	if autoClear && !c.ContextAware {
 		c.clear()
 	}
@@ -430,10 +883,114 @@ func (c *container) registerBean(b *BeanDefinition) {
 	}
 }
 
+// ExportError bean 的类型没有实现导出的接口，At 为调用 Export 方法的位置。
+type ExportError struct {
+	Bean      *BeanDefinition
+	Type      reflect.Type
+	Interface reflect.Type
+	At        string
+}
+
+func (e *ExportError) Error() string {
+	return fmt.Sprintf("%s type:%q doesn't implement interface %s exported at %s", e.Bean, e.Type, e.Interface, e.At)
+}
+
+// checkExports 在注入之前检查所有有效 bean 的导出接口，一次性报告所有的错误。构造
+// 函数返回接口类型时无法提前检查，留到创建 bean 之后再检查。
+func (c *container) checkExports() MultiError {
+	var errs MultiError
+	for _, b := range c.beans {
+		if b.status != Resolved || b.Type().Kind() == reflect.Interface {
+			continue
+		}
+		for i, typ := range b.exports {
+			if !b.Type().Implements(typ) {
+				errs = append(errs, &ExportError{Bean: b, Type: b.Type(), Interface: typ, At: b.exAt[i]})
+			}
+		}
+	}
+	return errs
+}
+
+// checkDuplicateBeans 在解析 bean 之前检查没有设置条件的 bean 是否重复注册，
+// 报告中包含所有重复 bean 的注册位置。设置了条件的 bean 可能是互斥的备选项，所以
+// 留到解析之后再检查。
+func (c *container) checkDuplicateBeans() error {
+
+	var allowed []string
+	tag := conf.Tag("${" + SpringBeanOverrideAllowed + ":=}")
+	if err := c.p.Bind(&allowed, tag); err != nil {
+		return err
+	}
+
+	overridable := func(b *BeanDefinition) bool {
+		for _, s := range allowed {
+			if s = strings.TrimSpace(s); s == b.name || s == b.typeName || s == b.ID() {
+				return true
+			}
+		}
+		return false
+	}
+
+	var ids []string
+	beansByID := make(map[string][]*BeanDefinition)
+	for _, b := range c.beans {
+		if b.status == Deleted || b.cond != nil {
+			continue
+		}
+		id := b.ID()
+		if _, ok := beansByID[id]; !ok {
+			ids = append(ids, id)
+		}
+		beansByID[id] = append(beansByID[id], b)
+	}
+
+	var buf strings.Builder
+	for _, id := range ids {
+		beans := beansByID[id]
+		if len(beans) < 2 {
+			continue
+		}
+		last := beans[len(beans)-1]
+		for i := len(beans) - 1; i >= 0; i-- {
+			if beans[i].replace {
+				last = beans[i]
+				break
+			}
+		}
+		if last.replace || overridable(last) {
+			for _, b := range beans {
+				if b != last {
+					c.replaceBean(b, last)
+				}
+			}
+			continue
+		}
+		buf.WriteString("\n" + id)
+		for _, b := range beans {
+			buf.WriteString("\n    " + b.getClass() + " registered at " + b.FileLine())
+		}
+	}
+
+	if buf.Len() > 0 {
+		return fmt.Errorf("found duplicate beans registered:%s", buf.String())
+	}
+	return nil
+}
+
+// replaceBean 使用 b 覆盖 old ，old 是主版本时 b 也成为主版本。
+func (c *container) replaceBean(old, b *BeanDefinition) {
+	old.status = Deleted
+	if old.primary {
+		b.primary = true
+	}
+	c.logger.Infof("%s is overridden by %s", old, b)
+}
+
 // resolveBean 判断 bean 的有效性，如果 bean 是无效的则被标记为已删除。
 func (c *container) resolveBean(b *BeanDefinition) error {
 
-	if b.status >= Resolving {
+	if b.status >= Resolving || b.status == Deleted {
 		return nil
 	}
 
@@ -464,7 +1021,10 @@ func (c *container) resolveBean(b *BeanDefinition) error {
 	}
 
 	if b.cond != nil {
-		if ok, err := b.cond.Matches(c); err != nil {
+		start := time.Now()
+		ok, err := b.cond.Matches(c)
+		b.timing.cond = time.Since(start)
+		if err != nil {
 			return err
 		} else if !ok {
 			b.status = Deleted
@@ -548,9 +1108,13 @@ func toWireString(tags []wireTag) string {
 // 即未被标记为删除的，而不能保证已经完成属性绑定和依赖注入。
 func (c *container) findBean(selector util.BeanSelector) ([]*BeanDefinition, error) {
 
+	c.beansMutex.RLock()
+	beans := c.beans
+	c.beansMutex.RUnlock()
+
 	finder := func(fn func(*BeanDefinition) bool) ([]*BeanDefinition, error) {
 		var result []*BeanDefinition
-		for _, b := range c.beans {
+		for _, b := range beans {
 			if b.status == Resolving || b.status == Deleted || !fn(b) {
 				continue
 			}
@@ -562,7 +1126,15 @@ func (c *container) findBean(selector util.BeanSelector) ([]*BeanDefinition, err
 			}
 			result = append(result, b)
 		}
-		return result, nil
+		return c.preferMocks(selector, result), nil
+	}
+
+	if s, ok := selector.(string); ok {
+		tag, err := c.resolveVariant(parseWireTag(s))
+		if err != nil {
+			return nil, err
+		}
+		selector = tag.String()
 	}
 
 	var t reflect.Type
@@ -643,7 +1215,12 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 		return nil
 	}
 
+	if c.state == Refreshing && b.stage > stack.stage {
+		return fmt.Errorf("%s in stage %d can't be wired in stage %d", b, b.stage, stack.stage)
+	}
+
 	b.status = Creating
+	start := time.Now()
 
 	// 对当前 bean 的间接依赖项进行注入。
 	for _, s := range b.depends {
@@ -667,38 +1244,102 @@ func (c *container) wireBean(b *BeanDefinition, stack *wiringStack) error {
 	b.status = Created
 
 	t := v.Type()
-	for _, typ := range b.exports {
+	for i, typ := range b.exports {
 		if !t.Implements(typ) {
-			return fmt.Errorf("%s doesn't implement interface %s", b, typ)
+			return &ExportError{Bean: b, Type: t, Interface: typ, At: b.exAt[i]}
 		}
 	}
 
-	err = c.wireBeanValue(v, t, stack)
+	err = c.wireBeanValue(v, t, b.ns, stack)
 	if err != nil {
 		return err
 	}
 
+	if err = c.initBean(b); err != nil {
+		return err
+	}
+
+	b.status = Wired
+	stack.popBack()
+
+	// 依赖项的注入耗时计入上一级 bean ，用于计算每个 bean 自身的耗时。
+	b.timing.total = time.Since(start)
+	if n := len(stack.beans); n > 0 {
+		stack.beans[n-1].timing.nested += b.timing.total
+	}
+	return nil
+}
+
+// initBean 执行 bean 的初始化函数，超过 InitTimeout 设置的时间时返回 error ，
+// 超过 spring.app.init-warn-threshold 设置的时间时打印告警日志。
+func (c *container) initBean(b *BeanDefinition) error {
+
+	_, ok := b.Interface().(BeanInit)
+	if b.init == nil && !ok {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		cost := time.Since(start)
+		b.timing.init = cost
+		if c.initWarnThreshold > 0 && cost > c.initWarnThreshold {
+			c.logger.Warnf("%s init cost %v exceeds %v", b, cost, c.initWarnThreshold)
+		}
+	}()
+
+	if b.timeout <= 0 {
+		return c.callInit(c.ctx, b)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, b.timeout)
+	defer cancel()
+
+	// 超时之后 ctx 被取消，不检查 ctx 的初始化函数会在后台继续执行直到返回。
+	// 初始化函数的 panic 转换成错误返回，避免后台的 goroutine 导致程序崩溃。
+	ch := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- fmt.Errorf("%s init panic: %v", b, r)
+			}
+		}()
+		ch <- c.callInit(ctx, b)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%s init timeout after %v", b, b.timeout)
+	}
+}
+
+func (c *container) callInit(ctx context.Context, b *BeanDefinition) error {
+
 	if b.init != nil {
 		fnValue := reflect.ValueOf(b.init)
-		out := fnValue.Call([]reflect.Value{b.Value()})
+		in := []reflect.Value{b.Value()}
+		if fnValue.Type().NumIn() == 2 {
+			in = append(in, reflect.ValueOf(ctx))
+		}
+		out := fnValue.Call(in)
 		if len(out) > 0 && !out[0].IsNil() {
 			return out[0].Interface().(error)
 		}
 	}
 
 	if f, ok := b.Interface().(BeanInit); ok {
-		if err = f.OnInit(c); err != nil {
+		if err := f.OnInit(c); err != nil {
 			return err
 		}
 	}
-
-	b.status = Wired
-	stack.popBack()
 	return nil
 }
 
 type argContext struct {
 	c     *container
+	ns    string
 	stack *wiringStack
 }
 
@@ -706,14 +1347,48 @@ func (a *argContext) Matches(c cond.Condition) (bool, error) {
 	return c.Matches(a.c)
 }
 
+// Bind 为参数绑定属性值。Lazy 是结构体类型，也会按照属性绑定处理，这里将其
+// 转换为 bean 的延迟注入，没有指定参数时 tag 为 "${}" ，此时按照 Get 的参数
+// 类型查找 bean 。
 func (a *argContext) Bind(v reflect.Value, tag string) error {
-	return a.c.p.Bind(v, conf.Tag(tag))
+	if v.Type() == lazyType {
+		if tag == "${}" {
+			tag = ""
+		}
+		a.c.ContextAware = true
+		v.Set(reflect.ValueOf(newLazy(a.c, tag)))
+		return nil
+	}
+	if a.ns == "" {
+		return a.c.p.Bind(v, conf.Tag(tag))
+	}
+	param := conf.BindParam{Key: a.ns, Path: v.Type().String()}
+	if err := param.BindTag(tag, ""); err != nil {
+		return err
+	}
+	return a.c.p.BindValue(v.Addr(), param)
 }
 
+// Wire 没有注册 context.Context 类型的 bean 时，为该类型的参数绑定容器的 ctx
+// 对象，如果设置了启动超时时间并且容器正在刷新，则绑定带有超时的 ctx 对象。
 func (a *argContext) Wire(v reflect.Value, tag string) error {
+	if v.Type() == stdCtxType && tag == "" {
+		if a.c.tempContainer == nil || len(a.c.beansByType[stdCtxType]) == 0 {
+			v.Set(reflect.ValueOf(a.c.startupContext()))
+			return nil
+		}
+	}
 	return a.c.wireByTag(v, tag, a.stack)
 }
 
+// startupContext 返回绑定给 context.Context 类型参数的 ctx 对象。
+func (c *container) startupContext() context.Context {
+	if c.startupCtx != nil && c.state != Refreshed {
+		return c.startupCtx
+	}
+	return c.ctx
+}
+
 // getBeanValue 获取 bean 的值，如果是构造函数 bean 则执行其构造函数然后返回执行结果。
 func (c *container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect.Value, error) {
 
@@ -721,7 +1396,7 @@ func (c *container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect
 		return b.Value(), nil
 	}
 
-	out, err := b.f.Call(&argContext{c: c, stack: stack})
+	out, err := b.f.Call(&argContext{c: c, ns: b.ns, stack: stack})
 	if err != nil {
 		return reflect.Value{}, err /* fmt.Errorf("%s:%s return error: %v", b.getClass(), b.ID(), err) */
 	}
@@ -752,8 +1427,9 @@ func (c *container) getBeanValue(b *BeanDefinition, stack *wiringStack) (reflect
 	return v, nil
 }
 
-// wireBeanValue 对 v 进行属性绑定和依赖注入，v 在传入时应该是一个已经初始化的值。
-func (c *container) wireBeanValue(v reflect.Value, t reflect.Type, stack *wiringStack) error {
+// wireBeanValue 对 v 进行属性绑定和依赖注入，v 在传入时应该是一个已经初始化的值，
+// ns 是 bean 的属性命名空间。
+func (c *container) wireBeanValue(v reflect.Value, t reflect.Type, ns string, stack *wiringStack) error {
 
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
@@ -770,7 +1446,7 @@ func (c *container) wireBeanValue(v reflect.Value, t reflect.Type, stack *wiring
 		typeName = t.String()
 	}
 
-	param := conf.BindParam{Path: typeName}
+	param := conf.BindParam{Key: ns, Path: typeName}
 	return c.wireStruct(v, t, param, stack)
 }
 
@@ -806,6 +1482,11 @@ func (c *container) wireStruct(v reflect.Value, t reflect.Type, opt conf.BindPar
 			tag, ok = ft.Tag.Lookup("inject")
 		}
 		if ok {
+			if ft.Type == lazyType {
+				c.ContextAware = true
+				fv.Set(reflect.ValueOf(newLazy(c, tag)))
+				continue
+			}
 			if strings.HasSuffix(tag, ",lazy") {
 				f := lazyField{v: fv, path: fieldPath, tag: tag}
 				stack.lazyFields = append(stack.lazyFields, f)
@@ -836,10 +1517,13 @@ func (c *container) wireStruct(v reflect.Value, t reflect.Type, opt conf.BindPar
 					return err
 				}
 			} else {
-				err := c.p.BindValue(fv.Addr(), subParam)
+				err := c.bindValue(fv.Addr(), subParam, stack)
 				if err != nil {
 					return err
 				}
+				if err = conf.ValidateField(fv, ft.Tag); err != nil {
+					return fmt.Errorf("%q bind error: %w", fieldPath, err)
+				}
 			}
 			continue
 		}
@@ -853,6 +1537,21 @@ func (c *container) wireStruct(v reflect.Value, t reflect.Type, opt conf.BindPar
 	return nil
 }
 
+// warnFrozen 冻结的属性发生变化时打印告警日志。
+func (c *container) warnFrozen(e dync.RefreshEvent) {
+	if len(e.Frozen) > 0 {
+		c.logger.Warnf("properties of %s are frozen, changes are ignored", strings.Join(e.Frozen, ", "))
+	}
+}
+
+// bindValue 绑定属性，正在注入的 bean 冻结了属性时只绑定一次。
+func (c *container) bindValue(v reflect.Value, param conf.BindParam, stack *wiringStack) error {
+	if n := len(stack.beans); n > 0 && stack.beans[n-1].frozen {
+		return c.p.BindFrozen(v, param)
+	}
+	return c.p.BindValue(v, param)
+}
+
 func (c *container) wireByTag(v reflect.Value, tag string, stack *wiringStack) error {
 
 	// tag 预处理，可能通过属性值进行指定。
@@ -904,6 +1603,11 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 		return fmt.Errorf("%s is not valid receiver type", t.String())
 	}
 
+	tag, err := c.resolveVariant(tag)
+	if err != nil {
+		return err
+	}
+
 	var foundBeans []*BeanDefinition
 	for _, b := range c.beansByType[t] {
 		if b.status == Deleted {
@@ -949,6 +1653,13 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 		return fmt.Errorf("can't find bean, bean:%q type:%q", tag, t)
 	}
 
+	// 同时存在 mock bean 和真实的 bean 时优先使用 mock bean
+	var selector util.BeanSelector = tag.String()
+	if tag.typeName == "" && tag.beanName == "" {
+		selector = t
+	}
+	foundBeans = c.preferMocks(selector, foundBeans)
+
 	// 优先使用设置成主版本的 bean
 	var primaryBeans []*BeanDefinition
 
@@ -984,7 +1695,7 @@ func (c *container) getBean(v reflect.Value, tag wireTag, stack *wiringStack) er
 	}
 
 	// 确保找到的 bean 已经完成依赖注入。
-	err := c.wireBean(result, stack)
+	err = c.wireBean(result, stack)
 	if err != nil {
 		return err
 	}
@@ -1024,6 +1735,16 @@ func filterBean(beans []*BeanDefinition, tag wireTag, t reflect.Type) (int, erro
 	return -1, fmt.Errorf("can't find bean, bean:%q type:%q", tag, t)
 }
 
+// hasNullableAny 返回 tags 中是否包含 *? 标记。
+func hasNullableAny(tags []wireTag) bool {
+	for _, tag := range tags {
+		if tag.beanName == "*" && tag.nullable {
+			return true
+		}
+	}
+	return false
+}
+
 type byOrder []*BeanDefinition
 
 func (b byOrder) Len() int           { return len(b) }
@@ -1033,8 +1754,8 @@ func (b byOrder) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool, stack *wiringStack) error {
 
 	t := v.Type()
-	if t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
-		return fmt.Errorf("should be slice or map in collection mode")
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Map && t.Kind() != reflect.Array {
+		return fmt.Errorf("should be slice, array or map in collection mode")
 	}
 
 	et := t.Elem()
@@ -1043,7 +1764,8 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool,
 	}
 
 	var beans []*BeanDefinition
-	if et.Kind() == reflect.Interface && et.NumMethod() == 0 {
+	anyType := et.Kind() == reflect.Interface && et.NumMethod() == 0
+	if anyType {
 		beans = c.beans
 	} else {
 		beans = c.beansByType[et]
@@ -1055,6 +1777,10 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool,
 			if b.status == Deleted {
 				continue
 			}
+			// 属性视图由容器自动注册，只在按类型注入时可见，不参与任意类型的收集。
+			if anyType && b.t == propertiesViewType {
+				continue
+			}
 			arr = append(arr, b)
 		}
 		beans = arr
@@ -1110,6 +1836,12 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool,
 		beans = arr
 	}
 
+	// 和单个 bean 的注入一样，同时收集到 mock bean 和真实的 bean 时只保留 mock bean ，
+	// 任意类型的收集不区分 bean 的类型，因此不做替换。
+	if !anyType {
+		beans = c.preferMocks(et, beans)
+	}
+
 	if len(beans) == 0 && !nullable {
 		if len(tags) == 0 {
 			return fmt.Errorf("no beans collected for %q", toWireString(tags))
@@ -1122,6 +1854,22 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool,
 		return nil
 	}
 
+	// 数组按照 order 和名称的顺序填充，超出容量的 bean 只有在使用 *? 标记时才被忽略。
+	if t.Kind() == reflect.Array {
+		sort.SliceStable(beans, func(i, j int) bool {
+			if beans[i].order != beans[j].order {
+				return beans[i].order < beans[j].order
+			}
+			return beans[i].name < beans[j].name
+		})
+		if n := t.Len(); len(beans) > n {
+			if !hasNullableAny(tags) {
+				return fmt.Errorf("found %d beans but %s can only hold %d, use \"*?\" to ignore the others", len(beans), t, n)
+			}
+			beans = beans[:n]
+		}
+	}
+
 	for _, b := range beans {
 		if err := c.wireBean(b, stack); err != nil {
 			return err
@@ -1130,6 +1878,11 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool,
 
 	var ret reflect.Value
 	switch t.Kind() {
+	case reflect.Array:
+		ret = reflect.New(t).Elem()
+		for i, b := range beans {
+			ret.Index(i).Set(b.Value())
+		}
 	case reflect.Slice:
 		sort.Sort(byOrder(beans))
 		ret = reflect.MakeSlice(t, 0, 0)
@@ -1146,28 +1899,248 @@ func (c *container) collectBeans(v reflect.Value, tags []wireTag, nullable bool,
 	return nil
 }
 
-// Close 关闭容器，此方法必须在 Refresh 之后调用。该方法会触发 ctx 的 Done 信
-// 号，然后等待所有 goroutine 结束，最后按照被依赖先销毁的原则执行所有的销毁函数。
-func (c *container) Close() {
+// Close 关闭容器，此方法必须在 Refresh 之后调用，否则返回 *IllegalStateError 类
+// 型的错误并且不做任何事情。该方法会触发 ctx 的 Done 信号，然后等待所有 goroutine
+// 结束，最后按照被依赖先销毁的原则执行所有的销毁函数。
+// 如果设置了 goroutine 的退出宽限期，超时未退出的 goroutine 会被记录到日志中，
+// 然后继续执行关闭流程，最后通过 *ShutdownTimeoutError 类型的错误返回。
+func (c *container) Close() error {
+
+	c.runtimeMutex.Lock()
+	err := c.checkAfterRefresh("Close")
+	if err == nil {
+		c.state = Closing
+	}
+	c.runtimeMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	childrenFirst, _ := strconv.ParseBool(c.p.Get(SpringCloseChildrenFirst, conf.Def("true")))
+	if childrenFirst {
+		c.closeChildren()
+	}
+
+	ctx, cancel := c.shutdownContext()
+	defer cancel()
 
+	c.runStopHooks(ctx)
+
+	stop := c.detectLeaks()
 	c.cancel()
-	c.wg.Wait()
+	err = c.waitJobs(ctx)
+	stop()
 
 	c.logger.Info("goroutines exited")
 
-	for _, f := range c.destroyers {
-		f()
+	c.runDestroyers(ctx)
+	c.reportListeners()
+
+	for _, f := range c.closedHooks {
+		c.runClosedHook(f)
+	}
+
+	if !childrenFirst {
+		c.closeChildren()
+	}
+
+	if c.parent != nil {
+		c.parent.removeChild(c)
 	}
 
+	c.runtimeMutex.Lock()
+	c.state = Closed
+	c.runtimeMutex.Unlock()
 	c.logger.Info("container closed")
+	return err
+}
+
+// AddChild 添加子容器，比如插件创建的容器。父容器关闭时会级联关闭所有存活的子容器，
+// 默认先按照添加顺序的逆序关闭子容器，然后关闭父容器自身，可以通过
+// spring.app.children.close-first 属性修改这个顺序。
+func (c *container) AddChild(child Container) {
+	x := child.(*container)
+	if x.parent != nil {
+		panic(errors.New("container already has a parent"))
+	}
+	x.parent = c
+	c.childrenMutex.Lock()
+	defer c.childrenMutex.Unlock()
+	c.children = append(c.children, x)
+}
+
+// Children 返回所有存活的子容器，已经关闭的子容器会被自动移除。
+func (c *container) Children() []Container {
+	c.childrenMutex.Lock()
+	defer c.childrenMutex.Unlock()
+	ret := make([]Container, 0, len(c.children))
+	for _, x := range c.children {
+		ret = append(ret, x)
+	}
+	return ret
+}
+
+func (c *container) removeChild(child *container) {
+	c.childrenMutex.Lock()
+	defer c.childrenMutex.Unlock()
+	for i, x := range c.children {
+		if x == child {
+			c.children = append(c.children[:i], c.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// closeChildren 按照添加顺序的逆序关闭子容器，未刷新的子容器直接移除。
+func (c *container) closeChildren() {
+	children := c.Children()
+	for i := len(children) - 1; i >= 0; i-- {
+		x := children[i].(*container)
+		if x.state != Refreshed {
+			c.removeChild(x)
+			continue
+		}
+		if err := x.Close(); err != nil {
+			c.logger.Error(err)
+		}
+	}
+}
+
+// OnClosed 注册在所有销毁函数执行完成之后执行的函数，按照注册的顺序执行，适合清理
+// unix socket、临时目录等容器级别的资源，单个函数的 panic 不会影响其他函数的执行。
+func (c *container) OnClosed(fn func()) {
+	c.closedHooks = append(c.closedHooks, fn)
+}
+
+func (c *container) runClosedHook(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error(r)
+		}
+	}()
+	fn()
+}
+
+// job 记录通过 Go 方法创建的 goroutine 。
+type job struct {
+	file    string
+	line    int
+	fn      string
+	grace   time.Duration
+	done    chan struct{}
+	created time.Time
+	stack   string
+}
+
+// GoOption 设置通过 Go 方法创建的 goroutine 的选项。
+type GoOption func(j *job)
+
+// GraceTimeout 设置 goroutine 在容器关闭后的退出宽限期，优先级高于属性
+// spring.app.jobs.shutdown-grace 的设置。
+func GraceTimeout(d time.Duration) GoOption {
+	return func(j *job) {
+		j.grace = d
+	}
+}
+
+// waitJobs 等待所有的 goroutine 退出，宽限期为 0 时一直等待，直到 ctx 结束。
+func (c *container) waitJobs(ctx context.Context) error {
+
+	c.jobsMutex.Lock()
+	jobs := make([]*job, 0, len(c.jobs))
+	for j := range c.jobs {
+		jobs = append(jobs, j)
+	}
+	c.jobsMutex.Unlock()
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		names []string
+	)
+
+	// 每个 goroutine 使用独立的计时器，宽限期都从发出退出信号时开始计算。
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			if c.waitJob(ctx, j) {
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			names = append(names, fmt.Sprintf("%s:%d %s", j.file, j.line, j.fn))
+		}(j)
+	}
+	wg.Wait()
+
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return &ShutdownTimeoutError{Jobs: names}
+}
+
+// waitJob 等待 goroutine 退出，超过宽限期或者关闭超时时返回 false 。
+func (c *container) waitJob(ctx context.Context, j *job) bool {
+
+	grace := j.grace
+	if grace <= 0 {
+		grace = c.ShutdownGrace
+	}
+
+	var timeout <-chan time.Time
+	if grace > 0 {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-j.done:
+		return true
+	case <-timeout:
+		c.logger.Errorf("goroutine %s:%d %s didn't exit in %v after shutdown", j.file, j.line, j.fn, grace)
+	case <-ctx.Done():
+		c.logger.Errorf("goroutine %s:%d %s didn't exit before shutdown timeout", j.file, j.line, j.fn)
+	}
+	return false
+}
+
+// ShutdownTimeoutError 关闭容器时存在没有在宽限期内退出的 goroutine 。
+type ShutdownTimeoutError struct {
+	Jobs []string // 没有退出的 goroutine ，格式为 file:line fn
+}
+
+func (e *ShutdownTimeoutError) Error() string {
+	return fmt.Sprintf("goroutines didn't exit in time: %s", strings.Join(e.Jobs, ", "))
 }
 
 // Go 创建安全可等待的 goroutine，fn 要求的 ctx 对象由 IoC 容器提供，当 IoC 容
 // 器关闭时 ctx会 发出 Done 信号， fn 在接收到此信号后应当立即退出。
-func (c *container) Go(fn func(ctx context.Context)) {
-	c.wg.Add(1)
+func (c *container) Go(fn func(ctx context.Context), opts ...GoOption) {
+
+	j := &job{done: make(chan struct{}), created: time.Now()}
+	j.file, j.line, j.fn = util.FileLine(fn)
+	if c.traceStacks() {
+		j.stack = string(debug.Stack())
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	c.jobsMutex.Lock()
+	c.jobs[j] = struct{}{}
+	c.jobsMutex.Unlock()
+	atomic.AddInt64(&c.stats.goroutines, 1)
+
 	go func() {
-		defer c.wg.Done()
+		defer func() {
+			c.jobsMutex.Lock()
+			delete(c.jobs, j)
+			c.jobsMutex.Unlock()
+			close(j.done)
+		}()
 		defer func() {
 			if r := recover(); r != nil {
 				c.logger.Panic(r)
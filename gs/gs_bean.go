@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/go-spring/spring-base/util"
 	"github.com/go-spring/spring-core/gs/arg"
@@ -73,6 +74,148 @@ type BeanDestroy interface {
 	OnDestroy()
 }
 
+// PostConstruct 嵌入到 bean 的结构体中用于声明初始化方法，默认使用名为 Init 的方法，
+// 也可以通过 method 标签指定方法名，例如 gs.PostConstruct `method:"Start"` ，方法
+// 的要求和 BeanDefinition.Init 相同。注册 bean 时会自动检测，显式调用 Init 时以
+// 显式设置的为准。
+type PostConstruct struct{}
+
+// PreDestroy 嵌入到 bean 的结构体中用于声明销毁方法，默认使用名为 Destroy 的方法，
+// 也可以通过 method 标签指定方法名，其他规则和 PostConstruct 相同。
+type PreDestroy struct{}
+
+var (
+	postConstructType = reflect.TypeOf(PostConstruct{})
+	preDestroyType    = reflect.TypeOf(PreDestroy{})
+)
+
+// markedMethod 返回 bean 通过嵌入 marker 声明的生命周期方法，没有嵌入 marker 时
+// 返回 nil 。
+func markedMethod(t reflect.Type, marker reflect.Type, defaultName string) interface{} {
+
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil
+	}
+
+	f, ok := st.FieldByName(marker.Name())
+	if !ok || !f.Anonymous || f.Type != marker {
+		return nil
+	}
+
+	name := defaultName
+	if s, ok := f.Tag.Lookup("method"); ok && s != "" {
+		name = s
+	}
+
+	m, ok := t.MethodByName(name)
+	if !ok {
+		panic(fmt.Errorf("%s declares %s but method %s not found", t, marker, name))
+	}
+	if !util.ReturnNothing(m.Type) && !util.ReturnOnlyError(m.Type) || m.Type.NumIn() != 1 {
+		panic(fmt.Errorf("%s.%s should be func() or func() error", t, name))
+	}
+	return m.Func.Interface()
+}
+
+// BeanRegistration 定义了第三方扩展(比如 starter)可以依赖的稳定的 bean 注册
+// 接口，包括 bean 的元数据以及设置条件、导出接口、初始化和销毁函数等方法，扩展应
+// 该只依赖这个接口，而不是 BeanDefinition 的其他方法或者 gs 的内部包。通过
+// BeanDefinition.Registration 获得，设置方法返回 BeanRegistration 以便链式调用，
+// 需要交给 Accept 等函数时通过 Definition 方法取回对应的 BeanDefinition 。
+type BeanRegistration interface {
+	ID() string
+	BeanName() string
+	TypeName() string
+	Type() reflect.Type
+	FileLine() string
+	Name(name string) BeanRegistration
+	On(cond cond.Condition) BeanRegistration
+	Order(order float32) BeanRegistration
+	DependsOn(selectors ...util.BeanSelector) BeanRegistration
+	Primary() BeanRegistration
+	Init(fn interface{}) BeanRegistration
+	Destroy(fn interface{}) BeanRegistration
+	Export(exports ...interface{}) BeanRegistration
+	Definition() *BeanDefinition
+}
+
+// beanRegistration 基于 BeanDefinition 实现的 BeanRegistration 。
+type beanRegistration struct {
+	d *BeanDefinition
+}
+
+// Registration 返回 bean 的 BeanRegistration 接口。
+func (d *BeanDefinition) Registration() BeanRegistration {
+	return &beanRegistration{d: d}
+}
+
+func (r *beanRegistration) ID() string         { return r.d.ID() }
+func (r *beanRegistration) BeanName() string   { return r.d.BeanName() }
+func (r *beanRegistration) TypeName() string   { return r.d.TypeName() }
+func (r *beanRegistration) Type() reflect.Type { return r.d.Type() }
+func (r *beanRegistration) FileLine() string   { return r.d.FileLine() }
+
+func (r *beanRegistration) Definition() *BeanDefinition {
+	return r.d
+}
+
+func (r *beanRegistration) Name(name string) BeanRegistration {
+	r.d.Name(name)
+	return r
+}
+
+func (r *beanRegistration) On(cond cond.Condition) BeanRegistration {
+	r.d.On(cond)
+	return r
+}
+
+func (r *beanRegistration) Order(order float32) BeanRegistration {
+	r.d.Order(order)
+	return r
+}
+
+func (r *beanRegistration) DependsOn(selectors ...util.BeanSelector) BeanRegistration {
+	r.d.DependsOn(selectors...)
+	return r
+}
+
+func (r *beanRegistration) Primary() BeanRegistration {
+	r.d.Primary()
+	return r
+}
+
+func (r *beanRegistration) Init(fn interface{}) BeanRegistration {
+	r.d.Init(fn)
+	return r
+}
+
+func (r *beanRegistration) Destroy(fn interface{}) BeanRegistration {
+	r.d.Destroy(fn)
+	return r
+}
+
+func (r *beanRegistration) Export(exports ...interface{}) BeanRegistration {
+	r.d.Export(exports...)
+	return r
+}
+
+// BeanProvider 以值的形式提供一组 bean ，类库可以通过它打包自己的 bean 集合，
+// 从而不必完全依赖包的 init 函数注册 bean ，也便于显式组合和测试。
+type BeanProvider interface {
+	Beans() []*BeanDefinition
+}
+
+// BeanProviderFunc func 形式的 BeanProvider 。
+type BeanProviderFunc func() []*BeanDefinition
+
+func (f BeanProviderFunc) Beans() []*BeanDefinition {
+	return f()
+}
+
 // BeanDefinition bean 元数据。
 type BeanDefinition struct {
 
@@ -89,13 +232,21 @@ type BeanDefinition struct {
 	name    string              // 名称
 	status  beanStatus          // 状态
 	primary bool                // 是否为主版本
+	replace bool                // 是否覆盖先注册的同 ID bean
 	method  bool                // 是否为成员方法
 	cond    cond.Condition      // 判断条件
 	order   float32             // 收集时的顺序
+	stage   int                 // 注入阶段
 	init    interface{}         // 初始化函数
+	timeout time.Duration       // 初始化超时时间
 	destroy interface{}         // 销毁函数
 	depends []util.BeanSelector // 间接依赖项
 	exports []reflect.Type      // 导出的接口
+	exAt    []string            // 导出接口的位置
+	ns      string              // 属性命名空间
+	mock    bool                // 是否为 mock bean
+	frozen  bool                // 属性是否只绑定一次
+	timing  beanTiming          // 刷新时的耗时
 }
 
 // Type 返回 bean 的类型。
@@ -189,6 +340,37 @@ func (d *BeanDefinition) Order(order float32) *BeanDefinition {
 	return d
 }
 
+// Stage 设置 bean 的注入阶段，默认为 0 。容器按照阶段从小到大的顺序注入 bean ，
+// 只有前一个阶段的所有 bean 都完成注入和初始化之后才会开始下一个阶段，因此较早阶
+// 段的 bean 不能依赖较晚阶段的 bean 。
+func (d *BeanDefinition) Stage(n int) *BeanDefinition {
+	d.stage = n
+	return d
+}
+
+// Namespace 设置 bean 的属性命名空间，bean 的属性绑定都相对于该命名空间进行，
+// 例如命名空间为 billing 时 ${db.url} 绑定的是 billing.db.url 属性，以 / 开头
+// 的 key 例如 ${/spring.app.name} 仍然绑定绝对的属性。属性值中的 ${} 引用不受命名
+// 空间的影响，总是按照绝对的属性解析，其中 ${/key} 和 ${key} 是等价的。
+func (d *BeanDefinition) Namespace(ns string) *BeanDefinition {
+	d.ns = ns
+	return d
+}
+
+// Mock 标记为 mock bean ，注入时如果 mock bean 和真实的 bean 同时满足条件，那么
+// 总是使用 mock bean ，收集模式的注入也只收集 mock bean ，一般通过 gstest.Mock 注册。
+func (d *BeanDefinition) Mock() *BeanDefinition {
+	d.mock = true
+	return d
+}
+
+// FreezeProperties 冻结 bean 的属性，属性只在注入时绑定一次，包括 dync 包中的
+// 动态属性，之后属性发生变化时不会刷新而是打印告警日志，适用于安全敏感的 bean 。
+func (d *BeanDefinition) FreezeProperties() *BeanDefinition {
+	d.frozen = true
+	return d
+}
+
 // DependsOn 设置 bean 的间接依赖项。
 func (d *BeanDefinition) DependsOn(selectors ...util.BeanSelector) *BeanDefinition {
 	d.depends = append(d.depends, selectors...)
@@ -201,6 +383,14 @@ func (d *BeanDefinition) Primary() *BeanDefinition {
 	return d
 }
 
+// Replace 显式覆盖先注册的 ID 相同 (名称和类型都相同) 的 bean ，而不是报告 bean
+// 重复注册的错误。有多个 bean 设置 Replace 时后注册的生效，被覆盖的 bean 是主版本
+// 时覆盖它的 bean 也成为主版本。常用于测试或者插件模块替换默认的实现。
+func (d *BeanDefinition) Replace() *BeanDefinition {
+	d.replace = true
+	return d
+}
+
 // validLifeCycleFunc 判断是否是合法的用于 bean 生命周期控制的函数，生命周期函数
 // 的要求：只能有一个入参并且必须是 bean 的类型，没有返回值或者只返回 error 类型值。
 func validLifeCycleFunc(fnType reflect.Type, beanValue reflect.Value) bool {
@@ -213,13 +403,34 @@ func validLifeCycleFunc(fnType reflect.Type, beanValue reflect.Value) bool {
 	return util.ReturnNothing(fnType) || util.ReturnOnlyError(fnType)
 }
 
-// Init 设置 bean 的初始化函数。
+// validInitFunc 判断是否是合法的初始化函数，除了生命周期函数的要求之外，还可以
+// 使用 context.Context 类型的第二个入参。
+func validInitFunc(fnType reflect.Type, beanValue reflect.Value) bool {
+	if util.IsFuncType(fnType) && fnType.NumIn() == 2 && fnType.In(1) == stdCtxType {
+		if !util.HasReceiver(fnType, beanValue) {
+			return false
+		}
+		return util.ReturnNothing(fnType) || util.ReturnOnlyError(fnType)
+	}
+	return validLifeCycleFunc(fnType, beanValue)
+}
+
+// Init 设置 bean 的初始化函数，支持 func(bean)、func(bean)error 以及带有
+// context.Context 入参的 func(bean,ctx)、func(bean,ctx)error 四种形式。
 func (d *BeanDefinition) Init(fn interface{}) *BeanDefinition {
-	if validLifeCycleFunc(reflect.TypeOf(fn), d.Value()) {
+	if validInitFunc(reflect.TypeOf(fn), d.Value()) {
 		d.init = fn
 		return d
 	}
-	panic(errors.New("init should be func(bean) or func(bean)error"))
+	panic(errors.New("init should be func(bean), func(bean)error, func(bean,ctx) or func(bean,ctx)error"))
+}
+
+// InitTimeout 设置 bean 初始化的超时时间，超时后容器注入失败，同时取消传给初始化
+// 函数的 context.Context 。Go 无法强制结束 goroutine ，不检查 ctx 的初始化函数在
+// 超时之后仍然会在后台继续执行，直到其自行返回。
+func (d *BeanDefinition) InitTimeout(timeout time.Duration) *BeanDefinition {
+	d.timeout = timeout
+	return d
 }
 
 // Destroy 设置 bean 的销毁函数。
@@ -239,6 +450,7 @@ func (d *BeanDefinition) Export(exports ...interface{}) *BeanDefinition {
 }
 
 func (d *BeanDefinition) export(exports ...interface{}) error {
+	_, file, line, _ := runtime.Caller(2)
 	for _, o := range exports {
 		var typ reflect.Type
 		if t, ok := o.(reflect.Type); ok {
@@ -260,6 +472,7 @@ func (d *BeanDefinition) export(exports ...interface{}) error {
 			continue
 		}
 		d.exports = append(d.exports, typ)
+		d.exAt = append(d.exAt, fmt.Sprintf("%s:%d", file, line))
 	}
 	return nil
 }
@@ -350,5 +563,7 @@ func NewBean(objOrCtor interface{}, ctorArgs ...arg.Arg) *BeanDefinition {
 		method:   method,
 		file:     file,
 		line:     line,
+		init:     markedMethod(t, postConstructType, "Init"),
+		destroy:  markedMethod(t, preDestroyType, "Destroy"),
 	}
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/go-spring/spring-base/util"
 	"github.com/go-spring/spring-core/gs"
 	"github.com/go-spring/spring-core/gs/arg"
+	"github.com/go-spring/spring-core/gs/cond"
 	pkg1 "github.com/go-spring/spring-core/gs/testdata/pkg/bar"
 	pkg2 "github.com/go-spring/spring-core/gs/testdata/pkg/foo"
 )
@@ -358,3 +359,46 @@ func TestNumMethod(t *testing.T) {
 	typ2 := reflect.TypeOf((*reCaller)(nil))
 	assert.True(t, typ1 == typ2)
 }
+
+type starterBean struct {
+	inited    bool
+	destroyed bool
+}
+
+func (b *starterBean) String() string { return "starter" }
+
+// configureStarter 模拟只依赖 BeanRegistration 的第三方扩展。
+func configureStarter(r gs.BeanRegistration) gs.BeanRegistration {
+	return r.Name("starter").
+		On(cond.OnProperty("starter.enabled", cond.HavingValue("true"))).
+		Init(func(b *starterBean) { b.inited = true }).
+		Destroy(func(b *starterBean) { b.destroyed = true }).
+		Export((*fmt.Stringer)(nil))
+}
+
+func TestBeanRegistration(t *testing.T) {
+
+	b := &starterBean{}
+	r := configureStarter(newBean(b).Registration())
+	assert.Equal(t, r.BeanName(), "starter")
+	assert.Equal(t, r.Type(), reflect.TypeOf(b))
+	assert.Equal(t, r.ID(), r.Definition().ID())
+
+	var h struct {
+		Stringers []fmt.Stringer `autowire:""`
+	}
+
+	c := gs.New()
+	c.Property("starter.enabled", "true")
+	c.RegisterProvider(gs.BeanProviderFunc(func() []*gs.BeanDefinition {
+		return []*gs.BeanDefinition{r.Definition()}
+	}))
+	c.Object(&h)
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.True(t, b.inited)
+	assert.Equal(t, h.Stringers, []fmt.Stringer{b})
+
+	c.Close()
+	assert.True(t, b.destroyed)
+}
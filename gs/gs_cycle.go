@@ -0,0 +1,209 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"sort"
+	"strings"
+)
+
+// beanCycle 依赖关系图中的一个环，首尾相连。
+type beanCycle []*BeanDefinition
+
+// String 使用 bean 的 ID 描述环中的每个 bean ，这样即使是没有命名的构造函数
+// bean 也能看出它的类型。
+func (cycle beanCycle) String() string {
+	var sb strings.Builder
+	for _, b := range cycle {
+		sb.WriteString(b.ID() + " " + b.FileLine())
+		sb.WriteString("\n    -> ")
+	}
+	sb.WriteString(cycle[0].ID() + " " + cycle[0].FileLine())
+	return sb.String()
+}
+
+// CycleError 注入之前发现的无法解决的循环依赖，每个环都包含完整的 bean 链路以及
+// 注册位置。
+type CycleError struct {
+	Cycles []string
+}
+
+func (e *CycleError) Error() string {
+	return "found circle autowire:\n    " + strings.Join(e.Cycles, "\n\n    ")
+}
+
+// checkCycles 在注入之前检查 bean 之间的循环依赖。只由构造函数创建的 bean 组成的
+// 环无论注入顺序如何都会失败，所以一次性报告所有这样的环；包含对象 bean 的环能否
+// 成功取决于注入顺序，只打印告警日志。延迟注入的边不参与检查。无法构建依赖关系图
+// 时跳过检查，由注入过程报告具体的错误。
+func (c *container) checkCycles(beans []*BeanDefinition) error {
+
+	g, err := c.graphOf(beans)
+	if err != nil {
+		c.logger.Debugf("skip cycle check: %v", err)
+		return nil
+	}
+
+	edges := make(map[*BeanDefinition][]*BeanDefinition)
+	for _, e := range g.edges {
+		if e.kind != "lazy" {
+			edges[e.from] = append(edges[e.from], e.to)
+		}
+	}
+
+	var ctorBeans []*BeanDefinition
+	for _, b := range g.beans {
+		if b.f != nil {
+			ctorBeans = append(ctorBeans, b)
+		}
+	}
+
+	fatal := findCycles(ctorBeans, edges)
+	inFatal := make(map[*BeanDefinition]bool)
+	for _, cycle := range fatal {
+		for _, b := range cycle {
+			inFatal[b] = true
+		}
+	}
+
+	for _, cycle := range findCycles(g.beans, edges) {
+		if inFatal[cycle[0]] {
+			continue
+		}
+		for _, b := range cycle {
+			if b.f != nil {
+				c.logger.Warnf("found circular dependencies, it may fail depending on the wiring order:\n    %s", cycle)
+				break
+			}
+		}
+	}
+
+	if len(fatal) == 0 {
+		return nil
+	}
+	e := &CycleError{}
+	for _, cycle := range fatal {
+		e.Cycles = append(e.Cycles, cycle.String())
+	}
+	return e
+}
+
+// findCycles 使用 Tarjan 算法查找由 beans 组成的子图中的强连通分量，对每个包含
+// 多个 bean 的强连通分量返回其中的一个环，环从 ID 最小的 bean 开始。
+func findCycles(beans []*BeanDefinition, edges map[*BeanDefinition][]*BeanDefinition) []beanCycle {
+
+	included := make(map[*BeanDefinition]bool)
+	for _, b := range beans {
+		included[b] = true
+	}
+
+	var (
+		index   = 0
+		indexes = make(map[*BeanDefinition]int)
+		lowLink = make(map[*BeanDefinition]int)
+		onStack = make(map[*BeanDefinition]bool)
+		stack   []*BeanDefinition
+		result  []beanCycle
+	)
+
+	var connect func(b *BeanDefinition)
+	connect = func(b *BeanDefinition) {
+		indexes[b] = index
+		lowLink[b] = index
+		index++
+		stack = append(stack, b)
+		onStack[b] = true
+
+		for _, to := range edges[b] {
+			if !included[to] {
+				continue
+			}
+			if _, ok := indexes[to]; !ok {
+				connect(to)
+				if lowLink[to] < lowLink[b] {
+					lowLink[b] = lowLink[to]
+				}
+			} else if onStack[to] && indexes[to] < lowLink[b] {
+				lowLink[b] = indexes[to]
+			}
+		}
+
+		if lowLink[b] != indexes[b] {
+			return
+		}
+
+		component := make(map[*BeanDefinition]bool)
+		for {
+			x := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[x] = false
+			component[x] = true
+			if x == b {
+				break
+			}
+		}
+		if len(component) > 1 {
+			result = append(result, cycleIn(component, edges))
+		}
+	}
+
+	for _, b := range beans {
+		if _, ok := indexes[b]; !ok {
+			connect(b)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0].ID() < result[j][0].ID()
+	})
+	return result
+}
+
+// cycleIn 返回强连通分量中从 ID 最小的 bean 出发并回到它自身的最短的环。
+func cycleIn(component map[*BeanDefinition]bool, edges map[*BeanDefinition][]*BeanDefinition) beanCycle {
+
+	var start *BeanDefinition
+	for b := range component {
+		if start == nil || b.ID() < start.ID() {
+			start = b
+		}
+	}
+
+	prev := make(map[*BeanDefinition]*BeanDefinition)
+	queue := []*BeanDefinition{start}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		for _, to := range edges[b] {
+			if !component[to] {
+				continue
+			}
+			if to == start {
+				var cycle beanCycle
+				for x := b; x != start; x = prev[x] {
+					cycle = append(beanCycle{x}, cycle...)
+				}
+				return append(beanCycle{start}, cycle...)
+			}
+			if _, ok := prev[to]; !ok {
+				prev[to] = b
+				queue = append(queue, to)
+			}
+		}
+	}
+	return beanCycle{start}
+}
@@ -156,3 +156,37 @@ func TestDynamic(t *testing.T) {
 		assert.Equal(t, string(b), `{"Wrapper":{"Int":3,"Float":1.5,"Map":{"a":"9","b":"8"},"Slice":["4","6"],"Event":{}}}`)
 	}
 }
+
+type FrozenConfig struct {
+	Token dync.String `value:"${token:=abc}"`
+	Level dync.Int64  `value:"${level:=1}"`
+}
+
+func TestFreezeProperties(t *testing.T) {
+
+	frozen := new(FrozenConfig)
+	watched := new(FrozenConfig)
+
+	c := gs.New()
+	c.Object(frozen).Name("frozen").FreezeProperties()
+	c.Object(watched).Name("watched")
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	var paths []string
+	c.Properties().Observe(dync.RefreshObserverFunc(func(e dync.RefreshEvent) {
+		paths = e.Frozen
+	}))
+
+	p := conf.New()
+	p.Set("token", "xyz")
+	p.Set("level", 2)
+	err = c.Properties().Refresh(p)
+	assert.Nil(t, err)
+
+	assert.Equal(t, frozen.Token.Value(), "abc")
+	assert.Equal(t, frozen.Level.Value(), int64(1))
+	assert.Equal(t, watched.Token.Value(), "xyz")
+	assert.Equal(t, watched.Level.Value(), int64(2))
+	assert.Equal(t, paths, []string{"FrozenConfig.Token", "FrozenConfig.Level"})
+}
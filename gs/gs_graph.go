@@ -0,0 +1,418 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/gs/cond"
+)
+
+// SpringDependencyGraphEnabled 是否在容器刷新之后保存 bean 的依赖关系图，开启后
+// 即使 bean 的索引已经被清除仍然可以通过 Graph 获取依赖关系图。
+const SpringDependencyGraphEnabled = "spring.app.dependency-graph.enabled"
+
+// Graph bean 的依赖关系图，可以直接序列化为 JSON ，也可以通过 WriteDOT 和
+// WriteMermaid 方法输出为 DOT 和 Mermaid 格式。
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode 依赖关系图中的 bean 。
+type GraphNode struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Source    string `json:"source"`
+	Condition string `json:"condition,omitempty"`
+	Mock      bool   `json:"mock,omitempty"`
+}
+
+// GraphEdge 依赖关系图中的边，Kind 为空表示直接注入，lazy 表示延迟注入，depends
+// 表示间接依赖。
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind,omitempty"`
+}
+
+// graphEdge bean 依赖关系图中的一条边。
+type graphEdge struct {
+	from *BeanDefinition
+	to   *BeanDefinition
+	kind string // 为空表示直接注入，lazy 表示延迟注入，depends 表示间接依赖
+}
+
+// beanGraph bean 的依赖关系图。
+type beanGraph struct {
+	beans []*BeanDefinition
+	edges []graphEdge
+}
+
+// DumpGraph 以 DOT 格式输出 bean 的依赖关系图。延迟注入的边使用虚线，间接依赖
+// 的边使用点线，设置了条件的 bean 会标注其条件，在测试代码中注册的 bean 被视为
+// mock bean 并使用不同的颜色。需要在容器刷新之后并且保留了 bean 索引时调用。
+func (c *container) DumpGraph(w io.Writer) error {
+	g, err := c.Graph()
+	if err != nil {
+		return err
+	}
+	return g.WriteDOT(w)
+}
+
+// Graph 返回 bean 的依赖关系图，需要在容器刷新之后调用。bean 的索引被清除之后
+// 只能返回开启 spring.app.dependency-graph.enabled 属性时保存的依赖关系图。
+func (c *container) Graph() (*Graph, error) {
+	if c.tempContainer == nil && c.graph != nil {
+		return c.graph, nil
+	}
+	g, err := c.buildGraph()
+	if err != nil {
+		return nil, err
+	}
+	return g.export(g.beans), nil
+}
+
+// DependencyGraph 返回 bean 的依赖关系图。应用启动之后 bean 的索引会被清除，因此
+// 需要开启 spring.app.dependency-graph.enabled 属性。
+func (app *App) DependencyGraph() (*Graph, error) {
+	return app.c.Graph()
+}
+
+// saveGraph 如果开启了 spring.app.dependency-graph.enabled 属性，则保存 bean 的
+// 依赖关系图。
+func (c *container) saveGraph() error {
+	if ok, _ := strconv.ParseBool(c.p.Get(SpringDependencyGraphEnabled)); !ok {
+		return nil
+	}
+	g, err := c.Graph()
+	if err != nil {
+		return err
+	}
+	c.graph = g
+	return nil
+}
+
+// DumpGraphAround 只输出和 selector 选中的 bean 之间距离不超过 depth 的 bean ，
+// 适用于包含成千上万个 bean 的大型应用。
+func (c *container) DumpGraphAround(w io.Writer, selector util.BeanSelector, depth int) error {
+
+	g, err := c.buildGraph()
+	if err != nil {
+		return err
+	}
+
+	roots, err := c.findBean(selector)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("can't find bean, bean:%q", selector)
+	}
+
+	neighbors := make(map[*BeanDefinition][]*BeanDefinition)
+	for _, e := range g.edges {
+		neighbors[e.from] = append(neighbors[e.from], e.to)
+		neighbors[e.to] = append(neighbors[e.to], e.from)
+	}
+
+	visited := make(map[*BeanDefinition]bool)
+	for _, b := range roots {
+		visited[b] = true
+	}
+	current := roots
+	for i := 0; i < depth && len(current) > 0; i++ {
+		var next []*BeanDefinition
+		for _, b := range current {
+			for _, n := range neighbors[b] {
+				if !visited[n] {
+					visited[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		current = next
+	}
+
+	var beans []*BeanDefinition
+	for _, b := range g.beans {
+		if visited[b] {
+			beans = append(beans, b)
+		}
+	}
+	return g.export(beans).WriteDOT(w)
+}
+
+// buildGraph 根据 bean 的依赖项、构造函数参数以及结构体字段上的注入标签构建依赖
+// 关系图。
+func (c *container) buildGraph() (*beanGraph, error) {
+
+	if err := c.checkAfterRefresh("DumpGraph"); err != nil {
+		return nil, err
+	}
+	if c.tempContainer == nil {
+		return nil, fmt.Errorf("bean indexes have been cleared, set %s=true to keep the graph", SpringDependencyGraphEnabled)
+	}
+
+	c.beansMutex.RLock()
+	beans := c.beans
+	c.beansMutex.RUnlock()
+	return c.graphOf(beans)
+}
+
+// graphOf 构建 beans 中有效的 bean 之间的依赖关系图。
+func (c *container) graphOf(beans []*BeanDefinition) (*beanGraph, error) {
+
+	g := &beanGraph{}
+	exists := make(map[string]bool)
+	addEdges := func(from *BeanDefinition, to []*BeanDefinition, kind string) {
+		for _, b := range to {
+			key := from.ID() + "->" + b.ID() + ":" + kind
+			if b == from || exists[key] {
+				continue
+			}
+			exists[key] = true
+			g.edges = append(g.edges, graphEdge{from: from, to: b, kind: kind})
+		}
+	}
+
+	for _, b := range beans {
+		if b.status == Deleted {
+			continue
+		}
+		g.beans = append(g.beans, b)
+
+		for _, s := range b.depends {
+			to, err := c.findBean(s)
+			if err != nil {
+				return nil, err
+			}
+			addEdges(b, to, "depends")
+		}
+
+		if b.f != nil {
+			for i := 0; ; i++ {
+				p, ok := b.f.Provenance(i)
+				if !ok {
+					break
+				}
+				if p.Kind != "bean" {
+					continue
+				}
+				to, err := c.findWireTargets(p.Type, p.Tag)
+				if err != nil {
+					return nil, err
+				}
+				addEdges(b, to, "")
+			}
+		}
+
+		if t := b.Type(); t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+			if err := c.findFieldTargets(b, t.Elem(), addEdges); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g, nil
+}
+
+// findFieldTargets 查找结构体字段注入的 bean ，嵌入的结构体会被展开。
+func (c *container) findFieldTargets(b *BeanDefinition, t reflect.Type, addEdges func(*BeanDefinition, []*BeanDefinition, string)) error {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+
+		tag, ok := ft.Tag.Lookup("autowire")
+		if !ok {
+			tag, ok = ft.Tag.Lookup("inject")
+		}
+		if !ok {
+			_, hasValue := ft.Tag.Lookup("value")
+			if !hasValue && ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+				if err := c.findFieldTargets(b, ft.Type, addEdges); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		kind := ""
+		if ft.Type == lazyType || strings.HasSuffix(tag, ",lazy") {
+			kind = "lazy"
+			tag = strings.TrimSuffix(tag, ",lazy")
+		}
+
+		if ft.Type == lazyType && tag == "" {
+			continue // 无法确定 Lazy 句柄的类型
+		}
+
+		to, err := c.findWireTargets(ft.Type, tag)
+		if err != nil {
+			return err
+		}
+		addEdges(b, to, kind)
+	}
+	return nil
+}
+
+// findWireTargets 查找 t 类型的注入点通过 tag 注入的 bean 。
+func (c *container) findWireTargets(t reflect.Type, tag string) ([]*BeanDefinition, error) {
+
+	if strings.HasPrefix(tag, "${") {
+		s, err := c.p.Resolve(tag)
+		if err != nil {
+			return nil, err
+		}
+		tag = s
+	}
+
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	var result []*BeanDefinition
+	for _, s := range strings.Split(tag, ",") {
+		w := parseWireTag(strings.TrimSpace(s))
+		var selector util.BeanSelector = t
+		if w.beanName != "" && w.beanName != "*" {
+			selector = w.String()
+		}
+		if t == lazyType {
+			selector = w.String()
+		}
+		beans, err := c.findBean(selector)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, beans...)
+	}
+	return result, nil
+}
+
+// export 返回只包含 beans 以及它们之间的边的依赖关系图。
+func (g *beanGraph) export(beans []*BeanDefinition) *Graph {
+
+	included := make(map[*BeanDefinition]bool)
+	for _, b := range beans {
+		included[b] = true
+	}
+
+	ret := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, b := range beans {
+		n := GraphNode{
+			ID:     b.ID(),
+			Name:   b.BeanName(),
+			Type:   b.TypeName(),
+			Source: b.FileLine(),
+			Mock:   isMockBean(b),
+		}
+		if b.cond != nil {
+			n.Condition = cond.Describe(b.cond)
+		}
+		ret.Nodes = append(ret.Nodes, n)
+	}
+
+	for _, e := range g.edges {
+		if included[e.from] && included[e.to] {
+			ret.Edges = append(ret.Edges, GraphEdge{From: e.from.ID(), To: e.to.ID(), Kind: e.kind})
+		}
+	}
+	return ret
+}
+
+// WriteDOT 以 DOT 格式输出依赖关系图。
+func (g *Graph) WriteDOT(w io.Writer) error {
+
+	var sb strings.Builder
+	sb.WriteString("digraph beans {\n")
+	sb.WriteString("  node [shape=box];\n")
+
+	for _, n := range g.Nodes {
+		label := n.Name + "\n" + n.Type + "\n" + n.Source
+		attrs := ""
+		if n.Condition != "" {
+			label += "\non " + n.Condition
+			attrs += ", style=dashed"
+		}
+		if n.Mock {
+			label += "\n(mock)"
+			attrs += ", color=orange"
+		}
+		sb.WriteString(fmt.Sprintf("  %q [label=%q%s];\n", n.ID, label, attrs))
+	}
+
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case "lazy":
+			sb.WriteString(fmt.Sprintf("  %q -> %q [style=dashed, label=\"lazy\"];\n", e.From, e.To))
+		case "depends":
+			sb.WriteString(fmt.Sprintf("  %q -> %q [style=dotted, label=\"depends\"];\n", e.From, e.To))
+		default:
+			sb.WriteString(fmt.Sprintf("  %q -> %q;\n", e.From, e.To))
+		}
+	}
+
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteMermaid 以 Mermaid 流程图的格式输出依赖关系图，节点的 ID 按照顺序编号。
+func (g *Graph) WriteMermaid(w io.Writer) error {
+
+	escape := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+
+	ids := make(map[string]string)
+	for i, n := range g.Nodes {
+		id := "n" + strconv.Itoa(i)
+		ids[n.ID] = id
+		label := escape.Replace(n.Name) + "<br/>" + escape.Replace(n.Type)
+		if n.Condition != "" {
+			label += "<br/>on " + escape.Replace(n.Condition)
+		}
+		sb.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", id, label))
+		if n.Mock {
+			sb.WriteString(fmt.Sprintf("  style %s stroke:orange\n", id))
+		}
+	}
+
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case "":
+			sb.WriteString(fmt.Sprintf("  %s --> %s\n", ids[e.From], ids[e.To]))
+		default:
+			sb.WriteString(fmt.Sprintf("  %s -. %s .-> %s\n", ids[e.From], e.Kind, ids[e.To]))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// isMockBean 返回 bean 是否是 mock bean ，通过 Mock 标记的以及在测试代码中注册的
+// bean 被视为 mock bean 。
+func isMockBean(b *BeanDefinition) bool {
+	return b.mock || strings.HasSuffix(b.file, "_test.go")
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// SpringLeakDetectionDelay 容器开始关闭之后经过多长时间报告仍在运行的 goroutine ，
+// 未设置时不报告。
+const SpringLeakDetectionDelay = "spring.app.leak-detection.delay"
+
+// SpringLeakDetectionStack 是否记录 goroutine 以及动态属性订阅者的创建堆栈，记录
+// 堆栈的开销较大，一般只在调试时开启。
+const SpringLeakDetectionStack = "spring.app.leak-detection.stack"
+
+// Leak 一个可能泄露的资源。
+type Leak struct {
+	Kind   string        // goroutine 或者 listener
+	Source string        // goroutine 的函数位置或者订阅者绑定的属性
+	Age    time.Duration // 创建之后经过的时间
+	Stack  string        // 创建堆栈，开启 spring.app.leak-detection.stack 时才会记录
+}
+
+func (l Leak) String() string {
+	return fmt.Sprintf("%s %s created %v ago", l.Kind, l.Source, l.Age.Truncate(time.Millisecond))
+}
+
+// Leaks 返回仍在运行的通过 Go 方法创建的 goroutine 以及还没有关闭的动态属性订阅者，
+// 在容器关闭之后调用可以找出没有正确退出的 goroutine 和没有关闭的订阅者。
+func (c *container) Leaks() []Leak {
+
+	var ret []Leak
+	now := time.Now()
+
+	c.jobsMutex.Lock()
+	for j := range c.jobs {
+		ret = append(ret, Leak{
+			Kind:   "goroutine",
+			Source: fmt.Sprintf("%s:%d %s", j.file, j.line, j.fn),
+			Age:    now.Sub(j.created),
+			Stack:  j.stack,
+		})
+	}
+	c.jobsMutex.Unlock()
+
+	for _, s := range c.p.Subscriptions() {
+		ret = append(ret, Leak{
+			Kind:   "listener",
+			Source: s.Key,
+			Age:    now.Sub(s.Created),
+			Stack:  s.Stack,
+		})
+	}
+	return ret
+}
+
+// traceStacks 返回是否记录创建堆栈。
+func (c *container) traceStacks() bool {
+	ok, _ := strconv.ParseBool(c.p.Get(SpringLeakDetectionStack))
+	return ok
+}
+
+// detectLeaks 在容器开始关闭之后经过 spring.app.leak-detection.delay 报告仍在运行
+// 的 goroutine ，返回的函数用于取消报告。
+func (c *container) detectLeaks() (stop func()) {
+	d, err := time.ParseDuration(c.p.Get(SpringLeakDetectionDelay, conf.Def("0s")))
+	if err != nil || d <= 0 {
+		return func() {}
+	}
+	t := time.AfterFunc(d, func() {
+		for _, l := range c.Leaks() {
+			if l.Kind == "goroutine" {
+				c.logger.Warnf("%s still running %v after close", l, d)
+				if l.Stack != "" {
+					c.logger.Warn(l.Stack)
+				}
+			}
+		}
+	})
+	return func() { t.Stop() }
+}
+
+// reportListeners 报告容器关闭之后还没有关闭的动态属性订阅者。
+func (c *container) reportListeners() {
+	for _, l := range c.Leaks() {
+		if l.Kind == "listener" {
+			c.logger.Warnf("%s isn't closed after close", l)
+			if l.Stack != "" {
+				c.logger.Warn(l.Stack)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// SpringMetadataExport 是否在容器刷新之后导出 bean 的元数据，IDE 插件可以根据
+// 这些元数据在注入点和 bean 的注册点之间跳转。
+const SpringMetadataExport = "spring.app.metadata-export"
+
+// SpringMetadataExportPath 导出 bean 元数据的文件路径。
+const SpringMetadataExportPath = "spring.app.metadata-export-path"
+
+// DefaultMetadataExportPath 导出 bean 元数据的默认文件路径。
+const DefaultMetadataExportPath = ".go-spring/metadata.json"
+
+// BeanMetadata bean 的元数据。
+type BeanMetadata struct {
+	Name       string              `json:"name"`
+	Type       string              `json:"type"`
+	Source     string              `json:"source"`
+	Exports    []string            `json:"exports,omitempty"`
+	Injections []InjectionMetadata `json:"injections,omitempty"`
+	Properties []string            `json:"properties,omitempty"`
+}
+
+// InjectionMetadata 注入点的元数据，注入点是结构体字段或者构造函数参数。
+type InjectionMetadata struct {
+	Field string `json:"field,omitempty"`
+	Arg   *int   `json:"arg,omitempty"`
+	Type  string `json:"type"`
+	Tag   string `json:"tag"`
+}
+
+// exportMetadata 如果开启了 spring.app.metadata-export 属性，则将所有有效 bean
+// 的元数据以 JSON 格式写入 spring.app.metadata-export-path 指定的文件。
+func (c *container) exportMetadata() error {
+
+	if ok, _ := strconv.ParseBool(c.p.Get(SpringMetadataExport)); !ok {
+		return nil
+	}
+
+	var beans []BeanMetadata
+	for _, b := range c.beans {
+		if b.status == Deleted {
+			continue
+		}
+		beans = append(beans, getBeanMetadata(b))
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{"beans": beans}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file := c.p.Get(SpringMetadataExportPath, conf.Def(DefaultMetadataExportPath))
+	if err = os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	c.logger.Infof("export metadata of %d beans to %s", len(beans), file)
+	return nil
+}
+
+// getBeanMetadata 返回 bean 的元数据，包括构造函数参数和结构体字段上的注入点以及
+// 引用的属性。
+func getBeanMetadata(b *BeanDefinition) BeanMetadata {
+
+	m := BeanMetadata{
+		Name:   b.BeanName(),
+		Type:   b.TypeName(),
+		Source: b.FileLine(),
+	}
+
+	for _, t := range b.exports {
+		m.Exports = append(m.Exports, t.String())
+	}
+
+	if b.f != nil {
+		for i := 0; ; i++ {
+			p, ok := b.f.Provenance(i)
+			if !ok {
+				break
+			}
+			switch p.Kind {
+			case "bean":
+				idx := p.Index
+				m.Injections = append(m.Injections, InjectionMetadata{
+					Arg:  &idx,
+					Type: p.Type.String(),
+					Tag:  p.Tag,
+				})
+			case "property":
+				if tag, err := conf.ParseTag(p.Tag); err == nil && tag.Key != "" {
+					m.Properties = append(m.Properties, tag.Key)
+				}
+			}
+		}
+	}
+
+	if t := b.Type(); t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		getFieldMetadata(&m, t.Elem(), "")
+	}
+
+	for i, key := range m.Properties {
+		if strings.HasPrefix(key, "/") {
+			m.Properties[i] = key[1:]
+		} else if b.ns != "" {
+			m.Properties[i] = b.ns + "." + key
+		}
+	}
+	return m
+}
+
+// getFieldMetadata 收集结构体字段上的注入点和引用的属性，嵌入的结构体会被展开。
+func getFieldMetadata(m *BeanMetadata, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		path := prefix + ft.Name
+
+		if tag, ok := ft.Tag.Lookup("value"); ok {
+			if parsed, err := conf.ParseTag(tag); err == nil && parsed.Key != "" {
+				m.Properties = append(m.Properties, parsed.Key)
+			}
+			continue
+		}
+
+		tag, ok := ft.Tag.Lookup("autowire")
+		if !ok {
+			tag, ok = ft.Tag.Lookup("inject")
+		}
+		if ok {
+			m.Injections = append(m.Injections, InjectionMetadata{
+				Field: path,
+				Type:  ft.Type.String(),
+				Tag:   tag,
+			})
+			continue
+		}
+
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			getFieldMetadata(m, ft.Type, path+".")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"github.com/go-spring/spring-base/util"
+)
+
+// MockSubstitution 记录一次 mock bean 替换真实 bean 的结果。
+type MockSubstitution struct {
+	Selector string   // 注入时使用的选择器
+	Mock     string   // 生效的 mock bean
+	Replaced []string // 被替换的真实 bean
+}
+
+// preferMocks 如果 beans 中同时存在 mock bean 和真实的 bean ，那么只保留 mock
+// bean 并记录替换的结果。
+func (c *container) preferMocks(selector util.BeanSelector, beans []*BeanDefinition) []*BeanDefinition {
+
+	var mocks, replaced []*BeanDefinition
+	for _, b := range beans {
+		if b.mock {
+			mocks = append(mocks, b)
+		} else {
+			replaced = append(replaced, b)
+		}
+	}
+	if len(mocks) == 0 || len(replaced) == 0 {
+		return beans
+	}
+
+	s := toWireTag(selector).String()
+	c.mocksMutex.Lock()
+	defer c.mocksMutex.Unlock()
+	for _, m := range mocks {
+		if c.hasMock(s, m.ID()) {
+			continue
+		}
+		r := MockSubstitution{Selector: s, Mock: m.ID()}
+		for _, b := range replaced {
+			r.Replaced = append(r.Replaced, b.ID())
+		}
+		c.mocks = append(c.mocks, r)
+		c.logger.Infof("mock bean %s replaces %v for %s", r.Mock, r.Replaced, s)
+	}
+	return mocks
+}
+
+func (c *container) hasMock(selector string, mock string) bool {
+	for _, r := range c.mocks {
+		if r.Selector == selector && r.Mock == mock {
+			return true
+		}
+	}
+	return false
+}
+
+// Mocks 返回所有生效的 mock bean 替换记录，按照第一次替换的顺序排列。
+func (c *container) Mocks() []MockSubstitution {
+	c.mocksMutex.Lock()
+	defer c.mocksMutex.Unlock()
+	return append([]MockSubstitution(nil), c.mocks...)
+}
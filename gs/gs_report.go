@@ -0,0 +1,127 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-spring/spring-core/gs/cond"
+)
+
+// SpringStartupReportTop 容器刷新完成时以表格形式打印耗时最长的 N 个 bean ，
+// 默认为 0 表示不打印。
+const SpringStartupReportTop = "spring.app.startup-report.top"
+
+// beanTiming 记录 bean 在容器刷新时的耗时。
+type beanTiming struct {
+	cond   time.Duration // 条件判断的耗时
+	total  time.Duration // 包含依赖项在内的注入耗时
+	nested time.Duration // 依赖项的注入耗时
+	init   time.Duration // 初始化函数的耗时
+}
+
+// StartupTimings 容器刷新的耗时报告，包括每个 bean 的注入耗时和条件判断的结果。
+type StartupTimings struct {
+	Total time.Duration `json:"total"`
+	Beans []BeanTiming  `json:"beans"` // 按照自身的注入耗时降序排列
+}
+
+// BeanTiming 单个 bean 的耗时和条件判断的结果。Self 是 Total 减去依赖项的注入
+// 耗时，包括构造函数、属性绑定以及初始化函数的耗时，通常用于定位启动缓慢的 bean 。
+type BeanTiming struct {
+	ID        string        `json:"id"`
+	Source    string        `json:"source"`
+	Condition string        `json:"condition,omitempty"`
+	Matched   bool          `json:"matched"`
+	CondCost  time.Duration `json:"condCost,omitempty"`
+	Total     time.Duration `json:"total"`
+	Self      time.Duration `json:"self"`
+	Init      time.Duration `json:"init,omitempty"`
+}
+
+// StartupReport 返回容器刷新的耗时报告，容器刷新成功之前返回 nil 。
+func (c *container) StartupReport() *StartupTimings {
+	return c.report
+}
+
+// StartupReport 返回应用启动时容器刷新的耗时报告。
+func (app *App) StartupReport() *StartupTimings {
+	return app.c.StartupReport()
+}
+
+// saveStartupReport 保存容器刷新的耗时报告，如果设置了 spring.app.startup-report.top
+// 属性则打印耗时最长的那些 bean 。
+func (c *container) saveStartupReport(total time.Duration) error {
+
+	r := &StartupTimings{Total: total}
+	for _, b := range c.beans {
+		t := BeanTiming{
+			ID:       b.ID(),
+			Source:   b.FileLine(),
+			Matched:  b.status != Deleted,
+			CondCost: b.timing.cond,
+			Total:    b.timing.total,
+			Self:     b.timing.total - b.timing.nested,
+			Init:     b.timing.init,
+		}
+		if b.cond != nil {
+			t.Condition = cond.Describe(b.cond)
+		}
+		r.Beans = append(r.Beans, t)
+	}
+	sort.SliceStable(r.Beans, func(i, j int) bool {
+		return r.Beans[i].Self > r.Beans[j].Self
+	})
+	c.report = r
+
+	s := c.p.Get(SpringStartupReportTop)
+	if s == "" {
+		return nil
+	}
+	top, err := strconv.Atoi(s)
+	if err != nil || top <= 0 {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = r.WriteTable(&buf, top); err != nil {
+		return err
+	}
+	c.logger.Infof("startup report:\n%s", buf.String())
+	return nil
+}
+
+// WriteTable 以表格形式输出自身耗时最长的 top 个 bean ，top 小于等于 0 时输出
+// 全部的 bean 。
+func (r *StartupTimings) WriteTable(w io.Writer, top int) error {
+	beans := r.Beans
+	if top > 0 && top < len(beans) {
+		beans = beans[:top]
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SELF\tTOTAL\tINIT\tCOND\tMATCHED\tBEAN")
+	for _, b := range beans {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%s\n", b.Self, b.Total, b.Init, b.CondCost, b.Matched, b.ID)
+	}
+	fmt.Fprintf(tw, "total %v, %d beans\n", r.Total, len(r.Beans))
+	return tw.Flush()
+}
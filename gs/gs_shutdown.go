@@ -0,0 +1,153 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// SpringShutdownTimeout 关闭容器的总超时时间，包括执行停止钩子、等待 goroutine 退出
+// 以及执行销毁函数，超时之后未完成的步骤会被跳过并打印日志，默认为 0 表示一直等待。
+const SpringShutdownTimeout = "spring.app.shutdown-timeout"
+
+// beanDestroyer bean 的销毁函数。
+type beanDestroyer struct {
+	bean *BeanDefinition
+	fn   func()
+}
+
+// stopHook 通过 OnStop 注册的停止钩子。
+type stopHook struct {
+	fn    func(ctx context.Context) error
+	order int
+	file  string
+	line  int
+}
+
+// StopOption 设置停止钩子的选项。
+type StopOption func(h *stopHook)
+
+// StopOrder 设置停止钩子的执行顺序，order 小的先执行，order 相同时按照注册顺序的
+// 逆序执行，默认为 0 。
+func StopOrder(order int) StopOption {
+	return func(h *stopHook) {
+		h.order = order
+	}
+}
+
+// OnStop 注册停止钩子，容器关闭时在通知 goroutine 退出之前执行，适合从注册中心下线、
+// 停止接收新请求等操作。ctx 在 spring.app.shutdown-timeout 设置的时间后结束，钩子
+// 超时未返回时会被跳过。
+func (c *container) OnStop(fn func(ctx context.Context) error, opts ...StopOption) {
+	h := &stopHook{fn: fn}
+	h.file, h.line, _ = util.FileLine(fn)
+	for _, opt := range opts {
+		opt(h)
+	}
+	c.stopHooks = append(c.stopHooks, h)
+}
+
+// shutdownContext 返回在 spring.app.shutdown-timeout 设置的时间后结束的 ctx 。
+func (c *container) shutdownContext() (context.Context, context.CancelFunc) {
+	s := c.p.Get(SpringShutdownTimeout)
+	if s == "" {
+		return context.WithCancel(context.Background())
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		c.logger.Warnf("invalid %s %q, wait forever", SpringShutdownTimeout, s)
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), d)
+}
+
+// runWithContext 在 goroutine 中执行 fn ，ctx 结束时不再等待并返回 false 。
+func (c *container) runWithContext(ctx context.Context, fn func() error) (bool, error) {
+	ch := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		ch <- fn()
+	}()
+	select {
+	case err := <-ch:
+		return true, err
+	case <-ctx.Done():
+		return false, nil
+	}
+}
+
+// runStopHooks 按照顺序执行停止钩子。
+func (c *container) runStopHooks(ctx context.Context) {
+
+	hooks := make([]*stopHook, len(c.stopHooks))
+	for i, h := range c.stopHooks {
+		hooks[len(hooks)-1-i] = h
+	}
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].order < hooks[j].order
+	})
+
+	for _, h := range hooks {
+		if ctx.Err() != nil {
+			c.logger.Errorf("stop hook %s:%d is skipped because of shutdown timeout", h.file, h.line)
+			continue
+		}
+		ok, err := c.runWithContext(ctx, func() error { return h.fn(ctx) })
+		if !ok {
+			c.logger.Errorf("stop hook %s:%d didn't return before shutdown timeout", h.file, h.line)
+		} else if err != nil {
+			c.logger.Errorf("stop hook %s:%d error: %v", h.file, h.line, err)
+		}
+	}
+}
+
+// runDestroyers 执行 bean 的销毁函数，超时未返回的销毁函数以及之后的销毁函数都会
+// 被跳过。
+func (c *container) runDestroyers(ctx context.Context) {
+	c.runtimeMutex.Lock()
+	destroyers := c.destroyers
+	c.runtimeMutex.Unlock()
+	_, hasDeadline := ctx.Deadline()
+	for _, d := range destroyers {
+		if !hasDeadline {
+			d.fn()
+			continue
+		}
+		if ctx.Err() != nil {
+			c.logger.Errorf("destroy %s is skipped because of shutdown timeout", d.bean)
+			continue
+		}
+		ok, err := c.runWithContext(ctx, func() error {
+			d.fn()
+			return nil
+		})
+		if !ok {
+			c.logger.Errorf("destroy %s didn't return before shutdown timeout", d.bean)
+		} else if err != nil {
+			c.logger.Error(err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+)
+
+// 容器的生命周期如下，每个方法只能在特定的状态下调用，否则返回或者抛出
+// *IllegalStateError 类型的错误:
+//
+//	              Object/Provide/Property/OnProperty/GroupOnCondition
+//	                 |
+//	Unrefreshed --Refresh--> RefreshInit --> Refreshing --> Refreshed --Close--> Closing --> Closed
+//	                                                         |
+//	                                 Get/Wire/RegisterRuntimeBean/DumpGraph/ExportState
+//
+// 其中 RefreshInit 阶段仍然可以注册 bean (用于条件成立的 bean 组)，Refresh 只能
+// 调用一次，Close 只能在 Refreshed 状态下调用一次。容器刷新之后是只读的，除了刷新
+// 属性以及开启运行时 bean 之后的 RegisterRuntimeBean ，修改容器的方法都会被拒绝，
+// 测试代码可以通过 TestingUnlock 解除这个限制。
+
+const (
+	beforeRefresh = "should call before Refresh"
+	afterRefresh  = "should call after Refresh"
+	refreshOnce   = "should call only once"
+)
+
+func (s refreshState) String() string {
+	switch s {
+	case Unrefreshed:
+		return "Unrefreshed"
+	case RefreshInit:
+		return "RefreshInit"
+	case Refreshing:
+		return "Refreshing"
+	case Refreshed:
+		return "Refreshed"
+	case Closing:
+		return "Closing"
+	case Closed:
+		return "Closed"
+	default:
+		return fmt.Sprintf("refreshState(%d)", int(s))
+	}
+}
+
+// IllegalStateError 在容器生命周期的错误阶段调用方法时产生的错误。
+type IllegalStateError struct {
+	Op     string       // 调用的方法
+	State  refreshState // 调用时容器的状态
+	Expect string       // 方法的调用要求
+}
+
+func (e *IllegalStateError) Error() string {
+	msg := fmt.Sprintf("%s %s, but container is %s", e.Op, e.Expect, e.State)
+	if e.State == Refreshed && e.Expect == beforeRefresh {
+		msg += " and read-only"
+	}
+	return msg
+}
+
+// TestingUnlock 解除容器刷新之后的只读限制，只能在测试代码中使用。解除之后 Property
+// 直接修改生效的属性，Object 、Provide 等方法在运行时注册 bean 。需要在 Refresh
+// 之前调用，否则容器不会保留运行时注册 bean 需要的索引。
+func (c *container) TestingUnlock() {
+	c.unlocked = true
+}
+
+// isUnlocked 容器刷新之后是否解除了只读限制。
+func (c *container) isUnlocked() bool {
+	return c.unlocked && c.state == Refreshed
+}
+
+// checkBeforeRefresh 检查是否可以调用注册 bean 以及设置属性等方法。
+func (c *container) checkBeforeRefresh(op string) {
+	if c.state >= Refreshing {
+		panic(&IllegalStateError{Op: op, State: c.state, Expect: beforeRefresh})
+	}
+}
+
+// currentState 返回容器当前的状态，可以在其他 goroutine 中调用。
+func (c *container) currentState() refreshState {
+	c.runtimeMutex.Lock()
+	defer c.runtimeMutex.Unlock()
+	return c.state
+}
+
+// checkAfterRefresh 检查是否可以调用只能在容器刷新之后调用的方法。
+func (c *container) checkAfterRefresh(op string) error {
+	if c.state != Refreshed {
+		return &IllegalStateError{Op: op, State: c.state, Expect: afterRefresh}
+	}
+	return nil
+}
@@ -17,13 +17,20 @@
 package gs_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +40,7 @@ import (
 	"github.com/go-spring/spring-base/log"
 	"github.com/go-spring/spring-base/util"
 	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/dync"
 	"github.com/go-spring/spring-core/gs"
 	"github.com/go-spring/spring-core/gs/arg"
 	"github.com/go-spring/spring-core/gs/cond"
@@ -231,6 +239,31 @@ func TestApplicationContext_ValueTag(t *testing.T) {
 	fmt.Printf("%+v\n", setting)
 }
 
+func TestApplicationContext_ValueValidate(t *testing.T) {
+
+	type Server struct {
+		Port int `value:"${zz.port}" validate:"min=1,max=65535"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		c := gs.New()
+		c.Property("zz.port", 8080)
+		s := &Server{}
+		c.Object(s)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, s.Port, 8080)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		c := gs.New()
+		c.Property("zz.port", 70000)
+		c.Object(&Server{})
+		err := c.Refresh()
+		assert.Error(t, err, "\"Server.Port\" bind error: validate failed on \"max=65535\" for value 70000")
+	})
+}
+
 type GreetingService struct {
 }
 
@@ -1308,6 +1341,39 @@ func TestOptionConstructorArg(t *testing.T) {
 		})
 		assert.Nil(t, err)
 	})
+
+	t.Run("option fallback chain", func(t *testing.T) {
+		newOption := func() arg.Arg {
+			return arg.Option(withClassName, arg.Value("一年级01班"), arg.Value(1)).On(cond.OnProperty("grade.one")).
+				Or(arg.Option(withClassName, arg.Value("二年级01班"), arg.Value(2)).On(cond.OnProperty("grade.two"))).
+				Or(arg.Option(withClassName, arg.Value("三年级01班"), arg.Value(3)).On(cond.OnProperty("grade.three")))
+		}
+		for _, tc := range []struct {
+			props     map[string]string
+			className string
+			floor     int
+		}{
+			{map[string]string{"grade.two": "true", "grade.three": "true"}, "二年级01班", 2},
+			{map[string]string{"grade.one": "true", "grade.two": "true"}, "一年级01班", 1},
+			{map[string]string{"grade.three": "true"}, "三年级01班", 3},
+			{map[string]string{}, "default", 0},
+		} {
+			c := gs.New()
+			c.Property("president", "CaiYuanPei")
+			for k, v := range tc.props {
+				c.Property(k, v)
+			}
+			c.Provide(NewClassRoom, newOption())
+			err := runTest(c, func(p gs.Context) {
+				var cls *ClassRoom
+				err := p.Get(&cls)
+				assert.Nil(t, err)
+				assert.Equal(t, cls.className, tc.className)
+				assert.Equal(t, cls.floor, tc.floor)
+			})
+			assert.Nil(t, err)
+		}
+	})
 }
 
 type ServerInterface interface {
@@ -1648,6 +1714,17 @@ func TestApplicationContext_CircleAutowire(t *testing.T) {
 		err := c.Refresh()
 		assert.Error(t, err, "found circle autowire")
 	})
+
+	t.Run("report all cycles", func(t *testing.T) {
+		c := gs.New()
+		c.Provide(func() *CircleA { return new(CircleA) })
+		c.Provide(func() *CircleB { return new(CircleB) })
+		c.Provide(func() *CircleC { return new(CircleC) })
+		c.Provide(func(s *Service) *Server { return new(Server) })
+		c.Provide(func(s *Server) *Service { return new(Service) })
+		err := c.Refresh()
+		assert.Error(t, err, "found circle autowire:\n    .*CircleA.*gs_test.go:\\d+\n    -> .*CircleB.*\n    -> .*CircleC.*\n    -> .*CircleA.*\n\n    .*Server.*\n    -> .*Service.*\n    -> .*Server")
+	})
 }
 
 type VarInterfaceOptionFunc func(opt *VarInterfaceOption)
@@ -2049,7 +2126,21 @@ func TestApplicationContext_BeanCache(t *testing.T) {
 		c := gs.New()
 		c.Object(func() {}).Export((*filter)(nil))
 		err := c.Refresh()
-		assert.Error(t, err, "doesn't implement interface gs_test.filter")
+		assert.Error(t, err, "doesn't implement interface gs_test.filter exported at .*/gs_test.go:")
+	})
+
+	t.Run("report all", func(t *testing.T) {
+		c := gs.New()
+		c.Object(func() {}).Name("f1").Export((*filter)(nil))
+		c.Object(new(Server)).Export((*filter)(nil), (*fmt.Stringer)(nil))
+		err := c.Refresh()
+		errs, ok := err.(gs.MultiError)
+		assert.True(t, ok)
+		assert.Equal(t, len(errs), 3)
+		e, ok := errs[0].(*gs.ExportError)
+		assert.True(t, ok)
+		assert.Equal(t, e.Bean.BeanName(), "f1")
+		assert.Equal(t, e.Interface, reflect.TypeOf((*filter)(nil)).Elem())
 	})
 
 	t.Run("implement interface", func(t *testing.T) {
@@ -2377,6 +2468,25 @@ func TestDefaultSpringContext_ConditionOnMissingBean(t *testing.T) {
 	}
 }
 
+func TestDefaultSpringContext_ConditionOnType(t *testing.T) {
+	c := gs.New()
+	c.Object(&BeanZero{5})
+	c.Object(new(strings.Builder)) // 没有导出 fmt.Stringer 接口
+	c.Object(new(BeanOne)).On(cond.OnType((*fmt.Stringer)(nil)))
+	c.Object(new(BeanTwo)).On(cond.OnMissingType((*fmt.Stringer)(nil)))
+	err := runTest(c, func(p gs.Context) {
+
+		var one *BeanOne
+		err := p.Get(&one)
+		assert.Nil(t, err)
+
+		var two *BeanTwo
+		err = p.Get(&two)
+		assert.Error(t, err, "can't find bean, bean:\"\"")
+	})
+	assert.Nil(t, err)
+}
+
 //func TestFunctionCondition(t *testing.T) {
 //	c := gs.New()
 //
@@ -2632,6 +2742,65 @@ func TestMapCollection(t *testing.T) {
 	})
 }
 
+func TestArrayCollection(t *testing.T) {
+
+	type arrayValue struct {
+		v string
+	}
+
+	register := func(c gs.Container) {
+		c.Object(&arrayValue{"c"}).Name("c").Order(1)
+		c.Object(&arrayValue{"b"}).Name("b").Order(1)
+		c.Object(&arrayValue{"a"}).Name("a").Order(2)
+	}
+
+	values := func(arr []*arrayValue) []string {
+		var ret []string
+		for _, v := range arr {
+			if v == nil {
+				ret = append(ret, "<nil>")
+			} else {
+				ret = append(ret, v.v)
+			}
+		}
+		return ret
+	}
+
+	t.Run("fill by order", func(t *testing.T) {
+		c := gs.New()
+		register(c)
+		holder := &struct {
+			Values [4]*arrayValue `autowire:""`
+		}{}
+		c.Object(holder)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, values(holder.Values[:]), []string{"b", "c", "a", "<nil>"})
+	})
+
+	t.Run("exceed capacity", func(t *testing.T) {
+		c := gs.New()
+		register(c)
+		c.Object(&struct {
+			Values [2]*arrayValue `autowire:""`
+		}{})
+		err := c.Refresh()
+		assert.Error(t, err, "found 3 beans but \\[2\\]\\*gs_test.arrayValue can only hold 2")
+	})
+
+	t.Run("nullable any", func(t *testing.T) {
+		c := gs.New()
+		register(c)
+		holder := &struct {
+			Values [2]*arrayValue `autowire:"*?"`
+		}{}
+		c.Object(holder)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, values(holder.Values[:]), []string{"b", "c"})
+	})
+}
+
 type circularA struct {
 	b *circularB
 }
@@ -2716,3 +2885,1129 @@ func TestContextAware(t *testing.T) {
 	a := b.Interface().(*ContextAware)
 	assert.Equal(t, a.Echo("gopher"), "hello gopher!")
 }
+
+func TestRegisterProvider(t *testing.T) {
+	c := gs.New()
+	c.Property("server.version", "1.0.0")
+	c.RegisterProvider(gs.BeanProviderFunc(func() []*gs.BeanDefinition {
+		return []*gs.BeanDefinition{
+			gs.NewBean(new(Server)),
+			gs.NewBean((*Server).Consumer),
+		}
+	}))
+	err := runTest(c, func(p gs.Context) {
+		var consumer *Consumer
+		err := p.Get(&consumer)
+		assert.Nil(t, err)
+		var s *Server
+		err = p.Get(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Version, "1.0.0")
+	})
+	assert.Nil(t, err)
+}
+
+func TestCollectAllErrors(t *testing.T) {
+
+	type MissingA struct {
+		S *Server `autowire:""`
+	}
+
+	type MissingB struct {
+		C *Consumer `autowire:""`
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		c := gs.New()
+		c.Object(new(MissingA))
+		c.Object(new(MissingB))
+		err := c.Refresh()
+		_, ok := err.(gs.MultiError)
+		assert.False(t, ok)
+	})
+
+	t.Run("collect all", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringCollectAllErrors, true)
+		c.Object(new(MissingA))
+		c.Object(new(MissingB))
+		err := c.Refresh()
+		errs, ok := err.(gs.MultiError)
+		assert.True(t, ok)
+		assert.Equal(t, len(errs), 2)
+		assert.Error(t, err, "found 2 errors:")
+	})
+}
+
+func TestLazyHandle(t *testing.T) {
+
+	type LazyHolder struct {
+		Server gs.Lazy `autowire:""`
+		Named  gs.Lazy `autowire:"consumer"`
+	}
+
+	c := gs.New()
+	c.Property("server.version", "1.0.0")
+	c.Object(new(Server))
+	c.Provide((*Server).Consumer).Name("consumer")
+	h := new(LazyHolder)
+	c.Object(h)
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	var s *Server
+	err = h.Server.Get(&s)
+	assert.Nil(t, err)
+	assert.Equal(t, s.Version, "1.0.0")
+
+	var consumer *Consumer
+	err = h.Named.Get(&consumer)
+	assert.Nil(t, err)
+	assert.NotNil(t, consumer)
+
+	// 之后的调用返回缓存的 bean ，接收者可以是 bean 实现的接口。
+	var si ServerInterface
+	err = h.Server.Get(&si)
+	assert.Nil(t, err)
+	assert.Equal(t, si, s)
+
+	var consumer2 *Consumer
+	err = h.Named.Get(&consumer2)
+	assert.Nil(t, err)
+	assert.Equal(t, consumer2, consumer)
+
+	err = h.Named.Get(&s)
+	assert.Error(t, err, "lazy bean \\*gs_test.Consumer can't be assigned to \\*gs_test.Server")
+
+	err = gs.Lazy{}.Get(&s)
+	assert.Error(t, err, "lazy handle isn't injected")
+}
+
+func TestLazyHandle_Once(t *testing.T) {
+
+	type LazyHolder struct {
+		Server gs.Lazy `autowire:""`
+	}
+
+	c := gs.New()
+	c.Property("server.version", "1.0.0")
+	c.Object(new(Server))
+	h := new(LazyHolder)
+	c.Object(h)
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	// 第一次查找失败之后，错误也会被缓存。
+	var consumer *Consumer
+	err = h.Server.Get(&consumer)
+	assert.Error(t, err, "can't find bean")
+	var s *Server
+	err = h.Server.Get(&s)
+	assert.Error(t, err, "can't find bean")
+
+	h2 := new(LazyHolder)
+	c2 := gs.New()
+	c2.Property("server.version", "1.0.0")
+	c2.Object(new(Server))
+	c2.Object(h2)
+	err = c2.Refresh()
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	servers := make([]*Server, 8)
+	for i := range servers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.Nil(t, h2.Server.Get(&servers[i]))
+		}(i)
+	}
+	wg.Wait()
+	for _, v := range servers {
+		assert.NotNil(t, v)
+		assert.Equal(t, v, servers[0])
+	}
+}
+
+type LazyArgService struct {
+	Consumer gs.Lazy
+	Server   gs.Lazy
+}
+
+func NewLazyArgService(consumer gs.Lazy, server gs.Lazy) *LazyArgService {
+	return &LazyArgService{Consumer: consumer, Server: server}
+}
+
+func TestLazyArg(t *testing.T) {
+	c := gs.New()
+	c.Property("server.version", "1.0.0")
+	c.Object(new(Server))
+	c.Provide((*Server).Consumer).Name("consumer")
+	c.Provide(NewLazyArgService, "consumer")
+	h := new(struct {
+		Service *LazyArgService `autowire:""`
+	})
+	c.Object(h)
+	err := c.Refresh()
+	assert.Nil(t, err)
+
+	s := h.Service
+
+	var consumer *Consumer
+	err = s.Consumer.Get(&consumer)
+	assert.Nil(t, err)
+	assert.NotNil(t, consumer)
+
+	var server *Server
+	err = s.Server.Get(&server)
+	assert.Nil(t, err)
+	assert.Equal(t, server.Version, "1.0.0")
+}
+
+func TestShutdownGrace(t *testing.T) {
+	c := gs.New()
+	c.Property("spring.app.jobs.shutdown-grace", "50ms")
+	err := runTest(c, func(ctx gs.Context) {
+		ctx.Go(func(_ context.Context) {
+			time.Sleep(time.Second)
+		})
+		ctx.Go(func(ctx context.Context) {
+			<-ctx.Done()
+		}, gs.GraceTimeout(time.Second))
+	})
+	assert.Nil(t, err)
+	start := time.Now()
+	err = c.Close()
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	var e *gs.ShutdownTimeoutError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, len(e.Jobs), 1)
+	assert.Matches(t, e.Jobs[0], "gs_test.go:[0-9]+ .*TestShutdownGrace.func1.1")
+}
+
+func TestOnClosed(t *testing.T) {
+	var result []string
+	c := gs.New()
+	c.Object(new(callDestroy)).Destroy(func(_ *callDestroy) {
+		result = append(result, "destroy")
+	})
+	c.OnClosed(func() {
+		result = append(result, "first")
+		panic("oops")
+	})
+	c.OnClosed(func() {
+		result = append(result, "second")
+	})
+	err := c.Refresh()
+	assert.Nil(t, err)
+	c.Close()
+	assert.Equal(t, result, []string{"destroy", "first", "second"})
+}
+
+func TestOnStop(t *testing.T) {
+
+	t.Run("order", func(t *testing.T) {
+		var result []string
+		c := gs.New()
+		c.Object(new(callDestroy)).Destroy(func(_ *callDestroy) {
+			result = append(result, "destroy")
+		})
+		c.(gs.Context).Go(func(ctx context.Context) {
+			<-ctx.Done()
+			result = append(result, "job")
+		})
+		c.OnStop(func(ctx context.Context) error {
+			result = append(result, "first")
+			return nil
+		})
+		c.OnStop(func(ctx context.Context) error {
+			result = append(result, "second")
+			return errors.New("error")
+		})
+		c.OnStop(func(ctx context.Context) error {
+			result = append(result, "last")
+			return nil
+		}, gs.StopOrder(1))
+		err := c.Refresh()
+		assert.Nil(t, err)
+		c.Close()
+		assert.Equal(t, result, []string{"second", "first", "last", "job", "destroy"})
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		var result []string
+		c := gs.New()
+		c.Property(gs.SpringShutdownTimeout, "100ms")
+		c.Object(new(callDestroy)).Name("a").Destroy(func(_ *callDestroy) {
+			result = append(result, "a")
+		})
+		c.Object(new(callDestroy)).Name("b").Destroy(func(_ *callDestroy) {
+			time.Sleep(time.Second)
+		})
+		c.OnStop(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		start := time.Now()
+		c.Close()
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+		assert.Equal(t, len(result), 0)
+	})
+}
+
+func TestContextArg(t *testing.T) {
+
+	t.Run("container ctx", func(t *testing.T) {
+		c := gs.New()
+		var ctx context.Context
+		c.Provide(func(x context.Context) *Server {
+			ctx = x
+			return new(Server)
+		})
+		c.Property("server.version", "1.0.0")
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, ctx, c.Context())
+	})
+
+	t.Run("startup timeout", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringStartupTimeout, "1s")
+		c.Property("server.version", "1.0.0")
+		var ctx context.Context
+		c.Provide(func(x context.Context) *Server {
+			ctx = x
+			_, ok := x.Deadline()
+			assert.True(t, ok)
+			return new(Server)
+		})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.NotNil(t, ctx.Err())
+	})
+}
+
+func TestStage(t *testing.T) {
+
+	t.Run("order", func(t *testing.T) {
+		var result []string
+		c := gs.New()
+		c.Object(&callDestroy{i: 1}).Stage(2).Init(func(_ *callDestroy) {
+			result = append(result, "stage 2")
+		})
+		c.Object(&DiffPkgOne{}).Stage(1).Init(func(_ *DiffPkgOne) {
+			result = append(result, "stage 1")
+		})
+		c.Object(&DiffPkgTwo{}).Init(func(_ *DiffPkgTwo) {
+			result = append(result, "stage 0")
+		})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, result, []string{"stage 0", "stage 1", "stage 2"})
+	})
+
+	t.Run("depends on later stage", func(t *testing.T) {
+		c := gs.New()
+		c.Property("server.version", "1.0.0")
+		c.Object(new(Server)).Stage(1)
+		c.Object(new(Service))
+		c.Provide((*Server).Consumer)
+		err := c.Refresh()
+		assert.Error(t, err, "in stage 1 can't be wired in stage 0")
+	})
+}
+
+func TestInitTimeout(t *testing.T) {
+
+	t.Run("timeout", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&callDestroy{i: 1}).InitTimeout(10 * time.Millisecond).Init(func(_ *callDestroy) {
+			time.Sleep(100 * time.Millisecond)
+		})
+		err := c.Refresh()
+		assert.Error(t, err, "init timeout after 10ms")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		c := gs.New()
+		done := make(chan error, 1)
+		c.Object(&callDestroy{i: 1}).InitTimeout(10 * time.Millisecond).Init(func(_ *callDestroy, ctx context.Context) error {
+			<-ctx.Done()
+			done <- ctx.Err()
+			return ctx.Err()
+		})
+		err := c.Refresh()
+		assert.Error(t, err, "init timeout after 10ms")
+		assert.Equal(t, <-done, context.DeadlineExceeded)
+	})
+
+	t.Run("leak", func(t *testing.T) {
+		c := gs.New()
+		release := make(chan struct{})
+		done := make(chan struct{})
+		c.Object(&callDestroy{i: 1}).InitTimeout(10 * time.Millisecond).Init(func(_ *callDestroy) {
+			defer close(done)
+			<-release
+		})
+		err := c.Refresh()
+		assert.Error(t, err, "init timeout after 10ms")
+		// 不检查 ctx 的初始化函数不会被中断，超时之后仍然在后台执行。
+		select {
+		case <-done:
+			t.Fatal("init should still be running")
+		default:
+		}
+		close(release)
+		<-done
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&callDestroy{i: 1}).InitTimeout(time.Second).Init(func(_ *callDestroy) {
+			panic("boom")
+		})
+		err := c.Refresh()
+		assert.Error(t, err, "init panic: boom")
+	})
+
+	t.Run("in time", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringInitWarnThreshold, "1ms")
+		inited := false
+		c.Object(&callDestroy{i: 1}).InitTimeout(time.Second).Init(func(_ *callDestroy) {
+			time.Sleep(5 * time.Millisecond)
+			inited = true
+		})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.True(t, inited)
+	})
+}
+
+func TestRefreshRollback(t *testing.T) {
+	c := gs.New()
+	d1 := &callDestroy{}
+	c.Object(d1).Name("d1").Init((*callDestroy).Init).Destroy((*callDestroy).Destroy)
+	d2 := &callDestroy{i: 1}
+	c.Object(d2).Name("d2").Stage(1).Init((*callDestroy).InitWithError).Destroy((*callDestroy).Destroy)
+	err := c.Refresh()
+	assert.Error(t, err, "error")
+	assert.True(t, d1.inited)
+	assert.True(t, d1.destroyed)
+	assert.False(t, d2.destroyed)
+	assert.Error(t, c.Context().Err(), "context canceled")
+}
+
+func TestDuplicateBeans(t *testing.T) {
+
+	t.Run("report", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&BeanZero{5})
+		c.Object(&BeanZero{6})
+		err := c.Refresh()
+		assert.Error(t, err, "found duplicate beans registered:\n.*BeanZero:BeanZero\n    object bean registered at .*gs_test.go:\\d+\n    object bean registered at .*gs_test.go:\\d+")
+	})
+
+	t.Run("override", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringBeanOverrideAllowed, "BeanZero")
+		c.Object(&BeanZero{5})
+		c.Object(&BeanZero{6})
+		err := runTest(c, func(p gs.Context) {
+			var b *BeanZero
+			err := p.Get(&b)
+			assert.Nil(t, err)
+			assert.Equal(t, b.Int, 6)
+		})
+		assert.Nil(t, err)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&BeanZero{5}).Primary()
+		c.Object(&BeanZero{6}).Replace()
+		c.Object(&BeanZero{7})
+		c.Object(&BeanZero{8}).Name("other")
+		err := runTest(c, func(p gs.Context) {
+			var b *BeanZero
+			err := p.Get(&b)
+			assert.Nil(t, err)
+			assert.Equal(t, b.Int, 6)
+		})
+		assert.Nil(t, err)
+	})
+
+	t.Run("replace conditional bean", func(t *testing.T) {
+		c := gs.New()
+		c.Property("plugin.enabled", "true")
+		c.Object(&BeanZero{5}).On(cond.OnProperty("default.enabled", cond.MatchIfMissing()))
+		c.Object(&BeanZero{6}).On(cond.OnProperty("plugin.enabled")).Replace()
+		err := runTest(c, func(p gs.Context) {
+			var b *BeanZero
+			err := p.Get(&b)
+			assert.Nil(t, err)
+			assert.Equal(t, b.Int, 6)
+		})
+		assert.Nil(t, err)
+	})
+}
+
+func TestChildren(t *testing.T) {
+
+	newChild := func(parent gs.Container, name string, result *[]string) gs.Container {
+		c := gs.New()
+		c.OnClosed(func() { *result = append(*result, name) })
+		err := c.Refresh()
+		assert.Nil(t, err)
+		parent.AddChild(c)
+		return c
+	}
+
+	t.Run("children first", func(t *testing.T) {
+		var result []string
+		c := gs.New()
+		c.OnClosed(func() { result = append(result, "parent") })
+		err := c.Refresh()
+		assert.Nil(t, err)
+		newChild(c, "child1", &result)
+		child2 := newChild(c, "child2", &result)
+		newChild(child2, "grandchild", &result)
+		assert.Equal(t, len(c.Children()), 2)
+
+		assert.Panic(t, func() { c.AddChild(child2) }, "container already has a parent")
+
+		c.Close()
+		assert.Equal(t, result, []string{"grandchild", "child2", "child1", "parent"})
+		assert.Equal(t, len(c.Children()), 0)
+	})
+
+	t.Run("parent first", func(t *testing.T) {
+		var result []string
+		c := gs.New()
+		c.Property(gs.SpringCloseChildrenFirst, false)
+		c.OnClosed(func() { result = append(result, "parent") })
+		err := c.Refresh()
+		assert.Nil(t, err)
+		child := newChild(c, "child", &result)
+		newChild(c, "closed", &result).Close()
+		assert.Equal(t, c.Children(), []gs.Container{child})
+		c.Close()
+		assert.Equal(t, result, []string{"closed", "parent", "child"})
+	})
+}
+
+func TestRegisterRuntimeBean(t *testing.T) {
+
+	t.Run("disabled", func(t *testing.T) {
+		c := gs.New()
+		err := c.Refresh()
+		assert.Nil(t, err)
+		err = c.RegisterRuntimeBean(gs.NewBean(&BeanZero{5}))
+		assert.Error(t, err, "runtime beans are disabled")
+	})
+
+	t.Run("before refresh", func(t *testing.T) {
+		c := gs.New()
+		err := c.RegisterRuntimeBean(gs.NewBean(&BeanZero{5}))
+		assert.Error(t, err, "should call after Refresh")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringRuntimeBeansEnabled, true)
+		c.Object(&BeanZero{5})
+		var ctx gs.Context
+		err := runTest(c, func(p gs.Context) { ctx = p })
+		assert.Nil(t, err)
+
+		d := new(callDestroy)
+		b := gs.NewBean(d).Init((*callDestroy).Init).Destroy((*callDestroy).Destroy)
+		err = c.RegisterRuntimeBean(b)
+		assert.Nil(t, err)
+		assert.True(t, d.inited)
+
+		err = c.RegisterRuntimeBean(gs.NewBean(new(callDestroy)))
+		assert.Error(t, err, "found duplicate bean")
+
+		var x *callDestroy
+		err = ctx.Get(&x)
+		assert.Nil(t, err)
+		assert.Equal(t, x, d)
+
+		c.Close()
+		assert.True(t, d.destroyed)
+	})
+}
+
+type metadataBean struct {
+	Zero *BeanZero `autowire:""`
+	Port int       `value:"${metadata.port:=8080}"`
+}
+
+func TestMetadataExport(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "metadata")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "metadata.json")
+
+	c := gs.New()
+	c.Property(gs.SpringMetadataExport, true)
+	c.Property(gs.SpringMetadataExportPath, file)
+	c.Object(&BeanZero{5})
+	c.Object(&metadataBean{})
+	c.Provide(func(name string, zero *BeanZero) *callDestroy {
+		return &callDestroy{}
+	}, "${metadata.name:=abc}")
+	err = c.Refresh()
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+
+	var m struct {
+		Beans []gs.BeanMetadata `json:"beans"`
+	}
+	err = json.Unmarshal(data, &m)
+	assert.Nil(t, err)
+
+	beans := make(map[string]gs.BeanMetadata)
+	for _, b := range m.Beans {
+		beans[b.Name] = b
+	}
+
+	b, ok := beans["metadataBean"]
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(b.Source, "gs_test.go:"))
+	assert.Equal(t, b.Properties, []string{"metadata.port"})
+	assert.Equal(t, len(b.Injections), 1)
+	assert.Equal(t, b.Injections[0].Field, "Zero")
+	assert.Equal(t, b.Injections[0].Type, "*gs_test.BeanZero")
+
+	b, ok = beans["TestMetadataExport.func1"]
+	assert.True(t, ok)
+	assert.Equal(t, b.Properties, []string{"metadata.name"})
+	assert.Equal(t, len(b.Injections), 1)
+	assert.Equal(t, *b.Injections[0].Arg, 1)
+}
+
+func TestPropertiesView(t *testing.T) {
+	c := gs.New()
+	c.Property("a.b", "1")
+	var view gs.PropertiesView
+	err := runTest(c, func(p gs.Context) {
+		err := p.Get(&view)
+		assert.Nil(t, err)
+	})
+	assert.Nil(t, err)
+	assert.True(t, view.Has("a.b"))
+	assert.Equal(t, view.Get("a.b"), "1")
+
+	snapshot := view.Snapshot()
+	err = snapshot.Set("a.c", "2")
+	assert.Nil(t, err)
+	assert.False(t, view.Has("a.c"))
+
+	p := conf.New()
+	err = p.Set("a.b", "3")
+	assert.Nil(t, err)
+	err = c.Properties().Refresh(p)
+	assert.Nil(t, err)
+	assert.Equal(t, view.Get("a.b"), "3")
+	assert.Equal(t, snapshot.Get("a.b"), "1")
+}
+
+func TestUnusedProperties(t *testing.T) {
+	c := gs.New()
+	c.Property(gs.SpringTrackUnusedProperties, true)
+	c.Property("server.version", "1.0.0")
+	c.Property("server.typo", "1.0.0")
+	c.Object(&Server{})
+	err := c.Refresh()
+	assert.Nil(t, err)
+	assert.Equal(t, c.Properties().UnusedKeys(), []string{"server.typo"})
+}
+
+type namespaceBean struct {
+	URL  string `value:"${db.url}"`
+	Name string `value:"${/app.name}"`
+	DSN  string `value:"${db.dsn:=}"`
+	Port int
+}
+
+func TestNamespace(t *testing.T) {
+	c := gs.New()
+	c.Property("app.name", "monorepo")
+	c.Property("db.url", "mysql://root")
+	c.Property("billing.db.url", "mysql://billing")
+	c.Property("billing.db.port", 3306)
+	c.Property("billing.db.dsn", "${/app.name}@${db.url}")
+	c.Object(&namespaceBean{}).Name("root")
+	c.Object(&namespaceBean{}).Name("billing").Namespace("billing")
+	c.Provide(func(port int) *namespaceBean {
+		return &namespaceBean{Port: port}
+	}, "${db.port}").Name("ctor").Namespace("billing")
+	err := runTest(c, func(p gs.Context) {
+		var b *namespaceBean
+		err := p.Get(&b, "root")
+		assert.Nil(t, err)
+		assert.Equal(t, b.URL, "mysql://root")
+		assert.Equal(t, b.Name, "monorepo")
+		err = p.Get(&b, "billing")
+		assert.Nil(t, err)
+		assert.Equal(t, b.URL, "mysql://billing")
+		assert.Equal(t, b.Name, "monorepo")
+		assert.Equal(t, b.DSN, "monorepo@mysql://root")
+		err = p.Get(&b, "ctor")
+		assert.Nil(t, err)
+		assert.Equal(t, b.Port, 3306)
+		assert.Equal(t, b.URL, "mysql://billing")
+	})
+	assert.Nil(t, err)
+}
+
+type graphA struct {
+	B *graphB `autowire:""`
+	C gs.Lazy `autowire:"graphC"`
+}
+
+type graphB struct{}
+
+type graphC struct{}
+
+func TestDumpGraph(t *testing.T) {
+	c := gs.New()
+	c.Property("graph.enabled", true)
+	c.Object(&graphA{})
+	c.Object(&graphB{}).On(cond.OnProperty("graph.enabled"))
+	c.Object(&graphC{}).DependsOn("graphB")
+	err := runTest(c, func(p gs.Context) {})
+	assert.Nil(t, err)
+
+	var buf strings.Builder
+	err = c.DumpGraph(&buf)
+	assert.Nil(t, err)
+	s := buf.String()
+	assert.True(t, strings.HasPrefix(s, "digraph beans {\n"))
+	assert.True(t, strings.Contains(s, `on OnProperty(\"graph.enabled\")\n(mock)", style=dashed, color=orange];`))
+	assert.True(t, strings.Contains(s, `"github.com/go-spring/spring-core/gs/gs_test.graphA:graphA" -> "github.com/go-spring/spring-core/gs/gs_test.graphB:graphB";`))
+	assert.True(t, strings.Contains(s, `"github.com/go-spring/spring-core/gs/gs_test.graphA:graphA" -> "github.com/go-spring/spring-core/gs/gs_test.graphC:graphC" [style=dashed, label="lazy"];`))
+	assert.True(t, strings.Contains(s, `"github.com/go-spring/spring-core/gs/gs_test.graphC:graphC" -> "github.com/go-spring/spring-core/gs/gs_test.graphB:graphB" [style=dotted, label="depends"];`))
+
+	buf.Reset()
+	err = c.DumpGraphAround(&buf, "graphC", 0)
+	assert.Nil(t, err)
+	s = buf.String()
+	assert.True(t, strings.Contains(s, "graphC"))
+	assert.False(t, strings.Contains(s, "graphA"))
+	assert.False(t, strings.Contains(s, "->"))
+
+	buf.Reset()
+	err = c.DumpGraphAround(&buf, "graphC", 1)
+	assert.Nil(t, err)
+	s = buf.String()
+	assert.True(t, strings.Contains(s, "graphA"))
+	assert.True(t, strings.Contains(s, "graphB"))
+}
+
+func TestGraph(t *testing.T) {
+
+	t.Run("export", func(t *testing.T) {
+		c := gs.New()
+		c.Property("graph.enabled", true)
+		c.Object(&graphA{})
+		c.Object(&graphB{}).On(cond.OnProperty("graph.enabled"))
+		c.Object(&graphC{}).DependsOn("graphB")
+		err := runTest(c, func(p gs.Context) {})
+		assert.Nil(t, err)
+
+		g, err := c.Graph()
+		assert.Nil(t, err)
+
+		nodes := make(map[string]gs.GraphNode)
+		for _, n := range g.Nodes {
+			nodes[n.Name] = n
+		}
+		assert.Equal(t, nodes["graphB"].Type, "github.com/go-spring/spring-core/gs/gs_test.graphB")
+		assert.Equal(t, nodes["graphB"].Condition, `OnProperty("graph.enabled")`)
+		assert.True(t, nodes["graphB"].Mock)
+		assert.True(t, strings.Contains(nodes["graphA"].Source, "gs_test.go"))
+
+		edges := make(map[string]string)
+		for _, e := range g.Edges {
+			edges[e.From+" -> "+e.To] = e.Kind
+		}
+		assert.Equal(t, edges[nodes["graphA"].ID+" -> "+nodes["graphB"].ID], "")
+		assert.Equal(t, edges[nodes["graphA"].ID+" -> "+nodes["graphC"].ID], "lazy")
+		assert.Equal(t, edges[nodes["graphC"].ID+" -> "+nodes["graphB"].ID], "depends")
+
+		b, err := json.Marshal(g)
+		assert.Nil(t, err)
+		var r gs.Graph
+		err = json.Unmarshal(b, &r)
+		assert.Nil(t, err)
+		assert.Equal(t, &r, g)
+
+		var buf strings.Builder
+		err = g.WriteMermaid(&buf)
+		assert.Nil(t, err)
+		s := buf.String()
+		assert.True(t, strings.HasPrefix(s, "graph LR\n"))
+		assert.True(t, strings.Contains(s, `on OnProperty(#quot;graph.enabled#quot;)"]`))
+		assert.True(t, strings.Contains(s, " -. lazy .-> "))
+		assert.True(t, strings.Contains(s, " -. depends .-> "))
+	})
+
+	t.Run("cleared", func(t *testing.T) {
+		c := gs.New()
+		c.Object(&graphB{})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		_, err = c.Graph()
+		assert.Error(t, err, "bean indexes have been cleared")
+	})
+
+	t.Run("saved", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringDependencyGraphEnabled, true)
+		c.Object(&graphB{})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		g, err := c.Graph()
+		assert.Nil(t, err)
+		var names []string
+		for _, n := range g.Nodes {
+			names = append(names, n.Name)
+		}
+		assert.Equal(t, names, []string{"graphB", "container", "propertiesView"})
+	})
+}
+
+func TestGroupOnCondition(t *testing.T) {
+	c := gs.New()
+	c.Property("server.version", "1.0.0")
+	c.Property("group.enabled", true)
+	c.GroupOnCondition(cond.OnProperty("group.enabled"), func() []*gs.BeanDefinition {
+		return []*gs.BeanDefinition{gs.NewBean(new(Server))}
+	})
+	called := false
+	c.GroupOnCondition(cond.OnProperty("group.disabled"), func() []*gs.BeanDefinition {
+		called = true
+		return []*gs.BeanDefinition{gs.NewBean(new(BeanZero))}
+	})
+	err := runTest(c, func(p gs.Context) {
+		var s *Server
+		err := p.Get(&s)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Version, "1.0.0")
+		var z *BeanZero
+		err = p.Get(&z)
+		assert.Error(t, err, "can't find bean")
+	})
+	assert.Nil(t, err)
+	assert.False(t, called)
+}
+
+func TestIllegalState(t *testing.T) {
+
+	t.Run("before refresh", func(t *testing.T) {
+		c := gs.New()
+		err := c.RegisterRuntimeBean(gs.NewBean(new(callDestroy)))
+		assert.Error(t, err, "RegisterRuntimeBean should call after Refresh, but container is Unrefreshed")
+		err = c.DumpGraph(ioutil.Discard)
+		assert.Error(t, err, "DumpGraph should call after Refresh, but container is Unrefreshed")
+		err = c.Close()
+		assert.Error(t, err, "Close should call after Refresh, but container is Unrefreshed")
+	})
+
+	t.Run("after refresh", func(t *testing.T) {
+		c := gs.New()
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		var e *gs.IllegalStateError
+		err = c.Refresh()
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, e.Op, "Refresh")
+		assert.Equal(t, e.State, gs.Refreshed)
+		assert.Error(t, err, "Refresh should call only once, but container is Refreshed")
+
+		assert.Panic(t, func() { c.Object(new(callDestroy)) }, "Accept should call before Refresh, but container is Refreshed")
+		assert.Panic(t, func() { c.Provide(NewStudent) }, "Accept should call before Refresh, but container is Refreshed")
+		assert.Panic(t, func() { c.Property("a", 1) }, "Property should call before Refresh, but container is Refreshed and read-only")
+		assert.Panic(t, func() { c.OnProperty("a", func(int) {}) }, "OnProperty should call before Refresh, but container is Refreshed")
+		assert.Panic(t, func() {
+			c.GroupOnCondition(cond.OK(), func() []*gs.BeanDefinition { return nil })
+		}, "GroupOnCondition should call before Refresh, but container is Refreshed")
+	})
+
+	t.Run("testing unlock", func(t *testing.T) {
+		c := gs.New()
+		c.TestingUnlock()
+		c.Property("a", 1)
+		var s struct {
+			A dync.Int64 `value:"${a}"`
+		}
+		c.Object(&s)
+		err := c.Refresh()
+		assert.Nil(t, err)
+
+		c.Property("a", 2)
+		assert.Equal(t, s.A.Value(), int64(2))
+
+		c.Object(new(callDestroy))
+		p := c.(gs.Context)
+		var d *callDestroy
+		err = p.Get(&d)
+		assert.Nil(t, err)
+		assert.NotNil(t, d)
+
+		assert.Panic(t, func() { c.OnProperty("a", func(int) {}) }, "OnProperty should call before Refresh, but container is Refreshed and read-only")
+		c.Close()
+	})
+
+	t.Run("after close", func(t *testing.T) {
+		c := gs.New()
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Nil(t, c.Close())
+		err = c.Close()
+		assert.Error(t, err, "Close should call after Refresh, but container is Closed")
+		err = c.Refresh()
+		assert.Error(t, err, "Refresh should call only once, but container is Closed")
+	})
+
+	t.Run("app run twice", func(t *testing.T) {
+		os.Clearenv()
+		gs.Setenv("GS_SPRING_CONFIG_LOCATIONS", "testdata/config/")
+		app := gs.NewApp()
+		app.DisableSignalHandler()
+		go func() { app.Signal("test done") }()
+		err := app.Run()
+		assert.Nil(t, err)
+		err = app.Run()
+		assert.Error(t, err, "Run should call only once, but container is Closed")
+	})
+}
+
+type paymentGateway interface {
+	Pay() string
+}
+
+type payGateway struct {
+	name string
+}
+
+func (g *payGateway) Pay() string {
+	return g.name
+}
+
+func TestVariant(t *testing.T) {
+
+	newContainer := func(profiles string) gs.Container {
+		c := gs.New()
+		c.Property(gs.SpringProfilesActive, profiles)
+		c.Object(&payGateway{"mock"}).Name("mockGateway").Export((*paymentGateway)(nil))
+		c.Object(&payGateway{"stripe"}).Name("stripeGateway").Export((*paymentGateway)(nil))
+		c.Variant("paymentGateway", map[string]util.BeanSelector{
+			"dev":             "mockGateway",
+			gs.DefaultVariant: "stripeGateway",
+		})
+		return c
+	}
+
+	var s struct {
+		Gateway paymentGateway `autowire:"paymentGateway"`
+	}
+
+	t.Run("profile", func(t *testing.T) {
+		c := newContainer("test,dev")
+		c.Object(&s)
+		err := runTest(c, func(p gs.Context) {
+			var g paymentGateway
+			err := p.Get(&g, "paymentGateway")
+			assert.Nil(t, err)
+			assert.Equal(t, g.Pay(), "mock")
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, s.Gateway.Pay(), "mock")
+	})
+
+	t.Run("default", func(t *testing.T) {
+		c := newContainer("prod")
+		c.Object(&s)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, s.Gateway.Pay(), "stripe")
+	})
+
+	t.Run("property", func(t *testing.T) {
+		c := newContainer("dev")
+		c.Property(gs.SpringVariants+".paymentGateway", "stripeGateway")
+		c.Object(&s)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		assert.Equal(t, s.Gateway.Pay(), "stripe")
+	})
+
+	t.Run("no variant", func(t *testing.T) {
+		c := gs.New()
+		c.Property(gs.SpringProfilesActive, "prod")
+		c.Variant("paymentGateway", map[string]util.BeanSelector{"dev": "mockGateway"})
+		c.Object(&s)
+		err := c.Refresh()
+		assert.Error(t, err, "no variant of \"paymentGateway\" for profiles \\[prod\\]")
+	})
+}
+
+func TestLeaks(t *testing.T) {
+	defer dync.TraceStacks(false)
+
+	c := gs.New()
+	c.Property(gs.SpringLeakDetectionStack, true)
+	c.Property(gs.SpringLeakDetectionDelay, "10ms")
+	c.Property("spring.app.jobs.shutdown-grace", "50ms")
+
+	var b struct {
+		Listener dync.Listener `value:"${leak.listener:=}"`
+	}
+	c.Object(&b)
+
+	var ctx gs.Context
+	err := runTest(c, func(p gs.Context) { ctx = p })
+	assert.Nil(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+	ctx.Go(func(_ context.Context) { <-release })
+	b.Listener.Listen(1, dync.Coalesce)
+	closed := b.Listener.Listen(1, dync.Coalesce)
+	b.Listener.Close(closed)
+
+	c.Close()
+
+	leaks := c.Leaks()
+	assert.Equal(t, len(leaks), 2)
+	assert.Equal(t, leaks[0].Kind, "goroutine")
+	assert.True(t, strings.Contains(leaks[0].Source, "gs_test.go"))
+	assert.True(t, strings.Contains(leaks[0].Stack, "TestLeaks"))
+	assert.Equal(t, leaks[1].Kind, "listener")
+	assert.Equal(t, leaks[1].Source, "leak.listener")
+	assert.True(t, strings.Contains(leaks[1].Stack, "TestLeaks"))
+}
+
+type slowBean struct{}
+
+type slowAware struct {
+	Slow *slowBean `autowire:""`
+}
+
+func TestStartupReport(t *testing.T) {
+	c := gs.New()
+	c.Property(gs.SpringStartupReportTop, 3)
+	c.Provide(func() *slowBean {
+		time.Sleep(20 * time.Millisecond)
+		return &slowBean{}
+	})
+	c.Object(&slowAware{}) // 先于 slowBean 注入
+	c.Object(&BeanZero{}).On(cond.OnProperty("zero.enabled"))
+	assert.Nil(t, c.StartupReport())
+	err := runTest(c, func(p gs.Context) {})
+	assert.Nil(t, err)
+
+	r := c.StartupReport()
+	beans := make(map[string]gs.BeanTiming)
+	for _, b := range r.Beans {
+		beans[b.ID] = b
+	}
+	slow := r.Beans[0]
+	assert.True(t, strings.Contains(slow.ID, "gs_test.slowBean:"))
+	assert.True(t, slow.Self >= 20*time.Millisecond)
+
+	aware := beans["github.com/go-spring/spring-core/gs/gs_test.slowAware:slowAware"]
+	assert.True(t, aware.Matched)
+	assert.True(t, aware.Total >= slow.Total)
+	assert.True(t, aware.Self < slow.Self)
+
+	zero := beans["github.com/go-spring/spring-core/gs/gs_test.BeanZero:BeanZero"]
+	assert.False(t, zero.Matched)
+	assert.Equal(t, zero.Condition, `OnProperty("zero.enabled")`)
+
+	var buf bytes.Buffer
+	err = r.WriteTable(&buf, 1)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, len(lines), 3)
+	assert.True(t, strings.HasSuffix(lines[1], slow.ID))
+}
+
+type markedLifecycle struct {
+	gs.PostConstruct
+	gs.PreDestroy
+	events []string
+}
+
+func (m *markedLifecycle) Init() error {
+	m.events = append(m.events, "init")
+	return nil
+}
+
+func (m *markedLifecycle) Destroy() {
+	m.events = append(m.events, "destroy")
+}
+
+type namedLifecycle struct {
+	gs.PostConstruct `method:"Start"`
+	gs.PreDestroy    `method:"Stop"`
+	events           []string
+}
+
+func (m *namedLifecycle) Start() { m.events = append(m.events, "start") }
+func (m *namedLifecycle) Stop()  { m.events = append(m.events, "stop") }
+
+type missingLifecycle struct {
+	gs.PostConstruct `method:"Setup"`
+}
+
+func TestLifecycleMarkers(t *testing.T) {
+
+	t.Run("default", func(t *testing.T) {
+		c := gs.New()
+		m := new(markedLifecycle)
+		c.Object(m)
+		err := c.Refresh()
+		assert.Nil(t, err)
+		c.Close()
+		assert.Equal(t, m.events, []string{"init", "destroy"})
+	})
+
+	t.Run("method tag", func(t *testing.T) {
+		c := gs.New()
+		m := new(namedLifecycle)
+		c.Provide(func() *namedLifecycle { return m })
+		err := c.Refresh()
+		assert.Nil(t, err)
+		c.Close()
+		assert.Equal(t, m.events, []string{"start", "stop"})
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		c := gs.New()
+		m := new(markedLifecycle)
+		c.Object(m).Init(func(m *markedLifecycle) {
+			m.events = append(m.events, "explicit")
+		})
+		err := c.Refresh()
+		assert.Nil(t, err)
+		c.Close()
+		assert.Equal(t, m.events, []string{"explicit", "destroy"})
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		assert.Panic(t, func() {
+			gs.New().Object(new(missingLifecycle))
+		}, "declares gs.PostConstruct but method Setup not found")
+	})
+}
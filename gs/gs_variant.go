@@ -0,0 +1,89 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// SpringVariants 通过属性指定逻辑名称对应的 bean 的前缀，比如设置
+// spring.variants.paymentGateway=mockGateway 之后逻辑名称 paymentGateway 总是
+// 选择 mockGateway ，优先级高于 Variant 中按照 profile 设置的选择器。
+const SpringVariants = "spring.variants"
+
+// DefaultVariant 没有激活的 profile 匹配时使用的选择器对应的 key 。
+const DefaultVariant = "default"
+
+// Variant 注册名为 name 的逻辑名称，注入时使用逻辑名称作为 bean 名称的选择器会根据
+// 激活的 profile 替换成 selectors 中对应的选择器，多个 profile 都匹配时使用最先激
+// 活的 profile ，都不匹配时使用 key 为 default 的选择器。
+//
+//	c.Variant("paymentGateway", map[string]util.BeanSelector{
+//		"dev":     "mockGateway",
+//		"default": "stripeGateway",
+//	})
+func (c *container) Variant(name string, selectors map[string]util.BeanSelector) {
+	c.checkBeforeRefresh("Variant")
+	if c.variants == nil {
+		c.variants = make(map[string]map[string]util.BeanSelector)
+	}
+	c.variants[name] = selectors
+}
+
+// resolveVariant 如果 tag 的 bean 名称是逻辑名称，那么返回当前环境中逻辑名称对应
+// 的选择器，否则原样返回。
+func (c *container) resolveVariant(tag wireTag) (wireTag, error) {
+
+	if tag.typeName != "" || tag.beanName == "" {
+		return tag, nil
+	}
+	selectors, ok := c.variants[tag.beanName]
+	if !ok {
+		return tag, nil
+	}
+
+	var selector util.BeanSelector
+	if s := c.p.Get(SpringVariants + "." + tag.beanName); s != "" {
+		selector = s
+	} else {
+		var profiles []string
+		for _, s := range strings.Split(c.p.Get(SpringProfilesActive), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				profiles = append(profiles, s)
+			}
+		}
+		for _, profile := range profiles {
+			if s, ok := selectors[profile]; ok {
+				selector = s
+				break
+			}
+		}
+		if selector == nil {
+			if selector, ok = selectors[DefaultVariant]; !ok {
+				return tag, fmt.Errorf("no variant of %q for profiles %v", tag.beanName, profiles)
+			}
+		}
+	}
+
+	r := toWireTag(selector)
+	r.nullable = tag.nullable
+	c.logger.Debugf("variant %q resolved to %q", tag.beanName, r)
+	return r, nil
+}
@@ -16,7 +16,176 @@
 
 package gs
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
 const (
 	Version = "go-spring@v1.1.3"
 	Website = "https://go-spring.com/"
 )
+
+// CoreModule spring-core 在版本握手中使用的模块名。
+const CoreModule = "spring-core"
+
+// SpringVersionCheckEnabled 启动时是否检查模块之间的版本兼容性，默认开启。
+const SpringVersionCheckEnabled = "spring.app.version-check.enabled"
+
+// ModuleVersion 伴生模块 (比如 log 、gs-mock 以及各种 starter) 的版本信息。
+// Requires 为依赖的模块名到版本约束的映射，版本约束由空格分隔的若干个比较式组成，
+// 比如 ">=v1.1.0 <v1.2.0" ，比较符可以是 >= 、> 、<= 、< 、= ，省略时表示 = 。
+type ModuleVersion struct {
+	Name     string
+	Version  string
+	Requires map[string]string
+}
+
+var (
+	moduleVersionsMutex sync.Mutex
+	moduleVersions      = map[string]ModuleVersion{}
+)
+
+// RegisterModuleVersion 注册伴生模块的版本信息，一般在伴生模块的 init 函数中调用，
+// 启动时会检查所有已注册模块的版本约束，不满足时启动失败。未注册的模块不参与检查。
+func RegisterModuleVersion(m ModuleVersion) {
+	moduleVersionsMutex.Lock()
+	defer moduleVersionsMutex.Unlock()
+	moduleVersions[m.Name] = m
+}
+
+// ModuleVersions 返回所有已注册模块的版本信息，包括 spring-core 本身，按照模块名
+// 排序。
+func ModuleVersions() []ModuleVersion {
+	moduleVersionsMutex.Lock()
+	defer moduleVersionsMutex.Unlock()
+	ret := []ModuleVersion{{Name: CoreModule, Version: coreVersion()}}
+	for _, m := range moduleVersions {
+		if m.Name != CoreModule {
+			ret = append(ret, m)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// coreVersion 返回 spring-core 的版本号。
+func coreVersion() string {
+	if i := strings.LastIndex(Version, "@"); i >= 0 {
+		return Version[i+1:]
+	}
+	return Version
+}
+
+// CheckModuleVersions 检查所有已注册模块之间的版本约束，返回所有不满足的约束。
+func CheckModuleVersions() error {
+
+	versions := make(map[string]string)
+	for _, m := range ModuleVersions() {
+		versions[m.Name] = m.Version
+	}
+
+	var problems []string
+	for _, m := range ModuleVersions() {
+		var names []string
+		for name := range m.Requires {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			constraint := m.Requires[name]
+			v, ok := versions[name]
+			if !ok {
+				continue
+			}
+			matched, err := matchVersion(v, constraint)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("module %s@%s has invalid constraint %q for %s: %v", m.Name, m.Version, constraint, name, err))
+				continue
+			}
+			if !matched {
+				problems = append(problems, fmt.Sprintf("module %s@%s requires %s %q but found %s, please upgrade or downgrade one of them", m.Name, m.Version, name, constraint, v))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &VersionError{Problems: problems}
+	}
+	return nil
+}
+
+// matchVersion 返回版本号 v 是否满足版本约束 constraint 。
+func matchVersion(v string, constraint string) (bool, error) {
+	ver, err := parseVersion(v)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range strings.Fields(constraint) {
+		op := "="
+		for _, o := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(s, o) {
+				op, s = o, s[len(o):]
+				break
+			}
+		}
+		c, err := parseVersion(s)
+		if err != nil {
+			return false, err
+		}
+		r := compareVersion(ver, c)
+		var ok bool
+		switch op {
+		case ">=":
+			ok = r >= 0
+		case "<=":
+			ok = r <= 0
+		case ">":
+			ok = r > 0
+		case "<":
+			ok = r < 0
+		default:
+			ok = r == 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parseVersion 解析 v1.2.3 形式的版本号，可以省略 v 前缀以及次版本号和修订号，
+// 忽略 - 或者 + 之后的预发布和构建信息。
+func parseVersion(v string) ([3]int, error) {
+	var ret [3]int
+	s := strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return ret, fmt.Errorf("invalid version %q", v)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return ret, fmt.Errorf("invalid version %q", v)
+		}
+		ret[i] = n
+	}
+	return ret, nil
+}
+
+func compareVersion(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gstest 提供白盒集成测试的辅助函数，可以直接对容器中 bean 的字段以及注入
+// 状态进行断言，而不需要在应用代码中暴露内部实现。
+package gstest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/clock"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+)
+
+var (
+	ctx       gs.Context
+	container gs.Container
+)
+
+// tester 注入 gs.Context 的 bean 会使容器在刷新后保留 bean 的索引。
+type tester struct {
+	Context gs.Context `autowire:""`
+}
+
+// Init 刷新容器 c 并保留 bean 的索引，之后可以使用本包的其他函数进行断言。
+func Init(c gs.Container) error {
+	t := new(tester)
+	c.Object(t)
+	if err := c.Refresh(); err != nil {
+		return err
+	}
+	ctx = t.Context
+	container = c
+	return nil
+}
+
+// Mock 注册 mock bean ，当 mock bean 和真实的 bean 同时满足注入条件时总是使用
+// mock bean ，替换的结果可以通过 Mocks 获取。
+func Mock(c gs.Container, i interface{}) *gs.BeanDefinition {
+	return c.Object(i).Mock()
+}
+
+// FakeClock 注册从 now 开始的假时钟，替换容器中基于系统时间的 gs.Clock ，测试
+// 代码通过 Advance 方法推进时间。
+func FakeClock(c gs.Container, now time.Time) *clock.Fake {
+	f := clock.NewFake(now)
+	Mock(c, f).Export((*gs.Clock)(nil))
+	return f
+}
+
+// Mocks 返回 Init 刷新的容器中所有生效的 mock bean 替换记录。
+func Mocks() []gs.MockSubstitution {
+	if container == nil {
+		return nil
+	}
+	return container.Mocks()
+}
+
+// Context 返回 Init 刷新的容器。
+func Context() gs.Context {
+	return ctx
+}
+
+func findBean(selector util.BeanSelector) (*gs.BeanDefinition, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("gstest.Init should be called first")
+	}
+	// 容器同时实现了条件判断使用的 cond.Context 接口
+	beans, err := ctx.(cond.Context).Find(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(beans) == 0 {
+		return nil, fmt.Errorf("can't find bean, selector=%v", selector)
+	}
+	if len(beans) > 1 {
+		return nil, fmt.Errorf("found %d beans, selector=%v", len(beans), selector)
+	}
+	return beans[0].(*gs.BeanDefinition), nil
+}
+
+// GetBeanField 返回 selector 对应的 bean 在 path 处的字段值，path 使用 . 分隔
+// 多级字段，例如 "Config.Port"，未导出的字段也可以访问。
+func GetBeanField(t testing.TB, selector util.BeanSelector, path string) interface{} {
+	t.Helper()
+	b, err := findBean(selector)
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	v, err := getField(b.Value(), path)
+	if err != nil {
+		t.Fatalf("get field %q of %s error: %v", path, b, err)
+		return nil
+	}
+	return v.Interface()
+}
+
+func getField(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("field %q is nil", name)
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("%s isn't struct", v.Type())
+		}
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return reflect.Value{}, fmt.Errorf("field %q not found in %s", name, v.Type())
+		}
+		if f.CanAddr() {
+			f = util.PatchValue(f)
+		}
+		v = f
+	}
+	return v, nil
+}
+
+// AssertWired 断言 selector 对应的 bean 存在并且已经完成属性绑定和依赖注入。
+func AssertWired(t testing.TB, selector util.BeanSelector) {
+	t.Helper()
+	b, err := findBean(selector)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !b.Wired() {
+		t.Errorf("%s isn't wired", b)
+	}
+}
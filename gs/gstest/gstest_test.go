@@ -0,0 +1,188 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gstest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/gstest"
+)
+
+func init() {
+	err := log.Refresh("../testdata/config/logger.xml")
+	util.Panic(err).When(err != nil)
+}
+
+type Config struct {
+	Port int `value:"${port:=8080}"`
+}
+
+type Server struct {
+	Config Config `value:"${server}"`
+	name   string
+}
+
+type Service struct {
+	Server *Server `autowire:""`
+}
+
+func TestGetBeanField(t *testing.T) {
+
+	c := gs.New()
+	c.Property("server.port", 9090)
+	c.Object(&Server{name: "echo"})
+	c.Object(new(Service))
+	err := gstest.Init(c)
+	assert.Nil(t, err)
+
+	assert.Equal(t, gstest.GetBeanField(t, (*Server)(nil), "Config.Port"), 9090)
+	assert.Equal(t, gstest.GetBeanField(t, (*Server)(nil), "name"), "echo")
+	assert.Equal(t, gstest.GetBeanField(t, (*Service)(nil), "Server.name"), "echo")
+
+	gstest.AssertWired(t, (*Server)(nil))
+	gstest.AssertWired(t, (*Service)(nil))
+}
+
+type Greeter interface {
+	Greet() string
+}
+
+type realGreeter struct{}
+
+func (g *realGreeter) Greet() string { return "real" }
+
+type mockGreeter struct{}
+
+func (g *mockGreeter) Greet() string { return "mock" }
+
+type Client struct {
+	Greeter Greeter `autowire:""`
+}
+
+func TestMock(t *testing.T) {
+
+	c := gs.New()
+	c.Object(new(realGreeter)).Export((*Greeter)(nil))
+	gstest.Mock(c, new(mockGreeter)).Export((*Greeter)(nil))
+	c.Object(new(Client))
+	err := gstest.Init(c)
+	assert.Nil(t, err)
+
+	var client *Client
+	err = gstest.Context().Get(&client)
+	assert.Nil(t, err)
+	assert.Equal(t, client.Greeter.Greet(), "mock")
+
+	mocks := gstest.Mocks()
+	assert.Equal(t, len(mocks), 1)
+	assert.True(t, strings.HasSuffix(mocks[0].Mock, ".mockGreeter:mockGreeter"))
+	assert.Equal(t, len(mocks[0].Replaced), 1)
+	assert.True(t, strings.HasSuffix(mocks[0].Replaced[0], ".realGreeter:realGreeter"))
+}
+
+type Scheduler struct {
+	Clock gs.Clock `autowire:""`
+}
+
+func TestFakeClock(t *testing.T) {
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := gs.New()
+	f := gstest.FakeClock(c, now)
+	c.Object(new(Scheduler))
+	err := gstest.Init(c)
+	assert.Nil(t, err)
+
+	var s *Scheduler
+	err = gstest.Context().Get(&s)
+	assert.Nil(t, err)
+	f.Advance(time.Hour)
+	assert.Equal(t, s.Clock.Now(), now.Add(time.Hour))
+}
+
+type closer struct {
+	closed bool
+}
+
+func TestRun(t *testing.T) {
+
+	c := new(closer)
+	t.Run("scoped", func(t *testing.T) {
+		ctx := gstest.Run(t,
+			gstest.Property("server.port", 9090),
+			gstest.Object(new(realGreeter), func(b *gs.BeanDefinition) {
+				b.Export((*Greeter)(nil))
+			}),
+			gstest.MockBean(new(mockGreeter), (*Greeter)(nil)),
+			gstest.Object(&Server{name: "echo"}),
+			gstest.Provide(func(s *Server) *Service { return &Service{Server: s} }),
+			gstest.Object(new(Client)),
+			gstest.Object(c, func(b *gs.BeanDefinition) {
+				b.Destroy(func(c *closer) { c.closed = true })
+			}),
+		)
+
+		var client *Client
+		assert.Nil(t, ctx.Get(&client))
+		assert.Equal(t, client.Greeter.Greet(), "mock")
+
+		var s *Service
+		assert.Nil(t, ctx.Get(&s))
+		assert.Equal(t, s.Server.Config.Port, 9090)
+		assert.False(t, c.closed)
+	})
+	assert.True(t, c.closed)
+
+	t.Run("isolated", func(t *testing.T) {
+		ctx := gstest.Run(t)
+		var client *Client
+		assert.Error(t, ctx.Get(&client), "can't find bean")
+	})
+}
+
+type Greeters struct {
+	List []Greeter          `autowire:""`
+	Map  map[string]Greeter `autowire:""`
+}
+
+func TestMock_Collection(t *testing.T) {
+
+	c := gs.New()
+	c.Object(new(realGreeter)).Export((*Greeter)(nil))
+	gstest.Mock(c, new(mockGreeter)).Export((*Greeter)(nil))
+	c.Object(new(Greeters))
+	err := gstest.Init(c)
+	assert.Nil(t, err)
+
+	var g *Greeters
+	err = gstest.Context().Get(&g)
+	assert.Nil(t, err)
+	assert.Equal(t, len(g.List), 1)
+	assert.Equal(t, g.List[0].Greet(), "mock")
+	assert.Equal(t, len(g.Map), 1)
+	assert.Equal(t, g.Map["mockGreeter"].Greet(), "mock")
+
+	mocks := gstest.Mocks()
+	assert.Equal(t, len(mocks), 1)
+	assert.True(t, strings.HasSuffix(mocks[0].Mock, ".mockGreeter:mockGreeter"))
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gstest
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/arg"
+)
+
+// Option 配置 Run 创建的容器。
+type Option func(c gs.Container)
+
+// Property 设置容器的属性。
+func Property(key string, value interface{}) Option {
+	return func(c gs.Container) {
+		c.Property(key, value)
+	}
+}
+
+// Object 注册对象形式的 bean ，fn 可以继续设置 bean 的名称、条件等。
+func Object(i interface{}, fn ...func(b *gs.BeanDefinition)) Option {
+	return func(c gs.Container) {
+		b := c.Object(i)
+		for _, f := range fn {
+			f(b)
+		}
+	}
+}
+
+// Provide 注册构造函数形式的 bean 。
+func Provide(ctor interface{}, args ...arg.Arg) Option {
+	return func(c gs.Container) {
+		c.Provide(ctor, args...)
+	}
+}
+
+// MockBean 注册 mock bean 并导出 exports 指定的接口，参考 Mock 的解释。
+func MockBean(impl interface{}, exports ...interface{}) Option {
+	return func(c gs.Container) {
+		Mock(c, impl).Export(exports...)
+	}
+}
+
+// Setup 使用容器执行任意的配置，例如调用 FakeClock 注册假时钟。
+func Setup(fn func(c gs.Container)) Option {
+	return Option(fn)
+}
+
+// Run 为当前测试创建一个独立的容器，依次执行 opts 之后刷新容器，刷新失败时测试
+// 立即失败。测试结束时自动关闭容器并执行 bean 的销毁函数。与 Init 不同，Run 不
+// 使用全局的 App 或者包级别的状态，多个测试 (包括并行的测试) 之间互不影响。
+func Run(t testing.TB, opts ...Option) gs.Context {
+	t.Helper()
+	c := gs.New()
+	for _, opt := range opts {
+		opt(c)
+	}
+	x := new(tester)
+	c.Object(x)
+	if err := c.Refresh(); err != nil {
+		t.Fatalf("refresh container error: %v", err)
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := c.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return x.Context
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package legacy 把 v1.0 版本 ApplicationContext 风格的调用映射到 gs.Context
+// 上，老用户可以先替换导入路径，再逐步改成直接使用 gs.Context 。
+package legacy
+
+import (
+	"context"
+
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/arg"
+)
+
+// ContextAware 嵌入到 bean 中即可获得 gs.Context ，等同于 gs.ContextAware 。
+type ContextAware = gs.ContextAware
+
+// ApplicationContext 老版本 ApplicationContext 接口的兼容层，所有的调用都转发
+// 给 gs.Context ，只保留了老版本的方法名和返回值。
+type ApplicationContext struct {
+	ctx    gs.Context
+	logger *log.Logger
+}
+
+// New 使用 gs.Context 创建兼容层，ctx 一般通过 ContextAware 注入。
+func New(ctx gs.Context) *ApplicationContext {
+	ret := &ApplicationContext{ctx: ctx}
+	ret.logger = log.GetLogger(util.TypeName(ret))
+	return ret
+}
+
+// Context 返回被包装的 gs.Context 。
+func (c *ApplicationContext) Context() gs.Context {
+	return c.ctx
+}
+
+// GetProperty 返回属性值，属性不存在时返回空字符串。
+func (c *ApplicationContext) GetProperty(key string) string {
+	return c.ctx.Prop(key)
+}
+
+// GetBean 获取符合条件的 bean ，参考 gs.Context.Get 的解释。老版本只返回是否
+// 获取成功，失败的原因会输出到日志中。
+func (c *ApplicationContext) GetBean(i interface{}, selectors ...util.BeanSelector) bool {
+	if err := c.ctx.Get(i, selectors...); err != nil {
+		c.logger.Warnf("get bean error: %v", err)
+		return false
+	}
+	return true
+}
+
+// WireBean 对 bean 进行属性绑定和依赖注入，参考 gs.Context.Wire 的解释。
+func (c *ApplicationContext) WireBean(i interface{}) error {
+	_, err := c.ctx.Wire(i)
+	return err
+}
+
+// Invoke 调用函数并注入函数的参数，参考 gs.Context.Invoke 的解释。
+func (c *ApplicationContext) Invoke(fn interface{}, args ...arg.Arg) ([]interface{}, error) {
+	return c.ctx.Invoke(fn, args...)
+}
+
+// Go 启动一个由容器管理的 goroutine ，容器关闭时等待它结束，老版本的函数不接收
+// context.Context 参数，需要感知关闭的代码应该改用 gs.Context.Go 。
+func (c *ApplicationContext) Go(fn func()) {
+	c.ctx.Go(func(context.Context) { fn() })
+}
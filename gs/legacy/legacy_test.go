@@ -0,0 +1,77 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package legacy_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/legacy"
+)
+
+func init() {
+	err := log.Refresh("../testdata/config/logger.xml")
+	util.Panic(err).When(err != nil)
+}
+
+type legacyService struct {
+	legacy.ContextAware
+	Name string `value:"${service.name:=legacy}"`
+}
+
+type legacyClient struct {
+	Service *legacyService `autowire:""`
+	Addr    string         `value:"${client.addr}"`
+}
+
+func TestApplicationContext(t *testing.T) {
+
+	c := gs.New()
+	c.Property("client.addr", "127.0.0.1:8080")
+	s := new(legacyService)
+	c.Object(s)
+	assert.Nil(t, c.Refresh())
+
+	ac := legacy.New(s.GSContext)
+	assert.Equal(t, ac.GetProperty("client.addr"), "127.0.0.1:8080")
+	assert.Equal(t, ac.GetProperty("client.port"), "")
+
+	var found *legacyService
+	assert.True(t, ac.GetBean(&found))
+	assert.Equal(t, found, s)
+
+	var missing *legacyClient
+	assert.False(t, ac.GetBean(&missing))
+
+	client := new(legacyClient)
+	assert.Nil(t, ac.WireBean(client))
+	assert.Equal(t, client.Service, s)
+	assert.Equal(t, client.Addr, "127.0.0.1:8080")
+
+	r, err := ac.Invoke(func(s *legacyService) string { return s.Name })
+	assert.Nil(t, err)
+	assert.Equal(t, r, []interface{}{"legacy"})
+
+	var n int32
+	ac.Go(func() { atomic.AddInt32(&n, 1) })
+	c.Close()
+	assert.Equal(t, atomic.LoadInt32(&n), int32(1))
+}
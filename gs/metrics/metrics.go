@@ -0,0 +1,118 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics 以 Prometheus 文本格式在内置的 HTTP 服务器上输出容器的运行指标，
+// 包括按照状态统计的 bean 数量、容器刷新的耗时、动态属性的刷新次数和耗时分布以及
+// 通过 Go 方法创建的 goroutine 数量。导入该包并且开启 spring.app.metrics.enabled 属性后生效:
+//
+//	import _ "github.com/go-spring/spring-core/gs/metrics"
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/cond"
+	"github.com/go-spring/spring-core/web"
+)
+
+// Endpoint 输出运行指标的端点。
+const Endpoint = "/metrics"
+
+// SpringMetricsEnabled 是否开启运行指标的端点。
+const SpringMetricsEnabled = "spring.app.metrics.enabled"
+
+// ContentType Prometheus 文本格式的 Content-Type 。
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func init() {
+	c := cond.OnProperty(SpringMetricsEnabled, cond.HavingValue("true"))
+	gs.Object(new(Exporter)).On(c)
+}
+
+// Exporter 在 Router 上注册 GET /metrics 端点的 bean 。
+type Exporter struct {
+	App    *gs.App    `autowire:""`
+	Router web.Router `autowire:""`
+}
+
+func (e *Exporter) OnInit(ctx gs.Context) error {
+	e.Router.HttpGet(Endpoint, e.ServeHTTP)
+	return nil
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	Write(w, e.App.Metrics())
+}
+
+// Write 以 Prometheus 文本格式输出运行指标。
+func Write(w io.Writer, m *gs.Metrics) {
+
+	var status []string
+	for s := range m.Beans {
+		status = append(status, s)
+	}
+	sort.Strings(status)
+
+	writeHeader(w, "gs_beans", "gauge", "Number of beans by status.")
+	for _, s := range status {
+		fmt.Fprintf(w, "gs_beans{status=%q} %d\n", s, m.Beans[s])
+	}
+
+	writeHeader(w, "gs_startup_seconds", "gauge", "Time spent refreshing the container.")
+	fmt.Fprintf(w, "gs_startup_seconds %g\n", m.Startup.Seconds())
+
+	writeHeader(w, "gs_property_refreshes_total", "counter", "Number of dynamic property refreshes.")
+	fmt.Fprintf(w, "gs_property_refreshes_total %d\n", m.Refreshes)
+
+	writeHeader(w, "gs_property_refresh_errors_total", "counter", "Number of failed dynamic property refreshes.")
+	fmt.Fprintf(w, "gs_property_refresh_errors_total %d\n", m.RefreshErrors)
+
+	writeHeader(w, "gs_property_refresh_seconds_total", "counter", "Time spent refreshing dynamic properties.")
+	fmt.Fprintf(w, "gs_property_refresh_seconds_total %g\n", m.RefreshDuration.Seconds())
+
+	writeHeader(w, "gs_property_refresh_keys_total", "counter", "Number of changed properties in dynamic property refreshes.")
+	fmt.Fprintf(w, "gs_property_refresh_keys_total %d\n", m.RefreshKeys)
+
+	writeHeader(w, "gs_property_refresh_fields_total", "counter", "Number of bound fields updated by dynamic property refreshes.")
+	fmt.Fprintf(w, "gs_property_refresh_fields_total %d\n", m.RefreshFields)
+
+	writeHeader(w, "gs_property_refresh_dropped_total", "counter", "Number of listener notifications dropped by dynamic property refreshes.")
+	fmt.Fprintf(w, "gs_property_refresh_dropped_total %d\n", m.RefreshDropped)
+
+	h := m.RefreshHistogram
+	writeHeader(w, "gs_property_refresh_duration_seconds", "histogram", "Distribution of dynamic property refresh durations.")
+	for i, b := range h.Buckets {
+		fmt.Fprintf(w, "gs_property_refresh_duration_seconds_bucket{le=\"%g\"} %d\n", b.Seconds(), h.Counts[i])
+	}
+	fmt.Fprintf(w, "gs_property_refresh_duration_seconds_bucket{le=\"+Inf\"} %d\n", h.Count)
+	fmt.Fprintf(w, "gs_property_refresh_duration_seconds_sum %g\n", h.Sum.Seconds())
+	fmt.Fprintf(w, "gs_property_refresh_duration_seconds_count %d\n", h.Count)
+
+	writeHeader(w, "gs_goroutines_started_total", "counter", "Number of goroutines started via Go.")
+	fmt.Fprintf(w, "gs_goroutines_started_total %d\n", m.GoroutinesStarted)
+
+	writeHeader(w, "gs_goroutines", "gauge", "Number of running goroutines started via Go.")
+	fmt.Fprintf(w, "gs_goroutines %d\n", m.GoroutinesRunning)
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/gs"
+	"github.com/go-spring/spring-core/gs/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	metrics.Write(&buf, &gs.Metrics{
+		Beans:           map[string]int{"Wired": 3, "Deleted": 1},
+		Startup:         1500 * time.Millisecond,
+		Refreshes:       2,
+		RefreshErrors:   1,
+		RefreshDuration: 250 * time.Millisecond,
+		RefreshKeys:     3,
+		RefreshFields:   4,
+		RefreshDropped:  1,
+		RefreshHistogram: gs.Histogram{
+			Buckets: []time.Duration{10 * time.Millisecond, time.Second},
+			Counts:  []int64{1, 2},
+			Count:   2,
+			Sum:     250 * time.Millisecond,
+		},
+		GoroutinesStarted: 5,
+		GoroutinesRunning: 2,
+	})
+	assert.Equal(t, buf.String(), `# HELP gs_beans Number of beans by status.
+# TYPE gs_beans gauge
+gs_beans{status="Deleted"} 1
+gs_beans{status="Wired"} 3
+# HELP gs_startup_seconds Time spent refreshing the container.
+# TYPE gs_startup_seconds gauge
+gs_startup_seconds 1.5
+# HELP gs_property_refreshes_total Number of dynamic property refreshes.
+# TYPE gs_property_refreshes_total counter
+gs_property_refreshes_total 2
+# HELP gs_property_refresh_errors_total Number of failed dynamic property refreshes.
+# TYPE gs_property_refresh_errors_total counter
+gs_property_refresh_errors_total 1
+# HELP gs_property_refresh_seconds_total Time spent refreshing dynamic properties.
+# TYPE gs_property_refresh_seconds_total counter
+gs_property_refresh_seconds_total 0.25
+# HELP gs_property_refresh_keys_total Number of changed properties in dynamic property refreshes.
+# TYPE gs_property_refresh_keys_total counter
+gs_property_refresh_keys_total 3
+# HELP gs_property_refresh_fields_total Number of bound fields updated by dynamic property refreshes.
+# TYPE gs_property_refresh_fields_total counter
+gs_property_refresh_fields_total 4
+# HELP gs_property_refresh_dropped_total Number of listener notifications dropped by dynamic property refreshes.
+# TYPE gs_property_refresh_dropped_total counter
+gs_property_refresh_dropped_total 1
+# HELP gs_property_refresh_duration_seconds Distribution of dynamic property refresh durations.
+# TYPE gs_property_refresh_duration_seconds histogram
+gs_property_refresh_duration_seconds_bucket{le="0.01"} 1
+gs_property_refresh_duration_seconds_bucket{le="1"} 2
+gs_property_refresh_duration_seconds_bucket{le="+Inf"} 2
+gs_property_refresh_duration_seconds_sum 0.25
+gs_property_refresh_duration_seconds_count 2
+# HELP gs_goroutines_started_total Number of goroutines started via Go.
+# TYPE gs_goroutines_started_total counter
+gs_goroutines_started_total 5
+# HELP gs_goroutines Number of running goroutines started via Go.
+# TYPE gs_goroutines gauge
+gs_goroutines 2
+`)
+}
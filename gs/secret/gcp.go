@@ -0,0 +1,149 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPConfig GCP Secret Manager 的配置，AccessToken 为空时从 GCE/GKE 的
+// metadata 服务获取访问令牌。
+type GCPConfig struct {
+	Project     string `value:"${project:=}"`
+	Endpoint    string `value:"${endpoint:=https://secretmanager.googleapis.com}"`
+	AccessToken string `value:"${access-token:=}"`
+	TokenURL    string `value:"${token-url:=}"` // 为空时使用 metadata 服务的地址
+}
+
+type gcpClient struct {
+	config GCPConfig
+	client *http.Client
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewGCPClient 创建 GCP Secret Manager 的客户端。
+func NewGCPClient(config GCPConfig) (Client, error) {
+	if config.Project == "" {
+		config.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if config.Project == "" {
+		return nil, errors.New("gcp project should be set")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://secretmanager.googleapis.com"
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = gcpMetadataTokenURL
+	}
+	return &gcpClient{config: config, client: http.DefaultClient}, nil
+}
+
+func (c *gcpClient) GetSecret(ctx context.Context, name string) (string, error) {
+
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	path := fmt.Sprintf("/v1/projects/%s/secrets/%s/versions/latest:access",
+		url.PathEscape(c.config.Project), url.PathEscape(name))
+	var r struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	header := map[string]string{"Authorization": "Bearer " + token}
+	if err = c.get(ctx, c.config.Endpoint+path, header, &r); err != nil {
+		return "", err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(r.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// getToken 返回访问令牌，从 metadata 服务获取的令牌在过期前会被缓存。
+func (c *gcpClient) getToken(ctx context.Context) (string, error) {
+
+	if c.config.AccessToken != "" {
+		return c.config.AccessToken, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+
+	var r struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	header := map[string]string{"Metadata-Flavor": "Google"}
+	if err := c.get(ctx, c.config.TokenURL, header, &r); err != nil {
+		return "", err
+	}
+
+	c.token = r.AccessToken
+	c.expiry = time.Now().Add(time.Duration(r.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+func (c *gcpClient) get(ctx context.Context, rawURL string, header map[string]string, i interface{}) error {
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, b)
+	}
+	return json.Unmarshal(b, i)
+}
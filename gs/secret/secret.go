@@ -0,0 +1,153 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package secret 提供从 AWS Secrets Manager、GCP Secret Manager 等云厂商的
+// 密钥管理服务中加载属性的 gs.ConfigSource ，和其他配置来源一样，加载的属性优先级
+// 高于配置文件，低于环境变量和命令行参数。可以通过 bootstrap 注册，这时配置来源的
+// 名称就是 bean 的名称:
+//
+//	gs.Bootstrap().Provide(secret.NewAWSClient, "${spring.secrets.aws}")
+//	gs.Bootstrap().Provide(secret.NewSource, "", "${spring.secrets}").Name("secret").Export((*gs.ConfigSource)(nil))
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/clock"
+	"github.com/go-spring/spring-core/conf"
+)
+
+// Client 从密钥管理服务中获取名为 name 的密钥的最新版本。
+type Client interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// Config 密钥属性的配置。
+type Config struct {
+	Names   []string      `value:"${names:=}"`      // 需要加载的密钥名称
+	Prefix  string        `value:"${prefix:=}"`     // 属性名的前缀
+	Timeout time.Duration `value:"${timeout:=10s}"` // 每次加载的超时时间
+	Refresh time.Duration `value:"${refresh:=0s}"`  // 定时刷新的间隔，为 0 时不刷新
+}
+
+// Source 将密钥转换成属性的 gs.ConfigSource 。如果密钥的值是 JSON 对象，那么
+// 对象的每个字段都会转换成一个属性，否则密钥名称(将 / 替换为 .)就是属性名。
+type Source struct {
+	Clock  clock.Clock `autowire:"?"` // 定时刷新使用的时钟，为空时使用系统时间
+	client Client
+	config Config
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewSource 创建将密钥转换成属性的 gs.ConfigSource 。
+func NewSource(client Client, config Config) *Source {
+	return &Source{client: client, config: config, stop: make(chan struct{})}
+}
+
+// Load 加载所有的密钥，任何一个密钥加载失败都会返回错误。
+func (s *Source) Load() (*conf.Properties, error) {
+	m, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	p := conf.New()
+	for k, v := range m {
+		if err = p.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Watch 按照 Config.Refresh 的间隔重新加载密钥并发送到 ch 中，应用通过
+// RefreshProperties 刷新动态属性。加载失败时记录错误日志并保留原有的属性值，
+// 直到调用 Close 方法。
+func (s *Source) Watch(ch chan<- *conf.Properties) {
+	if s.config.Refresh <= 0 || len(s.config.Names) == 0 {
+		return
+	}
+	logger := log.GetLogger(util.TypeName(s))
+	ticker := clock.Or(s.Clock).NewTicker(s.config.Refresh)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C():
+				p, err := s.Load()
+				if err != nil {
+					logger.Errorf("reload secrets error: %v", err)
+					continue
+				}
+				select {
+				case ch <- p:
+				case <-s.stop:
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Close 停止定时刷新。
+func (s *Source) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+// load 加载所有的密钥并转换成属性。
+func (s *Source) load() (map[string]interface{}, error) {
+
+	ctx := context.Background()
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	ret := make(map[string]interface{})
+	for _, name := range s.config.Names {
+		value, err := s.client.GetSecret(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get secret %q error: %w", name, err)
+		}
+		var obj map[string]interface{}
+		if err = json.Unmarshal([]byte(value), &obj); err == nil {
+			for k, v := range obj {
+				ret[s.key(k)] = v
+			}
+			continue
+		}
+		ret[s.key(strings.ReplaceAll(name, "/", "."))] = value
+	}
+	return ret, nil
+}
+
+func (s *Source) key(k string) string {
+	if s.config.Prefix == "" {
+		return k
+	}
+	return s.config.Prefix + "." + k
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-core/conf"
+	"github.com/go-spring/spring-core/gs/secret"
+)
+
+func init() {
+	err := log.Refresh("../testdata/config/logger.xml")
+	if err != nil {
+		panic(err)
+	}
+}
+
+type mapClient map[string]string
+
+func (c mapClient) GetSecret(ctx context.Context, name string) (string, error) {
+	v, ok := c[name]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+type funcClient func(name string) (string, error)
+
+func (f funcClient) GetSecret(ctx context.Context, name string) (string, error) {
+	return f(name)
+}
+
+func TestSource(t *testing.T) {
+
+	client := mapClient{
+		"db":        `{"db":{"user":"root","password":"123456"}}`,
+		"app/token": "abc",
+	}
+	s := secret.NewSource(client, secret.Config{Names: []string{"db", "app/token"}, Prefix: "secret"})
+
+	p, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, p.Keys(), []string{"secret.app.token", "secret.db.password", "secret.db.user"})
+	assert.Equal(t, p.Get("secret.db.user"), "root")
+
+	s = secret.NewSource(client, secret.Config{Names: []string{"db", "other"}})
+	_, err = s.Load()
+	assert.Error(t, err, "get secret \"other\" error: not found")
+}
+
+func TestSource_Watch(t *testing.T) {
+
+	var ready int32
+	client := funcClient(func(name string) (string, error) {
+		if atomic.LoadInt32(&ready) == 0 {
+			return "", errors.New("not ready")
+		}
+		return "abc", nil
+	})
+	s := secret.NewSource(client, secret.Config{Names: []string{"token"}, Refresh: 10 * time.Millisecond})
+	ch := make(chan *conf.Properties)
+	s.Watch(ch)
+	defer s.Close()
+
+	// 加载失败时不会发送属性。
+	select {
+	case <-ch:
+		t.Fatal("should not send properties")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	atomic.StoreInt32(&ready, 1)
+	select {
+	case p := <-ch:
+		assert.Equal(t, p.Get("token"), "abc")
+	case <-time.After(time.Second):
+		t.Fatal("should send properties")
+	}
+}
+
+func TestAWSClient(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("X-Amz-Target"), "secretsmanager.GetSecretValue")
+		auth := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.True(t, strings.Contains(auth, "/us-east-1/secretsmanager/aws4_request"))
+		var req struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": "value-of-" + req.SecretId})
+	}))
+	defer server.Close()
+
+	c, err := secret.NewAWSClient(secret.AWSConfig{
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+	})
+	assert.Nil(t, err)
+
+	s, err := c.GetSecret(context.Background(), "db")
+	assert.Nil(t, err)
+	assert.Equal(t, s, "value-of-db")
+}
+
+func TestGCPClient(t *testing.T) {
+
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+			assert.Equal(t, r.Header.Get("Metadata-Flavor"), "Google")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "tk", "expires_in": 3600})
+			return
+		}
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer tk")
+		assert.Equal(t, r.URL.Path, "/v1/projects/demo/secrets/db/versions/latest:access")
+		data := base64.StdEncoding.EncodeToString([]byte("123456"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"payload": map[string]string{"data": data}})
+	}))
+	defer server.Close()
+
+	c, err := secret.NewGCPClient(secret.GCPConfig{
+		Project:  "demo",
+		Endpoint: server.URL,
+		TokenURL: server.URL + "/token",
+	})
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		s, err := c.GetSecret(context.Background(), "db")
+		assert.Nil(t, err)
+		assert.Equal(t, s, "123456")
+	}
+	assert.Equal(t, tokens, 1)
+}
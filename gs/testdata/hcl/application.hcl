@@ -0,0 +1,4 @@
+server {
+  name = "hcl"
+  port = 8080
+}
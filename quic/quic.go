@@ -0,0 +1,193 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package quic provides the UDP and QUIC servers that live in the lifecycle of
+// the application. The QUIC server is usually implemented by a starter that
+// wraps quic-go, so that this module doesn't depend on any QUIC implementation,
+// and a plain UDP server is built in for the simple realtime services.
+package quic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Start after Stop is called.
+var ErrServerClosed = errors.New("quic: server closed")
+
+// ServerConfig is the configuration of the UDP or QUIC server.
+type ServerConfig struct {
+	Port         int           `value:"${port:=4433}"`
+	Addr         string        `value:"${addr:=}"`             // overrides Port when set, such as 127.0.0.1:4433
+	CertFile     string        `value:"${cert-file:=}"`        // used by QUIC servers
+	KeyFile      string        `value:"${key-file:=}"`         // used by QUIC servers
+	BufferSize   int           `value:"${buffer-size:=65535}"` // max size of a UDP packet
+	DrainTimeout time.Duration `value:"${drain-timeout:=10s}"` // max time to wait for the connections to drain
+}
+
+// Container is a UDP or QUIC server, the QUIC implementation should accept
+// connections until Stop is called, then stop accepting and wait for the
+// active connections to drain.
+type Container interface {
+
+	// Start listens on addr and serves until Stop is called.
+	Start(addr string) error
+
+	// Stop stops the server gracefully, the active connections are closed
+	// when ctx is done.
+	Stop(ctx context.Context) error
+}
+
+// Packet is a UDP packet received by the server.
+type Packet struct {
+	Addr net.Addr // address of the sender
+	Data []byte   // payload of the packet
+	conn net.PacketConn
+}
+
+// Reply sends b back to the sender of the packet.
+func (p *Packet) Reply(b []byte) error {
+	_, err := p.conn.WriteTo(b, p.Addr)
+	return err
+}
+
+// PacketHandler handles the UDP packets, every packet is handled in its own
+// goroutine, ctx is canceled when the server stops draining.
+type PacketHandler interface {
+	ServePacket(ctx context.Context, p *Packet)
+}
+
+// UDPServer is the built-in UDP server.
+type UDPServer struct {
+	config  ServerConfig
+	handler PacketHandler
+
+	mutex  sync.Mutex
+	conn   net.PacketConn
+	closed bool
+	done   chan struct{} // closed when the read loop exits
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewUDPServer returns a UDP server that dispatches packets to handler.
+func NewUDPServer(config ServerConfig, handler PacketHandler) *UDPServer {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 65535
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &UDPServer{config: config, handler: handler, ctx: ctx, cancel: cancel}
+}
+
+// Addr returns the listening address, it's nil before Start is called.
+func (s *UDPServer) Addr() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// Start listens on addr and serves until Stop is called, it returns
+// ErrServerClosed after Stop is called.
+func (s *UDPServer) Start(addr string) error {
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		_ = conn.Close()
+		return ErrServerClosed
+	}
+	s.conn = conn
+	s.done = make(chan struct{})
+	s.mutex.Unlock()
+
+	defer close(s.done)
+	buf := make([]byte, s.config.BufferSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var e net.Error
+			if errors.As(err, &e) && e.Temporary() {
+				continue
+			}
+			return err
+		}
+		p := &Packet{Addr: from, Data: append([]byte(nil), buf[:n]...), conn: conn}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handler.ServePacket(s.ctx, p)
+		}()
+	}
+}
+
+func (s *UDPServer) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+// Stop stops reading packets and waits for the handling ones to finish, the
+// context of handlers is canceled and the connection is closed when ctx is
+// done.
+func (s *UDPServer) Stop(ctx context.Context) error {
+
+	s.mutex.Lock()
+	s.closed = true
+	conn, done := s.conn, s.done
+	s.mutex.Unlock()
+
+	defer s.cancel()
+	if conn == nil {
+		return nil
+	}
+
+	// wakes up the blocking read, no more handlers after the read loop exits.
+	_ = conn.SetReadDeadline(time.Now())
+	<-done
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if e := conn.Close(); err == nil {
+		err = e
+	}
+	return err
+}
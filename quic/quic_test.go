@@ -0,0 +1,121 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package quic_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/quic"
+)
+
+type echoHandler struct {
+	delay time.Duration
+	done  chan struct{}
+}
+
+func (h *echoHandler) ServePacket(ctx context.Context, p *quic.Packet) {
+	select {
+	case <-time.After(h.delay):
+	case <-ctx.Done():
+	}
+	_ = p.Reply(p.Data)
+	if h.done != nil {
+		h.done <- struct{}{}
+	}
+}
+
+func startServer(t *testing.T, h quic.PacketHandler) (*quic.UDPServer, chan error) {
+	s := quic.NewUDPServer(quic.ServerConfig{}, h)
+	errs := make(chan error, 1)
+	go func() { errs <- s.Start("127.0.0.1:0") }()
+	for i := 0; i < 100 && s.Addr() == nil; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NotNil(t, s.Addr())
+	return s, errs
+}
+
+func TestUDPServer(t *testing.T) {
+
+	s, errs := startServer(t, &echoHandler{})
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	assert.Nil(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, string(buf[:n]), "ping")
+
+	assert.Nil(t, s.Stop(context.Background()))
+	assert.Equal(t, <-errs, quic.ErrServerClosed)
+}
+
+func TestUDPServer_Drain(t *testing.T) {
+
+	t.Run("drained", func(t *testing.T) {
+		h := &echoHandler{delay: 50 * time.Millisecond, done: make(chan struct{}, 1)}
+		s, errs := startServer(t, h)
+
+		conn, err := net.Dial("udp", s.Addr().String())
+		assert.Nil(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("ping"))
+		assert.Nil(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Nil(t, s.Stop(context.Background()))
+		assert.Equal(t, <-errs, quic.ErrServerClosed)
+
+		select {
+		case <-h.done:
+		default:
+			t.Fatal("handler should finish before Stop returns")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		h := &echoHandler{delay: time.Minute, done: make(chan struct{}, 1)}
+		s, errs := startServer(t, h)
+
+		conn, err := net.Dial("udp", s.Addr().String())
+		assert.Nil(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("ping"))
+		assert.Nil(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, s.Stop(ctx), context.DeadlineExceeded)
+		assert.Equal(t, <-errs, quic.ErrServerClosed)
+
+		select {
+		case <-h.done:
+		case <-time.After(time.Second):
+			t.Fatal("handler should be canceled")
+		}
+	})
+}
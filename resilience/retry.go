@@ -0,0 +1,187 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resilience provides retry utilities driven by configuration, so that
+// services share consistent retry policies.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-spring/spring-core/clock"
+)
+
+// RetryConfig is the configuration of a retry policy, it's usually bound to
+// properties with the prefix spring.resilience.retry.<name>.
+type RetryConfig struct {
+	Attempts   int           `value:"${attempts:=3}"`      // max attempts, including the first call
+	Backoff    time.Duration `value:"${backoff:=100ms}"`   // wait time before the first retry
+	MaxBackoff time.Duration `value:"${max-backoff:=10s}"` // upper limit of the wait time
+	Multiplier float64       `value:"${multiplier:=2}"`    // growth factor of the wait time
+	Jitter     float64       `value:"${jitter:=0}"`        // random factor of the wait time, in [0,1]
+	RetryOn    []string      `value:"${retry-on:=}"`       // regexps matching retryable error messages
+}
+
+// Policy executes functions with retries.
+type Policy struct {
+	name     string
+	config   RetryConfig
+	retryOn  []*regexp.Regexp
+	registry *Registry
+}
+
+// NewPolicy returns a Policy, all errors are retryable if RetryOn is empty.
+func NewPolicy(name string, config RetryConfig) (*Policy, error) {
+	if config.Attempts <= 0 {
+		return nil, fmt.Errorf("retry policy %q: attempts should be positive", name)
+	}
+	if config.Jitter < 0 || config.Jitter > 1 {
+		return nil, fmt.Errorf("retry policy %q: jitter should be in [0,1]", name)
+	}
+	if config.Multiplier < 1 {
+		config.Multiplier = 1
+	}
+	p := &Policy{name: name, config: config}
+	for _, s := range config.RetryOn {
+		r, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("retry policy %q: %w", name, err)
+		}
+		p.retryOn = append(p.retryOn, r)
+	}
+	return p, nil
+}
+
+// Name returns the name of the policy.
+func (p *Policy) Name() string {
+	return p.name
+}
+
+// Execute calls fn until it succeeds, returns a non-retryable error, or the
+// attempts run out. It stops waiting and returns the last error when ctx is
+// done.
+func (p *Policy) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.config.Attempts || !p.Retryable(err) {
+			return err
+		}
+		if p.clock().Sleep(ctx, p.backoff(attempt)) != nil {
+			return err
+		}
+	}
+}
+
+// clock returns the Clock of the Registry, or the real Clock if the policy
+// isn't created by a Registry.
+func (p *Policy) clock() clock.Clock {
+	if p.registry == nil {
+		return clock.Real()
+	}
+	return clock.Or(p.registry.Clock)
+}
+
+// Retryable returns whether err should be retried by the policy.
+func (p *Policy) Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if len(p.retryOn) == 0 {
+		return true
+	}
+	for _, r := range p.retryOn {
+		if r.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait time after the attempt.
+func (p *Policy) backoff(attempt int) time.Duration {
+	d := float64(p.config.Backoff)
+	for i := 1; i < attempt; i++ {
+		d *= p.config.Multiplier
+		if p.config.MaxBackoff > 0 && d > float64(p.config.MaxBackoff) {
+			d = float64(p.config.MaxBackoff)
+			break
+		}
+	}
+	if p.config.Jitter > 0 {
+		d *= 1 + p.config.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// Registry holds the named retry policies.
+type Registry struct {
+	Clock    clock.Clock `autowire:"?"` // waits the backoff, real time if nil
+	policies map[string]*Policy
+}
+
+// NewRegistry returns a Registry, it's registered as a bean and bound to the
+// properties with the prefix spring.resilience.retry, for example:
+//
+//	spring.resilience.retry.payment.attempts=5
+//	spring.resilience.retry.payment.backoff=200ms
+//	spring.resilience.retry.payment.retry-on=timeout,connection refused
+func NewRegistry(configs map[string]RetryConfig) (*Registry, error) {
+	r := &Registry{policies: make(map[string]*Policy)}
+	for name, config := range configs {
+		p, err := NewPolicy(name, config)
+		if err != nil {
+			return nil, err
+		}
+		p.registry = r
+		r.policies[name] = p
+	}
+	return r, nil
+}
+
+// Policy returns the policy named name, it returns an error if not found.
+func (r *Registry) Policy(name string) (*Policy, error) {
+	p, ok := r.policies[name]
+	if !ok {
+		return nil, fmt.Errorf("retry policy %q not found", name)
+	}
+	return p, nil
+}
+
+// Names returns the sorted names of all policies.
+func (r *Registry) Names() []string {
+	var names []string
+	for name := range r.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PolicyOf returns a constructor that gets the policy named name from the
+// Registry, it can be used to register a Policy bean, for example:
+//
+//	gs.Provide(resilience.PolicyOf("payment")).Name("payment")
+func PolicyOf(name string) func(r *Registry) (*Policy, error) {
+	return func(r *Registry) (*Policy, error) {
+		return r.Policy(name)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/clock"
+	"github.com/go-spring/spring-core/resilience"
+)
+
+func TestPolicy_Execute(t *testing.T) {
+
+	config := resilience.RetryConfig{
+		Attempts:   3,
+		Backoff:    time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		Multiplier: 2,
+	}
+
+	t.Run("success after retry", func(t *testing.T) {
+		p, err := resilience.NewPolicy("test", config)
+		assert.Nil(t, err)
+		count := 0
+		err = p.Execute(context.Background(), func(ctx context.Context) error {
+			if count++; count < 3 {
+				return errors.New("timeout")
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, count, 3)
+	})
+
+	t.Run("attempts run out", func(t *testing.T) {
+		p, err := resilience.NewPolicy("test", config)
+		assert.Nil(t, err)
+		count := 0
+		err = p.Execute(context.Background(), func(ctx context.Context) error {
+			count++
+			return errors.New("timeout")
+		})
+		assert.Error(t, err, "timeout")
+		assert.Equal(t, count, 3)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		c := config
+		c.RetryOn = []string{"timeout", "connection refused"}
+		p, err := resilience.NewPolicy("test", c)
+		assert.Nil(t, err)
+		count := 0
+		err = p.Execute(context.Background(), func(ctx context.Context) error {
+			count++
+			return errors.New("bad request")
+		})
+		assert.Error(t, err, "bad request")
+		assert.Equal(t, count, 1)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		c := config
+		c.Backoff = time.Hour
+		p, err := resilience.NewPolicy("test", c)
+		assert.Nil(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		count := 0
+		err = p.Execute(ctx, func(ctx context.Context) error {
+			count++
+			return errors.New("timeout")
+		})
+		assert.Error(t, err, "timeout")
+		assert.Equal(t, count, 1)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+
+	_, err := resilience.NewRegistry(map[string]resilience.RetryConfig{
+		"bad": {Attempts: 0},
+	})
+	assert.Error(t, err, "retry policy \"bad\": attempts should be positive")
+
+	r, err := resilience.NewRegistry(map[string]resilience.RetryConfig{
+		"payment": {Attempts: 5},
+		"order":   {Attempts: 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, r.Names(), []string{"order", "payment"})
+
+	p, err := resilience.PolicyOf("payment")(r)
+	assert.Nil(t, err)
+	assert.Equal(t, p.Name(), "payment")
+
+	_, err = r.Policy("unknown")
+	assert.Error(t, err, "retry policy \"unknown\" not found")
+}
+
+func TestRegistry_Clock(t *testing.T) {
+
+	r, err := resilience.NewRegistry(map[string]resilience.RetryConfig{
+		"payment": {Attempts: 2, Backoff: time.Hour, Multiplier: 1},
+	})
+	assert.Nil(t, err)
+	f := clock.NewFake(time.Now())
+	r.Clock = f
+
+	p, err := r.Policy("payment")
+	assert.Nil(t, err)
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Execute(context.Background(), func(ctx context.Context) error {
+			calls++
+			return errors.New("timeout")
+		})
+	}()
+	for f.Sleepers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	f.Advance(time.Hour)
+	assert.Error(t, <-done, "timeout")
+	assert.Equal(t, calls, 2)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// httpServerHandler 把实现了 http.Handler 的第三方路由适配成 ServerHandler，
+// 路由规则由第三方路由自己维护，无法向其注册通过 Router 添加的 Mapper 。
+type httpServerHandler struct {
+	http.Handler
+}
+
+// HttpServerHandler 将 gin.Engine、echo.Echo 等实现了 http.Handler 接口的
+// 第三方路由适配成 ServerHandler，fasthttp 系的路由(如 fiber)需要先转换成
+// http.Handler 才能使用。路由需要直接注册到第三方路由上，服务器上存在 Mapper
+// (比如通过 gs 注册的 Router 端点) 时启动失败，避免这些路由被静默丢弃。
+func HttpServerHandler(h http.Handler) ServerHandler {
+	return &httpServerHandler{Handler: h}
+}
+
+func (h *httpServerHandler) Start(s Server) error {
+	if mappers := s.Mappers(); len(mappers) > 0 {
+		m := mappers[0]
+		return fmt.Errorf("%T can't serve mappers, register %v %s on it directly", h.Handler, GetMethod(m.Method()), m.Path())
+	}
+	return nil
+}
+
+func (h *httpServerHandler) RecoveryFilter(errHandler ErrorHandler) Filter {
+	return FuncFilter(func(ctx Context, chain FilterChain) {
+		defer func() {
+			if r := recover(); r != nil {
+				ctx.SetStatus(http.StatusInternalServerError)
+				httpE := NewHttpError(http.StatusInternalServerError)
+				httpE.Internal = r
+				errHandler.Invoke(ctx, httpE)
+			}
+		}()
+		chain.Next(ctx, Recursive)
+	})
+}
+
+// NewHttpServer 创建以第三方路由作为处理器的 web 服务器，监听地址等配置通过
+// ServerConfig 指定，服务器的优雅关闭由 Stop 方法完成，例如:
+//
+//	gs.Provide(func(config web.ServerConfig) web.Server {
+//		return web.NewHttpServer(config, gin.New())
+//	}, "${web.server}")
+func NewHttpServer(config ServerConfig, h http.Handler) Server {
+	return NewServer(config, HttpServerHandler(h))
+}
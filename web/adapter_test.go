@@ -0,0 +1,58 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/web"
+)
+
+func TestHttpServer(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	})
+
+	s := web.NewHttpServer(web.ServerConfig{Port: 8080}, mux)
+	h, ok := s.(http.Handler)
+	assert.True(t, ok)
+
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/hello", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Body.String(), "hello")
+
+	r, _ = http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/panic", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestHttpServer_Mappers(t *testing.T) {
+	s := web.NewHttpServer(web.ServerConfig{Host: "127.0.0.1", Port: 18087}, http.NewServeMux())
+	s.HttpGet("/hello", func(w http.ResponseWriter, r *http.Request) {})
+	err := s.Start()
+	assert.Error(t, err, "\\*http.ServeMux can't serve mappers, register \\[GET\\] /hello on it directly")
+}
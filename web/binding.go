@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/go-spring/spring-core/conf"
 	"github.com/go-spring/spring-core/validate"
 )
 
@@ -77,7 +78,13 @@ func Bind(i interface{}, ctx Context) error {
 	if err := bindBody(i, ctx); err != nil {
 		return err
 	}
-	return validate.Struct(i)
+	if err := validate.Struct(i); err != nil {
+		return err
+	}
+	if validationEnabled(ctx) {
+		return conf.ValidateStruct(i)
+	}
+	return nil
 }
 
 func bindBody(i interface{}, ctx Context) error {
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// Codec 请求和响应的编解码器，可以通过 RegisterCodec 接入 protobuf 、msgpack 等格式。
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                     { return MIMEApplicationJSON }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error { return json.Unmarshal(b, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string                     { return MIMEApplicationXML }
+func (xmlCodec) Marshal(v interface{}) ([]byte, error)   { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(b []byte, v interface{}) error { return xml.Unmarshal(b, v) }
+
+var (
+	codecMutex sync.RWMutex
+	codecs     = map[string]Codec{
+		MIMEApplicationJSON: jsonCodec{},
+		MIMEApplicationXML:  xmlCodec{},
+	}
+)
+
+// RegisterCodec 注册编解码器，同时注册 Content-Type 对应的请求体绑定函数。
+func RegisterCodec(c Codec) {
+	codecMutex.Lock()
+	codecs[c.ContentType()] = c
+	codecMutex.Unlock()
+	RegisterBodyBinder(c.ContentType(), func(i interface{}, ctx Context) error {
+		body, err := ctx.RequestBody()
+		if err != nil {
+			return err
+		}
+		return c.Unmarshal(body, i)
+	})
+}
+
+// GetCodec 返回 Content-Type 对应的编解码器。
+func GetCodec(contentType string) (Codec, bool) {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = t
+	}
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+	c, ok := codecs[contentType]
+	return c, ok
+}
+
+// CodecConfig 定义响应的编码方式，一般绑定到 spring.http.codec 前缀的属性。
+type CodecConfig struct {
+	Default   string `value:"${default:=application/json}"` // 默认的 Content-Type
+	Negotiate bool   `value:"${negotiate:=true}"`           // 是否根据 Accept 头选择编解码器
+	Envelope  bool   `value:"${envelope:=false}"`           // 是否使用 RpcResult 包装成功的返回值
+}
+
+// CodecInvoker 使用编解码器输出 BIND 形式处理函数的返回值，返回值是 error 或者
+// 处理函数抛出 error 时统一输出 RpcResult 格式的错误。
+type CodecInvoker struct {
+	config CodecConfig
+	codec  Codec
+}
+
+// NewCodecInvoker 创建 CodecInvoker ，可以通过 InvokerFilter(invoker.Invoke) 替换
+// 服务器默认的 JSON 输出方式。
+func NewCodecInvoker(config CodecConfig) (*CodecInvoker, error) {
+	if config.Default == "" {
+		config.Default = MIMEApplicationJSON
+	}
+	c, ok := GetCodec(config.Default)
+	if !ok {
+		return nil, fmt.Errorf("codec %q not found", config.Default)
+	}
+	return &CodecInvoker{config: config, codec: c}, nil
+}
+
+// Invoke 执行处理函数并使用编解码器输出返回值。
+func (invoker *CodecInvoker) Invoke(ctx Context, fn func(Context) interface{}) {
+	c := invoker.negotiate(ctx)
+	result, err := invoker.call(ctx, fn)
+	var e *conf.ValidationError
+	if errors.As(err, &e) {
+		ctx.SetStatus(http.StatusBadRequest)
+		result = ValidationResult(e)
+	} else if err != nil {
+		ctx.SetStatus(http.StatusInternalServerError)
+		result = &RpcResult{ErrorCode: ErrorCode(ERROR), Err: err.Error()}
+	} else if _, ok := result.(*RpcResult); !ok && invoker.config.Envelope {
+		result = SUCCESS.Data(result)
+	}
+	b, err := c.Marshal(result)
+	if err != nil {
+		ctx.SetStatus(http.StatusInternalServerError)
+		ctx.String("%s", err.Error())
+		return
+	}
+	ctx.Blob(c.ContentType(), b)
+}
+
+// call 执行处理函数，处理函数返回或者抛出 error 时转换为 error 返回，抛出
+// *RpcResult 时作为返回值，其他的异常继续抛出。
+func (invoker *CodecInvoker) call(ctx Context, fn func(Context) interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch v := r.(type) {
+			case *RpcResult:
+				result = v
+			case error:
+				err = v
+			default:
+				panic(r)
+			}
+		}
+	}()
+	result = fn(ctx)
+	if e, ok := result.(error); ok {
+		return nil, e
+	}
+	return result, nil
+}
+
+// negotiate 根据 Accept 头选择编解码器，找不到时使用默认的编解码器。
+func (invoker *CodecInvoker) negotiate(ctx Context) Codec {
+	if !invoker.config.Negotiate {
+		return invoker.codec
+	}
+	for _, s := range strings.Split(ctx.Header(HeaderAccept), ",") {
+		if c, ok := GetCodec(strings.TrimSpace(s)); ok {
+			return c
+		}
+	}
+	return invoker.codec
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/web"
+)
+
+type textCodec struct{}
+
+func (textCodec) ContentType() string { return web.MIMETextPlain }
+
+func (textCodec) Marshal(v interface{}) ([]byte, error) {
+	if r, ok := v.(*web.RpcResult); ok {
+		return []byte(r.Msg), nil
+	}
+	return []byte(v.(string)), nil
+}
+
+func (textCodec) Unmarshal(b []byte, v interface{}) error {
+	*(v.(*string)) = string(b)
+	return nil
+}
+
+func invokeCodec(invoker *web.CodecInvoker, accept string, fn func(web.Context) interface{}) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/", nil)
+	r.Header.Set(web.HeaderAccept, accept)
+	w := httptest.NewRecorder()
+	ctx := web.NewBaseContext("/", nil, r, &web.SimpleResponse{ResponseWriter: w})
+	invoker.Invoke(ctx, fn)
+	return w
+}
+
+func TestCodecInvoker(t *testing.T) {
+
+	_, err := web.NewCodecInvoker(web.CodecConfig{Default: "application/yaml"})
+	assert.Error(t, err, "codec \"application/yaml\" not found")
+
+	web.RegisterCodec(textCodec{})
+	c, ok := web.GetCodec("text/plain; charset=UTF-8")
+	assert.True(t, ok)
+	assert.Equal(t, c.ContentType(), web.MIMETextPlain)
+
+	invoker, err := web.NewCodecInvoker(web.CodecConfig{Negotiate: true, Envelope: true})
+	assert.Nil(t, err)
+
+	t.Run("json", func(t *testing.T) {
+		w := invokeCodec(invoker, "", func(web.Context) interface{} { return "ok" })
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), `{"code":200,"msg":"SUCCESS","data":"ok"}`)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		w := invokeCodec(invoker, "", func(web.Context) interface{} { return errors.New("bad request") })
+		assert.Equal(t, w.Code, http.StatusInternalServerError)
+		assert.Equal(t, w.Body.String(), `{"code":-1,"msg":"ERROR","err":"bad request"}`)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		w := invokeCodec(invoker, "", func(web.Context) interface{} { panic(errors.New("bad request")) })
+		assert.Equal(t, w.Code, http.StatusInternalServerError)
+		assert.True(t, strings.Contains(w.Body.String(), `"err":"bad request"`))
+	})
+
+	t.Run("negotiate", func(t *testing.T) {
+		w := invokeCodec(invoker, "text/html, text/plain", func(web.Context) interface{} { return "ok" })
+		assert.Equal(t, w.Header().Get(web.HeaderContentType), web.MIMETextPlain)
+		assert.Equal(t, w.Body.String(), "SUCCESS")
+	})
+}
+
+func TestInvokerFilter(t *testing.T) {
+
+	h := web.BIND(func(ctx context.Context, req *struct{}) interface{} { return "ok" })
+	invoke := func(filters ...web.Filter) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/", nil)
+		w := httptest.NewRecorder()
+		ctx := web.NewBaseContext("/", h, r, &web.SimpleResponse{ResponseWriter: w})
+		next := web.FuncFilter(func(ctx web.Context, chain web.FilterChain) { h.Invoke(ctx) })
+		web.NewFilterChain(append(filters, next)).Next(ctx, web.Recursive)
+		return w
+	}
+
+	w := invoke()
+	assert.Equal(t, strings.TrimSpace(w.Body.String()), `"ok"`)
+
+	w = invoke(web.InvokerFilter(func(ctx web.Context, fn func(web.Context) interface{}) {
+		ctx.String("custom %v", fn(ctx))
+	}))
+	assert.Equal(t, w.Body.String(), "custom ok")
+
+	// 没有设置执行函数的请求仍然使用全局的 RPCInvoke 。
+	w = invoke()
+	assert.Equal(t, strings.TrimSpace(w.Body.String()), `"ok"`)
+}
@@ -0,0 +1,439 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+const (
+	bearerPrefix = "Bearer "
+	PrincipalKey = "::principal::"
+)
+
+// defaultJWKSInterval 两次拉取 JWKS 之间的默认最小间隔。
+const defaultJWKSInterval = time.Minute
+
+// JWTAuthConfig 定义 JWT/OIDC 认证配置，一般绑定到 spring.http.auth 前缀的属性。
+type JWTAuthConfig struct {
+	Issuer   string            `value:"${issuer:=}"`      // 期望的签发者，为空时不校验
+	Audience string            `value:"${audience:=}"`    // 期望的受众，为空时不校验
+	JWKSURL  string            `value:"${jwks-url:=}"`    // 获取公钥的 JWKS 地址
+	Secret   string            `value:"${secret:=}"`      // HS256 等对称算法的秘钥
+	Paths    []string          `value:"${paths:=/*}"`     // 需要认证的路径
+	Claims   map[string]string `value:"${claims:=}"`      // Principal 属性名到 claim 名的映射
+	Leeway   int               `value:"${leeway:=60}"`    // 校验时间时允许的误差，秒
+	Timeout  int               `value:"${timeout:=5000}"` // 获取 JWKS 的超时时间，毫秒
+
+	// 两次拉取 JWKS 之间的最小间隔，秒，不大于 0 时使用默认值 60 秒。在这个间隔内
+	// 遇到未知的 kid 时直接拒绝，不会重新拉取 JWKS 。
+	JWKSInterval int `value:"${jwks-interval:=60}"`
+
+	// 是否接受没有 exp claim 的 token ，默认拒绝。
+	AllowMissingExp bool `value:"${allow-missing-exp:=false}"`
+}
+
+// Principal 认证通过的用户信息。
+type Principal struct {
+	Subject    string                 // sub claim
+	Claims     map[string]interface{} // 所有的 claim
+	Attributes map[string]interface{} // 通过 Claims 映射得到的属性
+}
+
+type principalKey struct{}
+
+// PrincipalFrom 从 context.Context 中获取认证通过的用户信息。
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// jwtAuthFilter 校验 Bearer token 的过滤器。
+type jwtAuthFilter struct {
+	config   JWTAuthConfig
+	client   *http.Client
+	interval time.Duration
+	mutex    sync.RWMutex
+	keys     map[string]crypto.PublicKey
+	fetched  time.Time  // 最近一次拉取 JWKS 的时间
+	fetching sync.Mutex // 保证同一时间只有一个请求在拉取 JWKS
+}
+
+// NewJWTAuthFilter 创建校验 Bearer token 的过滤器，认证通过后 Principal 会被保存
+// 到 Context 的 PrincipalKey 中，同时也可以通过 PrincipalFrom 从 ctx.Context()
+// 中获取。支持 RS256、RS384、RS512、ES256、ES384、ES512 以及 HS256 等签名算法。
+func NewJWTAuthFilter(config JWTAuthConfig) (Filter, error) {
+	if config.JWKSURL == "" && config.Secret == "" {
+		return nil, errors.New("jwks-url or secret should be set")
+	}
+	if len(config.Paths) == 0 {
+		config.Paths = []string{"/*"}
+	}
+	f := &jwtAuthFilter{
+		config:   config,
+		client:   &http.Client{Timeout: time.Duration(config.Timeout) * time.Millisecond},
+		interval: time.Duration(config.JWKSInterval) * time.Second,
+	}
+	if f.interval <= 0 {
+		f.interval = defaultJWKSInterval
+	}
+	return URLPatternFilter(f, config.Paths...), nil
+}
+
+func (f *jwtAuthFilter) Invoke(ctx Context, chain FilterChain) {
+
+	auth := ctx.Header(HeaderAuthorization)
+	if len(auth) <= len(bearerPrefix) || !strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
+		f.unauthorized(ctx, "missing bearer token")
+		return
+	}
+
+	claims, err := f.verify(auth[len(bearerPrefix):])
+	if err != nil {
+		f.unauthorized(ctx, err.Error())
+		return
+	}
+
+	p := &Principal{Claims: claims, Attributes: make(map[string]interface{})}
+	p.Subject, _ = claims["sub"].(string)
+	for attr, claim := range f.config.Claims {
+		if v, ok := claims[claim]; ok {
+			p.Attributes[attr] = v
+		}
+	}
+
+	_ = ctx.Set(PrincipalKey, p)
+	ctx.SetContext(context.WithValue(ctx.Context(), principalKey{}, p))
+	chain.Next(ctx, Iterative)
+}
+
+func (f *jwtAuthFilter) unauthorized(ctx Context, msg string) {
+	ctx.SetHeader(HeaderWWWAuthenticate, fmt.Sprintf("Bearer error=\"invalid_token\", error_description=%q", msg))
+	ctx.SetStatus(http.StatusUnauthorized)
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify 校验 token 的签名和标准 claim ，返回所有的 claim 。
+func (f *jwtAuthFilter) verify(token string) (map[string]interface{}, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if err = f.verifySignature(header, signed, sig); err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err = f.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeSegment(s string, i interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, i)
+}
+
+func hashOf(alg string) (crypto.Hash, error) {
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, nil
+	case "384":
+		return crypto.SHA384, nil
+	case "512":
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported alg %q", alg)
+	}
+}
+
+func (f *jwtAuthFilter) verifySignature(header jwtHeader, signed []byte, sig []byte) error {
+
+	if len(header.Alg) != 5 {
+		return fmt.Errorf("unsupported alg %q", header.Alg)
+	}
+
+	h, err := hashOf(header.Alg)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(header.Alg, "HS") {
+		if f.config.Secret == "" {
+			return fmt.Errorf("unsupported alg %q", header.Alg)
+		}
+		mac := hmac.New(h.New, []byte(f.config.Secret))
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	key, err := f.getKey(header.Kid)
+	if err != nil {
+		return err
+	}
+
+	hasher := h.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(header.Alg, "RS") {
+			return fmt.Errorf("alg %q mismatches key", header.Alg)
+		}
+		if rsa.VerifyPKCS1v15(k, h, digest, sig) != nil {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(header.Alg, "ES") || len(sig)%2 != 0 {
+			return fmt.Errorf("alg %q mismatches key", header.Alg)
+		}
+		n := len(sig) / 2
+		r := new(big.Int).SetBytes(sig[:n])
+		s := new(big.Int).SetBytes(sig[n:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil
+}
+
+func (f *jwtAuthFilter) verifyClaims(claims map[string]interface{}) error {
+
+	now := time.Now().Unix()
+	leeway := int64(f.config.Leeway)
+
+	exp, ok := claims["exp"].(float64)
+	if !ok && !f.config.AllowMissingExp {
+		return errors.New("token has no expiration")
+	}
+	if ok && now > int64(exp)+leeway {
+		return errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < int64(nbf)-leeway {
+		return errors.New("token is not valid yet")
+	}
+
+	if f.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != f.config.Issuer {
+			return errors.New("invalid issuer")
+		}
+	}
+
+	if f.config.Audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == f.config.Audience
+		case []interface{}:
+			for _, a := range aud {
+				if s, _ := a.(string); s == f.config.Audience {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return errors.New("invalid audience")
+		}
+	}
+	return nil
+}
+
+// getKey 获取 kid 对应的公钥，找不到时重新加载 JWKS ，但是两次加载之间至少间隔
+// interval 的时间，避免伪造的 kid 导致频繁请求 JWKS 地址。
+func (f *jwtAuthFilter) getKey(kid string) (crypto.PublicKey, error) {
+
+	if key, ok, _ := f.cachedKey(kid); ok {
+		return key, nil
+	}
+
+	if f.config.JWKSURL == "" {
+		return nil, errors.New("jwks-url isn't set")
+	}
+
+	f.fetching.Lock()
+	defer f.fetching.Unlock()
+
+	// 等待期间其他请求可能已经重新加载了 JWKS 。
+	key, ok, fetched := f.cachedKey(kid)
+	if ok {
+		return key, nil
+	}
+	if !fetched.IsZero() && time.Since(fetched) < f.interval {
+		return nil, fmt.Errorf("key %q not found", kid)
+	}
+
+	keys, err := f.loadKeys()
+
+	f.mutex.Lock()
+	f.fetched = time.Now()
+	if err == nil {
+		f.keys = keys
+	}
+	f.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if key, ok = keys[kid]; !ok {
+		return nil, fmt.Errorf("key %q not found", kid)
+	}
+	return key, nil
+}
+
+// cachedKey 返回已经加载的 kid 对应的公钥以及最近一次拉取 JWKS 的时间。
+func (f *jwtAuthFilter) cachedKey(kid string) (crypto.PublicKey, bool, time.Time) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	key, ok := f.keys[kid]
+	return key, ok, f.fetched
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadKeys 从 JWKS 地址加载所有的公钥。
+func (f *jwtAuthFilter) loadKeys() (map[string]crypto.PublicKey, error) {
+
+	resp, err := f.client.Get(f.config.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get jwks return %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			continue // 忽略不支持的公钥
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
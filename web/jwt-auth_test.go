@@ -0,0 +1,189 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-core/web"
+)
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hs256Token(secret string, claims map[string]interface{}) string {
+	s := encodeSegment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(s))
+	return s + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func invokeJWTFilter(f web.Filter, path string, token string) (web.Context, *httptest.ResponseRecorder, bool) {
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080"+path, nil)
+	if token != "" {
+		r.Header.Set(web.HeaderAuthorization, "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ctx := web.NewBaseContext(path, nil, r, &web.SimpleResponse{ResponseWriter: w})
+	called := false
+	next := web.FuncFilter(func(ctx web.Context, chain web.FilterChain) { called = true })
+	web.NewFilterChain([]web.Filter{f, next}).Next(ctx, web.Recursive)
+	return ctx, w, called
+}
+
+func TestJWTAuthFilter(t *testing.T) {
+
+	_, err := web.NewJWTAuthFilter(web.JWTAuthConfig{})
+	assert.Error(t, err, "jwks-url or secret should be set")
+
+	f, err := web.NewJWTAuthFilter(web.JWTAuthConfig{
+		Issuer:   "https://go-spring.com",
+		Audience: "api",
+		Secret:   "secret",
+		Paths:    []string{"/api/*"},
+		Claims:   map[string]string{"role": "roles"},
+	})
+	assert.Nil(t, err)
+
+	claims := map[string]interface{}{
+		"sub":   "jim",
+		"iss":   "https://go-spring.com",
+		"aud":   []string{"web", "api"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": "admin",
+	}
+
+	p, ok := f.(interface{ URLPatterns() []string })
+	assert.True(t, ok)
+	assert.Equal(t, p.URLPatterns(), []string{"/api/*"})
+
+	t.Run("missing", func(t *testing.T) {
+		_, w, called := invokeJWTFilter(f, "/api/user", "")
+		assert.False(t, called)
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+		assert.Equal(t, w.Header().Get(web.HeaderWWWAuthenticate), `Bearer error="invalid_token", error_description="missing bearer token"`)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctx, _, called := invokeJWTFilter(f, "/api/user", hs256Token("secret", claims))
+		assert.True(t, called)
+		p, ok := web.PrincipalFrom(ctx.Context())
+		assert.True(t, ok)
+		assert.Equal(t, p.Subject, "jim")
+		assert.Equal(t, p.Attributes, map[string]interface{}{"role": "admin"})
+		assert.Equal(t, ctx.Get(web.PrincipalKey), p)
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		_, w, called := invokeJWTFilter(f, "/api/user", hs256Token("error", claims))
+		assert.False(t, called)
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := map[string]interface{}{
+			"iss": "https://go-spring.com",
+			"aud": "api",
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		}
+		_, w, called := invokeJWTFilter(f, "/api/user", hs256Token("secret", expired))
+		assert.False(t, called)
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("no exp", func(t *testing.T) {
+		noExp := map[string]interface{}{"iss": "https://go-spring.com", "aud": "api"}
+		_, w, called := invokeJWTFilter(f, "/api/user", hs256Token("secret", noExp))
+		assert.False(t, called)
+		assert.Equal(t, w.Header().Get(web.HeaderWWWAuthenticate), `Bearer error="invalid_token", error_description="token has no expiration"`)
+
+		g, err := web.NewJWTAuthFilter(web.JWTAuthConfig{Secret: "secret", AllowMissingExp: true})
+		assert.Nil(t, err)
+		_, _, called = invokeJWTFilter(g, "/api/user", hs256Token("secret", noExp))
+		assert.True(t, called)
+	})
+
+	t.Run("audience", func(t *testing.T) {
+		other := map[string]interface{}{"iss": "https://go-spring.com", "aud": "web", "exp": time.Now().Add(time.Hour).Unix()}
+		_, w, called := invokeJWTFilter(f, "/api/user", hs256Token("secret", other))
+		assert.False(t, called)
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	})
+}
+
+func TestJWTAuthFilter_JWKS(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	f, err := web.NewJWTAuthFilter(web.JWTAuthConfig{JWKSURL: server.URL})
+	assert.Nil(t, err)
+
+	sign := func(kid string) string {
+		s := encodeSegment(map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(map[string]interface{}{
+			"sub": "jim",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		digest := sha256.Sum256([]byte(s))
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return s + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	ctx, _, called := invokeJWTFilter(f, "/", sign("k1"))
+	assert.True(t, called)
+	p, _ := web.PrincipalFrom(ctx.Context())
+	assert.Equal(t, p.Subject, "jim")
+
+	assert.Equal(t, atomic.LoadInt32(&fetches), int32(1))
+
+	// 最小间隔内遇到未知的 kid 不会重新拉取 JWKS 。
+	for i := 0; i < 3; i++ {
+		_, w, called := invokeJWTFilter(f, "/", sign("k2"))
+		assert.False(t, called)
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	}
+	assert.Equal(t, atomic.LoadInt32(&fetches), int32(1))
+}
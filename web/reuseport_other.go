@@ -0,0 +1,29 @@
+//go:build (!darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !linux) || (linux && mips) || (linux && mipsle) || (linux && mips64) || (linux && mips64le)
+// +build !darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!linux linux,mips linux,mipsle linux,mips64 linux,mips64le
+
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"errors"
+	"syscall"
+)
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT isn't supported on this platform")
+}
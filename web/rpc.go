@@ -28,6 +28,7 @@ import (
 
 const (
 	httpRequestKey = "::HttpRequest::"
+	rpcInvokerKey  = "::RPCInvoker::"
 )
 
 // bindHandler BIND 形式的 Web 处理接口
@@ -41,7 +42,11 @@ type bindHandler struct {
 func (b *bindHandler) Invoke(ctx Context) {
 	err := knife.Store(ctx.Context(), httpRequestKey, ctx.Request())
 	util.Panic(err).When(err != nil)
-	RPCInvoke(ctx, b.call)
+	invoke := RPCInvoke
+	if fn, ok := ctx.Get(rpcInvokerKey).(RPCInvoker); ok {
+		invoke = fn
+	}
+	invoke(ctx, b.call)
 }
 
 func (b *bindHandler) call(ctx Context) interface{} {
@@ -98,7 +103,19 @@ func GetHTTPRequest(ctx context.Context) *http.Request {
 	return r
 }
 
-// RPCInvoke 可自定义的 rpc 执行函数。
+// RPCInvoker 执行 BIND 形式的处理函数并输出返回值。
+type RPCInvoker func(ctx Context, fn func(Context) interface{})
+
+// RPCInvoke 可自定义的 rpc 执行函数，请求没有通过 InvokerFilter 设置执行函数时使用。
 var RPCInvoke = func(ctx Context, fn func(Context) interface{}) {
 	ctx.JSON(fn(ctx))
 }
+
+// InvokerFilter 返回为请求设置 rpc 执行函数的过滤器，添加到服务器之后该服务器上
+// BIND 形式的处理函数都使用 invoke 执行，不同的服务器可以使用不同的执行函数。
+func InvokerFilter(invoke RPCInvoker) Filter {
+	return FuncFilter(func(ctx Context, chain FilterChain) {
+		_ = ctx.Set(rpcInvokerKey, invoke)
+		chain.Next(ctx, Iterative)
+	})
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Addresses 返回所有的监听地址，格式为 [network://]host:port 。
+func (s *server) Addresses() []string {
+	if len(s.config.Addresses) > 0 {
+		return s.config.Addresses
+	}
+	return []string{s.Address()}
+}
+
+// splitAddress 解析 [network://]host:port 格式的监听地址。
+func splitAddress(addr string, network string) (string, string) {
+	if i := strings.Index(addr, "://"); i > 0 {
+		return addr[:i], addr[i+3:]
+	}
+	if network == "" {
+		network = "tcp"
+	}
+	return network, addr
+}
+
+// listen 在所有的监听地址上创建 net.Listener ，任何一个失败时关闭已创建的。
+func (s *server) listen() ([]net.Listener, error) {
+
+	var lc net.ListenConfig
+	if s.config.ReusePort {
+		lc.Control = reusePortControl
+	}
+
+	var listeners []net.Listener
+	for _, addr := range s.Addresses() {
+		network, address := splitAddress(addr, s.config.Network)
+		l, err := lc.Listen(context.Background(), network, address)
+		if err != nil {
+			for _, c := range listeners {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -19,9 +19,11 @@ package web
 
 import (
 	"context"
-	"fmt"
+	"net"
 	"net/http"
 	"reflect"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-spring/spring-base/cast"
@@ -44,15 +46,18 @@ type Handler interface {
 
 // ServerConfig 定义 web 服务器配置
 type ServerConfig struct {
-	Prefix       string `value:"${prefix:=}"`          // 用于 WebStarter 选择路由匹配的 Server
-	Host         string `value:"${host:=}"`            // 监听 IP
-	Port         int    `value:"${port:=8080}"`        // HTTP 端口
-	EnableSSL    bool   `value:"${ssl.enable:=false}"` // 是否启用 HTTPS
-	KeyFile      string `value:"${ssl.key:=}"`         // SSL 秘钥
-	CertFile     string `value:"${ssl.cert:=}"`        // SSL 证书
-	BasePath     string `value:"${base-path:=}"`       // 当前 Server 的所有路由都具有这个路径前缀
-	ReadTimeout  int    `value:"${read-timeout:=0}"`   // 读取超时，毫秒
-	WriteTimeout int    `value:"${write-timeout:=0}"`  // 写入超时，毫秒
+	Prefix       string   `value:"${prefix:=}"`          // 用于 WebStarter 选择路由匹配的 Server
+	Host         string   `value:"${host:=}"`            // 监听 IP
+	Port         int      `value:"${port:=8080}"`        // HTTP 端口
+	Network      string   `value:"${network:=tcp}"`      // 监听的网络类型，tcp 表示双栈，tcp4 或 tcp6 表示单栈
+	Addresses    []string `value:"${addresses:=}"`       // 多个监听地址，格式为 [network://]host:port，设置后忽略 Host 和 Port
+	ReusePort    bool     `value:"${reuse-port:=false}"` // 是否设置 SO_REUSEPORT 选项
+	EnableSSL    bool     `value:"${ssl.enable:=false}"` // 是否启用 HTTPS
+	KeyFile      string   `value:"${ssl.key:=}"`         // SSL 秘钥
+	CertFile     string   `value:"${ssl.cert:=}"`        // SSL 证书
+	BasePath     string   `value:"${base-path:=}"`       // 当前 Server 的所有路由都具有这个路径前缀
+	ReadTimeout  int      `value:"${read-timeout:=0}"`   // 读取超时，毫秒
+	WriteTimeout int      `value:"${write-timeout:=0}"`  // 写入超时，毫秒
 }
 
 // ErrorHandler 错误处理接口
@@ -79,6 +84,12 @@ type Server interface {
 	// AddFilter 添加过滤器
 	AddFilter(filter ...Filter)
 
+	// Middlewares 返回中间件列表
+	Middlewares() []Middleware
+
+	// AddMiddleware 添加中间件
+	AddMiddleware(m ...Middleware)
+
 	// AccessFilter 获取访问记录 Filter
 	AccessFilter() Filter
 
@@ -99,8 +110,15 @@ type Server interface {
 
 	// Stop 停止 web 服务器
 	Stop(ctx context.Context) error
+
+	// Conns 返回当前的连接数
+	Conns() int
 }
 
+// Middleware 标准 http.Handler 形式的中间件，包装在过滤器链条的外层，因此可以
+// 直接复用 net/http 生态中的日志、异常恢复、跨域等中间件。
+type Middleware func(next http.Handler) http.Handler
+
 type ServerHandler interface {
 	http.Handler
 	Start(s Server) error
@@ -118,9 +136,12 @@ type server struct {
 	access     Filter       // 日志过滤器
 	filters    []Filter     // 其他过滤器
 	prefilters []*Prefilter // 前置过滤器
+	middleware []Middleware // 中间件
 	errHandler ErrorHandler // 错误处理接口
 
 	swagger Swagger // Swagger根
+
+	conns int64 // 当前的连接数，只能通过 atomic 操作访问
 }
 
 // NewServer server 的构造函数
@@ -130,9 +151,9 @@ func NewServer(config ServerConfig, handler ServerHandler) *server {
 	return ret
 }
 
-// Address 返回监听地址
+// Address 返回监听地址，IPv6 地址会被方括号包围。
 func (s *server) Address() string {
-	return fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	return net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
 }
 
 // Config 获取 web 服务器配置
@@ -160,6 +181,16 @@ func (s *server) AddFilter(filter ...Filter) {
 	s.filters = append(s.filters, filter...)
 }
 
+// Middlewares 返回中间件列表
+func (s *server) Middlewares() []Middleware {
+	return s.middleware
+}
+
+// AddMiddleware 添加中间件
+func (s *server) AddMiddleware(m ...Middleware) {
+	s.middleware = append(s.middleware, m...)
+}
+
 // AccessFilter 获取访问记录 Filter
 func (s *server) AccessFilter() Filter {
 	if s.access != nil {
@@ -267,19 +298,50 @@ func (s *server) Start() (err error) {
 	if err = s.handler.Start(s); err != nil {
 		return err
 	}
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
 	s.server = &http.Server{
-		Handler:      s,
+		Handler:      s.wrapHandler(),
 		Addr:         s.Address(),
 		ReadTimeout:  time.Duration(s.config.ReadTimeout) * time.Millisecond,
 		WriteTimeout: time.Duration(s.config.WriteTimeout) * time.Millisecond,
+		ConnState:    s.trackConn,
+	}
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- s.serve(l)
+		}(l)
 	}
-	s.logger.Info("⇨ http server started on ", s.Address())
+	for range listeners {
+		if e := <-errs; err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// wrapHandler 使用中间件包装服务器，先添加的中间件位于外层。
+func (s *server) wrapHandler() http.Handler {
+	var h http.Handler = s
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	return h
+}
+
+// serve 在 l 上提供 http 服务，直到服务器停止。
+func (s *server) serve(l net.Listener) (err error) {
+	addr := l.Addr().String()
+	s.logger.Info("⇨ http server started on ", addr)
 	if !s.config.EnableSSL {
-		err = s.server.ListenAndServe()
+		err = s.server.Serve(l)
 	} else {
-		err = s.server.ListenAndServeTLS(s.config.CertFile, s.config.KeyFile)
+		err = s.server.ServeTLS(l, s.config.CertFile, s.config.KeyFile)
 	}
-	s.logger.Infof("http server stopped on %s return %s", s.Address(), cast.ToString(err))
+	s.logger.Infof("http server stopped on %s return %s", addr, cast.ToString(err))
 	return err
 }
 
@@ -288,6 +350,21 @@ func (s *server) Stop(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
+// trackConn 根据连接状态的变化统计当前的连接数。
+func (s *server) trackConn(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&s.conns, 1)
+	case http.StateHijacked, http.StateClosed:
+		atomic.AddInt64(&s.conns, -1)
+	}
+}
+
+// Conns 返回当前的连接数
+func (s *server) Conns() int {
+	return int(atomic.LoadInt64(&s.conns))
+}
+
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	prefilters := []Filter{
 		s.AccessFilter(),
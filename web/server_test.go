@@ -17,8 +17,17 @@
 package web_test
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/assert"
 	"github.com/go-spring/spring-base/log"
 	"github.com/go-spring/spring-base/util"
+	"github.com/go-spring/spring-core/web"
 )
 
 func init() {
@@ -38,3 +47,97 @@ func init() {
 	err := log.RefreshBuffer(config, ".xml")
 	util.Panic(err).When(err != nil)
 }
+
+func TestServer_Addresses(t *testing.T) {
+
+	s := web.NewServer(web.ServerConfig{Host: "::1", Port: 8080}, nil)
+	assert.Equal(t, s.Address(), "[::1]:8080")
+	assert.Equal(t, s.Addresses(), []string{"[::1]:8080"})
+
+	s = web.NewServer(web.ServerConfig{
+		Port:      8080,
+		Addresses: []string{"tcp4://127.0.0.1:8080", "tcp6://[::1]:8080"},
+	}, nil)
+	assert.Equal(t, s.Addresses(), []string{"tcp4://127.0.0.1:8080", "tcp6://[::1]:8080"})
+}
+
+func TestServer_MultiListeners(t *testing.T) {
+
+	s := web.NewHttpServer(web.ServerConfig{
+		Addresses: []string{"127.0.0.1:18081", "tcp4://127.0.0.1:18082"},
+		ReusePort: true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Start() }()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, addr := range []string{"127.0.0.1:18081", "127.0.0.1:18082"} {
+		resp, err := http.Get("http://" + addr + "/")
+		assert.Nil(t, err)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		_ = resp.Body.Close()
+	}
+
+	assert.Nil(t, s.Stop(context.Background()))
+	assert.Equal(t, <-errs, http.ErrServerClosed)
+}
+
+func TestServer_Conns(t *testing.T) {
+
+	s := web.NewHttpServer(web.ServerConfig{Host: "127.0.0.1", Port: 18083}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Start() }()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, s.Conns(), 0)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Get("http://127.0.0.1:18083/")
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, s.Conns(), 1)
+
+	client.CloseIdleConnections()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, s.Conns(), 0)
+
+	assert.Nil(t, s.Stop(context.Background()))
+	assert.Equal(t, <-errs, http.ErrServerClosed)
+}
+
+func TestServer_Middleware(t *testing.T) {
+
+	s := web.NewHttpServer(web.ServerConfig{Host: "127.0.0.1", Port: 18085}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(r.Header["X-Trace"], ",")))
+	}))
+
+	trace := func(name string) web.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Header.Add("X-Trace", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	s.AddMiddleware(trace("a"), trace("b"))
+	assert.Equal(t, len(s.Middlewares()), 2)
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Start() }()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://127.0.0.1:18085/")
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, string(b), "a,b")
+
+	assert.Nil(t, s.Stop(context.Background()))
+	assert.Equal(t, <-errs, http.ErrServerClosed)
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-base/log"
+)
+
+func init() {
+	log.RegisterPlugin("TracingLayout", log.PluginTypeLayout, (*TracingLayout)(nil))
+}
+
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+const (
+	TraceIDKey = "::trace-id::"
+	SpanIDKey  = "::span-id::"
+)
+
+// TraceContext W3C Trace Context ，参考 https://www.w3.org/TR/trace-context/ 。
+type TraceContext struct {
+	TraceID  string // 32 位十六进制字符串
+	SpanID   string // 当前请求的 span id ，16 位十六进制字符串
+	ParentID string // 上游的 span id ，没有上游时为空
+	Sampled  bool   // 是否被采样
+	State    string // tracestate 头的内容，原样传递
+}
+
+// Traceparent 返回当前请求的 traceparent 头，可以传递给下游服务。
+func (tc *TraceContext) Traceparent() string {
+	flags := 0
+	if tc.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, flags)
+}
+
+// ParseTraceparent 解析 traceparent 头，返回的 TraceContext 的 SpanID 为空。
+func ParseTraceparent(s string) (*TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return nil, errors.New("invalid traceparent")
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return nil, errors.New("invalid traceparent")
+	}
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	if !isHex(traceID, 32) || !isHex(parentID, 16) || !isHex(flags, 2) {
+		return nil, errors.New("invalid traceparent")
+	}
+	if traceID == strings.Repeat("0", 32) || parentID == strings.Repeat("0", 16) {
+		return nil, errors.New("invalid traceparent")
+	}
+	b, _ := hex.DecodeString(flags)
+	return &TraceContext{TraceID: traceID, ParentID: parentID, Sampled: b[0]&1 == 1}, nil
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n/2)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type traceKey struct{}
+
+// TraceFrom 从 context.Context 中获取当前请求的 TraceContext 。
+func TraceFrom(ctx context.Context) (*TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(*TraceContext)
+	return tc, ok
+}
+
+// TraceFields 返回 ctx 中链路信息对应的日志字段，没有链路信息时返回 nil 。
+func TraceFields(ctx context.Context) []log.Field {
+	if ctx == nil {
+		return nil
+	}
+	tc, ok := TraceFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return []log.Field{
+		log.String("trace_id", tc.TraceID),
+		log.String("span_id", tc.SpanID),
+	}
+}
+
+// TracingLayout 在 PatternLayout 的基础上输出日志上下文中的 trace id 和
+// span id ，通过 WithContext(ctx.Context()) 打印的日志都会带上链路信息，例如:
+//
+//	<Console name="Console">
+//	    <TracingLayout/>
+//	</Console>
+type TracingLayout struct {
+	log.PatternLayout
+}
+
+func (c *TracingLayout) ToBytes(e *log.Event) ([]byte, error) {
+	if fields := TraceFields(e.Context); fields != nil {
+		event := *e
+		event.Fields = append(fields, e.Fields...)
+		if event.Message != "" { // 字段和消息之间没有分隔符
+			event.Message = "||" + event.Message
+		}
+		e = &event
+	}
+	return c.PatternLayout.ToBytes(e)
+}
+
+// Span 一次请求的处理过程，被采样的请求在处理完成后发送给 SpanExporter 。
+type Span struct {
+	TraceContext
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Status     int
+	Attributes map[string]string
+}
+
+// SpanExporter 接收处理完成的 Span ，可以通过实现该接口对接 OpenTelemetry 等
+// 链路追踪系统。
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+// TracingConfig 定义链路追踪配置，一般绑定到 spring.http.tracing 前缀的属性。
+type TracingConfig struct {
+	Paths      []string `value:"${paths:=/*}"`        // 需要追踪的路径
+	SampleRate float64  `value:"${sample-rate:=1.0}"` // 没有上游 traceparent 时的采样率
+	Export     bool     `value:"${export:=false}"`    // 是否将 Span 发送给 SpanExporter
+	Response   bool     `value:"${response:=true}"`   // 是否在响应头中返回 traceparent
+}
+
+// tracingFilter 提取或者创建 W3C Trace Context 的过滤器。
+type tracingFilter struct {
+	config    TracingConfig
+	exporters []SpanExporter
+}
+
+// NewTracingFilter 创建链路追踪过滤器。过滤器从 traceparent 头中提取 Trace Context
+// ，没有时创建新的 Trace Context ，然后保存到请求的 context.Context 中，可以通过
+// TraceFrom 获取，trace id 和 span id 同时保存在 TraceIDKey 和 SpanIDKey 中供日志
+// 使用。开启 Export 时被采样的请求在处理完成后发送给 exporters 。
+func NewTracingFilter(config TracingConfig, exporters ...SpanExporter) Filter {
+	if len(config.Paths) == 0 {
+		config.Paths = []string{"/*"}
+	}
+	f := &tracingFilter{config: config, exporters: exporters}
+	return URLPatternFilter(f, config.Paths...)
+}
+
+func (f *tracingFilter) Invoke(ctx Context, chain FilterChain) {
+
+	tc, err := ParseTraceparent(ctx.Header(HeaderTraceparent))
+	if err != nil {
+		tc = &TraceContext{TraceID: randomHex(32), Sampled: f.sample()}
+	} else {
+		tc.State = ctx.Header(HeaderTracestate)
+	}
+	tc.SpanID = randomHex(16)
+
+	_ = ctx.Set(TraceIDKey, tc.TraceID)
+	_ = ctx.Set(SpanIDKey, tc.SpanID)
+	ctx.SetContext(context.WithValue(ctx.Context(), traceKey{}, tc))
+
+	if f.config.Response {
+		ctx.SetHeader(HeaderTraceparent, tc.Traceparent())
+	}
+
+	if !f.config.Export || !tc.Sampled || len(f.exporters) == 0 {
+		chain.Next(ctx, Iterative)
+		return
+	}
+
+	w := &statusWriter{ResponseWriter: ctx.Response().Get(), status: http.StatusOK}
+	ctx.Response().Set(w)
+
+	span := &Span{
+		TraceContext: *tc,
+		Name:         ctx.Request().Method + " " + ctx.Path(),
+		Start:        time.Now(),
+		Attributes: map[string]string{
+			"http.method": ctx.Request().Method,
+			"http.target": ctx.Request().URL.RequestURI(),
+		},
+	}
+	defer func() {
+		span.End = time.Now()
+		span.Status = w.status
+		for _, e := range f.exporters {
+			e.ExportSpan(span)
+		}
+	}()
+
+	chain.Next(ctx, Recursive)
+}
+
+// sample 根据采样率决定没有上游 traceparent 的请求是否被采样。
+func (f *tracingFilter) sample() bool {
+	if f.config.SampleRate >= 1 {
+		return true
+	}
+	if f.config.SampleRate <= 0 {
+		return false
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return float64(n) < f.config.SampleRate*math.MaxUint64
+}
+
+// statusWriter 记录响应的状态码。
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-base/log"
+	"github.com/go-spring/spring-core/web"
+)
+
+func init() {
+	log.RegisterPlugin("BufferAppender", log.PluginTypeAppender, (*bufferAppender)(nil))
+}
+
+var logBuffer bytes.Buffer
+
+// bufferAppender 将日志写入 logBuffer 。
+type bufferAppender struct {
+	log.BaseAppender
+}
+
+func (c *bufferAppender) Append(e *log.Event) {
+	if data, err := c.Layout.ToBytes(e); err == nil {
+		logBuffer.Write(data)
+	}
+}
+
+type spanRecorder struct {
+	spans []*web.Span
+}
+
+func (r *spanRecorder) ExportSpan(span *web.Span) {
+	r.spans = append(r.spans, span)
+}
+
+func invokeTracingFilter(f web.Filter, traceparent string) (*web.TraceContext, *httptest.ResponseRecorder) {
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/api/books?id=1", nil)
+	if traceparent != "" {
+		r.Header.Set(web.HeaderTraceparent, traceparent)
+	}
+	w := httptest.NewRecorder()
+	ctx := web.NewBaseContext("/api/books", nil, r, &web.SimpleResponse{ResponseWriter: w})
+	var tc *web.TraceContext
+	next := web.FuncFilter(func(ctx web.Context, chain web.FilterChain) {
+		tc, _ = web.TraceFrom(ctx.Context())
+		if ctx.Get(web.TraceIDKey) != tc.TraceID {
+			panic("trace id not found")
+		}
+		ctx.SetStatus(http.StatusCreated)
+	})
+	web.NewFilterChain([]web.Filter{f, next}).Next(ctx, web.Recursive)
+	return tc, w
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := web.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.Equal(t, tc.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, tc.ParentID, "00f067aa0ba902b7")
+	assert.True(t, tc.Sampled)
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xx",
+	} {
+		_, err = web.ParseTraceparent(s)
+		assert.Error(t, err, "invalid traceparent")
+	}
+}
+
+func TestTracingFilter(t *testing.T) {
+
+	t.Run("continue trace", func(t *testing.T) {
+		exporter := &spanRecorder{}
+		f := web.NewTracingFilter(web.TracingConfig{Export: true, Response: true, SampleRate: 1}, exporter)
+		tc, w := invokeTracingFilter(f, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		assert.Equal(t, tc.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+		assert.Equal(t, tc.ParentID, "00f067aa0ba902b7")
+		assert.Equal(t, len(tc.SpanID), 16)
+		assert.Equal(t, w.Header().Get(web.HeaderTraceparent), tc.Traceparent())
+		assert.Equal(t, len(exporter.spans), 1)
+		span := exporter.spans[0]
+		assert.Equal(t, span.TraceContext, *tc)
+		assert.Equal(t, span.Name, "GET /api/books")
+		assert.Equal(t, span.Status, http.StatusCreated)
+		assert.Equal(t, span.Attributes["http.target"], "/api/books?id=1")
+	})
+
+	t.Run("new trace", func(t *testing.T) {
+		exporter := &spanRecorder{}
+		f := web.NewTracingFilter(web.TracingConfig{Export: true}, exporter)
+		tc, w := invokeTracingFilter(f, "invalid")
+		assert.Equal(t, len(tc.TraceID), 32)
+		assert.Equal(t, tc.ParentID, "")
+		assert.False(t, tc.Sampled)
+		assert.Equal(t, w.Header().Get(web.HeaderTraceparent), "")
+		assert.Equal(t, len(exporter.spans), 0)
+	})
+}
+
+func TestTracingLayout(t *testing.T) {
+
+	config := `
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Configuration>
+			<Appenders>
+				<Console name="Console"/>
+				<BufferAppender name="Buffer">
+					<TracingLayout/>
+				</BufferAppender>
+			</Appenders>
+			<Loggers>
+				<Root level="info">
+					<AppenderRef ref="Console"/>
+				</Root>
+				<Logger name="web/tracing_test" level="info" additivity="false">
+					<AppenderRef ref="Buffer"/>
+				</Logger>
+			</Loggers>
+		</Configuration>
+	`
+	err := log.RefreshBuffer(config, ".xml")
+	assert.Nil(t, err)
+	defer func() {
+		config = `
+			<?xml version="1.0" encoding="UTF-8"?>
+			<Configuration>
+				<Appenders>
+					<Console name="Console"/>
+				</Appenders>
+				<Loggers>
+					<Root level="info">
+						<AppenderRef ref="Console"/>
+					</Root>
+				</Loggers>
+			</Configuration>
+		`
+		_ = log.RefreshBuffer(config, ".xml")
+	}()
+
+	logBuffer.Reset()
+	logger := log.GetLogger("web/tracing_test")
+	logger.WithContext(context.Background()).Info("no trace")
+
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/api/books", nil)
+	r.Header.Set(web.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := web.NewBaseContext("/api/books", nil, r, &web.SimpleResponse{ResponseWriter: httptest.NewRecorder()})
+	var tc *web.TraceContext
+	next := web.FuncFilter(func(ctx web.Context, chain web.FilterChain) {
+		tc, _ = web.TraceFrom(ctx.Context())
+		logger.WithContext(ctx.Context()).Info("hello")
+	})
+	f := web.NewTracingFilter(web.TracingConfig{}, nil)
+	web.NewFilterChain([]web.Filter{f, next}).Next(ctx, web.Recursive)
+
+	lines := strings.Split(strings.TrimSuffix(logBuffer.String(), "\n"), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.True(t, strings.HasSuffix(lines[0], "] no trace"))
+	expect := "] trace_id=4bf92f3577b34da6a3ce929d0e0e4736||span_id=" + tc.SpanID + "||hello"
+	assert.True(t, strings.HasSuffix(lines[1], expect), lines[1])
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"path"
+
+	"github.com/go-spring/spring-core/conf"
+)
+
+// ValidationConfig 定义请求参数校验的配置，一般绑定到 spring.http.validation
+// 前缀的属性。
+type ValidationConfig struct {
+	Exclude []string `value:"${exclude:=}"` // 不需要校验的路径，支持 path.Match 形式的模式
+}
+
+type validationKey struct{}
+
+// validationFilter 开启请求参数校验的过滤器。
+type validationFilter struct {
+	config ValidationConfig
+}
+
+// NewValidationFilter 创建请求参数校验的过滤器，开启校验后 BIND 形式的处理函数在
+// 绑定请求参数之后使用 conf.ValidateStruct 校验请求结构体，和属性绑定一样支持
+// validate 标签的规则以及 expr 标签的表达式。校验失败时返回 400 以及 RpcResult
+// 格式的错误，Data 为校验失败的字段列表，配合 CodecInvoker 使用时按照协商的编解
+// 码器输出。Exclude 中的路径不做校验。
+func NewValidationFilter(config ValidationConfig) Filter {
+	return &validationFilter{config: config}
+}
+
+func (f *validationFilter) Invoke(ctx Context, chain FilterChain) {
+
+	if f.excluded(ctx.Request().URL.Path) {
+		chain.Next(ctx, Iterative)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*conf.ValidationError)
+			if !ok {
+				panic(r)
+			}
+			ctx.SetStatus(http.StatusBadRequest)
+			ctx.JSON(ValidationResult(e))
+		}
+	}()
+
+	ctx.SetContext(context.WithValue(ctx.Context(), validationKey{}, true))
+	chain.Next(ctx, Recursive)
+}
+
+func (f *validationFilter) excluded(urlPath string) bool {
+	for _, pattern := range f.config.Exclude {
+		if ok, _ := path.Match(pattern, urlPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationResult 返回校验失败时输出的 RpcResult 。
+func ValidationResult(e *conf.ValidationError) *RpcResult {
+	return &RpcResult{
+		ErrorCode: NewErrorCode(http.StatusBadRequest, "validation failed"),
+		Err:       e.Error(),
+		Data:      e.Fields,
+	}
+}
+
+// validationEnabled 返回当前请求是否开启了参数校验。
+func validationEnabled(ctx Context) bool {
+	enabled, _ := ctx.Context().Value(validationKey{}).(bool)
+	return enabled
+}
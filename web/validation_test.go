@@ -0,0 +1,98 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/assert"
+	"github.com/go-spring/spring-base/knife"
+	"github.com/go-spring/spring-core/web"
+)
+
+type createUserRequest struct {
+	Name string `json:"name" validate:"required"`
+	Age  int    `json:"age" expr:"$>=18"`
+}
+
+func TestValidationFilter(t *testing.T) {
+
+	filter := web.NewValidationFilter(web.ValidationConfig{Exclude: []string{"/admin/*"}})
+	handler := web.BIND(func(ctx context.Context, req *createUserRequest) *web.RpcResult {
+		return web.SUCCESS.Data(req.Name)
+	})
+
+	serve := func(path string, body string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080"+path, strings.NewReader(body))
+		r.Header.Set(web.HeaderContentType, web.MIMEApplicationJSON)
+		ctx, _ := knife.New(r.Context())
+		r = r.WithContext(ctx)
+		w := httptest.NewRecorder()
+		webCtx := web.NewBaseContext(path, handler, r, &web.SimpleResponse{ResponseWriter: w})
+		filters := []web.Filter{filter, web.HandlerFilter(handler)}
+		web.NewFilterChain(filters).Next(webCtx, web.Recursive)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		w := serve("/users", `{"name":"jim","age":20}`)
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), `{"code":200,"msg":"SUCCESS","data":"jim"}`)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		w := serve("/users", `{"age":16}`)
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		body := w.Body.String()
+		assert.True(t, strings.HasPrefix(body, `{"code":400,"msg":"validation failed"`))
+		assert.True(t, strings.Contains(body, `{"field":"name","rule":"required"`))
+		assert.True(t, strings.Contains(body, `{"field":"age","rule":"$\u003e=18"`))
+	})
+
+	t.Run("excluded", func(t *testing.T) {
+		w := serve("/admin/users", `{"age":16}`)
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), `{"code":200,"msg":"SUCCESS","data":""}`)
+	})
+}
+
+func TestCodecInvoker_Validation(t *testing.T) {
+
+	invoker, err := web.NewCodecInvoker(web.CodecConfig{})
+	assert.Nil(t, err)
+
+	filter := web.NewValidationFilter(web.ValidationConfig{})
+	r, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/users", strings.NewReader(`{"age":20}`))
+	r.Header.Set(web.HeaderContentType, web.MIMEApplicationJSON)
+	w := httptest.NewRecorder()
+	ctx := web.NewBaseContext("/users", nil, r, &web.SimpleResponse{ResponseWriter: w})
+
+	chain := web.NewFilterChain([]web.Filter{filter, web.FuncFilter(func(ctx web.Context, _ web.FilterChain) {
+		invoker.Invoke(ctx, func(ctx web.Context) interface{} {
+			var req createUserRequest
+			return ctx.Bind(&req)
+		})
+	})})
+	chain.Next(ctx, web.Recursive)
+
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.True(t, strings.Contains(w.Body.String(), `"data":[{"field":"name","rule":"required"`))
+}